@@ -0,0 +1,263 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// ErrBulkAborted is the error of items that were not started because an
+// earlier item failed and BulkOptions.FailFast is set.
+var ErrBulkAborted = errors.New("bulk operation aborted after an earlier failure")
+
+const defaultBulkConcurrency = 4
+
+// BulkOptions configures BulkCreate, BulkUpdate and BulkDelete.
+type BulkOptions[TResource any] struct {
+	// Concurrency is the maximum number of items processed at the same time.
+	// Defaults to 4. Requests are additionally subject to the client's
+	// RateLimiter, if set.
+	Concurrency int
+
+	// WaitFor, if set, makes BulkCreate and BulkUpdate wait for each resource
+	// to meet the condition before the item counts as done, e.g.
+	// ServerIsRunning.
+	WaitFor Condition[TResource]
+
+	// WaitForDeletion makes BulkDelete wait until each resource is gone.
+	WaitForDeletion bool
+
+	// RetryOptions are passed to the waits, e.g. backoff.WithMaxElapsedTime.
+	RetryOptions []backoff.RetryOption
+
+	// FailFast stops starting new items after the first failure. Items not
+	// started fail with ErrBulkAborted; items in flight are canceled.
+	FailFast bool
+}
+
+// BulkResult is the outcome of one item of a bulk operation. Results are
+// returned in the order of the input.
+type BulkResult[TResource any] struct {
+	// ID identifies the resource, see ResourceID. It is empty for items of
+	// BulkCreate that failed before the resource was created.
+	ID string
+	// Resource is the created resource for BulkCreate, or the resource as
+	// last seen by WaitFor. It is nil otherwise.
+	Resource *TResource
+	Err      error
+}
+
+// BulkItemError is the error of a single failed item, as joined into the
+// error returned by the bulk operations.
+type BulkItemError struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+func (e *BulkItemError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("item %d (%s): %v", e.Index, e.ID, e.Err)
+}
+
+func (e *BulkItemError) Unwrap() error {
+	return e.Err
+}
+
+// BulkUpdateItem is an update of one resource for BulkUpdate. The same
+// request may be used for several items.
+type BulkUpdateItem[TUpdateRequest any] struct {
+	ID      string
+	Request *TUpdateRequest
+}
+
+// BulkCreateService is implemented by services supported by BulkCreate.
+type BulkCreateService[TResource any, TCreateRequest any] interface {
+	GenericCreateService[TResource, TCreateRequest]
+	GenericWaitForService[TResource]
+}
+
+// BulkUpdateService is implemented by services supported by BulkUpdate.
+type BulkUpdateService[TResource any, TUpdateRequest any] interface {
+	GenericUpdateService[TResource, TUpdateRequest]
+	GenericWaitForService[TResource]
+}
+
+// BulkDeleteService is implemented by services supported by BulkDelete.
+type BulkDeleteService[TResource any] interface {
+	GenericGetService[TResource]
+	GenericDeleteService[TResource]
+}
+
+// BulkCreate creates a resource for every request, at most
+// options.Concurrency at a time. It returns one result per request and the
+// joined *BulkItemError of all failed items, or nil if all succeeded.
+func BulkCreate[TResource any, TCreateRequest any](
+	ctx context.Context,
+	service BulkCreateService[TResource, TCreateRequest],
+	requests []*TCreateRequest,
+	options BulkOptions[TResource],
+) ([]BulkResult[TResource], error) {
+	return runBulk(ctx, len(requests), options, func(ctx context.Context, index int, result *BulkResult[TResource]) error {
+		resource, err := service.Create(ctx, requests[index])
+		if err != nil {
+			return err
+		}
+		result.ID = ResourceID(resource)
+		result.Resource = resource
+		if options.WaitFor == nil {
+			return nil
+		}
+		resource, err = service.WaitFor(ctx, result.ID, options.WaitFor, options.RetryOptions...)
+		if resource != nil {
+			result.Resource = resource
+		}
+		return err
+	})
+}
+
+// BulkUpdate applies every update, at most options.Concurrency at a time.
+// It returns one result per item and the joined *BulkItemError of all failed
+// items, or nil if all succeeded.
+func BulkUpdate[TResource any, TUpdateRequest any](
+	ctx context.Context,
+	service BulkUpdateService[TResource, TUpdateRequest],
+	updates []BulkUpdateItem[TUpdateRequest],
+	options BulkOptions[TResource],
+) ([]BulkResult[TResource], error) {
+	return runBulk(ctx, len(updates), options, func(ctx context.Context, index int, result *BulkResult[TResource]) error {
+		result.ID = updates[index].ID
+		if err := service.Update(ctx, result.ID, updates[index].Request); err != nil {
+			return err
+		}
+		if options.WaitFor == nil {
+			return nil
+		}
+		resource, err := service.WaitFor(ctx, result.ID, options.WaitFor, options.RetryOptions...)
+		result.Resource = resource
+		return err
+	})
+}
+
+// BulkDelete deletes the resources with the given IDs, at most
+// options.Concurrency at a time. Resources that are already gone count as
+// deleted. It returns one result per ID and the joined *BulkItemError of all
+// failed items, or nil if all succeeded.
+func BulkDelete[TResource any](
+	ctx context.Context,
+	service BulkDeleteService[TResource],
+	ids []string,
+	options BulkOptions[TResource],
+) ([]BulkResult[TResource], error) {
+	return runBulk(ctx, len(ids), options, func(ctx context.Context, index int, result *BulkResult[TResource]) error {
+		result.ID = ids[index]
+		if err := service.Delete(ctx, result.ID); err != nil && !IsNotFound(err) {
+			return err
+		}
+		if !options.WaitForDeletion {
+			return nil
+		}
+		return WaitForDeletion(ctx, service, result.ID, options.RetryOptions...)
+	})
+}
+
+// runBulk calls do for count items with bounded parallelism. Canceling ctx
+// stops starting new items and cancels the requests in flight, including
+// those waiting for the client's RateLimiter.
+func runBulk[TResource any](
+	ctx context.Context,
+	count int,
+	options BulkOptions[TResource],
+	do func(ctx context.Context, index int, result *BulkResult[TResource]) error,
+) ([]BulkResult[TResource], error) {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]BulkResult[TResource], count)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for index := range results {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[index].Err = bulkSkipped(ctx)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result := &results[index]
+			if err := do(ctx, index, result); err != nil {
+				result.Err = err
+				if options.FailFast {
+					cancel(ErrBulkAborted)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	errs := []error{}
+	for index, result := range results {
+		if result.Err != nil {
+			errs = append(errs, &BulkItemError{Index: index, ID: result.ID, Err: result.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// bulkSkipped returns the error of an item that was not started: ctx's own
+// error if the caller canceled it, ErrBulkAborted after a failure.
+func bulkSkipped(ctx context.Context) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrBulkAborted) {
+		return ErrBulkAborted
+	}
+	return ctx.Err()
+}
+
+// IsNotFound reports whether err is an *ErrorResponse with status 404, e.g.
+// for a resource that was deleted.
+func IsNotFound(err error) bool {
+	var errorResponse *ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.StatusCode == http.StatusNotFound
+}
+
+// WaitForDeletion polls the resource with the given ID until the API reports
+// it as not found. By default, it polls every 2 seconds for up to 5 minutes;
+// opts are applied on top, like the options of WaitFor.
+func WaitForDeletion[TResource any](ctx context.Context, service GenericGetService[TResource], id string, opts ...backoff.RetryOption) error {
+	options := append([]backoff.RetryOption{
+		backoff.WithBackOff(backoff.NewConstantBackOff(2 * time.Second)),
+		backoff.WithMaxElapsedTime(5 * time.Minute),
+	}, opts...)
+
+	_, err := backoff.Retry(ctx, func() (struct{}, error) {
+		_, err := service.Get(ctx, id)
+		switch {
+		case IsNotFound(err):
+			return struct{}{}, nil
+		case err != nil:
+			return struct{}{}, err
+		}
+		return struct{}{}, errors.New("resource still exists")
+	}, options...)
+	return err
+}
@@ -0,0 +1,204 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// concurrencyProbe records the highest number of requests in flight.
+type concurrencyProbe struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (p *concurrencyProbe) enter() func() {
+	current := p.inFlight.Add(1)
+	for {
+		peak := p.peak.Load()
+		if current <= peak || p.peak.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return func() { p.inFlight.Add(-1) }
+}
+
+func TestBulkDelete(t *testing.T) {
+	setup()
+	defer teardown()
+
+	probe := &concurrencyProbe{}
+	var mu sync.Mutex
+	deleted := map[string]bool{}
+	mux.HandleFunc("/v1/volumes/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/volumes/")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case id == "missing" || (r.Method == http.MethodGet && deleted[id]):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"detail": "Not found."}`)
+		case id == "locked":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"detail": "Volume is attached."}`)
+		case r.Method == http.MethodDelete:
+			mu.Unlock()
+			probe.enter()()
+			mu.Lock()
+			deleted[id] = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			fmt.Fprintf(w, `{"uuid": %q}`, id)
+		}
+	})
+
+	ids := []string{"a", "b", "locked", "c", "missing", "d"}
+	results, err := BulkDelete(ctx, client.Volumes, ids, BulkOptions[Volume]{
+		Concurrency:     2,
+		WaitForDeletion: true,
+		RetryOptions:    []backoff.RetryOption{backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond))},
+	})
+
+	var itemErr *BulkItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 2 || itemErr.ID != "locked" {
+		t.Fatalf("expected the locked volume to fail, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "item 2 (locked): detail: Volume is attached.") {
+		t.Errorf("unexpected error message %q", err)
+	}
+	for index, result := range results {
+		if result.ID != ids[index] {
+			t.Errorf("result %d: expected ID %s, got %s", index, ids[index], result.ID)
+		}
+		if (result.Err != nil) != (ids[index] == "locked") {
+			t.Errorf("result %d: unexpected error %v", index, result.Err)
+		}
+	}
+	if peak := probe.peak.Load(); peak > 2 {
+		t.Errorf("expected at most 2 concurrent deletions, got %d", peak)
+	}
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	setup()
+	defer teardown()
+
+	polls := 0
+	mux.HandleFunc("/v1/volumes/a", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			fmt.Fprint(w, `{"uuid": "a"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"detail": "Not found."}`)
+	})
+
+	err := WaitForDeletion(ctx, client.Volumes, "a", backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)))
+	if err != nil || polls != 3 {
+		t.Errorf("expected to poll until the volume is gone, got %d polls: %v", polls, err)
+	}
+
+	_, err = client.Volumes.Get(ctx, "a")
+	if !IsNotFound(err) || IsNotFound(errors.New("not found")) {
+		t.Errorf("expected only a 404 response to be not found, got %v", err)
+	}
+}
+
+func TestBulkCreate_WaitFor(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var created atomic.Int32
+	mux.HandleFunc("/v1/servers", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodPost)
+		fmt.Fprintf(w, `{"uuid": "s%d", "status": "changing"}`, created.Add(1))
+	})
+	mux.HandleFunc("/v1/servers/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"uuid": %q, "status": "running"}`, strings.TrimPrefix(r.URL.Path, "/v1/servers/"))
+	})
+
+	requests := []*ServerRequest{{Name: "one"}, {Name: "two"}, {Name: "three"}}
+	results, err := BulkCreate(ctx, client.Servers, requests, BulkOptions[Server]{WaitFor: ServerIsRunning})
+	if err != nil {
+		t.Fatalf("BulkCreate returned error: %v", err)
+	}
+	for index, result := range results {
+		if result.ID == "" || result.Resource == nil || result.Resource.Status != ServerRunning {
+			t.Errorf("result %d: expected a running server, got %+v", index, result)
+		}
+	}
+}
+
+func TestBulkUpdate_FailFast(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var updates atomic.Int32
+	mux.HandleFunc("/v1/volumes/", func(w http.ResponseWriter, r *http.Request) {
+		updates.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"detail": "boom"}`)
+	})
+
+	request := &VolumeUpdateRequest{SizeGB: 100}
+	items := []BulkUpdateItem[VolumeUpdateRequest]{}
+	for i := 0; i < 10; i++ {
+		items = append(items, BulkUpdateItem[VolumeUpdateRequest]{ID: fmt.Sprintf("v%d", i), Request: request})
+	}
+
+	results, err := BulkUpdate(ctx, client.Volumes, items, BulkOptions[Volume]{Concurrency: 1, FailFast: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := updates.Load(); n != 1 {
+		t.Errorf("expected a single update to be attempted, got %d", n)
+	}
+	if !errors.Is(results[9].Err, ErrBulkAborted) || !errors.Is(err, ErrBulkAborted) {
+		t.Errorf("expected remaining items to be aborted, got %v", results[9].Err)
+	}
+}
+
+// blockingLimiter never grants a request.
+type blockingLimiter struct {
+	waiting chan struct{}
+}
+
+func (l blockingLimiter) Wait(ctx context.Context) error {
+	l.waiting <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBulkDelete_CancelWhileRateLimited(t *testing.T) {
+	setup()
+	defer teardown()
+
+	limiter := blockingLimiter{waiting: make(chan struct{}, 10)}
+	client.RateLimiter = limiter
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-limiter.waiting
+		cancel()
+	}()
+
+	results, err := BulkDelete(ctx, client.Servers, []string{"a", "b", "c"}, BulkOptions[Server]{Concurrency: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	for index, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("result %d: expected context.Canceled, got %v", index, result.Err)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/internal/fakeapi"
+)
+
+func uuids[TResource any](resources []TResource) []string {
+	ids := []string{}
+	for i := range resources {
+		ids = append(ids, cloudscale.ResourceID(&resources[i]))
+	}
+	return ids
+}
+
+func assertUUIDs[TResource any](t *testing.T, name string, resources []TResource, expected ...string) {
+	t.Helper()
+	if expected == nil {
+		expected = []string{}
+	}
+	if actual := uuids(resources); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("%s: expected %v, got %v", name, expected, actual)
+	}
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStore_Indexes(t *testing.T) {
+	store := NewStore(Indexers[cloudscale.Volume]{
+		IndexZone: func(v *cloudscale.Volume) []string { return []string{v.Zone.Slug} },
+	})
+
+	store.Set("a", &cloudscale.Volume{UUID: "a", ZonalResource: cloudscale.ZonalResource{Zone: cloudscale.ZoneStub{Slug: "rma1"}}})
+	store.Set("b", &cloudscale.Volume{UUID: "b", ZonalResource: cloudscale.ZonalResource{Zone: cloudscale.ZoneStub{Slug: "rma1"}}})
+	old, replaced := store.Set("a", &cloudscale.Volume{UUID: "a", ZonalResource: cloudscale.ZonalResource{Zone: cloudscale.ZoneStub{Slug: "lpg1"}}})
+	if !replaced || old.Zone.Slug != "rma1" {
+		t.Errorf("expected the previous volume to be returned, got %+v", old)
+	}
+
+	rma1, err := store.ByIndex(IndexZone, "rma1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUUIDs(t, "rma1", rma1, "b")
+	if values := store.IndexValues(IndexZone); !reflect.DeepEqual(values, []string{"lpg1", "rma1"}) {
+		t.Errorf("expected both zones, got %v", values)
+	}
+
+	store.Delete("b")
+	if values := store.IndexValues(IndexZone); !reflect.DeepEqual(values, []string{"lpg1"}) {
+		t.Errorf("expected empty index values to be dropped, got %v", values)
+	}
+	if _, err := store.ByIndex("missing", "x"); err == nil {
+		t.Error("expected an error for an unknown index")
+	}
+}
+
+func TestFactory_Listers(t *testing.T) {
+	client, _ := fakeapi.NewClient(t, map[string]string{
+		"v1/servers": `[
+			{"uuid": "s1", "zone": {"slug": "rma1"}, "tags": {"env": "prod"},
+			 "interfaces": [{"type": "private", "network": {"uuid": "n1"}}, {"type": "public"}],
+			 "server_groups": [{"uuid": "g1"}]},
+			{"uuid": "s2", "zone": {"slug": "lpg1"}, "tags": {"env": "dev"},
+			 "interfaces": [{"type": "private", "network": {"uuid": "n1"}}]}
+		]`,
+		"v1/volumes": `[
+			{"uuid": "v1", "zone": {"slug": "rma1"}, "server_uuids": ["s1"]},
+			{"uuid": "v2", "zone": {"slug": "rma1"}, "server_uuids": []}
+		]`,
+		"v1/load-balancers": `[
+			{"uuid": "l1", "zone": {"slug": "lpg1"}, "tags": {"team": "web"},
+			 "vip_addresses": [{"address": "10.0.0.5", "subnet": {"uuid": "sn1"}}]}
+		]`,
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	factory := NewFactory(client, cloudscale.WatchOptions{Interval: time.Hour})
+	servers := factory.Servers().Lister()
+	volumes := factory.Volumes().Lister()
+	loadBalancers := factory.LoadBalancers().Lister()
+	factory.Start(ctx)
+	if !factory.WaitForCacheSync(ctx) {
+		t.Fatal("expected caches to sync")
+	}
+
+	assertUUIDs(t, "servers", servers.List(), "s1", "s2")
+	assertUUIDs(t, "servers by zone", servers.ByZone("rma1"), "s1")
+	assertUUIDs(t, "servers by tag", servers.ByTag("env", "dev"), "s2")
+	assertUUIDs(t, "servers by tag key", servers.ByTagKey("env"), "s1", "s2")
+	assertUUIDs(t, "servers by network", servers.ByNetwork("n1"), "s1", "s2")
+	assertUUIDs(t, "servers by server group", servers.ByServerGroup("g1"), "s1")
+	assertUUIDs(t, "servers selected", servers.Select(cloudscale.MustParseTagSelector("env!=prod")), "s2")
+	assertUUIDs(t, "volumes by server", volumes.ByServer("s1"), "v1")
+	assertUUIDs(t, "volumes by zone", volumes.ByZone("rma1"), "v1", "v2")
+	assertUUIDs(t, "load balancers by subnet", loadBalancers.BySubnet("sn1"), "l1")
+	assertUUIDs(t, "load balancers by tag", loadBalancers.ByTag("team", "web"), "l1")
+	assertUUIDs(t, "load balancers by missing tag", loadBalancers.ByTag("team", "db"))
+	assertUUIDs(t, "load balancers by tag key", loadBalancers.ByTagKey("team=web"))
+
+	if server, ok := servers.Get("s2"); !ok || server.Zone.Slug != "lpg1" {
+		t.Errorf("expected s2 to be cached, got %+v", server)
+	}
+	if factory.Servers().Informer != factory.Servers().Informer {
+		t.Error("expected the informer to be shared")
+	}
+}
+
+func TestInformer_Handlers(t *testing.T) {
+	client, api := fakeapi.NewClient(t, map[string]string{
+		"v1/servers": `[{"uuid": "s1", "status": "running"}, {"uuid": "s2", "status": "running"}]`,
+	}, nil)
+
+	var mu sync.Mutex
+	events := []string{}
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+	recorded := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	informer := NewInformer[cloudscale.Server](client.Servers, cloudscale.WatchOptions{Interval: time.Millisecond}, nil)
+	informer.AddEventHandler(HandlerFuncs[cloudscale.Server]{
+		AddFunc: func(s *cloudscale.Server) { record("add %s", s.UUID) },
+		UpdateFunc: func(old, new *cloudscale.Server) {
+			record("update %s %s->%s", new.UUID, old.Status, new.Status)
+		},
+		DeleteFunc: func(s *cloudscale.Server) { record("delete %s", s.UUID) },
+	})
+	go informer.Run(ctx)
+	if !informer.WaitForSync(ctx) {
+		t.Fatal("expected informer to sync")
+	}
+
+	api.Set("v1/servers", `[{"uuid": "s1", "status": "stopped"}]`)
+	eventually(t, func() bool { return len(recorded()) >= 4 })
+
+	expected := []string{"add s1", "add s2", "update s1 running->stopped", "delete s2"}
+	if actual := recorded(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	assertUUIDs(t, "servers", informer.Lister().List(), "s1")
+
+	late := []string{}
+	informer.AddEventHandler(HandlerFuncs[cloudscale.Server]{
+		AddFunc: func(s *cloudscale.Server) { late = append(late, s.UUID) },
+	})
+	if !reflect.DeepEqual(late, []string{"s1"}) {
+		t.Errorf("expected late handler to be replayed the cache, got %v", late)
+	}
+}
+
+func TestInformer_HandlersAddHandlers(t *testing.T) {
+	client, _ := fakeapi.NewClient(t, map[string]string{
+		"v1/servers": `[{"uuid": "s1"}]`,
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	informer := NewInformer[cloudscale.Server](client.Servers, cloudscale.WatchOptions{Interval: time.Hour}, nil)
+	added := make(chan string, 10)
+	informer.AddEventHandler(HandlerFuncs[cloudscale.Server]{
+		AddFunc: func(s *cloudscale.Server) {
+			informer.SetErrorHandler(func(error) {})
+			informer.AddEventHandler(HandlerFuncs[cloudscale.Server]{
+				AddFunc: func(s *cloudscale.Server) { added <- s.UUID },
+			})
+		},
+	})
+	go informer.Run(ctx)
+
+	select {
+	case uuid := <-added:
+		if uuid != "s1" {
+			t.Errorf("expected s1 to be added, got %s", uuid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler added by a handler to be called")
+	}
+	if !informer.WaitForSync(ctx) {
+		t.Fatal("expected informer to sync")
+	}
+	select {
+	case uuid := <-added:
+		t.Errorf("expected s1 to be added once, got %s again", uuid)
+	default:
+	}
+}
+
+func TestInformer_RetriesInitialList(t *testing.T) {
+	client, api := fakeapi.NewClient(t, map[string]string{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	informer := NewInformer[cloudscale.Volume](client.Volumes, cloudscale.WatchOptions{
+		Interval: time.Hour,
+		BackOff:  backoff.NewConstantBackOff(time.Millisecond),
+	}, nil)
+	errs := make(chan error, 100)
+	informer.SetErrorHandler(func(err error) { errs <- err })
+	go informer.Run(ctx)
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the failed list to be reported")
+	}
+	if informer.HasSynced() {
+		t.Error("expected informer not to be synced after a failed list")
+	}
+
+	api.Set("v1/volumes", `[{"uuid": "v1"}]`)
+	syncCtx, syncCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer syncCancel()
+	if !informer.WaitForSync(syncCtx) {
+		t.Fatal("expected informer to sync once listing succeeds")
+	}
+	assertUUIDs(t, "volumes", informer.Lister().List(), "v1")
+}
@@ -0,0 +1,347 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Names of the indexes maintained by the informers of a Factory.
+const (
+	// IndexZone indexes resources by the slug of their zone.
+	IndexZone = "zone"
+	// IndexTag indexes resources by TagIndexValue(key, value) for every tag.
+	IndexTag = "tag"
+	// IndexTagKey indexes resources by the key of every tag.
+	IndexTagKey = "tag-key"
+	// IndexNetwork indexes servers by the UUIDs of the networks they have an
+	// interface in.
+	IndexNetwork = "network"
+	// IndexServerGroup indexes servers by the UUIDs of their server groups.
+	IndexServerGroup = "server-group"
+	// IndexServer indexes volumes by the UUIDs of the servers they are
+	// attached to.
+	IndexServer = "server"
+	// IndexSubnet indexes load balancers by the UUIDs of the subnets of their
+	// VIP addresses.
+	IndexSubnet = "subnet"
+)
+
+// Factory shares one informer per resource type, so that all consumers of a
+// process are served from the same cache and poll the API only once.
+type Factory struct {
+	client  *cloudscale.Client
+	options cloudscale.WatchOptions
+
+	mu            sync.Mutex
+	servers       *Informer[cloudscale.Server]
+	volumes       *Informer[cloudscale.Volume]
+	loadBalancers *Informer[cloudscale.LoadBalancer]
+	started       map[any]bool
+}
+
+// NewFactory creates a factory whose informers watch with options. As the
+// informers run concurrently, options.BackOff is ignored and each informer
+// uses its own default back-off.
+func NewFactory(client *cloudscale.Client, options cloudscale.WatchOptions) *Factory {
+	options.BackOff = nil
+	return &Factory{
+		client:  client,
+		options: options,
+		started: map[any]bool{},
+	}
+}
+
+// Servers returns the shared server informer.
+func (f *Factory) Servers() *ServerInformer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.servers == nil {
+		f.servers = NewInformer[cloudscale.Server](f.client.Servers, f.options, Indexers[cloudscale.Server]{
+			IndexZone:        func(s *cloudscale.Server) []string { return []string{s.Zone.Slug} },
+			IndexTag:         func(s *cloudscale.Server) []string { return tagValues(s.Tags) },
+			IndexTagKey:      func(s *cloudscale.Server) []string { return tagKeys(s.Tags) },
+			IndexNetwork:     serverNetworks,
+			IndexServerGroup: serverGroups,
+		})
+	}
+	return &ServerInformer{f.servers}
+}
+
+// Volumes returns the shared volume informer.
+func (f *Factory) Volumes() *VolumeInformer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.volumes == nil {
+		f.volumes = NewInformer[cloudscale.Volume](f.client.Volumes, f.options, Indexers[cloudscale.Volume]{
+			IndexZone:   func(v *cloudscale.Volume) []string { return []string{v.Zone.Slug} },
+			IndexTag:    func(v *cloudscale.Volume) []string { return tagValues(v.Tags) },
+			IndexTagKey: func(v *cloudscale.Volume) []string { return tagKeys(v.Tags) },
+			IndexServer: func(v *cloudscale.Volume) []string {
+				if v.ServerUUIDs == nil {
+					return nil
+				}
+				return *v.ServerUUIDs
+			},
+		})
+	}
+	return &VolumeInformer{f.volumes}
+}
+
+// LoadBalancers returns the shared load balancer informer.
+func (f *Factory) LoadBalancers() *LoadBalancerInformer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.loadBalancers == nil {
+		f.loadBalancers = NewInformer[cloudscale.LoadBalancer](f.client.LoadBalancers, f.options, Indexers[cloudscale.LoadBalancer]{
+			IndexZone:   func(l *cloudscale.LoadBalancer) []string { return []string{l.Zone.Slug} },
+			IndexTag:    func(l *cloudscale.LoadBalancer) []string { return tagValues(l.Tags) },
+			IndexTagKey: func(l *cloudscale.LoadBalancer) []string { return tagKeys(l.Tags) },
+			IndexSubnet: loadBalancerSubnets,
+		})
+	}
+	return &LoadBalancerInformer{f.loadBalancers}
+}
+
+// Start runs every informer requested so far in its own goroutine until ctx
+// is done. It may be called again after requesting further informers.
+func (f *Factory) Start(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	start := func(key any, run func(ctx context.Context)) {
+		if !f.started[key] {
+			f.started[key] = true
+			go run(ctx)
+		}
+	}
+	if f.servers != nil {
+		start(f.servers, f.servers.Run)
+	}
+	if f.volumes != nil {
+		start(f.volumes, f.volumes.Run)
+	}
+	if f.loadBalancers != nil {
+		start(f.loadBalancers, f.loadBalancers.Run)
+	}
+}
+
+// WaitForCacheSync blocks until all started informers synced or ctx is done,
+// and reports whether they all synced.
+func (f *Factory) WaitForCacheSync(ctx context.Context) bool {
+	f.mu.Lock()
+	waits := []func(ctx context.Context) bool{}
+	if f.servers != nil && f.started[f.servers] {
+		waits = append(waits, f.servers.WaitForSync)
+	}
+	if f.volumes != nil && f.started[f.volumes] {
+		waits = append(waits, f.volumes.WaitForSync)
+	}
+	if f.loadBalancers != nil && f.started[f.loadBalancers] {
+		waits = append(waits, f.loadBalancers.WaitForSync)
+	}
+	f.mu.Unlock()
+
+	for _, wait := range waits {
+		if !wait(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// ServerInformer is the informer for servers, with a typed lister.
+type ServerInformer struct {
+	*Informer[cloudscale.Server]
+}
+
+// Lister returns a ServerLister reading from the cache.
+func (i *ServerInformer) Lister() ServerLister {
+	return ServerLister{i.Informer.Lister()}
+}
+
+// ServerLister looks up cached servers.
+type ServerLister struct {
+	Lister[cloudscale.Server]
+}
+
+// ByZone returns the servers in the zone with the given slug.
+func (l ServerLister) ByZone(zone string) []cloudscale.Server {
+	return l.mustByIndex(IndexZone, zone)
+}
+
+// ByTag returns the servers tagged with key=value.
+func (l ServerLister) ByTag(key, value string) []cloudscale.Server {
+	return l.mustByIndex(IndexTag, TagIndexValue(key, value))
+}
+
+// ByTagKey returns the servers that have the tag key, whatever its value.
+func (l ServerLister) ByTagKey(key string) []cloudscale.Server {
+	return l.mustByIndex(IndexTagKey, key)
+}
+
+// ByNetwork returns the servers with an interface in the network.
+func (l ServerLister) ByNetwork(networkUUID string) []cloudscale.Server {
+	return l.mustByIndex(IndexNetwork, networkUUID)
+}
+
+// ByServerGroup returns the members of the server group.
+func (l ServerLister) ByServerGroup(serverGroupUUID string) []cloudscale.Server {
+	return l.mustByIndex(IndexServerGroup, serverGroupUUID)
+}
+
+// mustByIndex panics if the index does not exist, i.e. if the lister was not
+// created by a Factory.
+func (l ServerLister) mustByIndex(indexName, value string) []cloudscale.Server {
+	servers, err := l.ByIndex(indexName, value)
+	if err != nil {
+		panic(err)
+	}
+	return servers
+}
+
+// VolumeInformer is the informer for volumes, with a typed lister.
+type VolumeInformer struct {
+	*Informer[cloudscale.Volume]
+}
+
+// Lister returns a VolumeLister reading from the cache.
+func (i *VolumeInformer) Lister() VolumeLister {
+	return VolumeLister{i.Informer.Lister()}
+}
+
+// VolumeLister looks up cached volumes.
+type VolumeLister struct {
+	Lister[cloudscale.Volume]
+}
+
+// ByZone returns the volumes in the zone with the given slug.
+func (l VolumeLister) ByZone(zone string) []cloudscale.Volume {
+	return l.mustByIndex(IndexZone, zone)
+}
+
+// ByTag returns the volumes tagged with key=value.
+func (l VolumeLister) ByTag(key, value string) []cloudscale.Volume {
+	return l.mustByIndex(IndexTag, TagIndexValue(key, value))
+}
+
+// ByTagKey returns the volumes that have the tag key, whatever its value.
+func (l VolumeLister) ByTagKey(key string) []cloudscale.Volume {
+	return l.mustByIndex(IndexTagKey, key)
+}
+
+// ByServer returns the volumes attached to the server.
+func (l VolumeLister) ByServer(serverUUID string) []cloudscale.Volume {
+	return l.mustByIndex(IndexServer, serverUUID)
+}
+
+// mustByIndex panics if the index does not exist, i.e. if the lister was not
+// created by a Factory.
+func (l VolumeLister) mustByIndex(indexName, value string) []cloudscale.Volume {
+	volumes, err := l.ByIndex(indexName, value)
+	if err != nil {
+		panic(err)
+	}
+	return volumes
+}
+
+// LoadBalancerInformer is the informer for load balancers, with a typed
+// lister.
+type LoadBalancerInformer struct {
+	*Informer[cloudscale.LoadBalancer]
+}
+
+// Lister returns a LoadBalancerLister reading from the cache.
+func (i *LoadBalancerInformer) Lister() LoadBalancerLister {
+	return LoadBalancerLister{i.Informer.Lister()}
+}
+
+// LoadBalancerLister looks up cached load balancers.
+type LoadBalancerLister struct {
+	Lister[cloudscale.LoadBalancer]
+}
+
+// ByZone returns the load balancers in the zone with the given slug.
+func (l LoadBalancerLister) ByZone(zone string) []cloudscale.LoadBalancer {
+	return l.mustByIndex(IndexZone, zone)
+}
+
+// ByTag returns the load balancers tagged with key=value.
+func (l LoadBalancerLister) ByTag(key, value string) []cloudscale.LoadBalancer {
+	return l.mustByIndex(IndexTag, TagIndexValue(key, value))
+}
+
+// ByTagKey returns the load balancers that have the tag key, whatever its
+// value.
+func (l LoadBalancerLister) ByTagKey(key string) []cloudscale.LoadBalancer {
+	return l.mustByIndex(IndexTagKey, key)
+}
+
+// BySubnet returns the load balancers with a VIP address in the subnet.
+func (l LoadBalancerLister) BySubnet(subnetUUID string) []cloudscale.LoadBalancer {
+	return l.mustByIndex(IndexSubnet, subnetUUID)
+}
+
+// mustByIndex panics if the index does not exist, i.e. if the lister was not
+// created by a Factory.
+func (l LoadBalancerLister) mustByIndex(indexName, value string) []cloudscale.LoadBalancer {
+	loadBalancers, err := l.ByIndex(indexName, value)
+	if err != nil {
+		panic(err)
+	}
+	return loadBalancers
+}
+
+// TagIndexValue returns the value under which IndexTag indexes the tag
+// key=value. Key and value are quoted, so that keys containing "=" cannot
+// collide with values that do.
+func TagIndexValue(key, value string) string {
+	return strconv.Quote(key) + "=" + strconv.Quote(value)
+}
+
+func tagValues(tags cloudscale.TagMap) []string {
+	values := make([]string, 0, len(tags))
+	for key, value := range tags {
+		values = append(values, TagIndexValue(key, value))
+	}
+	return values
+}
+
+func tagKeys(tags cloudscale.TagMap) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func serverNetworks(server *cloudscale.Server) []string {
+	networks := []string{}
+	for _, iface := range server.Interfaces {
+		if iface.Network.UUID != "" {
+			networks = append(networks, iface.Network.UUID)
+		}
+	}
+	return networks
+}
+
+func serverGroups(server *cloudscale.Server) []string {
+	groups := make([]string, 0, len(server.ServerGroups))
+	for _, group := range server.ServerGroups {
+		groups = append(groups, group.UUID)
+	}
+	return groups
+}
+
+func loadBalancerSubnets(loadBalancer *cloudscale.LoadBalancer) []string {
+	subnets := []string{}
+	for _, vip := range loadBalancer.VIPAddresses {
+		subnets = append(subnets, vip.Subnet.UUID)
+	}
+	return subnets
+}
@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Handler is notified of changes to the cached resources. Each handler is
+// called with one event at a time, in the order the changes were applied to
+// the store, and must not block for long. The resources passed must not be
+// modified.
+type Handler[TResource any] interface {
+	OnAdd(resource *TResource)
+	// OnUpdate is called with old and new being equal on resyncs.
+	OnUpdate(old, new *TResource)
+	OnDelete(resource *TResource)
+}
+
+// HandlerFuncs adapts functions to a Handler. Nil functions are skipped.
+type HandlerFuncs[TResource any] struct {
+	AddFunc    func(resource *TResource)
+	UpdateFunc func(old, new *TResource)
+	DeleteFunc func(resource *TResource)
+}
+
+func (h HandlerFuncs[TResource]) OnAdd(resource *TResource) {
+	if h.AddFunc != nil {
+		h.AddFunc(resource)
+	}
+}
+
+func (h HandlerFuncs[TResource]) OnUpdate(old, new *TResource) {
+	if h.UpdateFunc != nil {
+		h.UpdateFunc(old, new)
+	}
+}
+
+func (h HandlerFuncs[TResource]) OnDelete(resource *TResource) {
+	if h.DeleteFunc != nil {
+		h.DeleteFunc(resource)
+	}
+}
+
+// Informer keeps a Store of one resource type in sync with the API.
+type Informer[TResource any] struct {
+	source  cloudscale.GenericWatchService[TResource]
+	options cloudscale.WatchOptions
+	store   *Store[TResource]
+
+	// mu guards the handlers and is held while applying a change to the
+	// store, so that a new handler either sees the change in its replay or
+	// is notified of it, but never both.
+	mu       sync.Mutex
+	handlers []*registration[TResource]
+	onError  func(err error)
+	started  bool
+	synced   chan struct{}
+}
+
+// registration serializes the calls of one handler, so that its replay is
+// delivered before the changes following it.
+type registration[TResource any] struct {
+	mu      sync.Mutex
+	handler Handler[TResource]
+}
+
+// NewInformer creates an informer for the resources of source, e.g.
+// client.Servers. options are passed on to source.WatchWithOptions:
+// Interval, ResyncPeriod, Selector, BackOff and Modifiers all apply to the
+// informer's polls.
+func NewInformer[TResource any](source cloudscale.GenericWatchService[TResource], options cloudscale.WatchOptions, indexers Indexers[TResource]) *Informer[TResource] {
+	options.Bookmarks = true
+	return &Informer[TResource]{
+		source:  source,
+		options: options,
+		store:   NewStore(indexers),
+		synced:  make(chan struct{}),
+	}
+}
+
+// AddEventHandler registers handler. It is called with OnAdd for all
+// resources cached so far first, and then notified of all later changes.
+// Handlers may add further handlers.
+func (i *Informer[TResource]) AddEventHandler(handler Handler[TResource]) {
+	r := &registration[TResource]{handler: handler}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i.mu.Lock()
+	cached := i.store.List()
+	i.handlers = append(i.handlers, r)
+	i.mu.Unlock()
+
+	for _, resource := range cached {
+		handler.OnAdd(&resource)
+	}
+}
+
+// SetErrorHandler sets a function called when listing the resources fails.
+// The informer keeps retrying with back-off.
+func (i *Informer[TResource]) SetErrorHandler(onError func(err error)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.onError = onError
+}
+
+// Lister returns a Lister reading from the informer's store.
+func (i *Informer[TResource]) Lister() Lister[TResource] {
+	return Lister[TResource]{store: i.store}
+}
+
+// Store returns the store kept up to date by the informer.
+func (i *Informer[TResource]) Store() *Store[TResource] {
+	return i.store
+}
+
+// HasSynced reports whether the first successful list has been stored.
+func (i *Informer[TResource]) HasSynced() bool {
+	select {
+	case <-i.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync blocks until the initial list has been stored or ctx is done,
+// and reports whether the informer synced.
+func (i *Informer[TResource]) WaitForSync(ctx context.Context) bool {
+	select {
+	case <-i.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Run watches the resources with source.WatchWithOptions and applies the
+// changes to the store until ctx is done. Failed polls are reported to the
+// error handler and retried with back-off. Run does nothing if the informer
+// is already running.
+func (i *Informer[TResource]) Run(ctx context.Context) {
+	i.mu.Lock()
+	if i.started {
+		i.mu.Unlock()
+		return
+	}
+	i.started = true
+	i.mu.Unlock()
+
+	for event := range i.source.WatchWithOptions(ctx, i.options) {
+		switch event.Type {
+		case cloudscale.EventAdded, cloudscale.EventModified:
+			resource := event.Resource
+			i.apply(func() func(h Handler[TResource]) {
+				if old, ok := i.store.Set(event.ID, resource); ok {
+					return func(h Handler[TResource]) { h.OnUpdate(old, resource) }
+				}
+				return func(h Handler[TResource]) { h.OnAdd(resource) }
+			})
+		case cloudscale.EventDeleted:
+			i.apply(func() func(h Handler[TResource]) {
+				old, ok := i.store.Delete(event.ID)
+				if !ok {
+					return nil
+				}
+				return func(h Handler[TResource]) { h.OnDelete(old) }
+			})
+		case cloudscale.EventSync:
+			resource := event.Resource
+			i.apply(func() func(h Handler[TResource]) {
+				return func(h Handler[TResource]) { h.OnUpdate(resource, resource) }
+			})
+		case cloudscale.EventBookmark:
+			if !i.HasSynced() {
+				close(i.synced)
+			}
+		case cloudscale.EventError:
+			i.reportError(event.Err)
+		}
+	}
+}
+
+// apply runs change, which modifies the store and returns the notification
+// of the handlers, if any, and calls the handlers without holding i.mu.
+func (i *Informer[TResource]) apply(change func() func(h Handler[TResource])) {
+	i.mu.Lock()
+	call := change()
+	handlers := slices.Clone(i.handlers)
+	i.mu.Unlock()
+
+	if call == nil {
+		return
+	}
+	for _, r := range handlers {
+		r.mu.Lock()
+		call(r.handler)
+		r.mu.Unlock()
+	}
+}
+
+func (i *Informer[TResource]) reportError(err error) {
+	i.mu.Lock()
+	onError := i.onError
+	i.mu.Unlock()
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// Lister answers lookups from a Store. The returned resources are copies,
+// but they share slices and maps with the cache and must not be modified.
+type Lister[TResource any] struct {
+	store *Store[TResource]
+}
+
+// Get returns the resource with the given ID.
+func (l Lister[TResource]) Get(id string) (TResource, bool) {
+	return l.store.Get(id)
+}
+
+// List returns all resources, ordered by ID.
+func (l Lister[TResource]) List() []TResource {
+	return l.store.List()
+}
+
+// ByIndex returns the resources indexed under value in the named index.
+func (l Lister[TResource]) ByIndex(indexName string, value string) ([]TResource, error) {
+	return l.store.ByIndex(indexName, value)
+}
+
+// Select returns the resources whose tags match selector.
+func (l Lister[TResource]) Select(selector cloudscale.TagSelector) []TResource {
+	selected := []TResource{}
+	for _, resource := range l.store.List() {
+		if tagged, ok := any(resource).(cloudscale.Tagged); ok && selector.Matches(tagged.GetTags()) {
+			selected = append(selected, resource)
+		}
+	}
+	return selected
+}
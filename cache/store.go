@@ -0,0 +1,164 @@
+// Package cache keeps in-memory, indexed copies of cloudscale.ch resources
+// up to date, modelled after the shared informers of Kubernetes' client-go.
+//
+// An Informer polls the list of a resource type and detects changes the same
+// way Watch does. Its Lister answers lookups from memory, by UUID or through
+// secondary indexes, and registered Handlers are notified of every change. A
+// Factory shares one informer per resource type between all consumers.
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// IndexFunc returns the values under which a resource is indexed, e.g. the
+// UUIDs of the networks a server is attached to.
+type IndexFunc[TResource any] func(resource *TResource) []string
+
+// Indexers maps index names to the functions computing their values.
+type Indexers[TResource any] map[string]IndexFunc[TResource]
+
+// Store is a thread-safe map of resources keyed by ID, maintaining secondary
+// indexes.
+type Store[TResource any] struct {
+	mu       sync.RWMutex
+	items    map[string]*TResource
+	indexers Indexers[TResource]
+	// indices maps an index name and value to the IDs of the resources.
+	indices map[string]map[string]map[string]struct{}
+}
+
+// NewStore creates an empty store maintaining the given indexes.
+func NewStore[TResource any](indexers Indexers[TResource]) *Store[TResource] {
+	s := &Store[TResource]{
+		items:    map[string]*TResource{},
+		indexers: Indexers[TResource]{},
+		indices:  map[string]map[string]map[string]struct{}{},
+	}
+	for name, indexFunc := range indexers {
+		s.indexers[name] = indexFunc
+		s.indices[name] = map[string]map[string]struct{}{}
+	}
+	return s
+}
+
+// Set adds or replaces the resource with the given ID and returns the
+// previous one, if any.
+func (s *Store[TResource]) Set(id string, resource *TResource) (*TResource, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.items[id]
+	if ok {
+		s.unindex(id, old)
+	}
+	s.items[id] = resource
+	s.index(id, resource)
+	return old, ok
+}
+
+// Delete removes the resource with the given ID and returns it.
+func (s *Store[TResource]) Delete(id string) (*TResource, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.items[id]
+	if ok {
+		s.unindex(id, old)
+		delete(s.items, id)
+	}
+	return old, ok
+}
+
+// Get returns a copy of the resource with the given ID.
+func (s *Store[TResource]) Get(id string) (TResource, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resource, ok := s.items[id]
+	if !ok {
+		var zero TResource
+		return zero, false
+	}
+	return *resource, true
+}
+
+// List returns copies of all resources, ordered by ID.
+func (s *Store[TResource]) List() []TResource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.copies(keysOf(s.items))
+}
+
+// IDs returns the IDs of all resources in ascending order.
+func (s *Store[TResource]) IDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return keysOf(s.items)
+}
+
+// ByIndex returns copies of the resources indexed under value in the named
+// index, ordered by ID.
+func (s *Store[TResource]) ByIndex(indexName string, value string) ([]TResource, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	index, ok := s.indices[indexName]
+	if !ok {
+		return nil, fmt.Errorf("index %q does not exist", indexName)
+	}
+	return s.copies(keysOf(index[value])), nil
+}
+
+// IndexValues returns the values present in the named index, e.g. all zones.
+func (s *Store[TResource]) IndexValues(indexName string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return keysOf(s.indices[indexName])
+}
+
+func (s *Store[TResource]) index(id string, resource *TResource) {
+	for name, indexFunc := range s.indexers {
+		for _, value := range indexFunc(resource) {
+			ids, ok := s.indices[name][value]
+			if !ok {
+				ids = map[string]struct{}{}
+				s.indices[name][value] = ids
+			}
+			ids[id] = struct{}{}
+		}
+	}
+}
+
+func (s *Store[TResource]) unindex(id string, resource *TResource) {
+	for name, indexFunc := range s.indexers {
+		for _, value := range indexFunc(resource) {
+			delete(s.indices[name][value], id)
+			if len(s.indices[name][value]) == 0 {
+				delete(s.indices[name], value)
+			}
+		}
+	}
+}
+
+func (s *Store[TResource]) copies(ids []string) []TResource {
+	resources := make([]TResource, 0, len(ids))
+	for _, id := range ids {
+		resources = append(resources, *s.items[id])
+	}
+	return resources
+}
+
+func keysOf[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,283 @@
+package cloudscale
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCatalogTTL = time.Hour
+
+// customImagePrefix marks a custom image in ServerRequest.Image, e.g.
+// "custom:my-image".
+const customImagePrefix = "custom:"
+
+// Catalog is a cached view of the regions, zones, flavors, public images and
+// custom images available to an account. Once loaded, all queries are
+// answered from memory; Start refreshes the data in the background.
+//
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	regions      RegionService
+	flavors      FlavorService
+	images       ImageService
+	customImages GenericListService[CustomImage]
+
+	ttl     time.Duration
+	onError func(err error)
+
+	mu       sync.RWMutex
+	data     catalogData
+	loadedAt time.Time
+}
+
+type catalogData struct {
+	regions      []Region
+	flavors      []Flavor
+	images       []Image
+	customImages []CustomImage
+}
+
+// CatalogOption configures a Catalog.
+type CatalogOption func(*Catalog)
+
+// WithCatalogTTL sets how often Start refreshes the catalog. The default is
+// one hour.
+func WithCatalogTTL(ttl time.Duration) CatalogOption {
+	return func(c *Catalog) {
+		c.ttl = ttl
+	}
+}
+
+// WithCatalogErrorHandler sets a function called when a background refresh
+// fails. The previously loaded data stays in use.
+func WithCatalogErrorHandler(onError func(err error)) CatalogOption {
+	return func(c *Catalog) {
+		c.onError = onError
+	}
+}
+
+// NewCatalog creates an empty catalog backed by the services of client. Call
+// Load or Start before querying it.
+func NewCatalog(client *Client, opts ...CatalogOption) *Catalog {
+	c := &Catalog{
+		regions:      client.Regions,
+		flavors:      client.Flavors,
+		images:       client.Images,
+		customImages: client.CustomImages,
+		ttl:          defaultCatalogTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Load fetches all data from the API and replaces the cached data. If any
+// request fails, the cached data is left unchanged.
+func (c *Catalog) Load(ctx context.Context) error {
+	data := catalogData{}
+	var err error
+
+	if data.regions, err = c.regions.List(ctx); err != nil {
+		return err
+	}
+	if data.flavors, err = c.flavors.List(ctx); err != nil {
+		return err
+	}
+	if data.images, err = c.images.List(ctx); err != nil {
+		return err
+	}
+	if data.customImages, err = c.customImages.List(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = data
+	c.loadedAt = time.Now()
+	return nil
+}
+
+// Start loads the catalog and then refreshes it in the background every TTL
+// until ctx is done. Only the initial load error is returned; later failures
+// are passed to the handler set with WithCatalogErrorHandler.
+func (c *Catalog) Start(ctx context.Context) error {
+	if err := c.Load(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(c.ttl)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Load(ctx); err != nil && c.onError != nil && ctx.Err() == nil {
+					c.onError(err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// LoadedAt returns when the catalog was last loaded successfully, or the
+// zero time if it has never been loaded.
+func (c *Catalog) LoadedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loadedAt
+}
+
+// Regions returns all regions.
+func (c *Catalog) Regions() []Region {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Region(nil), c.data.regions...)
+}
+
+// Zones returns the zones of all regions.
+func (c *Catalog) Zones() []ZoneStub {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	zones := []ZoneStub{}
+	for _, region := range c.data.regions {
+		zones = append(zones, region.Zones...)
+	}
+	return zones
+}
+
+// Flavors returns all flavors.
+func (c *Catalog) Flavors() []Flavor {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Flavor(nil), c.data.flavors...)
+}
+
+// Images returns all public images.
+func (c *Catalog) Images() []Image {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Image(nil), c.data.images...)
+}
+
+// CustomImages returns all custom images.
+func (c *Catalog) CustomImages() []CustomImage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]CustomImage(nil), c.data.customImages...)
+}
+
+// RegionOfZone returns the slug of the region containing zone.
+func (c *Catalog) RegionOfZone(zone string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, region := range c.data.regions {
+		if containsZone(region.Zones, zone) {
+			return region.Slug, true
+		}
+	}
+	return "", false
+}
+
+// HasZone reports whether zone exists.
+func (c *Catalog) HasZone(zone string) bool {
+	_, ok := c.RegionOfZone(zone)
+	return ok
+}
+
+// Flavor returns the flavor with the given slug.
+func (c *Catalog) Flavor(slug string) (Flavor, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, flavor := range c.data.flavors {
+		if flavor.Slug == slug {
+			return flavor, true
+		}
+	}
+	return Flavor{}, false
+}
+
+// FlavorAvailableIn reports whether the flavor with the given slug is offered
+// in zone.
+func (c *Catalog) FlavorAvailableIn(slug string, zone string) bool {
+	flavor, ok := c.Flavor(slug)
+	return ok && flavor.AvailableIn(zone)
+}
+
+// Image returns the public image with the given slug.
+func (c *Catalog) Image(slug string) (Image, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if image, ok := FindImage(c.data.images, slug); ok {
+		return *image, true
+	}
+	return Image{}, false
+}
+
+// CustomImage returns the custom image with the given slug or UUID.
+func (c *Catalog) CustomImage(slugOrUUID string) (CustomImage, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, image := range c.data.customImages {
+		if image.Slug == slugOrUUID || image.UUID == slugOrUUID {
+			return image, true
+		}
+	}
+	return CustomImage{}, false
+}
+
+// ZonesForImage returns the slugs of the zones in which servers can be
+// created from image, given as in ServerRequest.Image: a public image slug,
+// or "custom:" followed by a custom image slug or UUID. It returns false if
+// the image is unknown.
+func (c *Catalog) ZonesForImage(image string) ([]string, bool) {
+	var zones []ZoneStub
+	if custom, ok := strings.CutPrefix(image, customImagePrefix); ok {
+		customImage, found := c.CustomImage(custom)
+		if !found {
+			return nil, false
+		}
+		zones = customImage.Zones
+	} else {
+		publicImage, found := c.Image(image)
+		if !found {
+			return nil, false
+		}
+		zones = publicImage.Zones
+	}
+
+	slugs := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		slugs = append(slugs, zone.Slug)
+	}
+	return slugs, true
+}
+
+// ImageAvailableIn reports whether servers can be created from image in
+// zone. See ZonesForImage for the accepted image references.
+func (c *Catalog) ImageAvailableIn(image string, zone string) bool {
+	zones, ok := c.ZonesForImage(image)
+	if !ok {
+		return false
+	}
+	for _, z := range zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+func containsZone(zones []ZoneStub, zone string) bool {
+	for _, z := range zones {
+		if z.Slug == zone {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package cloudscale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const imagesResponse = `[
+  {
+    "slug": "debian-12",
+    "name": "Debian 12 (2023-06-10)",
+    "operating_system": "Debian",
+    "default_username": "debian",
+    "zones": [{"slug": "rma1"}, {"slug": "lpg1"}]
+  },
+  {
+    "slug": "windows-2022",
+    "name": "Windows Server 2022",
+    "operating_system": "Windows",
+    "default_username": "Administrator",
+    "zones": [{"slug": "rma1"}]
+  }
+]`
+
+func handleCatalog(t *testing.T) *atomic.Int32 {
+	loads := &atomic.Int32{}
+	mux.HandleFunc("/v1/regions", func(w http.ResponseWriter, r *http.Request) {
+		loads.Add(1)
+		fmt.Fprint(w, `[{"slug": "rma", "zones": [{"slug": "rma1"}]}, {"slug": "lpg", "zones": [{"slug": "lpg1"}]}]`)
+	})
+	mux.HandleFunc("/v1/flavors", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, flavorsResponse)
+	})
+	mux.HandleFunc("/v1/images", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, imagesResponse)
+	})
+	mux.HandleFunc("/v1/custom-images", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"uuid": "11111111-1864-4608-853a-0771b6885a3a", "slug": "golden", "zones": [{"slug": "lpg1"}]}]`)
+	})
+	return loads
+}
+
+func TestCatalog_Queries(t *testing.T) {
+	setup()
+	defer teardown()
+	handleCatalog(t)
+
+	catalog := NewCatalog(client)
+	if !catalog.LoadedAt().IsZero() {
+		t.Error("expected zero LoadedAt before loading")
+	}
+	if err := catalog.Load(ctx); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	assertEqual(t, []ZoneStub{{Slug: "rma1"}, {Slug: "lpg1"}}, catalog.Zones())
+	assertEqual(t, 2, len(catalog.Flavors()))
+	assertEqual(t, 2, len(catalog.Images()))
+
+	region, ok := catalog.RegionOfZone("lpg1")
+	assertEqual(t, "lpg", region)
+	assertEqual(t, true, ok)
+	assertEqual(t, false, catalog.HasZone("xyz1"))
+
+	assertEqual(t, true, catalog.FlavorAvailableIn("flex-4-1", "lpg1"))
+	assertEqual(t, false, catalog.FlavorAvailableIn("flex-4-1", "xyz1"))
+	assertEqual(t, false, catalog.FlavorAvailableIn("flex-999", "rma1"))
+
+	zones, ok := catalog.ZonesForImage("windows-2022")
+	assertEqual(t, []string{"rma1"}, zones)
+	assertEqual(t, true, ok)
+
+	zones, ok = catalog.ZonesForImage("custom:golden")
+	assertEqual(t, []string{"lpg1"}, zones)
+	assertEqual(t, true, ok)
+
+	_, ok = catalog.ZonesForImage("custom:unknown")
+	assertEqual(t, false, ok)
+
+	assertEqual(t, true, catalog.ImageAvailableIn("debian-12", "lpg1"))
+	assertEqual(t, false, catalog.ImageAvailableIn("windows-2022", "lpg1"))
+	assertEqual(t, true, catalog.ImageAvailableIn("custom:11111111-1864-4608-853a-0771b6885a3a", "lpg1"))
+}
+
+func TestCatalog_LoadErrorKeepsData(t *testing.T) {
+	setup()
+	defer teardown()
+	handleCatalog(t)
+
+	catalog := NewCatalog(client)
+	if err := catalog.Load(ctx); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	teardown()
+	if err := catalog.Load(ctx); err == nil {
+		t.Fatal("expected Load to fail after the server is gone")
+	}
+	assertEqual(t, 2, len(catalog.Flavors()))
+}
+
+func TestCatalog_Start(t *testing.T) {
+	setup()
+	defer teardown()
+	loads := handleCatalog(t)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	catalog := NewCatalog(client, WithCatalogTTL(5*time.Millisecond))
+	if err := catalog.Start(ctx); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for loads.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected background refreshes, got %d loads", loads.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,261 @@
+// Package chaos provides an http.RoundTripper that injects the failures seen
+// from the cloudscale.ch API in production, to test how clients cope with
+// them: rate limiting, 502 responses from the edge, slow responses,
+// connection resets and truncated response bodies.
+//
+// Faults are configured per endpoint, the path template the SDK attaches to
+// the request context (see cloudscale.OperationPath). Random decisions come
+// from a seeded source, so a sequence of requests fails the same way on
+// every run.
+//
+// The transport composes with instrumentation.InstrumentedTransport; wrap it
+// with the instrumented transport to see injected failures in metrics and
+// traces:
+//
+//	transport := instrumentation.InstrumentedTransport(
+//		chaos.NewTransport(http.DefaultTransport, chaos.Options{
+//			Seed:    42,
+//			Default: chaos.Faults{RateLimit: 0.1},
+//		}),
+//		instrumentation.Options{PrometheusRegistry: reg},
+//	)
+package chaos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Fault is a kind of injected failure.
+type Fault string
+
+const (
+	RateLimit       Fault = "rate_limit"
+	BadGateway      Fault = "bad_gateway"
+	ConnectionReset Fault = "connection_reset"
+	TruncatedBody   Fault = "truncated_body"
+	Latency         Fault = "latency"
+)
+
+// Faults are the failure rates, between 0 and 1, of the requests to an
+// endpoint. At most one of RateLimit, BadGateway, ConnectionReset and
+// TruncatedBody is injected per request; latency may be added on top.
+type Faults struct {
+	// RateLimit answers with 429 Too Many Requests and a Retry-After header
+	// without sending the request.
+	RateLimit float64
+	// RetryAfter is the Retry-After of rate limited responses. Defaults to
+	// one second.
+	RetryAfter time.Duration
+
+	// BadGateway answers with the HTML 502 Bad Gateway page of the edge
+	// without sending the request.
+	BadGateway float64
+
+	// ConnectionReset fails with a connection reset by peer error without
+	// sending the request.
+	ConnectionReset float64
+
+	// TruncatedBody sends the request but cuts the response body in half, as
+	// happens when a connection breaks during the response.
+	TruncatedBody float64
+
+	// Latency delays requests by this duration plus a random share of
+	// LatencyJitter, before they are sent. LatencyRate is the share of
+	// requests delayed; zero delays all of them.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	LatencyRate   float64
+}
+
+// Options configures the chaos transport.
+type Options struct {
+	// Seed of the random source deciding which requests fail.
+	Seed int64
+
+	// Default are the faults of endpoints without an entry in Endpoints,
+	// including requests without operation path.
+	Default Faults
+
+	// Endpoints are the faults per endpoint, keyed by the operation path
+	// template, e.g. "v1/servers/:id", or by method and template, e.g.
+	// "POST v1/servers", which takes precedence.
+	Endpoints map[string]Faults
+}
+
+// Transport injects faults into the requests it passes to the next transport.
+type Transport struct {
+	next    http.RoundTripper
+	options Options
+
+	mu       sync.Mutex
+	random   *rand.Rand
+	disabled bool
+	injected map[Fault]int
+}
+
+// NewTransport returns a transport injecting faults into the requests sent
+// through next. If next is nil, http.DefaultTransport is used.
+func NewTransport(next http.RoundTripper, options Options) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next:     next,
+		options:  options,
+		random:   rand.New(rand.NewSource(options.Seed)),
+		injected: map[Fault]int{},
+	}
+}
+
+// SetEnabled turns fault injection on or off, e.g. to set up fixtures
+// without failures. Transports are enabled when created.
+func (t *Transport) SetEnabled(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disabled = !enabled
+}
+
+// Injected returns how often each fault was injected.
+func (t *Transport) Injected() map[Fault]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	injected := make(map[Fault]int, len(t.injected))
+	for fault, count := range t.injected {
+		injected[fault] = count
+	}
+	return injected
+}
+
+func (t *Transport) faults(req *http.Request) Faults {
+	endpoint := cloudscale.OperationPath(req.Context())
+	if faults, ok := t.options.Endpoints[req.Method+" "+endpoint]; ok && endpoint != "" {
+		return faults
+	}
+	if faults, ok := t.options.Endpoints[endpoint]; ok && endpoint != "" {
+		return faults
+	}
+	return t.options.Default
+}
+
+// decide draws the fault and the latency of a request.
+func (t *Transport) decide(faults Faults) (Fault, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.disabled {
+		return "", 0
+	}
+
+	var delay time.Duration
+	if faults.Latency > 0 || faults.LatencyJitter > 0 {
+		if faults.LatencyRate == 0 || t.random.Float64() < faults.LatencyRate {
+			delay = faults.Latency
+			if faults.LatencyJitter > 0 {
+				delay += time.Duration(t.random.Int63n(int64(faults.LatencyJitter)))
+			}
+			t.injected[Latency]++
+		}
+	}
+
+	draw := t.random.Float64()
+	for _, candidate := range []struct {
+		fault Fault
+		rate  float64
+	}{
+		{RateLimit, faults.RateLimit},
+		{BadGateway, faults.BadGateway},
+		{ConnectionReset, faults.ConnectionReset},
+		{TruncatedBody, faults.TruncatedBody},
+	} {
+		if draw < candidate.rate {
+			t.injected[candidate.fault]++
+			return candidate.fault, delay
+		}
+		draw -= candidate.rate
+	}
+	return "", delay
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	faults := t.faults(req)
+	fault, delay := t.decide(faults)
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			closeBody(req)
+			return nil, req.Context().Err()
+		}
+	}
+
+	switch fault {
+	case RateLimit:
+		closeBody(req)
+		retryAfter := faults.RetryAfter
+		if retryAfter <= 0 {
+			retryAfter = time.Second
+		}
+		seconds := int((retryAfter + time.Second - 1) / time.Second)
+		resp := response(req, http.StatusTooManyRequests, "application/json",
+			fmt.Sprintf(`{"detail": "Request was throttled. Expected available in %d seconds."}`, seconds))
+		resp.Header.Set("Retry-After", strconv.Itoa(seconds))
+		return resp, nil
+	case BadGateway:
+		closeBody(req)
+		return response(req, http.StatusBadGateway, "text/html",
+			"<html>\r\n<head><title>502 Bad Gateway</title></head>\r\n<body>\r\n<center><h1>502 Bad Gateway</h1></center>\r\n</body>\r\n</html>\r\n"), nil
+	case ConnectionReset:
+		closeBody(req)
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || fault != TruncatedBody {
+		return resp, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data[:len(data)/2]))
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+func response(req *http.Request, status int, contentType, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,198 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/instrumentation"
+)
+
+// newClient returns a client for a fake API serving servers, and the number
+// of requests that reached it.
+func newClient(t *testing.T, transport func(http.RoundTripper) http.RoundTripper) (*cloudscale.Client, *atomic.Int32) {
+	received := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		server := `{"uuid": "s1", "name": "web", "status": "running", "flavor": {"slug": "flex-4-2"}}`
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/v1/servers":
+			server = "[" + server + "]"
+		}
+		fmt.Fprint(w, server)
+	}))
+	t.Cleanup(server.Close)
+
+	client := cloudscale.NewClient(&http.Client{Transport: transport(http.DefaultTransport)})
+	client.BaseURL, _ = url.Parse(server.URL)
+	return client, received
+}
+
+func TestTransport_Endpoints(t *testing.T) {
+	var chaos *Transport
+	client, received := newClient(t, func(next http.RoundTripper) http.RoundTripper {
+		chaos = NewTransport(next, Options{Endpoints: map[string]Faults{
+			"POST v1/servers":       {RateLimit: 1, RetryAfter: 1500 * time.Millisecond},
+			"v1/servers/:id":        {BadGateway: 1},
+			"DELETE v1/volumes/:id": {},
+		}})
+		return chaos
+	})
+	ctx := context.Background()
+
+	_, err := client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "web"})
+	var errorResponse *cloudscale.ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected a 429, got %v", err)
+	}
+	_, err = client.Servers.Get(ctx, "s1")
+	if !errors.As(err, &errorResponse) || errorResponse.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected a 502, got %v", err)
+	}
+	if _, err := client.Servers.List(ctx); err != nil {
+		t.Errorf("expected other endpoints to work, got %v", err)
+	}
+
+	if n := received.Load(); n != 1 {
+		t.Errorf("expected only the list to reach the API, got %d requests", n)
+	}
+	injected := chaos.Injected()
+	if injected[RateLimit] != 1 || injected[BadGateway] != 1 || len(injected) != 2 {
+		t.Errorf("unexpected injected faults %v", injected)
+	}
+
+	chaos.SetEnabled(false)
+	if _, err := client.Servers.Get(ctx, "s1"); err != nil {
+		t.Errorf("expected a disabled transport to pass requests, got %v", err)
+	}
+}
+
+func TestTransport_RateLimitResponse(t *testing.T) {
+	transport := NewTransport(http.DefaultTransport, Options{Default: Faults{RateLimit: 1, RetryAfter: 1500 * time.Millisecond}})
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/v1/servers", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	if string(body) != `{"detail": "Request was throttled. Expected available in 2 seconds."}` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestTransport_ConnectionResetAndTruncatedBody(t *testing.T) {
+	client, received := newClient(t, func(next http.RoundTripper) http.RoundTripper {
+		return NewTransport(next, Options{Endpoints: map[string]Faults{
+			"v1/servers":     {ConnectionReset: 1},
+			"v1/servers/:id": {TruncatedBody: 1},
+		}})
+	})
+	ctx := context.Background()
+
+	if _, err := client.Servers.List(ctx); !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("expected a connection reset, got %v", err)
+	}
+	if _, err := client.Servers.Get(ctx, "s1"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected truncated JSON, got %v", err)
+	}
+	if n := received.Load(); n != 1 {
+		t.Errorf("expected only the truncated request to reach the API, got %d requests", n)
+	}
+}
+
+func TestTransport_Seeded(t *testing.T) {
+	pattern := func(seed int64) string {
+		client, _ := newClient(t, func(next http.RoundTripper) http.RoundTripper {
+			return NewTransport(next, Options{Seed: seed, Default: Faults{RateLimit: 0.2, BadGateway: 0.2}})
+		})
+		result := ""
+		for i := 0; i < 50; i++ {
+			_, err := client.Servers.Get(context.Background(), "s1")
+			var errorResponse *cloudscale.ErrorResponse
+			switch {
+			case err == nil:
+				result += "."
+			case errors.As(err, &errorResponse):
+				result += fmt.Sprintf("%d ", errorResponse.StatusCode)
+			default:
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		return result
+	}
+
+	first, second, other := pattern(7), pattern(7), pattern(8)
+	if first != second {
+		t.Errorf("expected the same seed to fail the same requests:\n%s\n%s", first, second)
+	}
+	if first == other {
+		t.Errorf("expected another seed to fail other requests: %s", first)
+	}
+}
+
+func TestTransport_Latency(t *testing.T) {
+	client, _ := newClient(t, func(next http.RoundTripper) http.RoundTripper {
+		return NewTransport(next, Options{Default: Faults{Latency: 20 * time.Millisecond, LatencyJitter: 10 * time.Millisecond}})
+	})
+
+	start := time.Now()
+	if _, err := client.Servers.Get(context.Background(), "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the request to be delayed, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := client.Servers.Get(ctx, "s1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the delay to end with the context, got %v", err)
+	}
+}
+
+func TestTransport_Instrumented(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	client, _ := newClient(t, func(next http.RoundTripper) http.RoundTripper {
+		return instrumentation.InstrumentedTransport(
+			NewTransport(next, Options{Endpoints: map[string]Faults{"v1/servers/:id": {RateLimit: 1}}}),
+			instrumentation.Options{PrometheusRegistry: reg},
+		)
+	})
+	client.Servers.Get(context.Background(), "s1")
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "cloudscale_requests_total" {
+			continue
+		}
+		for _, metric := range family.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["endpoint"] == "v1/servers/:id" && labels["status"] == "429" && metric.Counter.GetValue() == 1 {
+				return
+			}
+		}
+	}
+	t.Error("expected the injected 429 to be counted")
+}
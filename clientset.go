@@ -0,0 +1,279 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ProjectTokenSource returns the current API token of a project, e.g. read
+// from a secret store. It is called when a project is added without a token,
+// by ClientSet.RefreshTokens and when the API rejects the token of a project.
+type ProjectTokenSource interface {
+	Token(ctx context.Context, project string) (string, error)
+}
+
+// ProjectTokenSourceFunc is a ProjectTokenSource calling a function.
+type ProjectTokenSourceFunc func(ctx context.Context, project string) (string, error)
+
+func (f ProjectTokenSourceFunc) Token(ctx context.Context, project string) (string, error) {
+	return f(ctx, project)
+}
+
+// ClientSetOptions configures a ClientSet. The HTTP client, rate limiter and
+// wait observer are shared by the clients of all projects.
+type ClientSetOptions struct {
+	// HTTPClient is the HTTP client whose transport sends the requests of
+	// all projects, e.g. one with an instrumentation.InstrumentedTransport.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// BaseURL overrides the API URL of NewClient.
+	BaseURL *url.URL
+
+	// RateLimiter, if set, is shared by all projects, so that the set as a
+	// whole stays within the request budget.
+	RateLimiter RateLimiter
+
+	// WaitObserver, if set, is notified about the WaitFor calls of all
+	// projects.
+	WaitObserver WaitObserver
+
+	// TokenSource, if set, provides the tokens of projects added without
+	// one, and rotated tokens on RefreshTokens.
+	TokenSource ProjectTokenSource
+}
+
+// ClientSet holds a named client per cloudscale.ch project. The clients
+// share a transport, rate limiter and wait observer, and their tokens can be
+// rotated without replacing them.
+type ClientSet struct {
+	options ClientSetOptions
+
+	mu       sync.RWMutex
+	projects map[string]*project
+}
+
+type project struct {
+	client      *Client
+	credentials *projectCredentials
+}
+
+// NewClientSet returns an empty client set.
+func NewClientSet(options ClientSetOptions) *ClientSet {
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+	return &ClientSet{options: options, projects: map[string]*project{}}
+}
+
+// Add adds a project with token and returns its client. If token is empty,
+// it is read from the TokenSource. Adding a project again replaces its
+// client.
+func (s *ClientSet) Add(ctx context.Context, name, token string) (*Client, error) {
+	if token == "" {
+		if s.options.TokenSource == nil {
+			return nil, fmt.Errorf("project %s: no token and no token source", name)
+		}
+		var err error
+		if token, err = s.options.TokenSource.Token(ctx, name); err != nil {
+			return nil, &ProjectError{Project: name, Err: err}
+		}
+	}
+
+	p := &project{credentials: &projectCredentials{name: name, source: s.options.TokenSource}}
+	p.credentials.token.Store(&token)
+
+	httpClient := *s.options.HTTPClient
+	httpClient.Transport = &projectTransport{project: name, next: httpClient.Transport}
+	p.client = NewClient(&httpClient)
+	p.client.Credentials = p.credentials
+	if s.options.BaseURL != nil {
+		p.client.BaseURL = s.options.BaseURL
+	}
+	p.client.RateLimiter = s.options.RateLimiter
+	p.client.WaitObserver = s.options.WaitObserver
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects[name] = p
+	return p.client, nil
+}
+
+// Remove removes a project.
+func (s *ClientSet) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.projects, name)
+}
+
+// Client returns the client of a project, or nil if there is no such
+// project.
+func (s *ClientSet) Client(name string) *Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p, ok := s.projects[name]; ok {
+		return p.client
+	}
+	return nil
+}
+
+// Projects returns the names of all projects, sorted.
+func (s *ClientSet) Projects() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.projects))
+	for name := range s.projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetToken rotates the token of a project. Requests started afterwards use
+// the new token.
+func (s *ClientSet) SetToken(name, token string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.projects[name]
+	if !ok {
+		return fmt.Errorf("unknown project %s", name)
+	}
+	p.credentials.token.Store(&token)
+	return nil
+}
+
+// RefreshTokens reads the tokens of all projects from the TokenSource, e.g.
+// periodically or after a rotation in the secret store. Projects whose token
+// cannot be read keep their current one; their *ProjectError are joined into
+// the returned error.
+func (s *ClientSet) RefreshTokens(ctx context.Context) error {
+	if s.options.TokenSource == nil {
+		return errors.New("no token source")
+	}
+	errs := []error{}
+	for _, name := range s.Projects() {
+		token, err := s.options.TokenSource.Token(ctx, name)
+		if err == nil {
+			err = s.SetToken(name, token)
+		}
+		if err != nil {
+			errs = append(errs, &ProjectError{Project: name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// projectCredentials holds the current token of a project. A rejected token
+// is replaced by the one of the TokenSource, if any.
+type projectCredentials struct {
+	name   string
+	source ProjectTokenSource
+	token  atomic.Pointer[string]
+}
+
+func (c *projectCredentials) Token(ctx context.Context) (string, error) {
+	return *c.token.Load(), nil
+}
+
+func (c *projectCredentials) Refresh(ctx context.Context) error {
+	if c.source == nil {
+		return nil
+	}
+	token, err := c.source.Token(ctx, c.name)
+	if err != nil {
+		return err
+	}
+	c.token.Store(&token)
+	return nil
+}
+
+// projectTransport attaches the project name to the context of requests.
+type projectTransport struct {
+	project string
+	next    http.RoundTripper
+}
+
+func (t *projectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.WithContext(WithProject(req.Context(), t.project))
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+// ProjectError is the error of a single project, as joined into the errors
+// of ListAcrossProjects and ClientSet.RefreshTokens.
+type ProjectError struct {
+	Project string
+	Err     error
+}
+
+func (e *ProjectError) Error() string {
+	return fmt.Sprintf("project %s: %v", e.Project, e.Err)
+}
+
+func (e *ProjectError) Unwrap() error {
+	return e.Err
+}
+
+// ProjectResource is a resource listed by ListAcrossProjects, annotated with
+// the name of its project.
+type ProjectResource[TResource any] struct {
+	Project  string
+	Resource TResource
+}
+
+// ListAcrossProjects lists resources in all projects of set at the same
+// time, e.g.
+//
+//	servers, err := cloudscale.ListAcrossProjects(ctx, set,
+//		func(c *cloudscale.Client) cloudscale.GenericListService[cloudscale.Server] { return c.Servers },
+//		cloudscale.WithTagFilter(cloudscale.TagMap{"env": "prod"}),
+//	)
+//
+// Resources are ordered by project name. If listing fails in some projects,
+// the resources of the others are returned along with the joined
+// *ProjectError of the failed ones.
+func ListAcrossProjects[TResource any](
+	ctx context.Context,
+	set *ClientSet,
+	service func(*Client) GenericListService[TResource],
+	modifiers ...ListRequestModifier,
+) ([]ProjectResource[TResource], error) {
+	names := set.Projects()
+	lists := make([][]TResource, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		client := set.Client(name)
+		if client == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resources, err := service(client).List(ctx, modifiers...)
+			if err != nil {
+				errs[i] = &ProjectError{Project: name, Err: err}
+			}
+			lists[i] = resources
+		}()
+	}
+	wg.Wait()
+
+	results := []ProjectResource[TResource]{}
+	for i, resources := range lists {
+		for _, resource := range resources {
+			results = append(results, ProjectResource[TResource]{Project: names[i], Resource: resource})
+		}
+	}
+	return results, errors.Join(errs...)
+}
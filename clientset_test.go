@@ -0,0 +1,199 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingLimiter counts the requests waiting on it.
+type countingLimiter struct {
+	waits atomic.Int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return ctx.Err()
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// projectServers serves the servers of the project authenticated by the
+// token, and fails for the token "broken".
+func projectServers(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	switch token {
+	case "broken":
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"detail": "Invalid token."}`)
+	default:
+		fmt.Fprintf(w, `[{"uuid": "%s-1"}, {"uuid": "%s-2"}]`, token, token)
+	}
+}
+
+func newTestClientSet(source ProjectTokenSource, limiter RateLimiter) *ClientSet {
+	baseURL, _ := url.Parse(server.URL)
+	return NewClientSet(ClientSetOptions{BaseURL: baseURL, RateLimiter: limiter, TokenSource: source})
+}
+
+func TestClientSet_ListAcrossProjects(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc("/v1/servers", projectServers)
+
+	limiter := &countingLimiter{}
+	set := newTestClientSet(nil, limiter)
+	for name, token := range map[string]string{"prod": "p", "dev": "d"} {
+		if _, err := set.Add(ctx, name, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	servers, err := ListAcrossProjects(ctx, set, func(c *Client) GenericListService[Server] { return c.Servers })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, server := range servers {
+		got = append(got, server.Project+"/"+server.Resource.UUID)
+	}
+	expected := []string{"dev/d-1", "dev/d-2", "prod/p-1", "prod/p-2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if n := limiter.waits.Load(); n != 2 {
+		t.Errorf("expected both projects to share the rate limiter, got %d waits", n)
+	}
+}
+
+func TestClientSet_ListAcrossProjectsPartialFailure(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc("/v1/servers", projectServers)
+
+	set := newTestClientSet(nil, nil)
+	set.Add(ctx, "prod", "p")
+	set.Add(ctx, "old", "broken")
+
+	servers, err := ListAcrossProjects(ctx, set, func(c *Client) GenericListService[Server] { return c.Servers })
+	var projectError *ProjectError
+	if !errors.As(err, &projectError) || projectError.Project != "old" {
+		t.Fatalf("expected an error of project old, got %v", err)
+	}
+	var errorResponse *ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the API error to be wrapped, got %v", err)
+	}
+	if len(servers) != 2 || servers[0].Project != "prod" {
+		t.Errorf("expected the servers of prod, got %v", servers)
+	}
+}
+
+func TestClientSet_TokenRotation(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc("/v1/servers", projectServers)
+
+	secrets := map[string]string{"prod": "p1"}
+	source := ProjectTokenSourceFunc(func(ctx context.Context, project string) (string, error) {
+		token, ok := secrets[project]
+		if !ok {
+			return "", errors.New("no such secret")
+		}
+		return token, nil
+	})
+	set := newTestClientSet(source, nil)
+
+	prod, err := set.Add(ctx, "prod", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Add(ctx, "missing", ""); err == nil {
+		t.Error("expected adding a project without secret to fail")
+	}
+	set.Add(ctx, "static", "s1")
+
+	listFirst := func() string {
+		servers, err := prod.Servers.List(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return servers[0].UUID
+	}
+	if id := listFirst(); id != "p1-1" {
+		t.Errorf("expected the token from the source, got %s", id)
+	}
+
+	secrets["prod"] = "p2"
+	err = set.RefreshTokens(ctx)
+	var projectError *ProjectError
+	if !errors.As(err, &projectError) || projectError.Project != "static" {
+		t.Errorf("expected refreshing static to fail, got %v", err)
+	}
+	if id := listFirst(); id != "p2-1" {
+		t.Errorf("expected the rotated token, got %s", id)
+	}
+	if set.Client("prod") != prod {
+		t.Error("expected rotation to keep the client")
+	}
+
+	if err := set.SetToken("prod", "p3"); err != nil {
+		t.Fatal(err)
+	}
+	if id := listFirst(); id != "p3-1" {
+		t.Errorf("expected the set token, got %s", id)
+	}
+	if err := set.SetToken("unknown", "x"); err == nil {
+		t.Error("expected an error for an unknown project")
+	}
+}
+
+func TestClientSet_ProjectContext(t *testing.T) {
+	var project string
+	set := NewClientSet(ClientSetOptions{HTTPClient: &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			project = Project(req.Context())
+			return nil, errors.New("not sent")
+		}),
+	}})
+	client, _ := set.Add(ctx, "prod", "p")
+	client.Servers.List(ctx)
+	if project != "prod" {
+		t.Errorf("expected the project in the request context, got %q", project)
+	}
+
+	set.Remove("prod")
+	if set.Client("prod") != nil || len(set.Projects()) != 0 {
+		t.Error("expected the project to be removed")
+	}
+}
+
+func TestClientSet_RefreshOnUnauthorized(t *testing.T) {
+	setup()
+	defer teardown()
+	mux.HandleFunc("/v1/servers", projectServers)
+
+	source := ProjectTokenSourceFunc(func(ctx context.Context, project string) (string, error) {
+		return "rotated", nil
+	})
+	set := newTestClientSet(source, nil)
+	client, _ := set.Add(ctx, "prod", "broken")
+
+	servers, err := client.Servers.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servers[0].UUID != "rotated-1" {
+		t.Errorf("expected the rejected token to be replaced from the source, got %s", servers[0].UUID)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 )
 
 const (
@@ -31,11 +32,40 @@ type Client struct {
 	// Authentication token
 	AuthToken string
 
+	// Credentials, if set, provides the token of every request instead of
+	// AuthToken. A request rejected with 401 Unauthorized is retried once if
+	// the token changed after refreshing the credentials.
+	Credentials CredentialsProvider
+
 	// User agent for client
 	UserAgent string
 
+	// WaitObserver, if set, is notified about the duration and outcome of
+	// every WaitFor call made through this client.
+	WaitObserver WaitObserver
+
+	// ValidateRequests makes NewRequest validate request bodies before they
+	// are sent and fail with a *ValidationError on mistakes.
+	ValidateRequests bool
+
+	// Catalog, if set, is used by request validation to check zones,
+	// flavors and images.
+	Catalog *Catalog
+
+	// StrictDecoding makes responses fail to decode with ErrUnknownField if
+	// the API returns fields the SDK does not model, e.g. in tests that keep
+	// the SDK in sync with the API. Otherwise unknown fields are kept in the
+	// UnknownFields of resources.
+	StrictDecoding bool
+
+	// RateLimiter, if set, is waited on before every request, e.g. a
+	// *rate.Limiter from golang.org/x/time/rate. Waiting ends early with an
+	// error once the request's context is done.
+	RateLimiter RateLimiter
+
 	Regions                    RegionService
 	Flavors                    FlavorService
+	Images                     ImageService
 	Servers                    ServerService
 	Volumes                    VolumeService
 	VolumeSnapshots            VolumeSnapshotService
@@ -79,6 +109,7 @@ func NewClient(httpClient *http.Client) *Client {
 		client: c,
 	}
 	c.Flavors = FlavorServiceOperations{client: c}
+	c.Images = ImageServiceOperations{client: c}
 	c.Networks = GenericServiceOperations[Network, NetworkCreateRequest, NetworkUpdateRequest]{
 		client: c,
 		path:   networkBasePath,
@@ -140,6 +171,10 @@ func NewClient(httpClient *http.Client) *Client {
 }
 
 func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
+	if err := c.validateRequest(body); err != nil {
+		return nil, err
+	}
+
 	rel, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, err
@@ -164,22 +199,33 @@ func (c *Client) NewRequest(ctx context.Context, method, urlStr string, body int
 	req.Header.Add("Accept", mediaType)
 	req.Header.Add("User-Agent", c.UserAgent)
 
-	if len(c.AuthToken) != 0 {
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(token) != 0 {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	}
 
 	return req, nil
 }
 
 func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error {
-
-	req = req.WithContext(ctx)
-
-	resp, err := c.client.Do(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		if retry := c.reauthenticate(ctx, req); retry != nil {
+			resp.Body.Close()
+			resp, err = c.send(WithRetryReason(ctx, "401"), retry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	defer func() {
 		if rerr := resp.Body.Close(); err == nil {
 			err = rerr
@@ -198,7 +244,11 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) error
 				return err
 			}
 		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return err
+			}
+			err = decodeResponse(data, v, c.StrictDecoding)
 			if err != nil {
 				return err
 			}
@@ -216,9 +266,11 @@ func CheckResponse(r *http.Response) error {
 	data, err := ioutil.ReadAll(r.Body)
 	res := map[string]string{}
 	if err == nil && len(data) > 0 {
-		err := json.Unmarshal(data, &res)
-		if err != nil {
-			return err
+		// Errors from proxies in front of the API, like an HTML 502 page,
+		// are not JSON but still need to carry the status code. Their body
+		// is kept, truncated, as it often tells which proxy failed.
+		if json.Unmarshal(data, &res) != nil {
+			res = map[string]string{"detail": http.StatusText(r.StatusCode) + ": " + truncateBody(data)}
 		}
 	}
 
@@ -228,6 +280,72 @@ func CheckResponse(r *http.Response) error {
 	}
 }
 
+// maxErrorBodyLength is the number of bytes of a body that is not JSON kept
+// in an ErrorResponse.
+const maxErrorBodyLength = 512
+
+func truncateBody(data []byte) string {
+	body := strings.TrimSpace(string(data))
+	if len(body) <= maxErrorBodyLength {
+		return body
+	}
+	return strings.ToValidUTF8(body[:maxErrorBodyLength], "") + "..."
+}
+
+// send waits for the RateLimiter and sends req.
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return c.client.Do(req.WithContext(ctx))
+}
+
+// token returns the token of the next request.
+func (c *Client) token(ctx context.Context) (string, error) {
+	if c.Credentials == nil {
+		return c.AuthToken, nil
+	}
+	token, err := c.Credentials.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("credentials: %w", err)
+	}
+	return token, nil
+}
+
+// reauthenticate refreshes the Credentials after req was rejected with 401
+// Unauthorized. It returns a copy of req with the new token, or nil if the
+// token did not change and retrying is pointless.
+func (c *Client) reauthenticate(ctx context.Context, req *http.Request) *http.Request {
+	if c.Credentials == nil || (req.Body != nil && req.GetBody == nil) {
+		return nil
+	}
+	if err := c.Credentials.Refresh(ctx); err != nil {
+		return nil
+	}
+	token, err := c.Credentials.Token(ctx)
+	if err != nil || token == "" || "Bearer "+token == req.Header.Get("Authorization") {
+		return nil
+	}
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return retry
+}
+
+// RateLimiter delays requests to stay within a request budget.
+type RateLimiter interface {
+	// Wait blocks until a request may be sent, or returns an error if ctx is
+	// done first.
+	Wait(ctx context.Context) error
+}
+
 type ErrorResponse struct {
 	StatusCode int
 	Message    map[string]string
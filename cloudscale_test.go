@@ -134,6 +134,43 @@ func TestCheckResponse(t *testing.T) {
 	}
 }
 
+func TestCheckResponse_NotJSON(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusBadGateway,
+		Body:       ioutil.NopCloser(strings.NewReader("<html><body><h1>502 Bad Gateway</h1></body></html>")),
+	}
+	err, ok := CheckResponse(res).(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Expected error response, got %v", err)
+	}
+
+	expected := &ErrorResponse{
+		StatusCode: http.StatusBadGateway,
+		Message:    map[string]string{"detail": "Bad Gateway: <html><body><h1>502 Bad Gateway</h1></body></html>"},
+	}
+	if !reflect.DeepEqual(err, expected) {
+		t.Errorf("Error = %#v, expected %#v", err, expected)
+	}
+}
+
+func TestCheckResponse_NotJSONTruncated(t *testing.T) {
+	res := &http.Response{
+		Request:    &http.Request{},
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       ioutil.NopCloser(strings.NewReader(strings.Repeat("x", 2*maxErrorBodyLength))),
+	}
+	err, ok := CheckResponse(res).(*ErrorResponse)
+	if !ok {
+		t.Fatalf("Expected error response, got %v", err)
+	}
+
+	expected := "Service Unavailable: " + strings.Repeat("x", maxErrorBodyLength) + "..."
+	if detail := err.Message["detail"]; detail != expected {
+		t.Errorf("detail = %q, expected %q", detail, expected)
+	}
+}
+
 func TestDo(t *testing.T) {
 	setup()
 	defer teardown()
@@ -0,0 +1,265 @@
+// Package cloudscalemock provides mocks of the service interfaces of
+// cloudscale.Client, for unit tests of code using the SDK.
+//
+// Every mock records its calls and answers them with canned responses set up
+// as expectations. Arguments are compared with reflect.DeepEqual, or with a
+// Matcher such as Any:
+//
+//	mocks := cloudscalemock.New()
+//	mocks.Servers.OnGet("uuid").Return(&cloudscale.Server{Status: "running"}, nil).Once()
+//	mocks.Servers.OnDelete(cloudscalemock.Any).ReturnError(nil)
+//
+//	client := mocks.Client()
+//	... // code under test using client
+//	mocks.AssertExpectations(t)
+//
+// Expectations are matched in the order they were set up, skipping those
+// that were called as often as allowed. Calls without a matching expectation
+// fail with ErrUnexpectedCall and are reported by AssertExpectations.
+//
+// WaitFor answers from its own expectations if there are any. Otherwise it
+// polls Get, without sleeping, until the condition is met, so a sequence of
+// canned Get responses plays out a resource changing its state.
+package cloudscalemock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrUnexpectedCall is returned by mocked methods called without a matching
+// expectation.
+var ErrUnexpectedCall = errors.New("unexpected call")
+
+// Call is a recorded call of a mocked method. Args exclude the context.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = fmt.Sprintf("%+v", arg)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method, strings.Join(args, ", "))
+}
+
+// Matcher matches an argument of a call.
+type Matcher interface {
+	Matches(arg any) bool
+}
+
+// MatcherFunc is a Matcher calling a function.
+type MatcherFunc func(arg any) bool
+
+func (f MatcherFunc) Matches(arg any) bool {
+	return f(arg)
+}
+
+// Any matches every argument.
+var Any Matcher = anyMatcher{}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Matches(any) bool { return true }
+func (anyMatcher) String() string   { return "Any" }
+
+// Match returns a Matcher for arguments of type T accepted by match, e.g.
+//
+//	cloudscalemock.Match(func(r *cloudscale.ServerRequest) bool { return r.Flavor == "flex-4-2" })
+func Match[T any](match func(T) bool) Matcher {
+	return MatcherFunc(func(arg any) bool {
+		typed, ok := arg.(T)
+		return ok && match(typed)
+	})
+}
+
+// TestingT is the part of testing.TB used to report failed assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Mock records calls and holds the expectations of a mocked service. It is
+// embedded in all service mocks; its zero value is ready to use.
+type Mock struct {
+	name string
+
+	mu           sync.Mutex
+	expectations []*expectation
+	calls        []Call
+	unexpected   []Call
+}
+
+type expectation struct {
+	method string
+	args   []any
+	// times limits the number of matching calls; zero allows any number.
+	times  int
+	calls  int
+	result any
+	err    error
+	run    func(Call)
+}
+
+func (e *expectation) matches(method string, args []any) bool {
+	if e.method != method || len(e.args) != len(args) {
+		return false
+	}
+	for i, expected := range e.args {
+		if matcher, ok := expected.(Matcher); ok {
+			if !matcher.Matches(args[i]) {
+				return false
+			}
+		} else if !reflect.DeepEqual(expected, args[i]) {
+			return false
+		}
+	}
+	return e.times == 0 || e.calls < e.times
+}
+
+// Expectation is the canned response of calls matching a method and its
+// arguments. R is the result type of the method.
+type Expectation[R any] struct {
+	e  *expectation
+	mu *sync.Mutex
+}
+
+func expect[R any](m *Mock, method string, args ...any) *Expectation[R] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &expectation{method: method, args: args}
+	m.expectations = append(m.expectations, e)
+	return &Expectation[R]{e: e, mu: &m.mu}
+}
+
+// Return sets the result and error of matching calls.
+func (x *Expectation[R]) Return(result R, err error) *Expectation[R] {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.e.result, x.e.err = result, err
+	return x
+}
+
+// ReturnError sets the error of matching calls, which return the zero result.
+func (x *Expectation[R]) ReturnError(err error) *Expectation[R] {
+	var zero R
+	return x.Return(zero, err)
+}
+
+// Times limits the expectation to n calls, and makes AssertExpectations
+// require exactly n calls.
+func (x *Expectation[R]) Times(n int) *Expectation[R] {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.e.times = n
+	return x
+}
+
+// Once is short for Times(1).
+func (x *Expectation[R]) Once() *Expectation[R] {
+	return x.Times(1)
+}
+
+// Run calls fn with every matching call before returning, e.g. to capture
+// requests.
+func (x *Expectation[R]) Run(fn func(Call)) *Expectation[R] {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.e.run = fn
+	return x
+}
+
+// call records a call and returns the result of the first matching
+// expectation. Without one, it fails with ErrUnexpectedCall and records the
+// call as unexpected, unless optional is set.
+func call[R any](m *Mock, optional bool, method string, args ...any) (R, bool, error) {
+	var zero R
+	c := Call{Method: method, Args: args}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, c)
+	var matched *expectation
+	for _, e := range m.expectations {
+		if e.matches(method, args) {
+			matched = e
+			matched.calls++
+			break
+		}
+	}
+	if matched == nil && !optional {
+		m.unexpected = append(m.unexpected, c)
+	}
+	m.mu.Unlock()
+
+	if matched == nil {
+		return zero, false, fmt.Errorf("%w: %s", ErrUnexpectedCall, m.describe(c))
+	}
+	if matched.run != nil {
+		matched.run(c)
+	}
+	result, _ := matched.result.(R)
+	return result, true, matched.err
+}
+
+func (m *Mock) describe(c Call) string {
+	if m.name == "" {
+		return c.String()
+	}
+	return m.name + "." + c.String()
+}
+
+// Calls returns the recorded calls of method, or all calls if method is
+// empty.
+func (m *Mock) Calls(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	calls := []Call{}
+	for _, c := range m.calls {
+		if method == "" || c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// CallCount returns how often method was called.
+func (m *Mock) CallCount(method string) int {
+	return len(m.Calls(method))
+}
+
+// Reset removes all expectations and recorded calls.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations, m.calls, m.unexpected = nil, nil, nil
+}
+
+// AssertExpectations reports expectations that were not called, or not as
+// often as set with Times, and unexpected calls.
+func (m *Mock) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if (e.times == 0 && e.calls == 0) || (e.times > 0 && e.calls != e.times) {
+			expected := "at least once"
+			if e.times > 0 {
+				expected = fmt.Sprintf("%d time(s)", e.times)
+			}
+			t.Errorf("expected %s to be called %s, got %d call(s)", m.describe(Call{Method: e.method, Args: e.args}), expected, e.calls)
+			ok = false
+		}
+	}
+	for _, c := range m.unexpected {
+		t.Errorf("unexpected call %s", m.describe(c))
+		ok = false
+	}
+	return ok
+}
@@ -0,0 +1,238 @@
+package cloudscalemock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// fakeT records the failures reported by AssertExpectations.
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestServices_Expectations(t *testing.T) {
+	mocks := New()
+	client := mocks.Client()
+	ctx := context.Background()
+
+	mocks.Servers.OnGet("s1").Return(&cloudscale.Server{UUID: "s1", Status: "changing"}, nil).Once()
+	mocks.Servers.OnGet("s1").Return(&cloudscale.Server{UUID: "s1", Status: "running"}, nil)
+	mocks.Servers.OnDelete(Any).ReturnError(nil)
+	mocks.Volumes.OnCreate(Match(func(r *cloudscale.VolumeCreateRequest) bool { return r.SizeGB == 50 })).
+		Return(&cloudscale.Volume{UUID: "v1"}, nil)
+
+	for _, status := range []string{"changing", "running", "running"} {
+		server, err := client.Servers.Get(ctx, "s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(server.Status) != status {
+			t.Errorf("expected %s, got %s", status, server.Status)
+		}
+	}
+	if err := client.Servers.Delete(ctx, "any"); err != nil {
+		t.Error(err)
+	}
+	volume, err := client.Volumes.Create(ctx, &cloudscale.VolumeCreateRequest{SizeGB: 50})
+	if err != nil || volume.UUID != "v1" {
+		t.Errorf("unexpected volume %v, %v", volume, err)
+	}
+
+	if n := mocks.Servers.CallCount("Get"); n != 3 {
+		t.Errorf("expected 3 calls of Get, got %d", n)
+	}
+	expected := []Call{{Method: "Delete", Args: []any{"any"}}}
+	if calls := mocks.Servers.Calls("Delete"); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("unexpected calls %v", calls)
+	}
+	if !mocks.AssertExpectations(t) {
+		t.Error("expected all expectations to be met")
+	}
+}
+
+func TestServices_UnexpectedCall(t *testing.T) {
+	mocks := New()
+	client := mocks.Client()
+	ctx := context.Background()
+
+	mocks.Networks.OnDelete("n1").ReturnError(nil).Once()
+	mocks.Subnets.OnGet("unused").Return(&cloudscale.Subnet{}, nil).Times(2)
+
+	client.Networks.Delete(ctx, "n1")
+	err := client.Networks.Delete(ctx, "n1")
+	if !errors.Is(err, ErrUnexpectedCall) || !strings.Contains(err.Error(), "Networks.Delete(n1)") {
+		t.Errorf("expected an unexpected call, got %v", err)
+	}
+
+	fake := &fakeT{}
+	if mocks.AssertExpectations(fake) {
+		t.Error("expected AssertExpectations to fail")
+	}
+	if len(fake.errors) != 2 {
+		t.Fatalf("expected 2 failures, got %v", fake.errors)
+	}
+	failures := strings.Join(fake.errors, "\n")
+	for _, failure := range []string{
+		"unexpected call Networks.Delete(n1)",
+		"expected Subnets.Get(unused) to be called 2 time(s), got 0 call(s)",
+	} {
+		if !strings.Contains(failures, failure) {
+			t.Errorf("expected %q in\n%s", failure, failures)
+		}
+	}
+
+	mocks.Networks.Reset()
+	mocks.Subnets.Reset()
+	if !mocks.AssertExpectations(fake) {
+		t.Error("expected Reset to remove expectations and calls")
+	}
+}
+
+func TestService_WaitForPollsGet(t *testing.T) {
+	mocks := New()
+	ctx := context.Background()
+
+	mocks.Servers.OnGet("s1").Return(&cloudscale.Server{Status: "changing"}, nil).Times(2)
+	mocks.Servers.OnGet("s1").Return(&cloudscale.Server{Status: "running"}, nil).Once()
+
+	server, err := mocks.Servers.WaitFor(ctx, "s1", cloudscale.ServerIsRunning)
+	if err != nil || server.Status != cloudscale.ServerRunning {
+		t.Errorf("unexpected server %v, %v", server, err)
+	}
+	mocks.AssertExpectations(t)
+
+	mocks.Servers.OnGet("s2").Return(&cloudscale.Server{Status: "stopped"}, nil)
+	_, err = mocks.Servers.WaitFor(ctx, "s2", cloudscale.ServerIsRunning)
+	if !errors.Is(err, ErrConditionNotMet) {
+		t.Errorf("expected the condition not to be met, got %v", err)
+	}
+	if n := mocks.Servers.CallCount("Get"); n != 3+maxWaitPolls {
+		t.Errorf("expected %d calls of Get, got %d", 3+maxWaitPolls, n)
+	}
+
+	mocks.Servers.OnGet("s3").Return(&cloudscale.Server{Status: cloudscale.ServerErrored}, nil).Once()
+	_, err = mocks.Servers.WaitFor(ctx, "s3", cloudscale.ServerIsRunning)
+	var stateError *cloudscale.StateError
+	if !errors.As(err, &stateError) {
+		t.Errorf("expected a permanent failure to stop polling, got %v", err)
+	}
+}
+
+func TestService_WaitForExpectation(t *testing.T) {
+	mocks := New()
+	ctx := context.Background()
+
+	mocks.Servers.OnWaitFor("s1").Return(&cloudscale.Server{Status: "running"}, nil)
+	mocks.Servers.OnWaitFor("s2").Return(&cloudscale.Server{Status: "stopped"}, nil)
+	timeout := errors.New("timeout")
+	mocks.Servers.OnWaitFor("s3").ReturnError(timeout)
+
+	if _, err := mocks.Servers.WaitFor(ctx, "s1", cloudscale.ServerIsRunning); err != nil {
+		t.Error(err)
+	}
+	if _, err := mocks.Servers.WaitFor(ctx, "s2", cloudscale.ServerIsRunning); !errors.Is(err, ErrConditionNotMet) {
+		t.Errorf("expected the condition not to be met, got %v", err)
+	}
+	if _, err := mocks.Servers.WaitFor(ctx, "s3", cloudscale.ServerIsRunning); !errors.Is(err, timeout) {
+		t.Errorf("expected the canned error, got %v", err)
+	}
+	if n := mocks.Servers.CallCount("Get"); n != 0 {
+		t.Errorf("expected no calls of Get, got %d", n)
+	}
+	mocks.AssertExpectations(t)
+}
+
+func TestLoadBalancerPoolMemberService(t *testing.T) {
+	mocks := New()
+	client := mocks.Client()
+	ctx := context.Background()
+
+	member := &cloudscale.LoadBalancerPoolMember{UUID: "m1", Name: "web"}
+	mocks.LoadBalancerPoolMembers.OnCreate("p1", Any).Return(member, nil)
+	mocks.LoadBalancerPoolMembers.OnGet("p1", "m1").Return(member, nil)
+	mocks.LoadBalancerPoolMembers.OnList("p2").Return(nil, nil)
+	mocks.LoadBalancerPoolMembers.OnUpdate("p1", "m1", Any).ReturnError(nil)
+
+	created, err := client.LoadBalancerPoolMembers.Create(ctx, "p1", &cloudscale.LoadBalancerPoolMemberRequest{Name: "web"})
+	if err != nil || created != member {
+		t.Errorf("unexpected member %v, %v", created, err)
+	}
+	if _, err := client.LoadBalancerPoolMembers.List(ctx, "p2"); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.LoadBalancerPoolMembers.List(ctx, "p1"); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("expected the pool to be matched, got %v", err)
+	}
+	if err := client.LoadBalancerPoolMembers.Update(ctx, "p1", "m1", &cloudscale.LoadBalancerPoolMemberRequest{}); err != nil {
+		t.Error(err)
+	}
+	named := func(m *cloudscale.LoadBalancerPoolMember) (bool, error) { return m.Name == "web", nil }
+	if _, err := client.LoadBalancerPoolMembers.WaitFor(ctx, "p1", "m1", named); err != nil {
+		t.Error(err)
+	}
+
+	expected := []Call{{Method: "Get", Args: []any{"p1", "m1"}}}
+	if calls := mocks.LoadBalancerPoolMembers.Calls("Get"); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+func TestService_Watch(t *testing.T) {
+	mocks := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mocks.Volumes.OnWatch().Return([]cloudscale.Event[cloudscale.Volume]{
+		{Type: cloudscale.EventAdded, ID: "v1", Resource: &cloudscale.Volume{UUID: "v1"}},
+		{Type: cloudscale.EventDeleted, ID: "v1", Resource: &cloudscale.Volume{UUID: "v1"}},
+	}, nil)
+
+	events := mocks.Client().Volumes.Watch(ctx, time.Second)
+	for _, eventType := range []cloudscale.EventType{cloudscale.EventAdded, cloudscale.EventDeleted} {
+		if event := <-events; event.Type != eventType || event.ID != "v1" {
+			t.Errorf("expected %s of v1, got %+v", eventType, event)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected the channel to stay open, got %+v", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("expected the channel to be closed with the context")
+	}
+}
+
+func TestListAndMetricsServices(t *testing.T) {
+	mocks := New()
+	client := mocks.Client()
+	ctx := context.Background()
+
+	mocks.Regions.OnList().Return([]cloudscale.Region{{Slug: "lpg"}}, nil)
+	request := &cloudscale.BucketMetricsRequest{BucketNames: []string{"b1"}}
+	mocks.Metrics.OnGetBucketMetrics(request).Return(&cloudscale.BucketMetrics{}, nil)
+
+	regions, err := client.Regions.List(ctx)
+	if err != nil || len(regions) != 1 || regions[0].Slug != "lpg" {
+		t.Errorf("unexpected regions %v, %v", regions, err)
+	}
+	if _, err := client.Metrics.GetBucketMetrics(ctx, &cloudscale.BucketMetricsRequest{BucketNames: []string{"b1"}}); err != nil {
+		t.Errorf("expected equal requests to match, got %v", err)
+	}
+	if _, err := client.Flavors.List(ctx); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("expected an unexpected call, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package cloudscalemock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// maxWaitPolls bounds the Get calls of an emulated WaitFor, so that a
+// condition that is never met fails instead of hanging the test.
+const maxWaitPolls = 100
+
+// ErrConditionNotMet is returned by WaitFor when the condition is not met by
+// the canned resource, or by any of maxWaitPolls responses of Get.
+var ErrConditionNotMet = errors.New("condition not met")
+
+// None is the result type of methods that only return an error.
+type None struct{}
+
+// Service mocks the generic operations shared by most services. Its
+// methods and those of ServerService and LoadBalancerPoolMemberService
+// record calls under their method names, e.g. "Get".
+type Service[TResource, TCreateRequest, TUpdateRequest any] struct {
+	Mock
+}
+
+// OnCreate sets up the response to Create calls with request.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnCreate(request any) *Expectation[*TResource] {
+	return expect[*TResource](&s.Mock, "Create", request)
+}
+
+// OnGet sets up the response to Get calls for resourceID.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnGet(resourceID any) *Expectation[*TResource] {
+	return expect[*TResource](&s.Mock, "Get", resourceID)
+}
+
+// OnList sets up the response to List calls. List modifiers are recorded
+// but not matched.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnList() *Expectation[[]TResource] {
+	return expect[[]TResource](&s.Mock, "List")
+}
+
+// OnUpdate sets up the response to Update calls for resourceID with request.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnUpdate(resourceID, request any) *Expectation[None] {
+	return expect[None](&s.Mock, "Update", resourceID, request)
+}
+
+// OnDelete sets up the response to Delete calls for resourceID.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnDelete(resourceID any) *Expectation[None] {
+	return expect[None](&s.Mock, "Delete", resourceID)
+}
+
+// OnWaitFor sets up the response to WaitFor calls for resourceID. The
+// condition is checked against the canned resource.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnWaitFor(resourceID any) *Expectation[*TResource] {
+	return expect[*TResource](&s.Mock, "WaitFor", resourceID)
+}
+
+// OnWatch sets up the events emitted by Watch and WatchWithOptions. The
+// channel stays open after the events until the context is done.
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) OnWatch() *Expectation[[]cloudscale.Event[TResource]] {
+	return expect[[]cloudscale.Event[TResource]](&s.Mock, "Watch")
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) Create(ctx context.Context, createRequest *TCreateRequest) (*TResource, error) {
+	resource, _, err := call[*TResource](&s.Mock, false, "Create", createRequest)
+	return resource, err
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) Get(ctx context.Context, resourceID string) (*TResource, error) {
+	resource, _, err := call[*TResource](&s.Mock, false, "Get", resourceID)
+	return resource, err
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) List(ctx context.Context, modifiers ...cloudscale.ListRequestModifier) ([]TResource, error) {
+	resources, _, err := call[[]TResource](&s.Mock, false, "List")
+	return resources, err
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) Update(ctx context.Context, resourceID string, updateRequest *TUpdateRequest) error {
+	_, _, err := call[None](&s.Mock, false, "Update", resourceID, updateRequest)
+	return err
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) Delete(ctx context.Context, resourceID string) error {
+	_, _, err := call[None](&s.Mock, false, "Delete", resourceID)
+	return err
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) WaitFor(ctx context.Context, resourceID string, condition func(resource *TResource) (bool, error), opts ...backoff.RetryOption) (*TResource, error) {
+	return waitFor(&s.Mock, resourceID, condition, func() (*TResource, error) {
+		return s.Get(ctx, resourceID)
+	})
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) Watch(ctx context.Context, interval time.Duration, modifiers ...cloudscale.ListRequestModifier) <-chan cloudscale.Event[TResource] {
+	return watch[TResource](ctx, &s.Mock)
+}
+
+func (s *Service[TResource, TCreateRequest, TUpdateRequest]) WatchWithOptions(ctx context.Context, options cloudscale.WatchOptions) <-chan cloudscale.Event[TResource] {
+	return watch[TResource](ctx, &s.Mock)
+}
+
+// waitFor answers from WaitFor expectations, or else polls get.
+func waitFor[TResource any](m *Mock, resourceID string, condition func(*TResource) (bool, error), get func() (*TResource, error), scope ...any) (*TResource, error) {
+	args := append(append([]any{}, scope...), resourceID)
+	resource, matched, err := call[*TResource](m, true, "WaitFor", args...)
+	if matched {
+		if err != nil || resource == nil {
+			return resource, err
+		}
+		return resource, check(resource, condition)
+	}
+
+	for i := 0; i < maxWaitPolls; i++ {
+		resource, err = get()
+		if err != nil {
+			return resource, err
+		}
+		if err := check(resource, condition); !errors.Is(err, ErrConditionNotMet) {
+			return resource, err
+		}
+	}
+	return resource, fmt.Errorf("%w after %d polls of %s", ErrConditionNotMet, maxWaitPolls, resourceID)
+}
+
+// check returns nil once condition is met, the error of a condition that
+// fails permanently, and otherwise ErrConditionNotMet wrapping the reason.
+func check[TResource any](resource *TResource, condition func(*TResource) (bool, error)) error {
+	done, err := condition(resource)
+	var permanent *backoff.PermanentError
+	switch {
+	case done:
+		return nil
+	case errors.As(err, &permanent):
+		return permanent.Err
+	case err != nil:
+		return fmt.Errorf("%w: %w", ErrConditionNotMet, err)
+	}
+	return ErrConditionNotMet
+}
+
+func watch[TResource any](ctx context.Context, m *Mock, scope ...any) <-chan cloudscale.Event[TResource] {
+	events, _, _ := call[[]cloudscale.Event[TResource]](m, false, "Watch", scope...)
+	ch := make(chan cloudscale.Event[TResource])
+	go func() {
+		defer close(ch)
+		for _, event := range events {
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return ch
+}
+
+// ServerService mocks cloudscale.ServerService.
+type ServerService struct {
+	Service[cloudscale.Server, cloudscale.ServerRequest, cloudscale.ServerUpdateRequest]
+}
+
+// OnReboot sets up the response to Reboot calls for serverID.
+func (s *ServerService) OnReboot(serverID any) *Expectation[None] {
+	return expect[None](&s.Mock, "Reboot", serverID)
+}
+
+// OnStart sets up the response to Start calls for serverID.
+func (s *ServerService) OnStart(serverID any) *Expectation[None] {
+	return expect[None](&s.Mock, "Start", serverID)
+}
+
+// OnStop sets up the response to Stop calls for serverID.
+func (s *ServerService) OnStop(serverID any) *Expectation[None] {
+	return expect[None](&s.Mock, "Stop", serverID)
+}
+
+func (s *ServerService) Reboot(ctx context.Context, serverID string) error {
+	_, _, err := call[None](&s.Mock, false, "Reboot", serverID)
+	return err
+}
+
+func (s *ServerService) Start(ctx context.Context, serverID string) error {
+	_, _, err := call[None](&s.Mock, false, "Start", serverID)
+	return err
+}
+
+func (s *ServerService) Stop(ctx context.Context, serverID string) error {
+	_, _, err := call[None](&s.Mock, false, "Stop", serverID)
+	return err
+}
+
+// LoadBalancerPoolMemberService mocks cloudscale.LoadBalancerPoolMemberService.
+// Its calls are recorded and matched with the pool ID as first argument.
+type LoadBalancerPoolMemberService struct {
+	Mock
+}
+
+type poolMember = cloudscale.LoadBalancerPoolMember
+
+// OnCreate sets up the response to Create calls in poolID with request.
+func (s *LoadBalancerPoolMemberService) OnCreate(poolID, request any) *Expectation[*poolMember] {
+	return expect[*poolMember](&s.Mock, "Create", poolID, request)
+}
+
+// OnGet sets up the response to Get calls in poolID for resourceID.
+func (s *LoadBalancerPoolMemberService) OnGet(poolID, resourceID any) *Expectation[*poolMember] {
+	return expect[*poolMember](&s.Mock, "Get", poolID, resourceID)
+}
+
+// OnList sets up the response to List calls in poolID.
+func (s *LoadBalancerPoolMemberService) OnList(poolID any) *Expectation[[]poolMember] {
+	return expect[[]poolMember](&s.Mock, "List", poolID)
+}
+
+// OnUpdate sets up the response to Update calls in poolID for resourceID
+// with request.
+func (s *LoadBalancerPoolMemberService) OnUpdate(poolID, resourceID, request any) *Expectation[None] {
+	return expect[None](&s.Mock, "Update", poolID, resourceID, request)
+}
+
+// OnDelete sets up the response to Delete calls in poolID for resourceID.
+func (s *LoadBalancerPoolMemberService) OnDelete(poolID, resourceID any) *Expectation[None] {
+	return expect[None](&s.Mock, "Delete", poolID, resourceID)
+}
+
+// OnWaitFor sets up the response to WaitFor calls in poolID for resourceID.
+func (s *LoadBalancerPoolMemberService) OnWaitFor(poolID, resourceID any) *Expectation[*poolMember] {
+	return expect[*poolMember](&s.Mock, "WaitFor", poolID, resourceID)
+}
+
+// OnWatch sets up the events emitted by Watch and WatchWithOptions in poolID.
+func (s *LoadBalancerPoolMemberService) OnWatch(poolID any) *Expectation[[]cloudscale.Event[poolMember]] {
+	return expect[[]cloudscale.Event[poolMember]](&s.Mock, "Watch", poolID)
+}
+
+func (s *LoadBalancerPoolMemberService) Create(ctx context.Context, poolID string, createRequest *cloudscale.LoadBalancerPoolMemberRequest) (*poolMember, error) {
+	member, _, err := call[*poolMember](&s.Mock, false, "Create", poolID, createRequest)
+	return member, err
+}
+
+func (s *LoadBalancerPoolMemberService) Get(ctx context.Context, poolID string, resourceID string) (*poolMember, error) {
+	member, _, err := call[*poolMember](&s.Mock, false, "Get", poolID, resourceID)
+	return member, err
+}
+
+func (s *LoadBalancerPoolMemberService) List(ctx context.Context, poolID string, modifiers ...cloudscale.ListRequestModifier) ([]poolMember, error) {
+	members, _, err := call[[]poolMember](&s.Mock, false, "List", poolID)
+	return members, err
+}
+
+func (s *LoadBalancerPoolMemberService) Update(ctx context.Context, poolID string, resourceID string, updateRequest *cloudscale.LoadBalancerPoolMemberRequest) error {
+	_, _, err := call[None](&s.Mock, false, "Update", poolID, resourceID, updateRequest)
+	return err
+}
+
+func (s *LoadBalancerPoolMemberService) Delete(ctx context.Context, poolID string, resourceID string) error {
+	_, _, err := call[None](&s.Mock, false, "Delete", poolID, resourceID)
+	return err
+}
+
+func (s *LoadBalancerPoolMemberService) WaitFor(ctx context.Context, poolID string, resourceID string, condition func(resource *poolMember) (bool, error), opts ...backoff.RetryOption) (*poolMember, error) {
+	return waitFor(&s.Mock, resourceID, condition, func() (*poolMember, error) {
+		return s.Get(ctx, poolID, resourceID)
+	}, poolID)
+}
+
+func (s *LoadBalancerPoolMemberService) Watch(ctx context.Context, poolID string, interval time.Duration, modifiers ...cloudscale.ListRequestModifier) <-chan cloudscale.Event[poolMember] {
+	return watch[poolMember](ctx, &s.Mock, poolID)
+}
+
+func (s *LoadBalancerPoolMemberService) WatchWithOptions(ctx context.Context, poolID string, options cloudscale.WatchOptions) <-chan cloudscale.Event[poolMember] {
+	return watch[poolMember](ctx, &s.Mock, poolID)
+}
+
+// ListService mocks the services of regions, flavors and images, which can
+// only be listed.
+type ListService[TResource any] struct {
+	Mock
+}
+
+// OnList sets up the response to List calls.
+func (s *ListService[TResource]) OnList() *Expectation[[]TResource] {
+	return expect[[]TResource](&s.Mock, "List")
+}
+
+func (s *ListService[TResource]) List(ctx context.Context) ([]TResource, error) {
+	resources, _, err := call[[]TResource](&s.Mock, false, "List")
+	return resources, err
+}
+
+// MetricsService mocks cloudscale.MetricsService.
+type MetricsService struct {
+	Mock
+}
+
+// OnGetBucketMetrics sets up the response to GetBucketMetrics calls with
+// request.
+func (s *MetricsService) OnGetBucketMetrics(request any) *Expectation[*cloudscale.BucketMetrics] {
+	return expect[*cloudscale.BucketMetrics](&s.Mock, "GetBucketMetrics", request)
+}
+
+func (s *MetricsService) GetBucketMetrics(ctx context.Context, request *cloudscale.BucketMetricsRequest) (*cloudscale.BucketMetrics, error) {
+	metrics, _, err := call[*cloudscale.BucketMetrics](&s.Mock, false, "GetBucketMetrics", request)
+	return metrics, err
+}
@@ -0,0 +1,164 @@
+package cloudscalemock
+
+import (
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Mocks of the services of cloudscale.Client without methods of their own.
+type (
+	VolumeService                    = Service[cloudscale.Volume, cloudscale.VolumeCreateRequest, cloudscale.VolumeUpdateRequest]
+	VolumeSnapshotService            = Service[cloudscale.VolumeSnapshot, cloudscale.VolumeSnapshotCreateRequest, cloudscale.VolumeSnapshotUpdateRequest]
+	NetworkService                   = Service[cloudscale.Network, cloudscale.NetworkCreateRequest, cloudscale.NetworkUpdateRequest]
+	SubnetService                    = Service[cloudscale.Subnet, cloudscale.SubnetCreateRequest, cloudscale.SubnetUpdateRequest]
+	FloatingIPsService               = Service[cloudscale.FloatingIP, cloudscale.FloatingIPCreateRequest, cloudscale.FloatingIPUpdateRequest]
+	ServerGroupService               = Service[cloudscale.ServerGroup, cloudscale.ServerGroupRequest, cloudscale.ServerGroupRequest]
+	ObjectsUsersService              = Service[cloudscale.ObjectsUser, cloudscale.ObjectsUserRequest, cloudscale.ObjectsUserRequest]
+	CustomImageService               = Service[cloudscale.CustomImage, cloudscale.CustomImageRequest, cloudscale.CustomImageRequest]
+	CustomImageImportsService        = Service[cloudscale.CustomImageImport, cloudscale.CustomImageImportRequest, cloudscale.CustomImageImportRequest]
+	LoadBalancerService              = Service[cloudscale.LoadBalancer, cloudscale.LoadBalancerRequest, cloudscale.LoadBalancerRequest]
+	LoadBalancerPoolService          = Service[cloudscale.LoadBalancerPool, cloudscale.LoadBalancerPoolRequest, cloudscale.LoadBalancerPoolRequest]
+	LoadBalancerListenerService      = Service[cloudscale.LoadBalancerListener, cloudscale.LoadBalancerListenerRequest, cloudscale.LoadBalancerListenerRequest]
+	LoadBalancerHealthMonitorService = Service[cloudscale.LoadBalancerHealthMonitor, cloudscale.LoadBalancerHealthMonitorRequest, cloudscale.LoadBalancerHealthMonitorRequest]
+	RegionService                    = ListService[cloudscale.Region]
+	FlavorService                    = ListService[cloudscale.Flavor]
+	ImageService                     = ListService[cloudscale.Image]
+)
+
+// _ ensures that the mocks implement the service interfaces at compile time.
+var (
+	_ cloudscale.RegionService                    = &RegionService{}
+	_ cloudscale.FlavorService                    = &FlavorService{}
+	_ cloudscale.ImageService                     = &ImageService{}
+	_ cloudscale.ServerService                    = &ServerService{}
+	_ cloudscale.VolumeService                    = &VolumeService{}
+	_ cloudscale.VolumeSnapshotService            = &VolumeSnapshotService{}
+	_ cloudscale.NetworkService                   = &NetworkService{}
+	_ cloudscale.SubnetService                    = &SubnetService{}
+	_ cloudscale.FloatingIPsService               = &FloatingIPsService{}
+	_ cloudscale.ServerGroupService               = &ServerGroupService{}
+	_ cloudscale.ObjectsUsersService              = &ObjectsUsersService{}
+	_ cloudscale.CustomImageService               = &CustomImageService{}
+	_ cloudscale.CustomImageImportsService        = &CustomImageImportsService{}
+	_ cloudscale.LoadBalancerService              = &LoadBalancerService{}
+	_ cloudscale.LoadBalancerPoolService          = &LoadBalancerPoolService{}
+	_ cloudscale.LoadBalancerPoolMemberService    = &LoadBalancerPoolMemberService{}
+	_ cloudscale.LoadBalancerListenerService      = &LoadBalancerListenerService{}
+	_ cloudscale.LoadBalancerHealthMonitorService = &LoadBalancerHealthMonitorService{}
+	_ cloudscale.MetricsService                   = &MetricsService{}
+)
+
+// Services holds a mock of every service of cloudscale.Client.
+type Services struct {
+	Regions                    *RegionService
+	Flavors                    *FlavorService
+	Images                     *ImageService
+	Servers                    *ServerService
+	Volumes                    *VolumeService
+	VolumeSnapshots            *VolumeSnapshotService
+	Networks                   *NetworkService
+	Subnets                    *SubnetService
+	FloatingIPs                *FloatingIPsService
+	ServerGroups               *ServerGroupService
+	ObjectsUsers               *ObjectsUsersService
+	CustomImages               *CustomImageService
+	CustomImageImports         *CustomImageImportsService
+	LoadBalancers              *LoadBalancerService
+	LoadBalancerPools          *LoadBalancerPoolService
+	LoadBalancerPoolMembers    *LoadBalancerPoolMemberService
+	LoadBalancerListeners      *LoadBalancerListenerService
+	LoadBalancerHealthMonitors *LoadBalancerHealthMonitorService
+	Metrics                    *MetricsService
+}
+
+// New returns mocks of all services, named after the fields of
+// cloudscale.Client in failure messages.
+func New() *Services {
+	s := &Services{
+		Regions:                    &RegionService{},
+		Flavors:                    &FlavorService{},
+		Images:                     &ImageService{},
+		Servers:                    &ServerService{},
+		Volumes:                    &VolumeService{},
+		VolumeSnapshots:            &VolumeSnapshotService{},
+		Networks:                   &NetworkService{},
+		Subnets:                    &SubnetService{},
+		FloatingIPs:                &FloatingIPsService{},
+		ServerGroups:               &ServerGroupService{},
+		ObjectsUsers:               &ObjectsUsersService{},
+		CustomImages:               &CustomImageService{},
+		CustomImageImports:         &CustomImageImportsService{},
+		LoadBalancers:              &LoadBalancerService{},
+		LoadBalancerPools:          &LoadBalancerPoolService{},
+		LoadBalancerPoolMembers:    &LoadBalancerPoolMemberService{},
+		LoadBalancerListeners:      &LoadBalancerListenerService{},
+		LoadBalancerHealthMonitors: &LoadBalancerHealthMonitorService{},
+		Metrics:                    &MetricsService{},
+	}
+	for name, mock := range s.mocks() {
+		mock.name = name
+	}
+	return s
+}
+
+func (s *Services) mocks() map[string]*Mock {
+	return map[string]*Mock{
+		"Regions":                    &s.Regions.Mock,
+		"Flavors":                    &s.Flavors.Mock,
+		"Images":                     &s.Images.Mock,
+		"Servers":                    &s.Servers.Mock,
+		"Volumes":                    &s.Volumes.Mock,
+		"VolumeSnapshots":            &s.VolumeSnapshots.Mock,
+		"Networks":                   &s.Networks.Mock,
+		"Subnets":                    &s.Subnets.Mock,
+		"FloatingIPs":                &s.FloatingIPs.Mock,
+		"ServerGroups":               &s.ServerGroups.Mock,
+		"ObjectsUsers":               &s.ObjectsUsers.Mock,
+		"CustomImages":               &s.CustomImages.Mock,
+		"CustomImageImports":         &s.CustomImageImports.Mock,
+		"LoadBalancers":              &s.LoadBalancers.Mock,
+		"LoadBalancerPools":          &s.LoadBalancerPools.Mock,
+		"LoadBalancerPoolMembers":    &s.LoadBalancerPoolMembers.Mock,
+		"LoadBalancerListeners":      &s.LoadBalancerListeners.Mock,
+		"LoadBalancerHealthMonitors": &s.LoadBalancerHealthMonitors.Mock,
+		"Metrics":                    &s.Metrics.Mock,
+	}
+}
+
+// Client returns a client whose services are the mocks. Functions of the SDK
+// taking a *cloudscale.Client, like BulkDelete with client.Servers, work with
+// it as well.
+func (s *Services) Client() *cloudscale.Client {
+	client := cloudscale.NewClient(nil)
+	client.Regions = s.Regions
+	client.Flavors = s.Flavors
+	client.Images = s.Images
+	client.Servers = s.Servers
+	client.Volumes = s.Volumes
+	client.VolumeSnapshots = s.VolumeSnapshots
+	client.Networks = s.Networks
+	client.Subnets = s.Subnets
+	client.FloatingIPs = s.FloatingIPs
+	client.ServerGroups = s.ServerGroups
+	client.ObjectsUsers = s.ObjectsUsers
+	client.CustomImages = s.CustomImages
+	client.CustomImageImports = s.CustomImageImports
+	client.LoadBalancers = s.LoadBalancers
+	client.LoadBalancerPools = s.LoadBalancerPools
+	client.LoadBalancerPoolMembers = s.LoadBalancerPoolMembers
+	client.LoadBalancerListeners = s.LoadBalancerListeners
+	client.LoadBalancerHealthMonitors = s.LoadBalancerHealthMonitors
+	client.Metrics = s.Metrics
+	return client
+}
+
+// AssertExpectations asserts the expectations of all mocks.
+func (s *Services) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	ok := true
+	for _, mock := range s.mocks() {
+		if !mock.AssertExpectations(t) {
+			ok = false
+		}
+	}
+	return ok
+}
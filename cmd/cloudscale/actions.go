@@ -0,0 +1,545 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// resource is a command like "server", with an action per operation of the
+// service.
+type resource struct {
+	name    string
+	summary string
+	columns []column
+	actions []*action
+	// scope, if set, defines flags shared by all actions, e.g. the pool of
+	// load balancer pool members.
+	scope func(fs *flag.FlagSet)
+}
+
+func (r *resource) action(name string) (*action, bool) {
+	for _, act := range r.actions {
+		if act.name == name {
+			return act, true
+		}
+	}
+	return nil, false
+}
+
+// action is a subcommand like "server list". Setup defines its flags and
+// returns the function running it once they are parsed.
+type action struct {
+	name    string
+	summary string
+	// args describes the positional arguments, nargs is their number.
+	args  string
+	nargs int
+	// columns overrides the table columns of the resource.
+	columns []column
+	setup   func(fs *flag.FlagSet) execFunc
+}
+
+// execFunc runs an action and returns the result to print, or nil.
+type execFunc func(ctx context.Context, args []string) (any, error)
+
+// service adapts an SDK service to the CLI. Actions are only created for the
+// operations that are set.
+type service[TResource, TCreateRequest, TUpdateRequest any] struct {
+	list    func(ctx context.Context, modifiers ...cloudscale.ListRequestModifier) ([]TResource, error)
+	get     func(ctx context.Context, resourceID string) (*TResource, error)
+	create  func(ctx context.Context, createRequest *TCreateRequest) (*TResource, error)
+	update  func(ctx context.Context, resourceID string, updateRequest *TUpdateRequest) error
+	delete  func(ctx context.Context, resourceID string) error
+	waitFor func(ctx context.Context, resourceID string, condition func(*TResource) (bool, error), opts ...backoff.RetryOption) (*TResource, error)
+
+	// created is the condition --wait waits for after a create.
+	created cloudscale.Condition[TResource]
+}
+
+// newResource returns a resource with the list, get, create, update and
+// delete actions supported by svc, followed by extra actions.
+func newResource[TResource, TCreateRequest, TUpdateRequest any](name, summary string, columns []column, svc service[TResource, TCreateRequest, TUpdateRequest], extra ...*action) *resource {
+	res := &resource{name: name, summary: summary, columns: columns}
+	if svc.list != nil {
+		res.actions = append(res.actions, listAction(name, svc.list))
+	}
+	if svc.get != nil {
+		res.actions = append(res.actions, getAction(name, svc.get))
+	}
+	if svc.create != nil {
+		res.actions = append(res.actions, createAction(name, svc))
+	}
+	if svc.update != nil {
+		res.actions = append(res.actions, updateAction(name, svc))
+	}
+	if svc.delete != nil {
+		res.actions = append(res.actions, deleteAction(name, svc))
+	}
+	res.actions = append(res.actions, extra...)
+	return res
+}
+
+func listAction[TResource any](name string, list func(context.Context, ...cloudscale.ListRequestModifier) ([]TResource, error)) *action {
+	return &action{
+		name:    "list",
+		summary: fmt.Sprintf("List %ss, optionally filtered by tags or name.", name),
+		setup: func(fs *flag.FlagSet) execFunc {
+			filters := listFlags(fs)
+			return func(ctx context.Context, _ []string) (any, error) {
+				modifiers, err := filters.modifiers()
+				if err != nil {
+					return nil, err
+				}
+				resources, err := list(ctx, modifiers...)
+				if resources == nil {
+					resources = []TResource{}
+				}
+				return resources, err
+			}
+		},
+	}
+}
+
+func getAction[TResource any](name string, get func(context.Context, string) (*TResource, error)) *action {
+	return &action{
+		name:    "get",
+		summary: fmt.Sprintf("Show a %s.", name),
+		args:    "<id>",
+		nargs:   1,
+		setup: func(fs *flag.FlagSet) execFunc {
+			return func(ctx context.Context, args []string) (any, error) {
+				return get(ctx, args[0])
+			}
+		},
+	}
+}
+
+func createAction[TResource, TCreateRequest, TUpdateRequest any](name string, svc service[TResource, TCreateRequest, TUpdateRequest]) *action {
+	summary := fmt.Sprintf("Create a %s.", name)
+	if svc.created != nil {
+		summary = fmt.Sprintf("Create a %s, with --wait until it is ready.", name)
+	}
+	return &action{
+		name:    "create",
+		summary: summary,
+		setup: func(fs *flag.FlagSet) execFunc {
+			request := requestFlags[TCreateRequest](fs)
+			var wait *waitFlags
+			if svc.created != nil && svc.waitFor != nil {
+				wait = addWaitFlags(fs)
+			}
+			return func(ctx context.Context, _ []string) (any, error) {
+				req, err := request.build(fs)
+				if err != nil {
+					return nil, err
+				}
+				created, err := svc.create(ctx, req)
+				if err != nil || wait == nil || !wait.enabled {
+					return created, err
+				}
+				return svc.waitFor(ctx, cloudscale.ResourceID(created), svc.created, wait.options()...)
+			}
+		},
+	}
+}
+
+func updateAction[TResource, TCreateRequest, TUpdateRequest any](name string, svc service[TResource, TCreateRequest, TUpdateRequest]) *action {
+	return &action{
+		name:    "update",
+		summary: fmt.Sprintf("Update a %s and show the result.", name),
+		args:    "<id>",
+		nargs:   1,
+		setup: func(fs *flag.FlagSet) execFunc {
+			request := requestFlags[TUpdateRequest](fs)
+			return func(ctx context.Context, args []string) (any, error) {
+				req, err := request.build(fs)
+				if err != nil {
+					return nil, err
+				}
+				if err := svc.update(ctx, args[0], req); err != nil {
+					return nil, err
+				}
+				if svc.get == nil {
+					return nil, nil
+				}
+				return svc.get(ctx, args[0])
+			}
+		},
+	}
+}
+
+func deleteAction[TResource, TCreateRequest, TUpdateRequest any](name string, svc service[TResource, TCreateRequest, TUpdateRequest]) *action {
+	return &action{
+		name:    "delete",
+		summary: fmt.Sprintf("Delete a %s, with --wait until it is gone.", name),
+		args:    "<id>",
+		nargs:   1,
+		setup: func(fs *flag.FlagSet) execFunc {
+			wait := addWaitFlags(fs)
+			return func(ctx context.Context, args []string) (any, error) {
+				if err := svc.delete(ctx, args[0]); err != nil {
+					return nil, err
+				}
+				if !wait.enabled || svc.get == nil {
+					return nil, nil
+				}
+				return nil, cloudscale.WaitForDeletion(ctx, getFunc[TResource](svc.get), args[0], wait.options()...)
+			}
+		},
+	}
+}
+
+// statusAction is an action like "server stop", which calls do and with
+// --wait waits for condition.
+func statusAction[TResource any](name, summary string, do func(context.Context, string) error, waitFor func(context.Context, string, func(*TResource) (bool, error), ...backoff.RetryOption) (*TResource, error), condition cloudscale.Condition[TResource]) *action {
+	return &action{
+		name:    name,
+		summary: summary,
+		args:    "<id>",
+		nargs:   1,
+		setup: func(fs *flag.FlagSet) execFunc {
+			wait := addWaitFlags(fs)
+			return func(ctx context.Context, args []string) (any, error) {
+				if err := do(ctx, args[0]); err != nil || !wait.enabled {
+					return nil, err
+				}
+				return waitFor(ctx, args[0], condition, wait.options()...)
+			}
+		},
+	}
+}
+
+// getFunc adapts the get function of a service to
+// cloudscale.GenericGetService.
+type getFunc[TResource any] func(ctx context.Context, resourceID string) (*TResource, error)
+
+func (f getFunc[TResource]) Get(ctx context.Context, resourceID string) (*TResource, error) {
+	return f(ctx, resourceID)
+}
+
+// waitFlags are the flags of actions that can wait for the result.
+type waitFlags struct {
+	enabled  bool
+	timeout  time.Duration
+	interval time.Duration
+}
+
+func addWaitFlags(fs *flag.FlagSet) *waitFlags {
+	wait := &waitFlags{}
+	fs.BoolVar(&wait.enabled, "wait", false, "wait for the operation to complete")
+	fs.DurationVar(&wait.timeout, "wait-timeout", 10*time.Minute, "how long to wait")
+	fs.DurationVar(&wait.interval, "wait-interval", 2*time.Second, "how often to poll while waiting")
+	return wait
+}
+
+func (w *waitFlags) options() []backoff.RetryOption {
+	return []backoff.RetryOption{
+		backoff.WithBackOff(backoff.NewConstantBackOff(w.interval)),
+		backoff.WithMaxElapsedTime(w.timeout),
+	}
+}
+
+// filterFlags are the flags of list actions.
+type filterFlags struct {
+	tags     tagFlag
+	selector string
+	name     string
+}
+
+func listFlags(fs *flag.FlagSet) *filterFlags {
+	filters := &filterFlags{tags: tagFlag{}}
+	fs.Var(filters.tags, "tag", "only list resources with this tag, as key=value (repeatable)")
+	fs.StringVar(&filters.selector, "selector", "", "only list resources matching this tag selector, e.g. \"env in (prod,staging),!legacy\"")
+	fs.StringVar(&filters.name, "name", "", "only list resources with this name")
+	return filters
+}
+
+func (f *filterFlags) modifiers() ([]cloudscale.ListRequestModifier, error) {
+	modifiers := []cloudscale.ListRequestModifier{}
+	if len(f.tags) > 0 {
+		modifiers = append(modifiers, cloudscale.WithTagFilter(cloudscale.TagMap(f.tags)))
+	}
+	if f.selector != "" {
+		selector, err := cloudscale.ParseTagSelector(f.selector)
+		if err != nil {
+			return nil, err
+		}
+		modifiers = append(modifiers, cloudscale.WithTagSelector(selector))
+	}
+	if f.name != "" {
+		modifiers = append(modifiers, cloudscale.WithNameFilter(f.name))
+	}
+	return modifiers, nil
+}
+
+// tagFlag collects repeated key=value flags.
+type tagFlag map[string]string
+
+func (t tagFlag) String() string {
+	return formatValue(reflect.ValueOf(map[string]string(t)))
+}
+
+func (t tagFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	t[key] = val
+	return nil
+}
+
+// stringsFlag collects repeated flags.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// requestBuilder creates a request from the flags defined by requestFlags.
+type requestBuilder[TRequest any] struct {
+	fromFile string
+	fields   map[string]*fieldFlag
+}
+
+var tagMapType = reflect.TypeFor[*cloudscale.TagMap]()
+
+// requestFlags defines a flag per field of the request type that has a
+// string, integer, boolean or string slice type, also as an optional or
+// nullable field; the flag is named after the JSON field, with dashes instead
+// of underscores. An empty value sets a nullable field to null, e.g.
+// --server= unassigns a floating IP. Tags are set with repeated
+// --tag key=value flags. Fields of other types, e.g. the interfaces of a
+// server, can be given in a JSON or YAML file with --from-file, which flags
+// override.
+func requestFlags[TRequest any](fs *flag.FlagSet) *requestBuilder[TRequest] {
+	builder := &requestBuilder[TRequest]{fields: map[string]*fieldFlag{}}
+	fs.StringVar(&builder.fromFile, "from-file", "", "read the request from a JSON or YAML `file`")
+
+	// Embedded fields hidden by a field of the same name, like the zone of
+	// a server request, are not visible.
+	for _, field := range reflect.VisibleFields(reflect.TypeFor[TRequest]()) {
+		builder.define(fs, field)
+	}
+	return builder
+}
+
+func (b *requestBuilder[TRequest]) define(fs *flag.FlagSet, field reflect.StructField) {
+	jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if !field.IsExported() || field.Anonymous || jsonName == "" || jsonName == "-" {
+		return
+	}
+
+	flagName := strings.ReplaceAll(jsonName, "_", "-")
+	usage := fmt.Sprintf("%s of the request", jsonName)
+	switch t := field.Type; {
+	case t == tagMapType:
+		flagName, usage = "tag", "tag as key=value (repeatable)"
+	case isScalar(t), isOptional(t) && isScalar(indirect(t)):
+	case isScalar(indirect(t)) && indirect(t).Kind() == reflect.Bool:
+	case indirect(t).Kind() == reflect.Slice && indirect(t).Elem().Kind() == reflect.String:
+		usage += " (repeatable)"
+	default:
+		return
+	}
+	if isNullable(field.Type) {
+		usage += ", empty for null"
+	}
+	if fs.Lookup(flagName) != nil || b.fields[flagName] != nil {
+		return
+	}
+
+	f := &fieldFlag{field: field}
+	b.fields[flagName] = f
+	fs.Var(f, flagName, usage)
+}
+
+// build reads the request file, if any, and applies the flags that were set.
+func (b *requestBuilder[TRequest]) build(fs *flag.FlagSet) (*TRequest, error) {
+	request := new(TRequest)
+	if b.fromFile != "" {
+		if err := readRequestFile(b.fromFile, request); err != nil {
+			return nil, err
+		}
+	}
+	value := reflect.ValueOf(request).Elem()
+	fs.Visit(func(f *flag.Flag) {
+		if field, ok := b.fields[f.Name]; ok {
+			field.apply(value)
+		}
+	})
+	return request, nil
+}
+
+// readRequestFile decodes a request from YAML, which includes JSON, using
+// the JSON field names of the request.
+func readRequestFile(path string, request any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var decoded any
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := convertJSON(decoded, request); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// convertJSON converts value to target through its JSON representation.
+func convertJSON(value, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// fieldFlag is the flag of a request field. Values are checked when parsed
+// and applied to the request by build.
+type fieldFlag struct {
+	field  reflect.StructField
+	values []string
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// indirect returns the type of the value of a pointer, optional or nullable
+// field.
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	if isOptional(t) {
+		return reflect.New(t).Interface().(cloudscale.OptionalField).ValueType()
+	}
+	return t
+}
+
+var (
+	optionalFieldType = reflect.TypeFor[cloudscale.OptionalField]()
+	nullableFieldType = reflect.TypeFor[cloudscale.NullableField]()
+)
+
+// isOptional reports whether t is an Optional or a Nullable.
+func isOptional(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(optionalFieldType)
+}
+
+func isNullable(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(nullableFieldType)
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(f.values, ",")
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return indirect(f.field.Type).Kind() == reflect.Bool
+}
+
+func (f *fieldFlag) Set(value string) error {
+	switch t := indirect(f.field.Type); {
+	case f.field.Type == tagMapType:
+		if key, _, ok := strings.Cut(value, "="); !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", value)
+		}
+	case value == "" && isNullable(f.field.Type):
+	case t.Kind() == reflect.Int:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+	case t.Kind() == reflect.Bool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected a boolean, got %q", value)
+		}
+	}
+	if indirect(f.field.Type).Kind() == reflect.Slice || f.field.Type == tagMapType {
+		f.values = append(f.values, value)
+	} else {
+		f.values = []string{value}
+	}
+	return nil
+}
+
+// apply sets the field of request, a struct value, to the values of the flag.
+func (f *fieldFlag) apply(request reflect.Value) {
+	target := request.FieldByIndex(f.field.Index)
+	if f.field.Type == tagMapType {
+		tags := cloudscale.TagMap{}
+		if existing := target.Interface().(*cloudscale.TagMap); existing != nil {
+			tags = *existing
+		}
+		for _, value := range f.values {
+			key, val, _ := strings.Cut(value, "=")
+			tags[key] = val
+		}
+		target.Set(reflect.ValueOf(&tags))
+		return
+	}
+
+	if target.Kind() == reflect.Pointer {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	if field, ok := target.Addr().Interface().(cloudscale.OptionalField); ok {
+		if nullable, ok := field.(cloudscale.NullableField); ok && f.values[len(f.values)-1] == "" {
+			nullable.SetNull()
+			return
+		}
+		value := reflect.New(field.ValueType()).Elem()
+		f.set(value)
+		field.SetValue(value.Interface())
+		return
+	}
+	f.set(target)
+}
+
+// set sets target, a string, integer, boolean or string slice value, to the
+// values of the flag.
+func (f *fieldFlag) set(target reflect.Value) {
+	value := f.values[len(f.values)-1]
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int:
+		number, _ := strconv.Atoi(value)
+		target.SetInt(int64(number))
+	case reflect.Bool:
+		enabled, _ := strconv.ParseBool(value)
+		target.SetBool(enabled)
+	case reflect.Slice:
+		values := reflect.MakeSlice(target.Type(), len(f.values), len(f.values))
+		for i, value := range f.values {
+			values.Index(i).SetString(value)
+		}
+		target.Set(values)
+	}
+}
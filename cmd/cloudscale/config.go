@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+const defaultProfile = "default"
+
+// profile holds the settings of one section of the configuration file, an INI
+// file with a section per profile:
+//
+//	[default]
+//	api_token = ...
+//
+//	[staging]
+//	api_token = ...
+//	api_url = https://api.example.com/
+type profile struct {
+	APIToken string
+	APIURL   string
+}
+
+// readConfig parses the profiles of a configuration file. Lines starting with
+// '#' or ';' are comments.
+func readConfig(r io.Reader) (map[string]profile, error) {
+	profiles := map[string]profile{}
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			profiles[section] = profiles[section]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			return nil, fmt.Errorf("line %d: expected a [profile] or key = value", number)
+		}
+		p := profiles[section]
+		switch strings.TrimSpace(key) {
+		case "api_token":
+			p.APIToken = strings.TrimSpace(value)
+		case "api_url":
+			p.APIURL = strings.TrimSpace(value)
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", number, strings.TrimSpace(key))
+		}
+		profiles[section] = p
+	}
+	return profiles, scanner.Err()
+}
+
+// configureClient sets the token and URL of client. An explicitly selected
+// profile takes precedence over CLOUDSCALE_API_TOKEN, which in turn takes
+// precedence over the default profile.
+func configureClient(client *cloudscale.Client, global globalFlags, getenv func(string) string) error {
+	name := global.profile
+	if name == "" {
+		name = getenv("CLOUDSCALE_PROFILE")
+	}
+
+	path := global.config
+	if path == "" {
+		path = getenv("CLOUDSCALE_CONFIG")
+	}
+	if path == "" {
+		if home := getenv("HOME"); home != "" {
+			path = filepath.Join(home, ".cloudscale.ini")
+		}
+	}
+
+	var selected profile
+	if name != "" || getenv("CLOUDSCALE_API_TOKEN") == "" {
+		profiles, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		p, ok := profiles[name]
+		if name == "" {
+			p, ok = profiles[defaultProfile], true
+		}
+		if !ok {
+			return fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		selected = p
+	}
+	if selected.APIToken == "" {
+		selected.APIToken = getenv("CLOUDSCALE_API_TOKEN")
+	}
+	if selected.APIToken == "" {
+		return errors.New("no API token: set CLOUDSCALE_API_TOKEN or add api_token to a profile")
+	}
+	client.AuthToken = selected.APIToken
+
+	apiURL := global.apiURL
+	if apiURL == "" {
+		apiURL = selected.APIURL
+	}
+	if apiURL == "" {
+		apiURL = getenv("CLOUDSCALE_API_URL")
+	}
+	if apiURL != "" {
+		// API paths are resolved relative to the base URL.
+		if !strings.HasSuffix(apiURL, "/") {
+			apiURL += "/"
+		}
+		baseURL, err := url.Parse(apiURL)
+		if err != nil {
+			return fmt.Errorf("invalid API URL: %w", err)
+		}
+		client.BaseURL = baseURL
+	}
+	return nil
+}
+
+// loadConfig reads the configuration file at path. A missing file has no
+// profiles.
+func loadConfig(path string) (map[string]profile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles, err := readConfig(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,185 @@
+// Command cloudscale manages cloudscale.ch resources from the command line.
+//
+// Commands have the form
+//
+//	cloudscale [global flags] <resource> <action> [flags] [arguments]
+//
+// e.g. "cloudscale server list --tag env=prod" or "cloudscale server create
+// --name web --flavor flex-4-2 --image debian-12 --ssh-keys "$(cat
+// ~/.ssh/id_ed25519.pub)" --wait". Run "cloudscale help" for the list of
+// resources and "cloudscale <resource> <action> -h" for the flags of an
+// action.
+//
+// The API token is read from the profile selected with --profile or
+// CLOUDSCALE_PROFILE in ~/.cloudscale.ini, an INI file with a section per
+// profile holding its api_token and optionally api_url, or else from
+// CLOUDSCALE_API_TOKEN. The API URL can be changed with --api-url, e.g. to
+// run against a local stand-in for the API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Exit codes of the command.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv)
+	stop()
+	os.Exit(code)
+}
+
+// globalFlags are the flags given before the resource name.
+type globalFlags struct {
+	profile string
+	config  string
+	apiURL  string
+	output  string
+}
+
+// run executes the command given by args and returns its exit code. The
+// environment is read with getenv only, which keeps tests independent of the
+// environment they run in.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	global := globalFlags{}
+	fs := flag.NewFlagSet("cloudscale", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&global.profile, "profile", "", "profile in the configuration file (default $CLOUDSCALE_PROFILE or \"default\")")
+	fs.StringVar(&global.config, "config", "", "configuration file (default $CLOUDSCALE_CONFIG or ~/.cloudscale.ini)")
+	fs.StringVar(&global.apiURL, "api-url", "", "base URL of the API (default from the profile or $CLOUDSCALE_API_URL)")
+	fs.StringVar(&global.output, "output", "table", "output format: table, json or yaml")
+	fs.StringVar(&global.output, "o", "table", "shorthand for -output")
+	fs.Usage = func() { printUsage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		return usageExitCode(err)
+	}
+	if _, ok := formats[global.output]; !ok {
+		fmt.Fprintf(stderr, "unknown output format %q\n", global.output)
+		return exitUsage
+	}
+
+	args = fs.Args()
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(stderr, fs)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	// The client is configured only once the action's arguments are valid,
+	// so that usage errors and help do not need credentials.
+	client := cloudscale.NewClient(nil)
+	res, ok := findResource(resources(client), args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown resource %q, run \"cloudscale help\" for a list\n", args[0])
+		return exitUsage
+	}
+	if len(args) < 2 || args[1] == "help" {
+		printResourceUsage(stderr, res)
+		if len(args) < 2 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	act, ok := res.action(args[1])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown action %q for %s\n", args[1], res.name)
+		printResourceUsage(stderr, res)
+		return exitUsage
+	}
+
+	actionFlags := flag.NewFlagSet(res.name+" "+act.name, flag.ContinueOnError)
+	actionFlags.SetOutput(stderr)
+	actionFlags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: cloudscale %s %s [flags] %s\n\n%s\n\nFlags:\n", res.name, act.name, act.args, act.summary)
+		actionFlags.PrintDefaults()
+	}
+	if res.scope != nil {
+		res.scope(actionFlags)
+	}
+	execute := act.setup(actionFlags)
+	if err := actionFlags.Parse(args[2:]); err != nil {
+		return usageExitCode(err)
+	}
+	if got := actionFlags.NArg(); got != act.nargs {
+		fmt.Fprintf(stderr, "%s %s takes %d argument(s), got %d\n", res.name, act.name, act.nargs, got)
+		actionFlags.Usage()
+		return exitUsage
+	}
+	if err := configureClient(client, global, getenv); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	result, err := execute(ctx, actionFlags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if result == nil {
+		return exitOK
+	}
+	columns := act.columns
+	if columns == nil {
+		columns = res.columns
+	}
+	if err := formats[global.output](stdout, result, columns); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func usageExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func findResource(resources []*resource, name string) (*resource, bool) {
+	for _, res := range resources {
+		if res.name == name {
+			return res, true
+		}
+	}
+	return nil, false
+}
+
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprint(w, "Usage: cloudscale [global flags] <resource> <action> [flags] [arguments]\n\nResources:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, res := range resources(cloudscale.NewClient(nil)) {
+		fmt.Fprintf(tw, "  %s\t%s\n", res.name, res.summary)
+	}
+	tw.Flush()
+	fmt.Fprint(w, "\nGlobal flags:\n")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+func printResourceUsage(w io.Writer, res *resource) {
+	fmt.Fprintf(w, "Usage: cloudscale %s <action> [flags] [arguments]\n\nActions:\n", res.name)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, act := range res.actions {
+		fmt.Fprintf(tw, "  %s\t%s\n", strings.TrimSpace(act.name+" "+act.args), act.summary)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/internal/fakeapi"
+)
+
+// fakeAPI records the requests it receives and answers them with handler.
+type fakeAPI struct {
+	*fakeapi.API
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Auth   string
+	Body   map[string]any
+}
+
+func newFakeAPI(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *fakeAPI {
+	api := &fakeAPI{}
+	api.API = fakeapi.New(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		request := recordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Auth: r.Header.Get("Authorization")}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			if err := json.Unmarshal(data, &request.Body); err != nil {
+				t.Errorf("invalid request body %s: %v", data, err)
+			}
+		}
+		api.mu.Lock()
+		api.requests = append(api.requests, request)
+		api.mu.Unlock()
+		handler(w, r)
+	})
+	return api
+}
+
+func (api *fakeAPI) recorded() []recordedRequest {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]recordedRequest(nil), api.requests...)
+}
+
+// runCLI runs the command against api with a token from the environment.
+func runCLI(t *testing.T, api *fakeAPI, env map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+	if env == nil {
+		env = map[string]string{"CLOUDSCALE_API_TOKEN": "env-token"}
+	}
+	if api != nil {
+		args = append([]string{"--api-url", api.URL}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr, func(key string) string { return env[key] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestServerCreate_Wait(t *testing.T) {
+	polls := 0
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/servers":
+			fmt.Fprint(w, `{"uuid": "s1", "name": "web", "status": "changing"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/servers/s1":
+			polls++
+			status := "changing"
+			if polls > 1 {
+				status = "running"
+			}
+			fmt.Fprintf(w, `{"uuid": "s1", "name": "web", "status": %q, "flavor": {"slug": "flex-4-2"}, "zone": {"slug": "lpg1"}}`, status)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "-o", "json", "server", "create",
+		"--name", "web", "--flavor", "flex-4-2", "--image", "debian-12", "--zone", "lpg1",
+		"--ssh-keys", "key-a", "--ssh-keys", "key-b", "--use-ipv6", "--tag", "env=prod",
+		"--wait", "--wait-interval", "1ms")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	requests := api.recorded()
+	body := requests[0].Body
+	expected := map[string]any{
+		"name": "web", "flavor": "flex-4-2", "image": "debian-12", "zone": "lpg1",
+		"ssh_keys": []any{"key-a", "key-b"}, "use_ipv6": true, "tags": map[string]any{"env": "prod"},
+	}
+	for key, value := range expected {
+		if fmt.Sprint(body[key]) != fmt.Sprint(value) {
+			t.Errorf("request field %s: expected %v, got %v", key, value, body[key])
+		}
+	}
+	if requests[0].Auth != "Bearer env-token" {
+		t.Errorf("unexpected authorization %q", requests[0].Auth)
+	}
+	if polls != 2 {
+		t.Errorf("expected to poll until running, got %d polls", polls)
+	}
+	if !strings.Contains(stdout, `"status": "running"`) {
+		t.Errorf("expected the running server, got %s", stdout)
+	}
+}
+
+func TestServerList_Table(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"uuid": "s1", "name": "web", "status": "running", "flavor": {"slug": "flex-4-2"}, "zone": {"slug": "lpg1"},
+			 "interfaces": [{"addresses": [{"address": "192.0.2.1"}, {"address": "2001:db8::1"}]}], "tags": {"env": "prod", "app": "web"}}
+		]`)
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "server", "list", "--tag", "env=prod", "--name", "web")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if query := api.recorded()[0].Query; query != "name=web&tag%3Aenv=prod" {
+		t.Errorf("unexpected query %q", query)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and a row, got %q", stdout)
+	}
+	for _, header := range []string{"UUID", "FLAVOR", "ADDRESSES", "TAGS"} {
+		if !strings.Contains(lines[0], header) {
+			t.Errorf("header %q lacks %s", lines[0], header)
+		}
+	}
+	for _, value := range []string{"s1", "flex-4-2", "lpg1", "192.0.2.1,2001:db8::1", "app=web,env=prod"} {
+		if !strings.Contains(lines[1], value) {
+			t.Errorf("row %q lacks %s", lines[1], value)
+		}
+	}
+}
+
+func TestOutput_YAML(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "n1", "name": "123", "mtu": 9000, "subnets": [], "tags": {}, "zone": {"slug": "rma1"}}`)
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "-o", "yaml", "network", "get", "n1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	expected := "zone:\n  slug: rma1\ntags: {}\nuuid: n1\nname: \"123\"\nmtu: 9000\nsubnets: []\ncreated_at: \"0001-01-01T00:00:00Z\"\n"
+	if stdout != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, stdout)
+	}
+}
+
+func TestUpdate_FromFile(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{"uuid": "s1", "name": "renamed"}`)
+	})
+
+	file := filepath.Join(t.TempDir(), "update.yaml")
+	content := "name: from-file\nflavor: flex-8-4\ninterfaces:\n  - network: public\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := runCLI(t, api, nil, "server", "update", "--from-file", file, "--name", "renamed", "s1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	body := api.recorded()[0].Body
+	if body["name"] != "renamed" || body["flavor"] != "flex-8-4" {
+		t.Errorf("expected flags to override the file, got %v", body)
+	}
+	if fmt.Sprint(body["interfaces"]) != "[map[network:public]]" {
+		t.Errorf("expected the interfaces of the file, got %v", body["interfaces"])
+	}
+}
+
+func TestUpdate_OptionalAndNullable(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+
+	code, _, stderr := runCLI(t, api, nil, "floating-ip", "update", "--server=", "192.0.2.1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	body := api.recorded()[0].Body
+	if server, ok := body["server"]; !ok || server != nil {
+		t.Errorf("expected the server to be set to null, got %v", body)
+	}
+	if _, ok := body["load_balancer"]; ok {
+		t.Errorf("expected the unset load balancer to be omitted, got %v", body)
+	}
+
+	code, _, stderr = runCLI(t, api, nil, "load-balancer-pool-member", "update", "--pool", "p1", "--enabled=false", "m1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if body := api.recorded()[2].Body; body["enabled"] != false {
+		t.Errorf("expected enabled to be sent as false, got %v", body)
+	}
+}
+
+func TestDelete_Wait(t *testing.T) {
+	gets := 0
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if gets++; gets < 3 {
+			fmt.Fprint(w, `{"uuid": "v1"}`)
+			return
+		}
+		fakeapi.NotFound(w)
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "volume", "delete", "--wait", "--wait-interval", "1ms", "v1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gets != 3 || stdout != "" {
+		t.Errorf("expected to poll until gone without output, got %d polls and %q", gets, stdout)
+	}
+}
+
+func TestPoolMembers(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+
+	code, _, stderr := runCLI(t, api, nil, "load-balancer-pool-member", "list")
+	if code != exitError || !strings.Contains(stderr, "--pool is required") {
+		t.Errorf("expected --pool to be required, got %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runCLI(t, api, nil, "-o", "json", "load-balancer-pool-member", "list", "--pool", "p1")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if path := api.recorded()[0].Path; path != "/v1/load-balancers/pools/p1/members" {
+		t.Errorf("unexpected path %s", path)
+	}
+	if stdout != "[]\n" {
+		t.Errorf("expected an empty list, got %q", stdout)
+	}
+}
+
+func TestBucketMetrics_Table(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"start": "2024-01-01T00:00:00Z", "end": "2024-01-03T00:00:00Z", "data": [{
+			"subject": {"name": "logs", "objects_user_id": "u1"},
+			"time_series": [
+				{"start": "2024-01-01T00:00:00Z", "usage": {"requests": 5, "object_count": 2, "storage_bytes": 1024}},
+				{"start": "2024-01-02T00:00:00Z", "usage": {"requests": 7, "object_count": 3, "storage_bytes": 2048}}
+			]}]}`)
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "bucket-metrics", "get", "--start", "2024-01-01", "--end", "2024-01-02", "--bucket", "logs")
+	if code != exitOK {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if query := api.recorded()[0].Query; !strings.Contains(query, "bucket_name=logs") || !strings.Contains(query, "start=2024-01-01") {
+		t.Errorf("unexpected query %q", query)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], "2024-01-02") || !strings.Contains(lines[2], "2048") {
+		t.Errorf("expected a row per day, got\n%s", stdout)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	config := filepath.Join(t.TempDir(), "cloudscale.ini")
+	content := "# tokens\n[default]\napi_token = default-token\n\n[staging]\napi_token = staging-token\napi_url = " + api.URL + "\n"
+	if err := os.WriteFile(config, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		expected string
+	}{
+		{"default profile", map[string]string{}, []string{"--api-url", api.URL}, "Bearer default-token"},
+		{"environment token over default profile", map[string]string{"CLOUDSCALE_API_TOKEN": "env-token"}, []string{"--api-url", api.URL}, "Bearer env-token"},
+		{"selected profile over environment token", map[string]string{"CLOUDSCALE_API_TOKEN": "env-token"}, []string{"--profile", "staging"}, "Bearer staging-token"},
+		{"profile from environment", map[string]string{"CLOUDSCALE_PROFILE": "staging"}, nil, "Bearer staging-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.env["CLOUDSCALE_CONFIG"] = config
+			before := len(api.recorded())
+			code, _, stderr := runCLI(t, nil, test.env, append(test.args, "flavor", "list")...)
+			if code != exitOK {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if auth := api.recorded()[before].Auth; auth != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, auth)
+			}
+		})
+	}
+
+	code, _, stderr := runCLI(t, nil, map[string]string{"CLOUDSCALE_CONFIG": config}, "--profile", "prod", "flavor", "list")
+	if code != exitError || !strings.Contains(stderr, `profile "prod" not found`) {
+		t.Errorf("expected an unknown profile to fail, got %d: %s", code, stderr)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{}, "Resources:"},
+		{[]string{"planet", "list"}, `unknown resource "planet"`},
+		{[]string{"server"}, "reboot <id>"},
+		{[]string{"custom-image", "create"}, `unknown action "create"`},
+		{[]string{"server", "get"}, "takes 1 argument(s), got 0"},
+		{[]string{"volume", "create", "--size-gb", "large"}, "expected an integer"},
+		{[]string{"-o", "xml", "server", "list"}, `unknown output format "xml"`},
+	}
+	for _, test := range tests {
+		code, _, stderr := runCLI(t, nil, nil, test.args...)
+		if code != exitUsage || !strings.Contains(stderr, test.expected) {
+			t.Errorf("%v: expected usage error containing %q, got %d: %s", test.args, test.expected, code, stderr)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	api := newFakeAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fakeapi.NotFound(w)
+	})
+
+	code, stdout, stderr := runCLI(t, api, nil, "server", "get", "missing")
+	if code != exitError || stdout != "" || !strings.Contains(stderr, "Not found.") {
+		t.Errorf("expected the API error, got %d: %q %q", code, stdout, stderr)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// column is a table column. Path names the value shown, e.g. "Flavor.Slug";
+// its elements are fields or methods without arguments. Slices along the
+// path show the values of all their elements.
+type column struct {
+	header string
+	path   []string
+}
+
+// columns builds table columns from "HEADER:Path" specs. Without a header,
+// the upper-cased path is used.
+func columns(specs ...string) []column {
+	result := make([]column, len(specs))
+	for i, spec := range specs {
+		header, path, ok := strings.Cut(spec, ":")
+		if !ok {
+			header, path = strings.ToUpper(spec), spec
+		}
+		result[i] = column{header: header, path: strings.Split(path, ".")}
+	}
+	return result
+}
+
+// formatter writes the result of an action, a resource or a slice of them.
+type formatter func(w io.Writer, result any, columns []column) error
+
+var formats = map[string]formatter{
+	"table": writeTable,
+	"json":  writeJSON,
+	"yaml":  writeYAML,
+}
+
+// tabular is implemented by results whose table rows differ from their JSON
+// representation.
+type tabular interface {
+	tableRows() any
+}
+
+func writeTable(w io.Writer, result any, columns []column) error {
+	if t, ok := result.(tabular); ok {
+		result = t.tableRows()
+	}
+	rows := reflect.ValueOf(result)
+	if rows.Kind() != reflect.Slice {
+		rows = reflect.Append(reflect.MakeSlice(reflect.SliceOf(rows.Type()), 0, 1), rows)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for i := 0; i < rows.Len(); i++ {
+		cells := make([]string, len(columns))
+		for j, column := range columns {
+			cells[j] = cell(rows.Index(i), column.path)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cell formats the value at path, or returns an empty string if the path
+// runs into a nil pointer or does not exist.
+func cell(value reflect.Value, path []string) string {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	if len(path) == 0 {
+		return formatValue(value)
+	}
+
+	if value.Kind() == reflect.Slice {
+		parts := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			if part := cell(value.Index(i), path); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, ",")
+	}
+	if value.Kind() == reflect.Struct {
+		if field := value.FieldByName(path[0]); field.IsValid() {
+			return cell(field, path[1:])
+		}
+	}
+	if method := value.MethodByName(path[0]); method.IsValid() && method.Type().NumIn() == 0 && method.Type().NumOut() == 1 {
+		return cell(method.Call(nil)[0], path[1:])
+	}
+	return ""
+}
+
+func formatValue(value reflect.Value) string {
+	if t, ok := value.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Map:
+		pairs := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", key, value.MapIndex(key)))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case reflect.Slice:
+		return cell(value, []string{})
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func writeJSON(w io.Writer, result any, _ []column) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// writeYAML writes the JSON representation of result as YAML, so that both
+// formats use the field names of the API.
+func writeYAML(w io.Writer, result any, _ []column) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	// Decoding into a node keeps the order of the fields.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style and quoting taken over from JSON. The
+// encoder still quotes strings that would otherwise be read as another type.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
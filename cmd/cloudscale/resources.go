@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// resources returns the commands for all services of client, in the order
+// they are listed by "cloudscale help".
+func resources(client *cloudscale.Client) []*resource {
+	servers := client.Servers
+	return []*resource{
+		newResource("server", "Servers, which can also be started, stopped and rebooted.",
+			columns("UUID", "Name", "FLAVOR:Flavor.Slug", "IMAGE:Image.Slug", "Status", "ZONE:Zone.Slug", "ADDRESSES:Interfaces.Addresses.Address", "Tags"),
+			service[cloudscale.Server, cloudscale.ServerRequest, cloudscale.ServerUpdateRequest]{
+				list: servers.List, get: servers.Get, create: servers.Create, update: servers.Update, delete: servers.Delete,
+				waitFor: servers.WaitFor, created: cloudscale.ServerIsRunning,
+			},
+			statusAction("start", "Start a server, with --wait until it is running.", servers.Start, servers.WaitFor, cloudscale.ServerIsRunning),
+			statusAction("stop", "Stop a server, with --wait until it is stopped.", servers.Stop, servers.WaitFor, cloudscale.ServerIsStopped),
+			statusAction("reboot", "Reboot a server, with --wait until it is running.", servers.Reboot, servers.WaitFor, cloudscale.ServerIsRunning),
+		),
+		newResource("volume", "Block storage volumes.",
+			columns("UUID", "Name", "SIZE_GB:SizeGB", "Type", "ZONE:Zone.Slug", "SERVERS:ServerUUIDs", "Tags"),
+			service[cloudscale.Volume, cloudscale.VolumeCreateRequest, cloudscale.VolumeUpdateRequest]{
+				list: client.Volumes.List, get: client.Volumes.Get, create: client.Volumes.Create, update: client.Volumes.Update, delete: client.Volumes.Delete,
+			},
+		),
+		newResource("volume-snapshot", "Snapshots of volumes.",
+			columns("UUID", "Name", "SIZE_GB:SizeGB", "SOURCE_VOLUME:SourceVolume.UUID", "Status", "ZONE:Zone.Slug", "Tags"),
+			service[cloudscale.VolumeSnapshot, cloudscale.VolumeSnapshotCreateRequest, cloudscale.VolumeSnapshotUpdateRequest]{
+				list: client.VolumeSnapshots.List, get: client.VolumeSnapshots.Get, create: client.VolumeSnapshots.Create,
+				update: client.VolumeSnapshots.Update, delete: client.VolumeSnapshots.Delete,
+				waitFor: client.VolumeSnapshots.WaitFor, created: cloudscale.VolumeSnapshotIsAvailable,
+			},
+		),
+		newResource("network", "Private networks.",
+			columns("UUID", "Name", "MTU", "SUBNETS:Subnets.CIDR", "ZONE:Zone.Slug", "Tags"),
+			service[cloudscale.Network, cloudscale.NetworkCreateRequest, cloudscale.NetworkUpdateRequest]{
+				list: client.Networks.List, get: client.Networks.Get, create: client.Networks.Create, update: client.Networks.Update, delete: client.Networks.Delete,
+			},
+		),
+		newResource("subnet", "Subnets of private networks.",
+			columns("UUID", "CIDR", "NETWORK:Network.Name", "GATEWAY:GatewayAddress", "DNS_SERVERS:DNSServers", "Tags"),
+			service[cloudscale.Subnet, cloudscale.SubnetCreateRequest, cloudscale.SubnetUpdateRequest]{
+				list: client.Subnets.List, get: client.Subnets.Get, create: client.Subnets.Create, update: client.Subnets.Update, delete: client.Subnets.Delete,
+			},
+		),
+		newResource("floating-ip", "Floating IPs, identified by their IP address.",
+			columns("IP:IP", "NETWORK:Network", "Type", "REGION:Region.Slug", "SERVER:Server.UUID", "LOAD_BALANCER:LoadBalancer.UUID", "REVERSE_PTR:ReversePointer", "Tags"),
+			service[cloudscale.FloatingIP, cloudscale.FloatingIPCreateRequest, cloudscale.FloatingIPUpdateRequest]{
+				list: client.FloatingIPs.List, get: client.FloatingIPs.Get, create: client.FloatingIPs.Create, update: client.FloatingIPs.Update, delete: client.FloatingIPs.Delete,
+			},
+		),
+		newResource("server-group", "Server groups for anti-affinity.",
+			columns("UUID", "Name", "Type", "SERVERS:Servers.UUID", "ZONE:Zone.Slug", "Tags"),
+			service[cloudscale.ServerGroup, cloudscale.ServerGroupRequest, cloudscale.ServerGroupRequest]{
+				list: client.ServerGroups.List, get: client.ServerGroups.Get, create: client.ServerGroups.Create, update: client.ServerGroups.Update, delete: client.ServerGroups.Delete,
+			},
+		),
+		newResource("objects-user", "Users of the object storage.",
+			columns("ID", "DISPLAY_NAME:DisplayName", "ACCESS_KEYS:Keys.AccessKey", "Tags"),
+			service[cloudscale.ObjectsUser, cloudscale.ObjectsUserRequest, cloudscale.ObjectsUserRequest]{
+				list: client.ObjectsUsers.List, get: client.ObjectsUsers.Get, create: client.ObjectsUsers.Create, update: client.ObjectsUsers.Update, delete: client.ObjectsUsers.Delete,
+			},
+		),
+		newResource("custom-image", "Custom images, which are created with custom-image-import.",
+			columns("UUID", "Name", "Slug", "SIZE_GB:SizeGB", "FIRMWARE:FirmwareType", "ZONES:Zones.Slug", "Tags"),
+			service[cloudscale.CustomImage, struct{}, cloudscale.CustomImageRequest]{
+				list: client.CustomImages.List, get: client.CustomImages.Get, update: client.CustomImages.Update, delete: client.CustomImages.Delete,
+			},
+		),
+		newResource("custom-image-import", "Imports of custom images from a URL.",
+			columns("UUID", "URL", "Status", "CUSTOM_IMAGE:CustomImage.UUID", "ERROR:ErrorMessage", "Tags"),
+			service[cloudscale.CustomImageImport, cloudscale.CustomImageImportRequest, struct{}]{
+				list: client.CustomImageImports.List, get: client.CustomImageImports.Get, create: client.CustomImageImports.Create,
+				waitFor: client.CustomImageImports.WaitFor, created: cloudscale.ImportIsSuccessful,
+			},
+		),
+		newResource("load-balancer", "Load balancers.",
+			columns("UUID", "Name", "FLAVOR:Flavor.Slug", "Status", "VIP_ADDRESSES:VIPAddresses.Address", "ZONE:Zone.Slug", "Tags"),
+			service[cloudscale.LoadBalancer, cloudscale.LoadBalancerRequest, cloudscale.LoadBalancerRequest]{
+				list: client.LoadBalancers.List, get: client.LoadBalancers.Get, create: client.LoadBalancers.Create,
+				update: client.LoadBalancers.Update, delete: client.LoadBalancers.Delete,
+				waitFor: client.LoadBalancers.WaitFor, created: cloudscale.LoadBalancerIsRunning,
+			},
+		),
+		newResource("load-balancer-pool", "Pools of load balancer members.",
+			columns("UUID", "Name", "LOAD_BALANCER:LoadBalancer.UUID", "Algorithm", "Protocol", "Tags"),
+			service[cloudscale.LoadBalancerPool, cloudscale.LoadBalancerPoolRequest, cloudscale.LoadBalancerPoolRequest]{
+				list: client.LoadBalancerPools.List, get: client.LoadBalancerPools.Get, create: client.LoadBalancerPools.Create,
+				update: client.LoadBalancerPools.Update, delete: client.LoadBalancerPools.Delete,
+			},
+		),
+		poolMemberResource(client.LoadBalancerPoolMembers),
+		newResource("load-balancer-listener", "Listeners of load balancers.",
+			columns("UUID", "Name", "Protocol", "PORT:ProtocolPort", "POOL:Pool.UUID", "LOAD_BALANCER:LoadBalancer.UUID", "Tags"),
+			service[cloudscale.LoadBalancerListener, cloudscale.LoadBalancerListenerRequest, cloudscale.LoadBalancerListenerRequest]{
+				list: client.LoadBalancerListeners.List, get: client.LoadBalancerListeners.Get, create: client.LoadBalancerListeners.Create,
+				update: client.LoadBalancerListeners.Update, delete: client.LoadBalancerListeners.Delete,
+			},
+		),
+		newResource("load-balancer-health-monitor", "Health monitors of load balancer pools.",
+			columns("UUID", "Type", "POOL:Pool.UUID", "DELAY_S:DelayS", "TIMEOUT_S:TimeoutS", "UP:UpThreshold", "DOWN:DownThreshold", "Tags"),
+			service[cloudscale.LoadBalancerHealthMonitor, cloudscale.LoadBalancerHealthMonitorRequest, cloudscale.LoadBalancerHealthMonitorRequest]{
+				list: client.LoadBalancerHealthMonitors.List, get: client.LoadBalancerHealthMonitors.Get, create: client.LoadBalancerHealthMonitors.Create,
+				update: client.LoadBalancerHealthMonitors.Update, delete: client.LoadBalancerHealthMonitors.Delete,
+			},
+		),
+		bucketMetricsResource(client.Metrics),
+		catalogResource("flavor", "Server flavors.", columns("Slug", "Name", "VCPUS:VCPUCount", "MEMORY_GB:MemoryGB", "GPU:GPU.Name", "ZONES:Zones.Slug"), client.Flavors.List),
+		catalogResource("image", "Public server images.", columns("Slug", "Name", "OS:OperatingSystem", "USER:DefaultUsername", "ZONES:Zones.Slug"), client.Images.List),
+		catalogResource("region", "Regions and their zones.", columns("Slug", "ZONES:Zones.Slug"), client.Regions.List),
+	}
+}
+
+// poolMemberResource adapts the pool member service, which is scoped to a
+// pool, with a --pool flag on all actions.
+func poolMemberResource(members cloudscale.LoadBalancerPoolMemberService) *resource {
+	pool := new(string)
+	res := newResource("load-balancer-pool-member", "Members of a load balancer pool, selected with --pool.",
+		columns("UUID", "Name", "Enabled", "Address", "PORT:ProtocolPort", "SUBNET:Subnet.CIDR", "STATUS:MonitorStatus", "Tags"),
+		service[cloudscale.LoadBalancerPoolMember, cloudscale.LoadBalancerPoolMemberRequest, cloudscale.LoadBalancerPoolMemberRequest]{
+			list: func(ctx context.Context, modifiers ...cloudscale.ListRequestModifier) ([]cloudscale.LoadBalancerPoolMember, error) {
+				return members.List(ctx, *pool, modifiers...)
+			},
+			get: func(ctx context.Context, id string) (*cloudscale.LoadBalancerPoolMember, error) {
+				return members.Get(ctx, *pool, id)
+			},
+			create: func(ctx context.Context, request *cloudscale.LoadBalancerPoolMemberRequest) (*cloudscale.LoadBalancerPoolMember, error) {
+				return members.Create(ctx, *pool, request)
+			},
+			update: func(ctx context.Context, id string, request *cloudscale.LoadBalancerPoolMemberRequest) error {
+				return members.Update(ctx, *pool, id, request)
+			},
+			delete: func(ctx context.Context, id string) error {
+				return members.Delete(ctx, *pool, id)
+			},
+		},
+	)
+	res.scope = func(fs *flag.FlagSet) {
+		fs.StringVar(pool, "pool", "", "UUID of the pool (required)")
+	}
+	for _, act := range res.actions {
+		setup := act.setup
+		act.setup = func(fs *flag.FlagSet) execFunc {
+			execute := setup(fs)
+			return func(ctx context.Context, args []string) (any, error) {
+				if *pool == "" {
+					return nil, errors.New("--pool is required")
+				}
+				return execute(ctx, args)
+			}
+		}
+	}
+	return res
+}
+
+// catalogResource is a resource that can only be listed.
+func catalogResource[TResource any](name, summary string, columns []column, list func(context.Context) ([]TResource, error)) *resource {
+	return &resource{
+		name:    name,
+		summary: summary,
+		columns: columns,
+		actions: []*action{{
+			name:    "list",
+			summary: fmt.Sprintf("List %ss.", name),
+			setup: func(fs *flag.FlagSet) execFunc {
+				return func(ctx context.Context, _ []string) (any, error) {
+					return list(ctx)
+				}
+			},
+		}},
+	}
+}
+
+// bucketMetrics is printed like the API response, except for tables, which
+// have a row per bucket and interval.
+type bucketMetrics struct {
+	*cloudscale.BucketMetrics
+}
+
+// bucketUsage is the usage of one bucket in one interval.
+type bucketUsage struct {
+	Bucket      string
+	ObjectsUser string
+	Start       time.Time
+	cloudscale.BucketMetricsIntervalUsage
+}
+
+func (m bucketMetrics) tableRows() any {
+	rows := []bucketUsage{}
+	for _, data := range m.Data {
+		for _, interval := range data.TimeSeries {
+			rows = append(rows, bucketUsage{
+				Bucket:                     data.Subject.BucketName,
+				ObjectsUser:                data.Subject.ObjectsUserID,
+				Start:                      interval.Start,
+				BucketMetricsIntervalUsage: interval.Usage,
+			})
+		}
+	}
+	return rows
+}
+
+const dateLayout = "2006-01-02"
+
+func bucketMetricsResource(metrics cloudscale.MetricsService) *resource {
+	return &resource{
+		name:    "bucket-metrics",
+		summary: "Usage metrics of object storage buckets.",
+		actions: []*action{{
+			name:    "get",
+			summary: "Show the daily usage of buckets. Table output has a row per bucket and day.",
+			columns: columns("Bucket", "OBJECTS_USER:ObjectsUser", "DAY:Start", "Requests", "OBJECTS:ObjectCount", "STORAGE_BYTES:StorageBytes", "RECEIVED_BYTES:ReceivedBytes", "SENT_BYTES:SentBytes"),
+			setup: func(fs *flag.FlagSet) execFunc {
+				yesterday := time.Now().AddDate(0, 0, -1).Format(dateLayout)
+				start := fs.String("start", yesterday, "first day, as YYYY-MM-DD")
+				end := fs.String("end", yesterday, "last day, as YYYY-MM-DD")
+				buckets, users := stringsFlag{}, stringsFlag{}
+				fs.Var(&buckets, "bucket", "only show this bucket (repeatable)")
+				fs.Var(&users, "objects-user", "only show buckets of this objects user ID (repeatable)")
+
+				return func(ctx context.Context, _ []string) (any, error) {
+					request := &cloudscale.BucketMetricsRequest{BucketNames: buckets, ObjectsUserIDs: users}
+					var err error
+					if request.Start, err = time.Parse(dateLayout, *start); err != nil {
+						return nil, fmt.Errorf("invalid --start: %w", err)
+					}
+					if request.End, err = time.Parse(dateLayout, *end); err != nil {
+						return nil, fmt.Errorf("invalid --end: %w", err)
+					}
+					result, err := metrics.GetBucketMetrics(ctx, request)
+					if err != nil {
+						return nil, err
+					}
+					return bucketMetrics{result}, nil
+				}
+			},
+		}},
+	}
+}
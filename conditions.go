@@ -0,0 +1,131 @@
+package cloudscale
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// Condition is a condition passed to WaitFor. It returns true once the
+// resource is in the awaited state. A non-nil error explains why it is not
+// yet; wrapping it with backoff.Permanent makes WaitFor give up right away,
+// which conditions do when the resource reached a state from which the
+// awaited one can no longer be reached.
+type Condition[TResource any] func(resource *TResource) (bool, error)
+
+// StateError is returned by conditions when a resource reached a terminal
+// error state, e.g. a server with status ServerErrored.
+type StateError struct {
+	Resource string
+	ID       string
+	Status   string
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("%s %s reached status %s", e.Resource, e.ID, e.Status)
+}
+
+// And returns a condition that is met once all conditions are met. If any of
+// them fails permanently, so does And.
+func And[TResource any](conditions ...Condition[TResource]) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		var unmet error
+		for _, condition := range conditions {
+			ok, err := condition(resource)
+			if isPermanent(err) {
+				return false, err
+			}
+			if !ok && unmet == nil {
+				unmet = unmetError(err)
+			}
+		}
+		return unmet == nil, unmet
+	}
+}
+
+// Or returns a condition that is met once any of conditions is met. It only
+// fails permanently if all conditions do.
+func Or[TResource any](conditions ...Condition[TResource]) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		errs := []error{}
+		permanent := 0
+		for _, condition := range conditions {
+			ok, err := condition(resource)
+			if ok {
+				return true, nil
+			}
+			var permanentError *backoff.PermanentError
+			if errors.As(err, &permanentError) {
+				// Only the combined error may stop WaitFor.
+				permanent++
+				err = permanentError.Unwrap()
+			}
+			errs = append(errs, unmetError(err))
+		}
+		if len(conditions) > 0 && permanent == len(conditions) {
+			return false, backoff.Permanent(errors.Join(errs...))
+		}
+		return false, errors.Join(errs...)
+	}
+}
+
+// Not returns a condition that is met while condition is not. Permanent
+// failures of condition are passed on unchanged.
+func Not[TResource any](condition Condition[TResource]) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		ok, err := condition(resource)
+		if isPermanent(err) {
+			return false, err
+		}
+		if ok {
+			return false, errors.New("waiting for condition to no longer be met")
+		}
+		return true, nil
+	}
+}
+
+// AbortIf returns condition extended by terminal-failure detection: if failed
+// returns an error for the resource, WaitFor stops polling and returns it.
+func AbortIf[TResource any](condition Condition[TResource], failed func(resource *TResource) error) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		if err := failed(resource); err != nil {
+			return false, backoff.Permanent(err)
+		}
+		return condition(resource)
+	}
+}
+
+// HasTags returns a condition that is met once the resource carries all of
+// tags with the given values.
+func HasTags[TResource Tagged](tags TagMap) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		if current := (*resource).GetTags(); !hasTags(current, tags) {
+			return false, fmt.Errorf("waiting for tags %v, current tags: %v", tags, current)
+		}
+		return true, nil
+	}
+}
+
+// MatchesTagSelector returns a condition that is met once the tags of the
+// resource match selector.
+func MatchesTagSelector[TResource Tagged](selector TagSelector) Condition[TResource] {
+	return func(resource *TResource) (bool, error) {
+		if current := (*resource).GetTags(); !selector.Matches(current) {
+			return false, fmt.Errorf("waiting for tags matching %s, current tags: %v", selector, current)
+		}
+		return true, nil
+	}
+}
+
+func isPermanent(err error) bool {
+	var permanent *backoff.PermanentError
+	return errors.As(err, &permanent)
+}
+
+func unmetError(err error) error {
+	if err == nil {
+		return errors.New("condition not met yet")
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package cloudscale
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+func TestConditions_Combinators(t *testing.T) {
+	serverUUIDs := []string{"s1"}
+	volume := &Volume{SizeGB: 50, ServerUUIDs: &serverUUIDs, TaggedResource: TaggedResource{Tags: TagMap{"env": "prod"}}}
+	failing := func(*Volume) (bool, error) { return false, backoff.Permanent(errors.New("broken")) }
+
+	cases := []struct {
+		name      string
+		condition Condition[Volume]
+		met       bool
+		permanent bool
+	}{
+		{"and met", And(VolumeHasSize(50), VolumeIsAttachedTo("s1"), HasTags[Volume](TagMap{"env": "prod"})), true, false},
+		{"and unmet", And(VolumeHasSize(50), VolumeIsDetached), false, false},
+		{"and permanent", And(VolumeHasSize(10), failing), false, true},
+		{"or met", Or(VolumeHasSize(10), VolumeIsAttachedTo("s1")), true, false},
+		{"or unmet", Or(VolumeHasSize(10), VolumeIsDetached), false, false},
+		{"or partly permanent", Or(VolumeHasSize(10), failing), false, false},
+		{"or permanent", Or(failing, failing), false, true},
+		{"not", Not(VolumeIsDetached), true, false},
+		{"not met", Not(VolumeIsAttachedTo("s1")), false, false},
+		{"not permanent", Not(failing), false, true},
+		{"selector", MatchesTagSelector[Volume](MustParseTagSelector("env in (prod, staging)")), true, false},
+		{"abort", AbortIf(VolumeHasSize(50), func(v *Volume) error {
+			if v.SizeGB > 20 {
+				return errors.New("too large")
+			}
+			return nil
+		}), false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			met, err := tc.condition(volume)
+			if met != tc.met {
+				t.Errorf("expected met=%v, got %v (%v)", tc.met, met, err)
+			}
+			if !met && err == nil {
+				t.Error("expected an explanation for the unmet condition")
+			}
+			if isPermanent(err) != tc.permanent {
+				t.Errorf("expected permanent=%v, got error %v", tc.permanent, err)
+			}
+		})
+	}
+}
+
+func TestConditions_Predicates(t *testing.T) {
+	server := &Server{
+		Status:     ServerRunning,
+		Flavor:     FlavorStub{Slug: "flex-4-2"},
+		Volumes:    []VolumeStub{{UUID: "v1"}},
+		Interfaces: []Interface{{Network: NetworkStub{UUID: "n1"}}},
+	}
+	assertMet(t, ServerIsRunning, server, true)
+	assertMet(t, ServerIsStopped, server, false)
+	assertMet(t, ServerHasFlavor("flex-4-2"), server, true)
+	assertMet(t, ServerHasVolume("v2"), server, false)
+	assertMet(t, ServerIsInNetwork("n1"), server, true)
+
+	floatingIP := &FloatingIP{Network: "192.0.2.1/32", Server: &ServerStub{UUID: "s1"}}
+	assertMet(t, FloatingIPIsAssignedToServer("s1"), floatingIP, true)
+	assertMet(t, FloatingIPIsAssignedToLoadBalancer("lb1"), floatingIP, false)
+	assertMet(t, FloatingIPIsUnassigned, floatingIP, false)
+
+	member := &LoadBalancerPoolMember{MonitorStatus: MonitorStatusDown}
+	assertMet(t, LoadBalancerPoolMemberIsDown, member, true)
+	assertMet(t, LoadBalancerPoolMemberIsUp, member, false)
+
+	assertMet(t, VolumeSnapshotIsAvailable, &VolumeSnapshot{Status: VolumeSnapshotAvailable}, true)
+	assertMet(t, NetworkHasSubnet("sub1"), &Network{Subnets: []SubnetStub{{UUID: "sub1"}}}, true)
+	assertMet(t, ServerGroupContains("s1"), &ServerGroup{}, false)
+	assertMet(t, CustomImageIsAvailableIn("lpg1"), &CustomImage{Zones: []ZoneStub{{Slug: "lpg1"}}}, true)
+}
+
+func assertMet[TResource any](t *testing.T, condition Condition[TResource], resource *TResource, expected bool) {
+	t.Helper()
+	if met, err := condition(resource); met != expected {
+		t.Errorf("expected met=%v for %+v, got %v (%v)", expected, resource, met, err)
+	}
+}
+
+func TestConditions_WaitForAbortsOnTerminalState(t *testing.T) {
+	setup()
+	defer teardown()
+
+	observer := &recordingWaitObserver{}
+	client.WaitObserver = observer
+
+	requests := 0
+	mux.HandleFunc("/v1/servers/abc", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		status := ServerChanging
+		if requests >= 2 {
+			status = ServerErrored
+		}
+		fmt.Fprintf(w, `{"uuid": "abc", "status": %q}`, status)
+	})
+
+	_, err := client.Servers.WaitFor(ctx, "abc", ServerIsRunning,
+		backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+	)
+
+	var stateError *StateError
+	if !errors.As(err, &stateError) {
+		t.Fatalf("expected *StateError, got %v", err)
+	}
+	assertEqual(t, "errored", stateError.Status)
+	assertEqual(t, 2, requests)
+	assertEqual(t, WaitOutcomeError, observer.outcome)
+}
+
+func TestConditions_StateErrors(t *testing.T) {
+	assertStateError(t, LoadBalancerIsRunning, &LoadBalancer{UUID: "lb1", Status: LoadBalancerError}, "error")
+	assertStateError(t, VolumeSnapshotIsAvailable, &VolumeSnapshot{UUID: "vs1", Status: VolumeSnapshotError}, "error")
+	assertStateError(t, LoadBalancerPoolMemberIsUp, &LoadBalancerPoolMember{UUID: "m1", MonitorStatus: MonitorStatusDown}, "disabled")
+	assertStateError(t, LoadBalancerPoolMemberIsDown, &LoadBalancerPoolMember{UUID: "m1", MonitorStatus: MonitorStatusNoMonitor}, "no_monitor")
+
+	member := &LoadBalancerPoolMember{Enabled: true, MonitorStatus: MonitorStatusChanging}
+	if _, err := LoadBalancerPoolMemberIsUp(member); isPermanent(err) {
+		t.Errorf("expected an enabled member to be waited for, got %v", err)
+	}
+}
+
+func assertStateError[TResource any](t *testing.T, condition Condition[TResource], resource *TResource, status string) {
+	t.Helper()
+	_, err := condition(resource)
+	var stateError *StateError
+	if !isPermanent(err) || !errors.As(err, &stateError) || stateError.Status != status {
+		t.Errorf("expected a permanent *StateError with status %s for %+v, got %v", status, resource, err)
+	}
+}
+
+func TestConditions_ImportIsSuccessfulFails(t *testing.T) {
+	_, err := ImportIsSuccessful(&CustomImageImport{UUID: "i1", Status: CustomImageImportFailed, ErrorMessage: "bad image"})
+
+	var failed *CustomImageImportFailedError
+	if !isPermanent(err) || !errors.As(err, &failed) {
+		t.Fatalf("expected permanent *CustomImageImportFailedError, got %v", err)
+	}
+	assertEqual(t, "bad image", failed.Message)
+}
@@ -0,0 +1,196 @@
+package cloudscale
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCredentials is returned by credential providers that have no token,
+// e.g. EnvCredentials for an unset variable.
+var ErrNoCredentials = errors.New("no credentials")
+
+// CredentialsProvider provides the API token of a client. Client.Credentials
+// is asked for the token of every request, so a rotated token takes effect
+// without rebuilding the client.
+type CredentialsProvider interface {
+	// Token returns the token to send with the next request.
+	Token(ctx context.Context) (string, error)
+	// Refresh discards cached credentials. The client calls it once the API
+	// rejected a token with 401 Unauthorized, and retries the request if
+	// Token then returns another token.
+	Refresh(ctx context.Context) error
+}
+
+// StaticCredentials is a token that never changes.
+type StaticCredentials string
+
+func (c StaticCredentials) Token(ctx context.Context) (string, error) {
+	if c == "" {
+		return "", ErrNoCredentials
+	}
+	return string(c), nil
+}
+
+func (c StaticCredentials) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// EnvCredentials reads the token from an environment variable on every
+// request. The empty name reads CLOUDSCALE_API_TOKEN.
+type EnvCredentials string
+
+func (c EnvCredentials) Token(ctx context.Context) (string, error) {
+	name := string(c)
+	if name == "" {
+		name = "CLOUDSCALE_API_TOKEN"
+	}
+	token := strings.TrimSpace(os.Getenv(name))
+	if token == "" {
+		return "", fmt.Errorf("%w: %s is not set", ErrNoCredentials, name)
+	}
+	return token, nil
+}
+
+func (c EnvCredentials) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// FileCredentials reads the token from a file, e.g. a mounted Kubernetes
+// secret, and reloads it once the file changes. Surrounding whitespace is
+// ignored.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewFileCredentials returns credentials read from path.
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+func (c *FileCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Stat follows symlinks, so the atomic symlink swap with which
+	// Kubernetes updates secrets is noticed as well.
+	info, err := os.Stat(c.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %w", ErrNoCredentials, err)
+		}
+		return "", err
+	}
+	if c.token != "" && info.ModTime().Equal(c.modTime) && info.Size() == c.size {
+		return c.token, nil
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrNoCredentials, c.path)
+	}
+	c.token, c.modTime, c.size = token, info.ModTime(), info.Size()
+	return c.token, nil
+}
+
+// Refresh makes the next Token call read the file again.
+func (c *FileCredentials) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	return nil
+}
+
+// CommandCredentials runs a command and uses its output as token, e.g. a CLI
+// of a secret store. The output is cached for TTL, or until Refresh if TTL
+// is zero.
+type CommandCredentials struct {
+	name string
+	args []string
+
+	// TTL is how long the output of the command is used.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewCommandCredentials returns credentials printed by the command name
+// with args.
+func NewCommandCredentials(name string, args ...string) *CommandCredentials {
+	return &CommandCredentials{name: name, args: args}
+}
+
+func (c *CommandCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.TTL == 0 || time.Now().Before(c.expires)) {
+		return c.token, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.name, c.args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s: %w: %s", c.name, err, message)
+		}
+		return "", fmt.Errorf("%s: %w", c.name, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("%w: %s printed no token", ErrNoCredentials, c.name)
+	}
+	c.token, c.expires = token, time.Now().Add(c.TTL)
+	return c.token, nil
+}
+
+// Refresh makes the next Token call run the command again.
+func (c *CommandCredentials) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	return nil
+}
+
+// ChainCredentials returns the token of the first provider that has one,
+// e.g. a file in production and an environment variable on a laptop.
+type ChainCredentials []CredentialsProvider
+
+func (c ChainCredentials) Token(ctx context.Context) (string, error) {
+	errs := []error{ErrNoCredentials}
+	for _, provider := range c {
+		token, err := provider.Token(ctx)
+		if err == nil {
+			return token, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
+}
+
+// Refresh refreshes all providers.
+func (c ChainCredentials) Refresh(ctx context.Context) error {
+	errs := []error{}
+	for _, provider := range c {
+		errs = append(errs, provider.Refresh(ctx))
+	}
+	return errors.Join(errs...)
+}
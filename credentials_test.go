@@ -0,0 +1,204 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rotatingCredentials returns tokens from a list, moving on to the next one
+// on Refresh.
+type rotatingCredentials struct {
+	tokens    []string
+	refreshes int
+}
+
+func (c *rotatingCredentials) Token(ctx context.Context) (string, error) {
+	return c.tokens[min(c.refreshes, len(c.tokens)-1)], nil
+}
+
+func (c *rotatingCredentials) Refresh(ctx context.Context) error {
+	c.refreshes++
+	return nil
+}
+
+func TestClient_CredentialsRetryOn401(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := []string{}
+	mux.HandleFunc("/v1/volumes", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Header.Get("Authorization")+" "+strings.TrimSpace(string(body)))
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"detail": "Invalid token."}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"uuid": "v1"}`)
+	})
+
+	credentials := &rotatingCredentials{tokens: []string{"old", "new"}}
+	client.Credentials = credentials
+	client.AuthToken = "ignored"
+
+	volume, err := client.Volumes.Create(ctx, &VolumeCreateRequest{Name: "data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume.UUID != "v1" {
+		t.Errorf("unexpected volume %v", volume)
+	}
+	expected := []string{`Bearer old {"name":"data"}`, `Bearer new {"name":"data"}`}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the request to be retried with its body:\n%s", strings.Join(requests, "\n"))
+	}
+	if credentials.refreshes != 1 {
+		t.Errorf("expected one refresh, got %d", credentials.refreshes)
+	}
+}
+
+func TestClient_CredentialsNoRetryWithSameToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/v1/volumes/v1", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"detail": "Invalid token."}`)
+	})
+
+	client.Credentials = StaticCredentials("revoked")
+	_, err := client.Volumes.Get(ctx, "v1")
+	var errorResponse *ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a 401, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected no retry with an unchanged token, got %d requests", calls)
+	}
+}
+
+func TestClient_CredentialsError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.Credentials = EnvCredentials("CLOUDSCALE_TEST_UNSET_TOKEN")
+	_, err := client.Volumes.Get(ctx, "v1")
+	if !errors.Is(err, ErrNoCredentials) || !strings.Contains(err.Error(), "CLOUDSCALE_TEST_UNSET_TOKEN") {
+		t.Errorf("expected missing credentials, got %v", err)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	t.Setenv("CLOUDSCALE_API_TOKEN", "default")
+	t.Setenv("CLOUDSCALE_TEST_TOKEN", " custom\n")
+
+	for provider, expected := range map[EnvCredentials]string{"": "default", "CLOUDSCALE_TEST_TOKEN": "custom"} {
+		token, err := provider.Token(ctx)
+		if err != nil || token != expected {
+			t.Errorf("expected %s, got %q, %v", expected, token, err)
+		}
+	}
+
+	t.Setenv("CLOUDSCALE_TEST_TOKEN", "rotated")
+	if token, _ := EnvCredentials("CLOUDSCALE_TEST_TOKEN").Token(ctx); token != "rotated" {
+		t.Errorf("expected the variable to be read on every call, got %q", token)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	credentials := NewFileCredentials(path)
+
+	if _, err := credentials.Token(ctx); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected a missing file to have no credentials, got %v", err)
+	}
+
+	// Kubernetes updates secrets by swapping a symlink to a new directory.
+	write := func(version, token string) {
+		target := filepath.Join(dir, version)
+		if err := os.WriteFile(target, []byte(token), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		os.Remove(path)
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1", "first\n")
+	if token, err := credentials.Token(ctx); err != nil || token != "first" {
+		t.Errorf("expected first, got %q, %v", token, err)
+	}
+
+	write("v2", "second-token\n")
+	if token, err := credentials.Token(ctx); err != nil || token != "second-token" {
+		t.Errorf("expected the changed file to be reloaded, got %q, %v", token, err)
+	}
+
+	write("v3", "")
+	if _, err := credentials.Token(ctx); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected an empty file to have no credentials, got %v", err)
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	counter := filepath.Join(t.TempDir(), "counter")
+	script := fmt.Sprintf(`echo x >> %s; printf 'token-%%s\n' $(wc -l < %s | tr -d ' ')`, counter, counter)
+	credentials := NewCommandCredentials("sh", "-c", script)
+
+	for _, expected := range []string{"token-1", "token-1"} {
+		if token, err := credentials.Token(ctx); err != nil || token != expected {
+			t.Errorf("expected %s, got %q, %v", expected, token, err)
+		}
+	}
+	credentials.Refresh(ctx)
+	if token, _ := credentials.Token(ctx); token != "token-2" {
+		t.Errorf("expected Refresh to run the command again, got %q", token)
+	}
+
+	credentials.TTL = time.Nanosecond
+	credentials.Refresh(ctx)
+	first, _ := credentials.Token(ctx)
+	time.Sleep(time.Millisecond)
+	if second, _ := credentials.Token(ctx); second == first {
+		t.Errorf("expected the token to expire after the TTL, got %q twice", first)
+	}
+
+	failing := NewCommandCredentials("sh", "-c", "echo denied >&2; exit 1")
+	if _, err := failing.Token(ctx); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("expected the error output of the command, got %v", err)
+	}
+}
+
+func TestChainCredentials(t *testing.T) {
+	t.Setenv("CLOUDSCALE_TEST_TOKEN", "from-env")
+	missing := NewFileCredentials(filepath.Join(t.TempDir(), "missing"))
+
+	chain := ChainCredentials{missing, EnvCredentials("CLOUDSCALE_TEST_TOKEN")}
+	if token, err := chain.Token(ctx); err != nil || token != "from-env" {
+		t.Errorf("expected the token of the environment, got %q, %v", token, err)
+	}
+
+	chain = ChainCredentials{missing, EnvCredentials("CLOUDSCALE_TEST_UNSET_TOKEN")}
+	if _, err := chain.Token(ctx); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected no credentials, got %v", err)
+	}
+	if err := chain.Refresh(ctx); err != nil {
+		t.Error(err)
+	}
+}
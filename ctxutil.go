@@ -17,3 +17,49 @@ func OperationPath(ctx context.Context) string {
 	template, _ := ctx.Value(operationPathKey{}).(string)
 	return template
 }
+
+type retryReasonKey struct{}
+
+// WithRetryReason marks the request made with ctx as a retry of an earlier
+// attempt and records why the earlier attempt failed, e.g. "429" or "error".
+// Transports use it to count retries per endpoint and reason.
+func WithRetryReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, retryReasonKey{}, reason)
+}
+
+// RetryReason returns the reason previously attached to ctx via
+// WithRetryReason. If the request is not a retry, it returns the empty string.
+func RetryReason(ctx context.Context) string {
+	reason, _ := ctx.Value(retryReasonKey{}).(string)
+	return reason
+}
+
+type waitPollKey struct{}
+
+// WithWaitPoll marks the request made with ctx as a repeated poll of WaitFor
+// after the previous one succeeded but did not meet the condition yet.
+// Transports count polls apart from retries of failed requests.
+func WithWaitPoll(ctx context.Context) context.Context {
+	return context.WithValue(ctx, waitPollKey{}, true)
+}
+
+// IsWaitPoll reports whether ctx was marked with WithWaitPoll.
+func IsWaitPoll(ctx context.Context) bool {
+	poll, _ := ctx.Value(waitPollKey{}).(bool)
+	return poll
+}
+
+type projectKey struct{}
+
+// WithProject attaches the name of the project a request is made for to ctx.
+// The clients of a ClientSet attach it to all their requests.
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// Project returns the project name previously attached to ctx via
+// WithProject. If no project is present, it returns the empty string.
+func Project(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
@@ -24,3 +24,22 @@ func TestOperationPath(t *testing.T) {
 		t.Fatalf("expected %q, got %q", "v1/servers/:id", got)
 	}
 }
+
+func TestRetryReason(t *testing.T) {
+	ctx := context.Background()
+
+	if got := RetryReason(ctx); got != "" {
+		t.Fatalf("expected empty string, got %q", got)
+	}
+
+	ctx = WithRetryReason(ctx, "429")
+	if got := RetryReason(ctx); got != "429" {
+		t.Fatalf("expected %q, got %q", "429", got)
+	}
+
+	// The operation path and the retry reason are independent.
+	ctx = WithOperationPath(ctx, "v1/servers/:id")
+	if got := RetryReason(ctx); got != "429" {
+		t.Fatalf("expected %q, got %q", "429", got)
+	}
+}
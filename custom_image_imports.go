@@ -1,7 +1,36 @@
 package cloudscale
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
 const customImageImportsBasePath = "v1/custom-images/import"
 
+// CustomImageImportStatus is the progress of a custom image import.
+type CustomImageImportStatus string
+
+const (
+	CustomImageImportStarted    CustomImageImportStatus = "started"
+	CustomImageImportInProgress CustomImageImportStatus = "in_progress"
+	CustomImageImportSuccess    CustomImageImportStatus = "success"
+	CustomImageImportFailed     CustomImageImportStatus = "failed"
+)
+
+// IsValid reports whether s is a known import status.
+func (s CustomImageImportStatus) IsValid() bool {
+	switch s {
+	case CustomImageImportStarted, CustomImageImportInProgress, CustomImageImportSuccess, CustomImageImportFailed:
+		return true
+	}
+	return false
+}
+
+func (s CustomImageImportStatus) String() string {
+	return string(s)
+}
+
 type CustomImageStub struct {
 	HREF string `json:"href,omitempty"`
 	UUID string `json:"uuid,omitempty"`
@@ -9,31 +38,68 @@ type CustomImageStub struct {
 }
 
 type CustomImageImport struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
-	HREF         string          `json:"href,omitempty"`
-	UUID         string          `json:"uuid,omitempty"`
-	CustomImage  CustomImageStub `json:"custom_image,omitempty"`
-	URL          string          `json:"url,omitempty"`
-	Status       string          `json:"status,omitempty"`
-	ErrorMessage string          `json:"error_message,omitempty"`
+	HREF         string                  `json:"href,omitempty"`
+	UUID         string                  `json:"uuid,omitempty"`
+	CustomImage  CustomImageStub         `json:"custom_image,omitempty"`
+	URL          string                  `json:"url,omitempty"`
+	Status       CustomImageImportStatus `json:"status,omitempty"`
+	ErrorMessage string                  `json:"error_message,omitempty"`
+}
+
+// MarshalJSON encodes the custom image import including its unknown fields.
+func (c CustomImageImport) MarshalJSON() ([]byte, error) {
+	type plain CustomImageImport
+	return marshalWithUnknownFields(plain(c), c.Extra)
+}
+
+// Raw returns the JSON of the custom image import, including the fields the SDK
+// does not model.
+func (c CustomImageImport) Raw() json.RawMessage {
+	return rawJSON(c)
 }
 
 type CustomImageImportRequest struct {
 	TaggedResourceRequest
-	URL              string   `json:"url,omitempty"`
-	Name             string   `json:"name,omitempty"`
-	Slug             string   `json:"slug,omitempty"`
-	UserDataHandling string   `json:"user_data_handling,omitempty"`
-	FirmwareType     string   `json:"firmware_type,omitempty"`
-	SourceFormat     string   `json:"source_format,omitempty"`
-	Zones            []string `json:"zones,omitempty"`
+	URL              string           `json:"url,omitempty"`
+	Name             string           `json:"name,omitempty"`
+	Slug             string           `json:"slug,omitempty"`
+	UserDataHandling UserDataHandling `json:"user_data_handling,omitempty"`
+	FirmwareType     FirmwareType     `json:"firmware_type,omitempty"`
+	SourceFormat     string           `json:"source_format,omitempty"`
+	Zones            []string         `json:"zones,omitempty"`
 }
 
 type CustomImageImportsService interface {
 	GenericCreateService[CustomImageImport, CustomImageImportRequest]
 	GenericGetService[CustomImageImport]
 	GenericListService[CustomImageImport]
+	GenericWatchService[CustomImageImport]
 	GenericWaitForService[CustomImageImport]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r CustomImageImportRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.required("url", r.URL)
+	if r.URL != "" {
+		if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			f.add("url", "must be an http or https URL, got %q", r.URL)
+		}
+	}
+	f.required("name", r.Name)
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the zones exist.
+func (r CustomImageImportRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	for i, zone := range r.Zones {
+		f.zone(catalog, fmt.Sprintf("zones[%d]", i), zone)
+	}
+	return mergeValidation(r.Validate(), f)
+}
@@ -0,0 +1,203 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// CustomImageVersion describes a version of a custom image that should be
+// available in a set of zones. A custom image belongs to the version if it
+// has the same slug, carries all Tags and has the given Checksums.
+type CustomImageVersion struct {
+	Slug string
+	// Checksums maps an algorithm ("sha256" or "md5") to the expected
+	// checksum. Images with different checksums are another version.
+	Checksums map[string]string
+	Tags      TagMap
+
+	// Zones lists the zones in which the version must be available.
+	Zones []string
+
+	// Import is the template for importing the version into missing zones.
+	// At least URL must be set; Slug, Tags and Zones are set from the
+	// version.
+	Import CustomImageImportRequest
+
+	// Family selects all versions of the image, e.g. "image-family=golden".
+	// With RetireOlder, images matching Family but not this version are
+	// deleted once the version is available in all zones, unless a server
+	// still uses their slug.
+	Family      TagSelector
+	RetireOlder bool
+}
+
+// CustomImageRollout is the result of EnsureCustomImage.
+type CustomImageRollout struct {
+	// Images are the custom images of the version, including Imported.
+	Images []CustomImage
+	// Imported are the images imported to cover missing zones.
+	Imported []CustomImage
+	// Retired are the older versions that were deleted.
+	Retired []CustomImage
+	// InUse are older versions kept because servers still use them.
+	InUse []CustomImage
+}
+
+// Zones returns the slugs of the zones in which the version is available.
+func (r *CustomImageRollout) Zones() []string {
+	zones := []string{}
+	for _, image := range r.Images {
+		for _, zone := range image.Zones {
+			if !slices.Contains(zones, zone.Slug) {
+				zones = append(zones, zone.Slug)
+			}
+		}
+	}
+	return zones
+}
+
+// Matches reports whether image belongs to the version.
+func (v CustomImageVersion) Matches(image CustomImage) bool {
+	if image.Slug != v.Slug || !hasTags(image.Tags, v.Tags) {
+		return false
+	}
+	for algorithm, checksum := range v.Checksums {
+		if !strings.EqualFold(image.Checksums[algorithm], checksum) {
+			return false
+		}
+	}
+	return true
+}
+
+// EnsureCustomImage makes sure that version is available in all of its zones.
+// Zones not covered by an existing image of the version are imported in a
+// single import from version.Import, as the zones of an image cannot be
+// changed after the import. EnsureCustomImage waits for the import, passing
+// opts on to WaitFor, and verifies the checksums of the new image.
+//
+// With version.RetireOlder, older versions selected by version.Family are
+// deleted afterwards, unless a server was created from an image with their
+// slug.
+func EnsureCustomImage(ctx context.Context, client *Client, version CustomImageVersion, opts ...backoff.RetryOption) (*CustomImageRollout, error) {
+	if version.Slug == "" {
+		return nil, errors.New("custom image version needs a slug")
+	}
+	if version.RetireOlder && version.Family.Empty() {
+		return nil, errors.New("retiring older custom image versions needs a family selector")
+	}
+
+	images, err := client.CustomImages.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rollout := &CustomImageRollout{}
+	for _, image := range images {
+		if version.Matches(image) {
+			rollout.Images = append(rollout.Images, image)
+		}
+	}
+
+	available := rollout.Zones()
+	missing := []string{}
+	for _, zone := range version.Zones {
+		if !slices.Contains(available, zone) {
+			missing = append(missing, zone)
+		}
+	}
+
+	if len(missing) > 0 {
+		imported, err := importVersion(ctx, client, version, missing, opts...)
+		if err != nil {
+			return rollout, err
+		}
+		rollout.Images = append(rollout.Images, *imported)
+		rollout.Imported = append(rollout.Imported, *imported)
+	}
+
+	if version.RetireOlder {
+		if err := retireOlderVersions(ctx, client, version, images, rollout); err != nil {
+			return rollout, err
+		}
+	}
+	return rollout, nil
+}
+
+func importVersion(ctx context.Context, client *Client, version CustomImageVersion, zones []string, opts ...backoff.RetryOption) (*CustomImage, error) {
+	request := version.Import
+	request.Slug = version.Slug
+	request.Zones = zones
+	if request.Name == "" {
+		request.Name = version.Slug
+	}
+	tags := TagMap{}
+	if request.Tags != nil {
+		for key, value := range *request.Tags {
+			tags[key] = value
+		}
+	}
+	for key, value := range version.Tags {
+		tags[key] = value
+	}
+	request.Tags = &tags
+
+	customImageImport, err := client.CustomImageImports.Create(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+	customImageImport, err = client.CustomImageImports.WaitFor(ctx, customImageImport.UUID, ImportIsSuccessful, opts...)
+	if err != nil {
+		return nil, err
+	}
+	image, err := client.CustomImages.Get(ctx, customImageImport.CustomImage.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	for algorithm, checksum := range version.Checksums {
+		if actual := image.Checksums[algorithm]; !strings.EqualFold(actual, checksum) {
+			return image, &ChecksumMismatchError{Algorithm: algorithm, Expected: checksum, Actual: actual}
+		}
+	}
+	return image, nil
+}
+
+func retireOlderVersions(ctx context.Context, client *Client, version CustomImageVersion, images []CustomImage, rollout *CustomImageRollout) error {
+	var older []CustomImage
+	for _, image := range images {
+		if version.Family.Matches(image.Tags) && !version.Matches(image) {
+			older = append(older, image)
+		}
+	}
+	if len(older) == 0 {
+		return nil
+	}
+
+	servers, err := client.Servers.List(ctx)
+	if err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, server := range servers {
+		used[strings.TrimPrefix(server.Image.Slug, customImagePrefix)] = true
+	}
+
+	errs := []error{}
+	for _, image := range older {
+		if used[image.Slug] || used[image.UUID] {
+			rollout.InUse = append(rollout.InUse, image)
+			continue
+		}
+		if err := client.CustomImages.Delete(ctx, image.UUID); err != nil {
+			errs = append(errs, fmt.Errorf("retiring custom image %s: %w", image.UUID, err))
+			continue
+		}
+		rollout.Retired = append(rollout.Retired, image)
+	}
+	return errors.Join(errs...)
+}
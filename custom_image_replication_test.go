@@ -0,0 +1,111 @@
+package cloudscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const customImagesForRollout = `[
+  {"uuid": "v2-rma", "slug": "golden-v2", "checksums": {"sha256": "AA"}, "zones": [{"slug": "rma1"}], "tags": {"family": "golden"}},
+  {"uuid": "v2-other", "slug": "golden-v2", "checksums": {"sha256": "ff"}, "zones": [{"slug": "lpg1"}], "tags": {"family": "golden"}},
+  {"uuid": "v1", "slug": "golden-v1", "checksums": {"sha256": "11"}, "zones": [{"slug": "rma1"}], "tags": {"family": "golden"}},
+  {"uuid": "v0", "slug": "golden-v0", "checksums": {"sha256": "00"}, "zones": [{"slug": "rma1"}], "tags": {"family": "golden"}},
+  {"uuid": "other", "slug": "other", "zones": [{"slug": "rma1"}], "tags": {}}
+]`
+
+func TestEnsureCustomImage(t *testing.T) {
+	setup()
+	defer teardown()
+
+	imported := CustomImageImportRequest{}
+	deleted := []string{}
+
+	mux.HandleFunc("/v1/custom-images", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, customImagesForRollout)
+	})
+	mux.HandleFunc("/v1/custom-images/import", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodPost)
+		json.NewDecoder(r.Body).Decode(&imported)
+		fmt.Fprint(w, `{"uuid": "import-1", "status": "started"}`)
+	})
+	mux.HandleFunc("/v1/custom-images/import/import-1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "import-1", "status": "success", "custom_image": {"uuid": "v2-lpg"}}`)
+	})
+	mux.HandleFunc("/v1/custom-images/v2-lpg", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "v2-lpg", "slug": "golden-v2", "checksums": {"sha256": "aa"}, "zones": [{"slug": "lpg1"}], "tags": {"family": "golden"}}`)
+	})
+	for _, uuid := range []string{"v2-other", "v1", "v0"} {
+		mux.HandleFunc("/v1/custom-images/"+uuid, func(w http.ResponseWriter, r *http.Request) {
+			testHTTPMethod(t, r, http.MethodDelete)
+			deleted = append(deleted, uuid)
+		})
+	}
+	mux.HandleFunc("/v1/servers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"uuid": "s1", "image": {"slug": "custom:golden-v1"}}]`)
+	})
+
+	rollout, err := EnsureCustomImage(ctx, client, CustomImageVersion{
+		Slug:        "golden-v2",
+		Checksums:   map[string]string{"sha256": "aa"},
+		Tags:        TagMap{"family": "golden"},
+		Zones:       []string{"rma1", "lpg1"},
+		Import:      CustomImageImportRequest{URL: "https://example.com/golden-v2.raw", Name: "Golden v2"},
+		Family:      MustParseTagSelector("family=golden"),
+		RetireOlder: true,
+	}, fastWait)
+	if err != nil {
+		t.Fatalf("EnsureCustomImage returned error: %v", err)
+	}
+
+	if imported.Slug != "golden-v2" || !reflect.DeepEqual(imported.Zones, []string{"lpg1"}) || (*imported.Tags)["family"] != "golden" {
+		t.Errorf("unexpected import request %+v", imported)
+	}
+	zones := rollout.Zones()
+	sort.Strings(zones)
+	if !reflect.DeepEqual(zones, []string{"lpg1", "rma1"}) {
+		t.Errorf("expected version in lpg1 and rma1, got %v", zones)
+	}
+	if len(rollout.Imported) != 1 || rollout.Imported[0].UUID != "v2-lpg" {
+		t.Errorf("unexpected imported images %+v", rollout.Imported)
+	}
+	if !reflect.DeepEqual(deleted, []string{"v2-other", "v0"}) {
+		t.Errorf("expected v2-other and v0 to be retired, got %v", deleted)
+	}
+	if len(rollout.InUse) != 1 || rollout.InUse[0].UUID != "v1" {
+		t.Errorf("expected v1 to be kept, got %+v", rollout.InUse)
+	}
+}
+
+func TestEnsureCustomImage_NothingMissing(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/custom-images", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, customImagesForRollout)
+	})
+	mux.HandleFunc("/v1/custom-images/import", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no import")
+	})
+
+	rollout, err := EnsureCustomImage(ctx, client, CustomImageVersion{
+		Slug:  "golden-v2",
+		Zones: []string{"rma1", "lpg1"},
+	})
+	if err != nil {
+		t.Fatalf("EnsureCustomImage returned error: %v", err)
+	}
+	if len(rollout.Images) != 2 || len(rollout.Imported) != 0 {
+		t.Errorf("unexpected rollout %+v", rollout)
+	}
+}
+
+func TestEnsureCustomImage_RetireNeedsFamily(t *testing.T) {
+	_, err := EnsureCustomImage(ctx, client, CustomImageVersion{Slug: "golden", RetireOlder: true})
+	if err == nil {
+		t.Error("expected error without family selector")
+	}
+}
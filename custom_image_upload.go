@@ -0,0 +1,362 @@
+package cloudscale
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// ErrImageContentConsumed is returned when the content of an image passed as
+// io.Reader is requested a second time.
+var ErrImageContentConsumed = errors.New("image content can only be read once")
+
+// CustomImageImportFailedError is returned when a custom image import ends in
+// status "failed". Message is the ErrorMessage reported by the API.
+type CustomImageImportFailedError struct {
+	ImportUUID string
+	Message    string
+}
+
+func (e *CustomImageImportFailedError) Error() string {
+	return fmt.Sprintf("custom image import %s failed: %s", e.ImportUUID, e.Message)
+}
+
+// ChecksumMismatchError is returned when the checksum of an imported custom
+// image differs from the one computed while uploading it.
+type ChecksumMismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: uploaded %s, imported %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// ImageContent is an image to be published by an ImageSource.
+type ImageContent struct {
+	// Name is the file name of the image, e.g. "alpine.raw".
+	Name string
+	// Size is the length of the image in bytes, or -1 if unknown.
+	Size int64
+	// Open returns a reader for the image. It may be called more than once
+	// if the image is read repeatedly, but content passed as io.Reader can
+	// only be opened once.
+	Open func() (io.ReadCloser, error)
+}
+
+// ImageSource makes an image downloadable for the cloudscale.ch API.
+//
+// Publish returns the URL to import the image from and a function to call
+// once the import has finished. All image data must be read through
+// content.Open, so that checksums can be computed while it is transferred.
+type ImageSource interface {
+	Publish(ctx context.Context, content ImageContent) (string, func() error, error)
+}
+
+// LocalHTTPSource serves images with an HTTP server started on this machine
+// for the duration of the import. The machine must be reachable from the
+// internet, e.g. a CI runner with a public IP address.
+type LocalHTTPSource struct {
+	// Addr is the address to listen on, e.g. ":8080". Defaults to ":0",
+	// which picks a free port.
+	Addr string
+
+	// BaseURL is the URL under which Addr is reachable by the API, e.g.
+	// "http://203.0.113.10:8080". Defaults to the address of the listener,
+	// which is only useful if it is a public address.
+	BaseURL string
+}
+
+// Publish starts the HTTP server. The image is served under a random path, so
+// it cannot be guessed by others while it is published.
+func (s *LocalHTTPSource) Publish(ctx context.Context, content ImageContent) (string, func() error, error) {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":0"
+	}
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "tcp", addr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		listener.Close()
+		return "", nil, err
+	}
+	path := "/" + hex.EncodeToString(token) + "/" + url.PathEscape(content.Name)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != path {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if content.Size >= 0 {
+			w.Header().Set("Content-Length", fmt.Sprint(content.Size))
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		body, err := content.Open()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+		io.Copy(w, body)
+	})}
+	go server.Serve(listener)
+
+	baseURL := strings.TrimSuffix(s.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = "http://" + listener.Addr().String()
+	}
+	return baseURL + path, server.Close, nil
+}
+
+// PresignedURLSource uploads images to a presigned URL, e.g. of a bucket in
+// cloudscale.ch Objects Storage, and imports them from a second presigned URL
+// for downloading.
+type PresignedURLSource struct {
+	// UploadURL is a presigned URL accepting a PUT request with the image.
+	UploadURL string
+	// DownloadURL is a presigned URL from which the API downloads the image.
+	DownloadURL string
+	// HTTPClient is used for the upload. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Publish uploads the image to UploadURL and returns DownloadURL.
+func (s *PresignedURLSource) Publish(ctx context.Context, content ImageContent) (string, func() error, error) {
+	httpClient := s.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	body, err := content.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.UploadURL, body)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if content.Size >= 0 {
+		req.ContentLength = content.Size
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", nil, fmt.Errorf("uploading image: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return s.DownloadURL, func() error { return nil }, nil
+}
+
+// ImportFromFile imports the image file at path as custom image. The file is
+// published through source, the import is created from request with its URL
+// set to the published one, and ImportFromFile waits for it to finish. If
+// request.Name is empty, the file name is used.
+//
+// The sha256 and md5 checksums of the file are computed while source
+// transfers it and compared to the Checksums of the imported image. If they
+// differ, the image is returned together with a *ChecksumMismatchError, so
+// that the caller can delete it. A failed import results in a
+// *CustomImageImportFailedError.
+//
+// opts are passed on to WaitFor.
+func ImportFromFile(
+	ctx context.Context,
+	client *Client,
+	source ImageSource,
+	path string,
+	request CustomImageImportRequest,
+	opts ...backoff.RetryOption,
+) (*CustomImage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	content := ImageContent{
+		Name: filepath.Base(path),
+		Size: info.Size(),
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+	return importContent(ctx, client, source, content, request, opts...)
+}
+
+// ImportFromReader works like ImportFromFile, but reads the image from r.
+// size is the length of the image in bytes, or -1 if unknown. As r can only
+// be read once, source must not download the image more than once.
+func ImportFromReader(
+	ctx context.Context,
+	client *Client,
+	source ImageSource,
+	name string,
+	r io.Reader,
+	size int64,
+	request CustomImageImportRequest,
+	opts ...backoff.RetryOption,
+) (*CustomImage, error) {
+	var once sync.Once
+	content := ImageContent{
+		Name: name,
+		Size: size,
+		Open: func() (io.ReadCloser, error) {
+			err := ErrImageContentConsumed
+			once.Do(func() { err = nil })
+			if err != nil {
+				return nil, err
+			}
+			return io.NopCloser(r), nil
+		},
+	}
+	return importContent(ctx, client, source, content, request, opts...)
+}
+
+func importContent(
+	ctx context.Context,
+	client *Client,
+	source ImageSource,
+	content ImageContent,
+	request CustomImageImportRequest,
+	opts ...backoff.RetryOption,
+) (*CustomImage, error) {
+	sums := &checksums{}
+	open := content.Open
+	content.Open = func() (io.ReadCloser, error) {
+		body, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return sums.reader(body), nil
+	}
+
+	imageURL, unpublish, err := source.Publish(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("publishing image: %w", err)
+	}
+	defer unpublish()
+
+	request.URL = imageURL
+	if request.Name == "" {
+		request.Name = content.Name
+	}
+
+	customImageImport, err := client.CustomImageImports.Create(ctx, &request)
+	if err != nil {
+		return nil, err
+	}
+	customImageImport, err = client.CustomImageImports.WaitFor(ctx, customImageImport.UUID, ImportIsSuccessful, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	customImage, err := client.CustomImages.Get(ctx, customImageImport.CustomImage.UUID)
+	if err != nil {
+		return nil, err
+	}
+	return customImage, sums.verify(customImage.Checksums)
+}
+
+// checksums records the digests of the last complete read of an image.
+type checksums struct {
+	mu     sync.Mutex
+	digest map[string]string
+}
+
+func (c *checksums) reader(body io.ReadCloser) io.ReadCloser {
+	return &hashingReader{
+		body:   body,
+		hashes: map[string]hash.Hash{"sha256": sha256.New(), "md5": md5.New()},
+		done:   c.record,
+	}
+}
+
+func (c *checksums) record(digest map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.digest = digest
+}
+
+// verify compares the recorded digests to those reported by the API.
+func (c *checksums) verify(actual map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.digest == nil {
+		return errors.New("image was imported without being read completely, checksums unknown")
+	}
+
+	verified := 0
+	for _, algorithm := range []string{"sha256", "md5"} {
+		imported, ok := actual[algorithm]
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(imported, c.digest[algorithm]) {
+			return &ChecksumMismatchError{Algorithm: algorithm, Expected: c.digest[algorithm], Actual: imported}
+		}
+		verified++
+	}
+	if verified == 0 {
+		return errors.New("imported image has no checksums to verify")
+	}
+	return nil
+}
+
+// hashingReader feeds everything read through it into hashes and reports the
+// digests once the end of body is reached.
+type hashingReader struct {
+	body   io.ReadCloser
+	hashes map[string]hash.Hash
+	done   func(digest map[string]string)
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.body.Read(p)
+	for _, hash := range h.hashes {
+		hash.Write(p[:n])
+	}
+	if err == io.EOF {
+		digest := map[string]string{}
+		for algorithm, hash := range h.hashes {
+			digest[algorithm] = hex.EncodeToString(hash.Sum(nil))
+		}
+		h.done(digest)
+	}
+	return n, err
+}
+
+func (h *hashingReader) Close() error {
+	return h.body.Close()
+}
@@ -0,0 +1,180 @@
+package cloudscale
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+var fastWait = backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond))
+
+// handleImport emulates the API: creating an import downloads the image from
+// the given URL, and the import ends with status. The imported image reports
+// checksums computed from the downloaded data unless checksums is set.
+func handleImport(t *testing.T, status string, checksums map[string]string) *CustomImageImportRequest {
+	created := &CustomImageImportRequest{}
+	var downloaded []byte
+
+	mux.HandleFunc("/v1/custom-images/import", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodPost)
+		if err := json.NewDecoder(r.Body).Decode(created); err != nil {
+			t.Fatalf("decode json: %v", err)
+		}
+		resp, err := http.Get(created.URL)
+		if err != nil {
+			t.Fatalf("downloading image: %v", err)
+		}
+		defer resp.Body.Close()
+		downloaded, _ = io.ReadAll(resp.Body)
+		fmt.Fprint(w, `{"uuid": "import-1", "status": "started"}`)
+	})
+	mux.HandleFunc("/v1/custom-images/import/import-1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"uuid": "import-1", "status": %q, "error_message": "unsupported format", "custom_image": {"uuid": "image-1"}}`, status)
+	})
+	mux.HandleFunc("/v1/custom-images/image-1", func(w http.ResponseWriter, r *http.Request) {
+		sums := checksums
+		if sums == nil {
+			sha := sha256.Sum256(downloaded)
+			md := md5.Sum(downloaded)
+			sums = map[string]string{"sha256": hex.EncodeToString(sha[:]), "md5": hex.EncodeToString(md[:])}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"uuid": "image-1", "name": created.Name, "checksums": sums})
+	})
+	return created
+}
+
+func TestImportFromFile(t *testing.T) {
+	setup()
+	defer teardown()
+	created := handleImport(t, "success", nil)
+
+	path := filepath.Join(t.TempDir(), "alpine.raw")
+	if err := os.WriteFile(path, []byte("image data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &LocalHTTPSource{Addr: "127.0.0.1:0"}
+	customImage, err := ImportFromFile(ctx, client, source, path, CustomImageImportRequest{Zones: []string{"rma1"}}, fastWait)
+	if err != nil {
+		t.Fatalf("ImportFromFile returned error: %v", err)
+	}
+	if customImage.UUID != "image-1" || customImage.Name != "alpine.raw" {
+		t.Errorf("unexpected custom image %+v", customImage)
+	}
+	if !strings.HasSuffix(created.URL, "/alpine.raw") || len(created.Zones) != 1 {
+		t.Errorf("unexpected import request %+v", created)
+	}
+
+	if resp, err := http.Get(created.URL); err == nil {
+		resp.Body.Close()
+		t.Error("expected image to be unpublished after the import")
+	}
+}
+
+func TestImportFromReader_ChecksumMismatch(t *testing.T) {
+	setup()
+	defer teardown()
+	handleImport(t, "success", map[string]string{"sha256": "0000", "md5": "1111"})
+
+	source := &LocalHTTPSource{Addr: "127.0.0.1:0"}
+	customImage, err := ImportFromReader(ctx, client, source, "image.raw", strings.NewReader("image data"), -1, CustomImageImportRequest{}, fastWait)
+
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected *ChecksumMismatchError, got %v", err)
+	}
+	if mismatch.Algorithm != "sha256" || mismatch.Actual != "0000" {
+		t.Errorf("unexpected mismatch %+v", mismatch)
+	}
+	if customImage == nil || customImage.UUID != "image-1" {
+		t.Errorf("expected the imported image to be returned, got %+v", customImage)
+	}
+}
+
+func TestImportFromReader_Failed(t *testing.T) {
+	setup()
+	defer teardown()
+	handleImport(t, "failed", nil)
+
+	source := &LocalHTTPSource{Addr: "127.0.0.1:0"}
+	start := time.Now()
+	_, err := ImportFromReader(ctx, client, source, "image.raw", strings.NewReader("image data"), -1, CustomImageImportRequest{})
+
+	var failed *CustomImageImportFailedError
+	if !errors.As(err, &failed) {
+		t.Fatalf("expected *CustomImageImportFailedError, got %v", err)
+	}
+	if failed.ImportUUID != "import-1" || failed.Message != "unsupported format" {
+		t.Errorf("unexpected error %+v", failed)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected a failed import to stop waiting immediately")
+	}
+}
+
+func TestPresignedURLSource(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var uploaded bytes.Buffer
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			io.Copy(&uploaded, r.Body)
+		case http.MethodGet:
+			w.Write(uploaded.Bytes())
+		}
+	}))
+	defer bucket.Close()
+	created := handleImport(t, "success", nil)
+
+	source := &PresignedURLSource{
+		UploadURL:   bucket.URL + "/upload?signature=a",
+		DownloadURL: bucket.URL + "/download?signature=b",
+	}
+	_, err := ImportFromReader(ctx, client, source, "image.raw", strings.NewReader("image data"), 10, CustomImageImportRequest{Name: "Image"}, fastWait)
+	if err != nil {
+		t.Fatalf("ImportFromReader returned error: %v", err)
+	}
+	if uploaded.String() != "image data" {
+		t.Errorf("expected image to be uploaded, got %q", uploaded.String())
+	}
+	if created.URL != source.DownloadURL || created.Name != "Image" {
+		t.Errorf("unexpected import request %+v", created)
+	}
+}
+
+func TestImportFromReader_ReadOnce(t *testing.T) {
+	_, err := ImportFromReader(ctx, nil, openTwiceSource{}, "image.raw", strings.NewReader(""), 0, CustomImageImportRequest{})
+	if !errors.Is(err, ErrImageContentConsumed) {
+		t.Errorf("expected ErrImageContentConsumed, got %v", err)
+	}
+}
+
+type openTwiceSource struct{}
+
+func (s openTwiceSource) Publish(ctx context.Context, content ImageContent) (string, func() error, error) {
+	for i := 0; i < 2; i++ {
+		body, err := content.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		body.Close()
+	}
+	return "", nil, nil
+}
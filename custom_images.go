@@ -1,18 +1,60 @@
 package cloudscale
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/cenkalti/backoff/v5"
 )
 
 const customImagesBasePath = "v1/custom-images"
 
+// UserDataHandling defines how the user data of servers created from a custom
+// image is processed.
 type UserDataHandling string
 
-const UserDataHandlingPassThrough UserDataHandling = "pass-through"
-const UserDataHandlingExtendCloudConfig UserDataHandling = "extend-cloud-config"
+const (
+	UserDataHandlingPassThrough       UserDataHandling = "pass-through"
+	UserDataHandlingExtendCloudConfig UserDataHandling = "extend-cloud-config"
+)
+
+// IsValid reports whether h is a known user data handling.
+func (h UserDataHandling) IsValid() bool {
+	switch h {
+	case UserDataHandlingPassThrough, UserDataHandlingExtendCloudConfig:
+		return true
+	}
+	return false
+}
+
+func (h UserDataHandling) String() string {
+	return string(h)
+}
+
+// FirmwareType is the firmware servers created from a custom image boot with.
+type FirmwareType string
+
+const (
+	FirmwareTypeBIOS FirmwareType = "bios"
+	FirmwareTypeUEFI FirmwareType = "uefi"
+)
+
+// IsValid reports whether t is a known firmware type.
+func (t FirmwareType) IsValid() bool {
+	switch t {
+	case FirmwareTypeBIOS, FirmwareTypeUEFI:
+		return true
+	}
+	return false
+}
+
+func (t FirmwareType) String() string {
+	return string(t)
+}
 
 type CustomImage struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
@@ -23,11 +65,23 @@ type CustomImage struct {
 	SizeGB           int               `json:"size_gb,omitempty"`
 	Checksums        map[string]string `json:"checksums,omitempty"`
 	UserDataHandling UserDataHandling  `json:"user_data_handling,omitempty"`
-	FirmwareType     string            `json:"firmware_type,omitempty"`
+	FirmwareType     FirmwareType      `json:"firmware_type,omitempty"`
 	Zones            []ZoneStub        `json:"zones"`
 	CreatedAt        time.Time         `json:"created_at"`
 }
 
+// MarshalJSON encodes the custom image including its unknown fields.
+func (c CustomImage) MarshalJSON() ([]byte, error) {
+	type plain CustomImage
+	return marshalWithUnknownFields(plain(c), c.Extra)
+}
+
+// Raw returns the JSON of the custom image, including the fields the SDK does
+// not model.
+func (c CustomImage) Raw() json.RawMessage {
+	return rawJSON(c)
+}
+
 type CustomImageRequest struct {
 	TaggedResourceRequest
 	Name             string           `json:"name,omitempty"`
@@ -38,6 +92,7 @@ type CustomImageRequest struct {
 type CustomImageService interface {
 	GenericGetService[CustomImage]
 	GenericListService[CustomImage]
+	GenericWatchService[CustomImage]
 	GenericUpdateService[CustomImage, CustomImageRequest]
 	GenericDeleteService[CustomImage]
 	GenericWaitForService[CustomImage]
@@ -47,9 +102,34 @@ type CustomImageServiceOperations struct {
 	client *Client
 }
 
-var ImportIsSuccessful = func(importInfo *CustomImageImport) (bool, error) {
-	if importInfo.Status == "success" {
+// ImportIsSuccessful waits for a custom image import to succeed. It fails
+// with a *CustomImageImportFailedError as soon as the import has failed.
+var ImportIsSuccessful Condition[CustomImageImport] = func(importInfo *CustomImageImport) (bool, error) {
+	switch importInfo.Status {
+	case CustomImageImportSuccess:
 		return true, nil
+	case CustomImageImportFailed:
+		return false, backoff.Permanent(&CustomImageImportFailedError{
+			ImportUUID: importInfo.UUID,
+			Message:    importInfo.ErrorMessage,
+		})
+	}
+	return false, fmt.Errorf("waiting for status: %s, current status: %s", CustomImageImportSuccess, importInfo.Status)
+}
+
+// CustomImageIsAvailableIn waits for a custom image to be available in zone.
+func CustomImageIsAvailableIn(zone string) Condition[CustomImage] {
+	return func(image *CustomImage) (bool, error) {
+		if containsZone(image.Zones, zone) {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for image to be available in %s", zone)
 	}
-	return false, fmt.Errorf("waiting for status: %s, current status: %s", "success", importInfo.Status)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r CustomImageRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	return f.err()
 }
@@ -0,0 +1,187 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlavorCategory is the family of a flavor, derived from its slug.
+type FlavorCategory string
+
+const (
+	// FlavorCategoryFlex flavors have shared vCPUs.
+	FlavorCategoryFlex FlavorCategory = "flex"
+	// FlavorCategoryPlus flavors have dedicated CPUs.
+	FlavorCategoryPlus FlavorCategory = "plus"
+	// FlavorCategoryGPU flavors have dedicated CPUs and GPUs.
+	FlavorCategoryGPU FlavorCategory = "gpu"
+)
+
+// ErrNoMatchingFlavor is returned when no flavor fulfils the requirements.
+var ErrNoMatchingFlavor = errors.New("no flavor matches the requirements")
+
+// Category returns the family of the flavor, e.g. FlavorCategoryFlex for
+// "flex-4-1" or FlavorCategoryGPU for "gpu2-128-16-1-200". Unknown families
+// are returned as they appear in the slug.
+func (f Flavor) Category() FlavorCategory {
+	family, _, _ := strings.Cut(f.Slug, "-")
+	family = strings.TrimRight(family, "0123456789")
+	if f.GPU != nil && family != string(FlavorCategoryGPU) {
+		return FlavorCategoryGPU
+	}
+	return FlavorCategory(family)
+}
+
+// DedicatedCPU reports whether the vCPUs of the flavor are dedicated rather
+// than shared.
+func (f Flavor) DedicatedCPU() bool {
+	category := f.Category()
+	return category == FlavorCategoryPlus || category == FlavorCategoryGPU
+}
+
+// AvailableIn reports whether the flavor is offered in the given zone.
+func (f Flavor) AvailableIn(zone string) bool {
+	return containsZone(f.Zones, zone)
+}
+
+// FlavorRequirements describes what a server needs. Zero values do not
+// restrict the selection.
+type FlavorRequirements struct {
+	MinVCPUs    int
+	MinMemoryGB int
+
+	// DedicatedCPU, if set, requires dedicated (true) or shared (false)
+	// vCPUs.
+	DedicatedCPU *bool
+
+	// Categories, if not empty, restricts the selection to these families.
+	Categories []FlavorCategory
+
+	// GPUModel, if set, requires a GPU whose name contains it, ignoring
+	// case, e.g. "RTX PRO 6000".
+	GPUModel string
+	// MinGPUs requires at least this many GPUs. Flavors with GPUs are only
+	// selected if MinGPUs or GPUModel is set.
+	MinGPUs int
+	// MinVRAMPerGPUGB requires at least this much memory per GPU.
+	MinVRAMPerGPUGB int
+
+	// Zone, if set, requires the flavor to be available in this zone.
+	Zone string
+}
+
+// Matches reports whether flavor fulfils the requirements.
+func (r FlavorRequirements) Matches(flavor Flavor) bool {
+	if flavor.VCPUCount < r.MinVCPUs || flavor.MemoryGB < r.MinMemoryGB {
+		return false
+	}
+	if r.DedicatedCPU != nil && flavor.DedicatedCPU() != *r.DedicatedCPU {
+		return false
+	}
+	if len(r.Categories) > 0 {
+		found := false
+		for _, category := range r.Categories {
+			if flavor.Category() == category {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Zone != "" && !flavor.AvailableIn(r.Zone) {
+		return false
+	}
+
+	wantsGPU := r.GPUModel != "" || r.MinGPUs > 0 || r.MinVRAMPerGPUGB > 0
+	if !wantsGPU {
+		return flavor.GPU == nil || flavor.GPU.Count == 0
+	}
+	if flavor.GPU == nil {
+		return false
+	}
+	if r.GPUModel != "" && !strings.Contains(strings.ToLower(flavor.GPU.Name), strings.ToLower(r.GPUModel)) {
+		return false
+	}
+	return flavor.GPU.Count >= max(r.MinGPUs, 1) && flavor.GPU.VRAMPerGPUGB >= r.MinVRAMPerGPUGB
+}
+
+// FlavorCostFunc returns the relative cost of a flavor. Lower is cheaper.
+type FlavorCostFunc func(flavor Flavor) float64
+
+// DefaultFlavorCost approximates the relative price of a flavor from its
+// resources: shared vCPUs are cheaper than dedicated ones, and GPUs dominate
+// everything else. It is meant for ranking flavors, not as a price list;
+// pass a FlavorCostFunc based on actual prices if exact ordering matters.
+func DefaultFlavorCost(flavor Flavor) float64 {
+	vcpuWeight := 2.0
+	if flavor.DedicatedCPU() {
+		vcpuWeight = 6.0
+	}
+	cost := float64(flavor.VCPUCount)*vcpuWeight + float64(flavor.MemoryGB)
+	if flavor.GPU != nil {
+		cost += float64(flavor.GPU.Count) * (200 + float64(flavor.GPU.VRAMPerGPUGB))
+	}
+	return cost
+}
+
+// SelectFlavor returns the cheapest of flavors that fulfils requirements,
+// according to cost. If cost is nil, DefaultFlavorCost is used. Flavors with
+// the same cost are ordered by vCPUs, memory and slug, so the result is
+// deterministic.
+func SelectFlavor(flavors []Flavor, requirements FlavorRequirements, cost FlavorCostFunc) (*Flavor, error) {
+	if cost == nil {
+		cost = DefaultFlavorCost
+	}
+
+	candidates := []Flavor{}
+	for _, flavor := range flavors {
+		if requirements.Matches(flavor) {
+			candidates = append(candidates, flavor)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %+v", ErrNoMatchingFlavor, requirements)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if costA, costB := cost(a), cost(b); costA != costB {
+			return costA < costB
+		}
+		if a.VCPUCount != b.VCPUCount {
+			return a.VCPUCount < b.VCPUCount
+		}
+		if a.MemoryGB != b.MemoryGB {
+			return a.MemoryGB < b.MemoryGB
+		}
+		return a.Slug < b.Slug
+	})
+
+	return &candidates[0], nil
+}
+
+// FlavorSelector picks flavors from the ones offered by a FlavorService.
+type FlavorSelector struct {
+	Flavors FlavorService
+	// Cost ranks matching flavors. If nil, DefaultFlavorCost is used.
+	Cost FlavorCostFunc
+}
+
+// NewFlavorSelector returns a FlavorSelector listing flavors from service.
+func NewFlavorSelector(service FlavorService, cost FlavorCostFunc) *FlavorSelector {
+	return &FlavorSelector{Flavors: service, Cost: cost}
+}
+
+// Select lists the available flavors and returns the cheapest one fulfilling
+// requirements.
+func (s *FlavorSelector) Select(ctx context.Context, requirements FlavorRequirements) (*Flavor, error) {
+	flavors, err := s.Flavors.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return SelectFlavor(flavors, requirements, s.Cost)
+}
@@ -0,0 +1,115 @@
+package cloudscale
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var selectableFlavors = []Flavor{
+	{Slug: "flex-4-1", VCPUCount: 1, MemoryGB: 4, Zones: []ZoneStub{{Slug: "rma1"}, {Slug: "lpg1"}}},
+	{Slug: "flex-8-2", VCPUCount: 2, MemoryGB: 8, Zones: []ZoneStub{{Slug: "rma1"}, {Slug: "lpg1"}}},
+	{Slug: "flex-16-4", VCPUCount: 4, MemoryGB: 16, Zones: []ZoneStub{{Slug: "rma1"}}},
+	{Slug: "plus-8-2", VCPUCount: 2, MemoryGB: 8, Zones: []ZoneStub{{Slug: "rma1"}, {Slug: "lpg1"}}},
+	{Slug: "plus-32-8", VCPUCount: 8, MemoryGB: 32, Zones: []ZoneStub{{Slug: "lpg1"}}},
+	{
+		Slug: "gpu2-128-16-1-200", VCPUCount: 16, MemoryGB: 128,
+		GPU:   &FlavorGPU{Name: "NVIDIA RTX PRO 6000 Max-Q", Count: 1, VRAMPerGPUGB: 96},
+		Zones: []ZoneStub{{Slug: "rma1"}},
+	},
+	{
+		Slug: "gpu2-256-32-2-400", VCPUCount: 32, MemoryGB: 256,
+		GPU:   &FlavorGPU{Name: "NVIDIA RTX PRO 6000 Max-Q", Count: 2, VRAMPerGPUGB: 96},
+		Zones: []ZoneStub{{Slug: "rma1"}},
+	},
+}
+
+func TestFlavor_Category(t *testing.T) {
+	cases := map[string]FlavorCategory{
+		"flex-4-1":          FlavorCategoryFlex,
+		"plus-32-8":         FlavorCategoryPlus,
+		"gpu2-128-16-1-200": FlavorCategoryGPU,
+		"gpu-64-8-1-200":    FlavorCategoryGPU,
+		"mega-8-2":          FlavorCategory("mega"),
+	}
+	for slug, expected := range cases {
+		flavor := Flavor{Slug: slug}
+		assertEqual(t, expected, flavor.Category())
+	}
+
+	assertEqual(t, false, Flavor{Slug: "flex-4-1"}.DedicatedCPU())
+	assertEqual(t, true, Flavor{Slug: "plus-32-8"}.DedicatedCPU())
+}
+
+func TestSelectFlavor(t *testing.T) {
+	dedicated := true
+	shared := false
+
+	cases := []struct {
+		name         string
+		requirements FlavorRequirements
+		expected     string
+	}{
+		{"smallest", FlavorRequirements{}, "flex-4-1"},
+		{"memory", FlavorRequirements{MinMemoryGB: 6}, "flex-8-2"},
+		{"vcpus and zone", FlavorRequirements{MinVCPUs: 3, Zone: "rma1"}, "flex-16-4"},
+		{"dedicated", FlavorRequirements{DedicatedCPU: &dedicated}, "plus-8-2"},
+		{"dedicated in zone", FlavorRequirements{MinVCPUs: 4, DedicatedCPU: &dedicated, Zone: "lpg1"}, "plus-32-8"},
+		{"shared", FlavorRequirements{MinVCPUs: 2, DedicatedCPU: &shared}, "flex-8-2"},
+		{"category", FlavorRequirements{Categories: []FlavorCategory{FlavorCategoryPlus}}, "plus-8-2"},
+		{"gpu model", FlavorRequirements{GPUModel: "rtx pro 6000"}, "gpu2-128-16-1-200"},
+		{"gpu count", FlavorRequirements{MinGPUs: 2}, "gpu2-256-32-2-400"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			flavor, err := SelectFlavor(selectableFlavors, tc.requirements, nil)
+			if err != nil {
+				t.Fatalf("SelectFlavor returned error: %v", err)
+			}
+			assertEqual(t, tc.expected, flavor.Slug)
+		})
+	}
+}
+
+func TestSelectFlavor_CustomCost(t *testing.T) {
+	// Pretend dedicated CPUs are on sale.
+	cost := func(flavor Flavor) float64 {
+		if flavor.Category() == FlavorCategoryPlus {
+			return 1
+		}
+		return DefaultFlavorCost(flavor)
+	}
+
+	flavor, err := SelectFlavor(selectableFlavors, FlavorRequirements{MinVCPUs: 2}, cost)
+	if err != nil {
+		t.Fatalf("SelectFlavor returned error: %v", err)
+	}
+	// plus-8-2 and plus-32-8 cost the same, the smaller one wins the tie.
+	assertEqual(t, "plus-8-2", flavor.Slug)
+}
+
+func TestSelectFlavor_NoMatch(t *testing.T) {
+	_, err := SelectFlavor(selectableFlavors, FlavorRequirements{MinGPUs: 1, Zone: "lpg1"}, nil)
+	if !errors.Is(err, ErrNoMatchingFlavor) {
+		t.Errorf("expected ErrNoMatchingFlavor, got %v", err)
+	}
+}
+
+func TestFlavorSelector_Select(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/flavors", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, flavorsResponse)
+	})
+
+	selector := NewFlavorSelector(client.Flavors, nil)
+	flavor, err := selector.Select(ctx, FlavorRequirements{GPUModel: "RTX", Zone: "lpg1"})
+	if err != nil {
+		t.Fatalf("Select returned error: %v", err)
+	}
+	assertEqual(t, "gpu2-128-16-1-200", flavor.Slug)
+}
@@ -2,6 +2,7 @@ package cloudscale
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 )
 
@@ -9,8 +10,10 @@ const flavorsBasePath = "v1/flavors"
 
 // Flavor represents a server flavor, i.e., a combination of vCPUs and memory.
 // Flavors are read-only and zonal. Categories include shared vCPU (flex-*),
-// dedicated CPU (plus-*), and FlavorGPU (gpu*).
+// dedicated CPU (plus-*), and FlavorGPU (gpu*); use Category instead of
+// parsing the slug. SelectFlavor picks the cheapest flavor for given needs.
 type Flavor struct {
+	UnknownFields
 	Slug      string     `json:"slug"`
 	Name      string     `json:"name"`
 	VCPUCount int        `json:"vcpu_count"`
@@ -19,6 +22,18 @@ type Flavor struct {
 	Zones     []ZoneStub `json:"zones"`
 }
 
+// MarshalJSON encodes the flavor including its unknown fields.
+func (f Flavor) MarshalJSON() ([]byte, error) {
+	type plain Flavor
+	return marshalWithUnknownFields(plain(f), f.Extra)
+}
+
+// Raw returns the JSON of the flavor, including the fields the SDK does not
+// model.
+func (f Flavor) Raw() json.RawMessage {
+	return rawJSON(f)
+}
+
 type FlavorGPU struct {
 	Name         string `json:"name"`
 	Count        int    `json:"count"`
@@ -1,6 +1,8 @@
 package cloudscale
 
 import (
+	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -8,7 +10,30 @@ import (
 
 const floatingIPsBasePath = "v1/floating-ips"
 
+// FloatingIPType tells whether a floating IP can only be assigned within its
+// region or in all regions.
+type FloatingIPType string
+
+const (
+	FloatingIPTypeRegional FloatingIPType = "regional"
+	FloatingIPTypeGlobal   FloatingIPType = "global"
+)
+
+// IsValid reports whether t is a known floating IP type.
+func (t FloatingIPType) IsValid() bool {
+	switch t {
+	case FloatingIPTypeRegional, FloatingIPTypeGlobal:
+		return true
+	}
+	return false
+}
+
+func (t FloatingIPType) String() string {
+	return string(t)
+}
+
 type FloatingIP struct {
+	UnknownFields
 	Region *RegionStub `json:"region"` // not using RegionalResource here, as FloatingIP can be regional or global
 	TaggedResource
 	HREF           string            `json:"href"`
@@ -17,20 +42,32 @@ type FloatingIP struct {
 	NextHop        string            `json:"next_hop"`
 	Server         *ServerStub       `json:"server"`
 	LoadBalancer   *LoadBalancerStub `json:"load_balancer"`
-	Type           string            `json:"type"`
+	Type           FloatingIPType    `json:"type"`
 	ReversePointer string            `json:"reverse_ptr,omitempty"`
 	CreatedAt      time.Time         `json:"created_at"`
 }
 
+// MarshalJSON encodes the floating IP including its unknown fields.
+func (f FloatingIP) MarshalJSON() ([]byte, error) {
+	type plain FloatingIP
+	return marshalWithUnknownFields(plain(f), f.Extra)
+}
+
+// Raw returns the JSON of the floating IP, including the fields the SDK does
+// not model.
+func (f FloatingIP) Raw() json.RawMessage {
+	return rawJSON(f)
+}
+
 type FloatingIPCreateRequest struct {
 	RegionalResourceRequest
 	TaggedResourceRequest
-	IPVersion      int    `json:"ip_version"`
-	Server         string `json:"server,omitempty"`
-	LoadBalancer   string `json:"load_balancer,omitempty"`
-	Type           string `json:"type,omitempty"`
-	PrefixLength   int    `json:"prefix_length,omitempty"`
-	ReversePointer string `json:"reverse_ptr,omitempty"`
+	IPVersion      int            `json:"ip_version"`
+	Server         string         `json:"server,omitempty"`
+	LoadBalancer   string         `json:"load_balancer,omitempty"`
+	Type           FloatingIPType `json:"type,omitempty"`
+	PrefixLength   int            `json:"prefix_length,omitempty"`
+	ReversePointer string         `json:"reverse_ptr,omitempty"`
 }
 
 func (f FloatingIP) IP() string {
@@ -42,18 +79,92 @@ func (f FloatingIP) PrefixLength() int {
 	return result
 }
 
+// FloatingIPUpdateRequest assigns a floating IP to a server or load
+// balancer, or unassigns it by setting both to null. A null ReversePointer
+// resets the reverse pointer to the default.
 type FloatingIPUpdateRequest struct {
 	TaggedResourceRequest
-	Server         string `json:"server,omitempty"`
-	LoadBalancer   string `json:"load_balancer,omitempty"`
-	ReversePointer string `json:"reverse_ptr,omitempty"`
+	Server         Nullable[string] `json:"server,omitzero"`
+	LoadBalancer   Nullable[string] `json:"load_balancer,omitzero"`
+	ReversePointer Nullable[string] `json:"reverse_ptr,omitzero"`
 }
 
 type FloatingIPsService interface {
 	GenericCreateService[FloatingIP, FloatingIPCreateRequest]
 	GenericGetService[FloatingIP]
 	GenericListService[FloatingIP]
+	GenericWatchService[FloatingIP]
 	GenericUpdateService[FloatingIP, FloatingIPUpdateRequest]
 	GenericDeleteService[FloatingIP]
 	GenericWaitForService[FloatingIP]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r FloatingIPCreateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	if r.IPVersion != 4 && r.IPVersion != 6 {
+		f.add("ip_version", "must be 4 or 6, got %d", r.IPVersion)
+	}
+	if r.Server != "" && r.LoadBalancer != "" {
+		f.add("load_balancer", "cannot be combined with server")
+	}
+	maxPrefixLength := 32
+	if r.IPVersion == 6 {
+		maxPrefixLength = 128
+	}
+	if r.PrefixLength < 0 || r.PrefixLength > maxPrefixLength {
+		f.add("prefix_length", "must be between 0 and %d, got %d", maxPrefixLength, r.PrefixLength)
+	}
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the region exists.
+func (r FloatingIPCreateRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.region(catalog, "region", r.Region)
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r FloatingIPUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	server, hasServer := r.Server.Get()
+	loadBalancer, hasLoadBalancer := r.LoadBalancer.Get()
+	if hasServer && server != "" && hasLoadBalancer && loadBalancer != "" {
+		f.add("load_balancer", "cannot be combined with server")
+	}
+	return f.err()
+}
+
+// FloatingIPIsAssignedToServer waits for a floating IP to point at the server
+// with the given UUID.
+func FloatingIPIsAssignedToServer(serverUUID string) Condition[FloatingIP] {
+	return func(floatingIP *FloatingIP) (bool, error) {
+		if floatingIP.Server != nil && floatingIP.Server.UUID == serverUUID {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for %s to be assigned to server %s", floatingIP.Network, serverUUID)
+	}
+}
+
+// FloatingIPIsAssignedToLoadBalancer waits for a floating IP to point at the
+// load balancer with the given UUID.
+func FloatingIPIsAssignedToLoadBalancer(loadBalancerUUID string) Condition[FloatingIP] {
+	return func(floatingIP *FloatingIP) (bool, error) {
+		if floatingIP.LoadBalancer != nil && floatingIP.LoadBalancer.UUID == loadBalancerUUID {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for %s to be assigned to load balancer %s", floatingIP.Network, loadBalancerUUID)
+	}
+}
+
+// FloatingIPIsUnassigned waits for a floating IP to point at neither a server
+// nor a load balancer.
+var FloatingIPIsUnassigned Condition[FloatingIP] = func(floatingIP *FloatingIP) (bool, error) {
+	if floatingIP.Server == nil && floatingIP.LoadBalancer == nil {
+		return true, nil
+	}
+	return false, fmt.Errorf("waiting for %s to be unassigned", floatingIP.Network)
+}
@@ -85,7 +85,7 @@ func TestFloatingIPs_Update(t *testing.T) {
 	defer teardown()
 
 	updateRequest := &FloatingIPUpdateRequest{
-		Server: "47777777-fcd2-482f-bdb6-24461b2d47b1",
+		Server: NewNullable("47777777-fcd2-482f-bdb6-24461b2d47b1"),
 	}
 
 	mux.HandleFunc("/v1/floating-ips/192.0.2.123", func(w http.ResponseWriter, r *http.Request) {
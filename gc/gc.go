@@ -0,0 +1,336 @@
+// Package gc implements a tag-based garbage collector for ephemeral
+// cloudscale.ch resources, e.g. those created by CI pipelines that may be
+// killed before they clean up after themselves.
+//
+// Resources are selected by an expiry tag holding a timestamp, a TTL tag
+// holding a duration relative to the creation time, or an owner tag. Selected
+// resources are deleted in dependency order, so that e.g. a floating IP is
+// released before its server and a subnet before its network. The collector
+// waits until the resources of a kind are gone before deleting the next kind.
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// ResourceKind names a type of resource handled by the collector.
+type ResourceKind string
+
+const (
+	LoadBalancerListeners      ResourceKind = "load-balancer-listener"
+	LoadBalancerHealthMonitors ResourceKind = "load-balancer-health-monitor"
+	LoadBalancerPools          ResourceKind = "load-balancer-pool"
+	LoadBalancers              ResourceKind = "load-balancer"
+	FloatingIPs                ResourceKind = "floating-ip"
+	Servers                    ResourceKind = "server"
+	ServerGroups               ResourceKind = "server-group"
+	VolumeSnapshots            ResourceKind = "volume-snapshot"
+	Volumes                    ResourceKind = "volume"
+	Subnets                    ResourceKind = "subnet"
+	Networks                   ResourceKind = "network"
+	CustomImages               ResourceKind = "custom-image"
+	ObjectsUsers               ResourceKind = "objects-user"
+)
+
+// DeletionOrder lists all resource kinds in the order they are deleted.
+// Resources depending on others come first.
+var DeletionOrder = []ResourceKind{
+	LoadBalancerListeners,
+	LoadBalancerHealthMonitors,
+	LoadBalancerPools,
+	LoadBalancers,
+	FloatingIPs,
+	Servers,
+	ServerGroups,
+	VolumeSnapshots,
+	Volumes,
+	Subnets,
+	Networks,
+	CustomImages,
+	ObjectsUsers,
+}
+
+// Options configures which resources a Collector selects.
+type Options struct {
+	// ExpiresAtTag is the tag key holding the point in time after which a
+	// resource may be deleted, either as RFC 3339 timestamp or as Unix
+	// seconds.
+	ExpiresAtTag string
+
+	// TTLTag is the tag key holding a duration (e.g. "6h") after the
+	// creation of a resource after which it may be deleted. Resources
+	// without a known creation time are never selected by TTL.
+	TTLTag string
+
+	// OwnerTag is the tag key identifying who created a resource, e.g. a
+	// pipeline ID. Resources whose OwnerTag value is in Owners are selected
+	// regardless of their expiry.
+	OwnerTag string
+	Owners   []string
+
+	// Selector, if not empty, additionally restricts the collector to
+	// resources matching it.
+	Selector cloudscale.TagSelector
+
+	// Kinds restricts the collector to some resource kinds. All kinds in
+	// DeletionOrder are handled if empty.
+	Kinds []ResourceKind
+
+	// DryRun makes Collect only report what would be deleted.
+	DryRun bool
+
+	// SkipWaitForDeletion stops the collector from waiting until the deleted
+	// resources of a kind are gone before deleting the next kind. Deletions
+	// are asynchronous, so without waiting the dependency order is not
+	// enforced, and e.g. a volume may still be attached to a server that is
+	// being deleted when its deletion is requested.
+	SkipWaitForDeletion bool
+
+	// WaitOptions are passed on to the polling done between kinds, like the
+	// options of WaitFor.
+	WaitOptions []backoff.RetryOption
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Candidate is a resource selected for deletion.
+type Candidate struct {
+	Kind   ResourceKind
+	ID     string
+	Name   string
+	Tags   cloudscale.TagMap
+	Reason string
+	// Err is set if deleting the resource failed.
+	Err error
+}
+
+// Report is the result of a collection run. Candidates are in deletion order.
+type Report struct {
+	DryRun     bool
+	Candidates []Candidate
+}
+
+// Deleted returns the candidates that were deleted successfully.
+func (r *Report) Deleted() []Candidate {
+	if r.DryRun {
+		return nil
+	}
+	deleted := []Candidate{}
+	for _, candidate := range r.Candidates {
+		if candidate.Err == nil {
+			deleted = append(deleted, candidate)
+		}
+	}
+	return deleted
+}
+
+// Err joins the errors of all candidates that could not be deleted.
+func (r *Report) Err() error {
+	errs := []error{}
+	for _, candidate := range r.Candidates {
+		if candidate.Err != nil {
+			errs = append(errs, candidate.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteTo writes a human-readable table of the report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	tw := tabwriter.NewWriter(counter, 0, 4, 2, ' ', 0)
+
+	action := "deleted"
+	if r.DryRun {
+		action = "would delete"
+	}
+
+	fmt.Fprintln(tw, "KIND\tID\tNAME\tREASON\tRESULT")
+	for _, candidate := range r.Candidates {
+		result := action
+		if candidate.Err != nil {
+			result = "failed: " + candidate.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", candidate.Kind, candidate.ID, candidate.Name, candidate.Reason, result)
+	}
+	err := tw.Flush()
+	return counter.n, err
+}
+
+// String returns the report as produced by WriteTo.
+func (r *Report) String() string {
+	var builder strings.Builder
+	r.WriteTo(&builder)
+	return builder.String()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Collector finds and deletes expired or owned resources.
+type Collector struct {
+	options Options
+	kinds   map[ResourceKind]kind
+}
+
+// NewCollector creates a collector using the services of client.
+func NewCollector(client *cloudscale.Client, options Options) *Collector {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	return &Collector{
+		options: options,
+		kinds:   kindsOf(client),
+	}
+}
+
+// Collect lists all resources, selects the candidates and, unless DryRun is
+// set, deletes them in DeletionOrder. Failures to delete single resources are
+// recorded on the candidates and returned joined by Report.Err; listing
+// failures abort the run.
+func (c *Collector) Collect(ctx context.Context) (*Report, error) {
+	report := &Report{DryRun: c.options.DryRun}
+
+	for _, kindName := range c.orderedKinds() {
+		k := c.kinds[kindName]
+		resources, err := k.list(ctx)
+		if err != nil {
+			return report, fmt.Errorf("listing %s: %w", kindName, err)
+		}
+		for _, resource := range resources {
+			if reason, ok := c.selects(resource); ok {
+				report.Candidates = append(report.Candidates, Candidate{
+					Kind:   kindName,
+					ID:     resource.id,
+					Name:   resource.name,
+					Tags:   resource.tags,
+					Reason: reason,
+				})
+			}
+		}
+	}
+
+	if c.options.DryRun {
+		return report, nil
+	}
+
+	// Deleted resources of earlier kinds, which are waited for before the
+	// first resource of a later kind is deleted.
+	var pending []*Candidate
+	for _, kindName := range c.orderedKinds() {
+		k := c.kinds[kindName]
+		var deleted []*Candidate
+		for i := range report.Candidates {
+			candidate := &report.Candidates[i]
+			if candidate.Kind != kindName {
+				continue
+			}
+			if err := c.waitForDeletion(ctx, pending); err != nil {
+				return report, err
+			}
+			pending = nil
+
+			if err := k.delete(ctx, candidate.ID); err != nil && !cloudscale.IsNotFound(err) {
+				candidate.Err = fmt.Errorf("deleting %s %s: %w", kindName, candidate.ID, err)
+				continue
+			}
+			deleted = append(deleted, candidate)
+		}
+		pending = append(pending, deleted...)
+	}
+
+	return report, report.Err()
+}
+
+func (c *Collector) waitForDeletion(ctx context.Context, deleted []*Candidate) error {
+	if c.options.SkipWaitForDeletion {
+		return nil
+	}
+	for _, candidate := range deleted {
+		if err := c.kinds[candidate.Kind].wait(ctx, candidate.ID, c.options.WaitOptions...); err != nil {
+			return fmt.Errorf("waiting for deletion of %s %s: %w", candidate.Kind, candidate.ID, err)
+		}
+	}
+	return nil
+}
+
+func (c *Collector) orderedKinds() []ResourceKind {
+	if len(c.options.Kinds) == 0 {
+		return DeletionOrder
+	}
+	kinds := []ResourceKind{}
+	for _, kindName := range DeletionOrder {
+		for _, wanted := range c.options.Kinds {
+			if kindName == wanted {
+				kinds = append(kinds, kindName)
+			}
+		}
+	}
+	return kinds
+}
+
+// selects decides whether a resource is a candidate and explains why.
+func (c *Collector) selects(r resource) (string, bool) {
+	if !c.options.Selector.Matches(r.tags) {
+		return "", false
+	}
+
+	now := c.options.Now()
+
+	if c.options.OwnerTag != "" {
+		if owner, ok := r.tags[c.options.OwnerTag]; ok {
+			for _, wanted := range c.options.Owners {
+				if owner == wanted {
+					return fmt.Sprintf("owned by %s", owner), true
+				}
+			}
+		}
+	}
+
+	if c.options.ExpiresAtTag != "" {
+		if value, ok := r.tags[c.options.ExpiresAtTag]; ok {
+			if expiresAt, err := parseExpiresAt(value); err == nil && !now.Before(expiresAt) {
+				return fmt.Sprintf("expired at %s", expiresAt.Format(time.RFC3339)), true
+			}
+		}
+	}
+
+	if c.options.TTLTag != "" && !r.createdAt.IsZero() {
+		if value, ok := r.tags[c.options.TTLTag]; ok {
+			if ttl, err := time.ParseDuration(value); err == nil {
+				if expiresAt := r.createdAt.Add(ttl); !now.Before(expiresAt) {
+					return fmt.Sprintf("TTL %s expired at %s", ttl, expiresAt.Format(time.RFC3339)), true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func parseExpiresAt(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,225 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/internal/fakeapi"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeAPI serves list responses per base path and records deletions.
+type fakeAPI struct {
+	*fakeapi.API
+	lists   map[string]string
+	deleted []string
+	gone    map[string]bool
+}
+
+func newFakeAPI(t *testing.T, lists map[string]string) (*cloudscale.Client, *fakeAPI) {
+	t.Helper()
+	api := &fakeAPI{lists: lists, gone: map[string]bool{}}
+	client, server := fakeapi.NewClient(t, lists, api.handle)
+	api.API = server
+	return client, api
+}
+
+func (a *fakeAPI) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodGet:
+		if a.isResourcePath(path) {
+			if a.gone[path] {
+				fakeapi.NotFound(w)
+				return
+			}
+			// Deleted resources disappear after the first poll.
+			a.gone[path] = true
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	case http.MethodDelete:
+		a.deleted = append(a.deleted, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// isResourcePath reports whether path addresses a single resource below one
+// of the list paths.
+func (a *fakeAPI) isResourcePath(path string) bool {
+	for listPath := range a.lists {
+		if id, ok := strings.CutPrefix(path, listPath+"/"); ok && !strings.Contains(id, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCollector_Collect(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers": `[
+			{"uuid": "s-expired", "name": "ci-1", "tags": {"ci/expires-at": "2024-05-01T11:00:00Z"}},
+			{"uuid": "s-fresh", "name": "ci-2", "tags": {"ci/expires-at": "2024-05-01T13:00:00Z"}},
+			{"uuid": "s-untagged", "name": "prod"}
+		]`,
+		"v1/volumes": `[
+			{"uuid": "v-ttl", "name": "ci-1-data", "created_at": "2024-05-01T05:00:00Z", "tags": {"ci/ttl": "6h"}},
+			{"uuid": "v-ttl-fresh", "name": "ci-2-data", "created_at": "2024-05-01T10:00:00Z", "tags": {"ci/ttl": "6h"}}
+		]`,
+		"v1/floating-ips": `[
+			{"network": "192.0.2.1/32", "tags": {"ci/pipeline": "1234"}}
+		]`,
+		"v1/networks": `[
+			{"uuid": "n-owned", "name": "ci-net", "tags": {"ci/pipeline": "1234"}},
+			{"uuid": "n-other", "name": "ci-net", "tags": {"ci/pipeline": "5678"}}
+		]`,
+		"v1/load-balancers/listeners": `[
+			{"uuid": "l-owned", "name": "ci-listener", "tags": {"ci/pipeline": "1234"}}
+		]`,
+		"v1/custom-images": `[
+			{"uuid": "i-owned", "name": "ci-image", "tags": {"ci/pipeline": "1234"}}
+		]`,
+		"v1/objects-users": `[
+			{"id": "u-owned", "display_name": "ci-user", "tags": {"ci/pipeline": "1234"}}
+		]`,
+	})
+
+	collector := NewCollector(client, Options{
+		ExpiresAtTag: "ci/expires-at",
+		TTLTag:       "ci/ttl",
+		OwnerTag:     "ci/pipeline",
+		Owners:       []string{"1234"},
+		WaitOptions:  []backoff.RetryOption{backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond))},
+		Now:          func() time.Time { return now },
+	})
+
+	report, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+
+	expected := []string{
+		"v1/load-balancers/listeners/l-owned",
+		"v1/floating-ips/192.0.2.1",
+		"v1/servers/s-expired",
+		"v1/volumes/v-ttl",
+		"v1/networks/n-owned",
+		"v1/custom-images/i-owned",
+		"v1/objects-users/u-owned",
+	}
+	if !reflect.DeepEqual(api.deleted, expected) {
+		t.Errorf("deleted\n got=%v\nwant=%v", api.deleted, expected)
+	}
+	if name := report.Candidates[1].Name; name != "192.0.2.1" {
+		t.Errorf("floating IP name=%q, want %q", name, "192.0.2.1")
+	}
+	if got := len(report.Deleted()); got != len(expected) {
+		t.Errorf("len(report.Deleted())=%d, want %d", got, len(expected))
+	}
+	if reason := report.Candidates[0].Reason; reason != "owned by 1234" {
+		t.Errorf("reason=%q, want %q", reason, "owned by 1234")
+	}
+}
+
+func TestCollector_DryRun(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers": `[{"uuid": "s-expired", "name": "ci-1", "tags": {"ci/expires-at": "1714561200"}}]`,
+	})
+
+	collector := NewCollector(client, Options{
+		ExpiresAtTag: "ci/expires-at",
+		DryRun:       true,
+		Now:          func() time.Time { return now },
+	})
+
+	report, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+
+	if len(api.deleted) != 0 {
+		t.Errorf("dry run deleted %v", api.deleted)
+	}
+	if len(report.Candidates) != 1 || report.Candidates[0].ID != "s-expired" {
+		t.Fatalf("unexpected candidates %#v", report.Candidates)
+	}
+	if out := report.String(); !strings.Contains(out, "would delete") || !strings.Contains(out, "s-expired") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+}
+
+func TestCollector_SelectorAndKinds(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers": `[
+			{"uuid": "s-keep", "tags": {"ci/pipeline": "1234", "keep": ""}},
+			{"uuid": "s-delete", "tags": {"ci/pipeline": "1234"}}
+		]`,
+		"v1/volumes": `[{"uuid": "v-owned", "tags": {"ci/pipeline": "1234"}}]`,
+	})
+
+	collector := NewCollector(client, Options{
+		OwnerTag: "ci/pipeline",
+		Owners:   []string{"1234"},
+		Selector: cloudscale.MustParseTagSelector("!keep"),
+		Kinds:    []ResourceKind{Servers},
+	})
+
+	if _, err := collector.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if expected := []string{"v1/servers/s-delete"}; !reflect.DeepEqual(api.deleted, expected) {
+		t.Errorf("deleted\n got=%v\nwant=%v", api.deleted, expected)
+	}
+}
+
+func TestCollector_WaitForDeletion(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers": `[{"uuid": "s-owned", "tags": {"ci/pipeline": "1234"}}]`,
+		"v1/volumes": `[{"uuid": "v-owned", "tags": {"ci/pipeline": "1234"}}]`,
+	})
+
+	collector := NewCollector(client, Options{
+		OwnerTag:    "ci/pipeline",
+		Owners:      []string{"1234"},
+		WaitOptions: []backoff.RetryOption{backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond))},
+	})
+
+	if _, err := collector.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if !api.gone["v1/servers/s-owned"] {
+		t.Error("expected collector to wait for the deleted server")
+	}
+	if api.gone["v1/volumes/v-owned"] {
+		t.Error("expected collector not to wait after the last kind")
+	}
+}
+
+func TestCollector_SkipWaitForDeletion(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers": `[{"uuid": "s-owned", "tags": {"ci/pipeline": "1234"}}]`,
+	})
+
+	collector := NewCollector(client, Options{
+		OwnerTag:            "ci/pipeline",
+		Owners:              []string{"1234"},
+		SkipWaitForDeletion: true,
+	})
+
+	if _, err := collector.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if api.gone["v1/servers/s-owned"] {
+		t.Error("expected collector not to poll the deleted server")
+	}
+}
@@ -0,0 +1,96 @@
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// resource is the kind-independent view of a listed resource.
+type resource struct {
+	id        string
+	name      string
+	tags      cloudscale.TagMap
+	createdAt time.Time
+}
+
+// kind bundles the operations the collector needs for one resource type.
+type kind struct {
+	list   func(ctx context.Context) ([]resource, error)
+	delete func(ctx context.Context, id string) error
+	wait   func(ctx context.Context, id string, opts ...backoff.RetryOption) error
+}
+
+type service[TResource any] interface {
+	cloudscale.GenericListService[TResource]
+	cloudscale.GenericGetService[TResource]
+	cloudscale.GenericDeleteService[TResource]
+}
+
+func newKind[TResource any](s service[TResource], describe func(r TResource) resource) kind {
+	return kind{
+		list: func(ctx context.Context) ([]resource, error) {
+			listed, err := s.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]resource, 0, len(listed))
+			for _, r := range listed {
+				resources = append(resources, describe(r))
+			}
+			return resources, nil
+		},
+		delete: s.Delete,
+		wait: func(ctx context.Context, id string, opts ...backoff.RetryOption) error {
+			return cloudscale.WaitForDeletion(ctx, s, id, opts...)
+		},
+	}
+}
+
+func kindsOf(client *cloudscale.Client) map[ResourceKind]kind {
+	return map[ResourceKind]kind{
+		LoadBalancerListeners: newKind(client.LoadBalancerListeners, func(r cloudscale.LoadBalancerListener) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		LoadBalancerHealthMonitors: newKind(client.LoadBalancerHealthMonitors, func(r cloudscale.LoadBalancerHealthMonitor) resource {
+			return resource{id: r.UUID, name: r.Pool.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		LoadBalancerPools: newKind(client.LoadBalancerPools, func(r cloudscale.LoadBalancerPool) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		LoadBalancers: newKind(client.LoadBalancers, func(r cloudscale.LoadBalancer) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		FloatingIPs: newKind(client.FloatingIPs, func(r cloudscale.FloatingIP) resource {
+			return resource{id: r.IP(), name: r.IP(), tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		Servers: newKind(client.Servers, func(r cloudscale.Server) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		ServerGroups: newKind(client.ServerGroups, func(r cloudscale.ServerGroup) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags}
+		}),
+		VolumeSnapshots: newKind(client.VolumeSnapshots, func(r cloudscale.VolumeSnapshot) resource {
+			createdAt, _ := time.Parse(time.RFC3339, r.CreatedAt)
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: createdAt}
+		}),
+		Volumes: newKind(client.Volumes, func(r cloudscale.Volume) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		Subnets: newKind(client.Subnets, func(r cloudscale.Subnet) resource {
+			return resource{id: r.UUID, name: r.CIDR, tags: r.Tags}
+		}),
+		Networks: newKind(client.Networks, func(r cloudscale.Network) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		CustomImages: newKind(client.CustomImages, func(r cloudscale.CustomImage) resource {
+			return resource{id: r.UUID, name: r.Name, tags: r.Tags, createdAt: r.CreatedAt}
+		}),
+		ObjectsUsers: newKind(client.ObjectsUsers, func(r cloudscale.ObjectsUser) resource {
+			return resource{id: r.ID, name: r.DisplayName, tags: r.Tags}
+		}),
+	}
+}
@@ -2,12 +2,29 @@ package cloudscale
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/cenkalti/backoff/v5"
 	"net/http"
+	"reflect"
+	"strconv"
 	"time"
 )
 
+// Outcomes reported to a WaitObserver.
+const (
+	WaitOutcomeSuccess  = "success"
+	WaitOutcomeTimeout  = "timeout"
+	WaitOutcomeCanceled = "canceled"
+	WaitOutcomeError    = "error"
+)
+
+// WaitObserver receives the outcome of WaitFor calls. resourceType is the
+// name of the polled resource type, e.g. "Server".
+type WaitObserver interface {
+	ObserveWait(resourceType string, outcome string, duration time.Duration)
+}
+
 type GenericCreateService[TResource any, TCreateRequest any] interface {
 	Create(ctx context.Context, createRequest *TCreateRequest) (*TResource, error)
 }
@@ -147,9 +164,24 @@ func (g GenericServiceOperations[TResource, TCreateRequest, TUpdateRequest]) Wai
 		backoff.WithMaxElapsedTime(5 * time.Minute),
 	}, opts...)
 
-	return backoff.Retry(ctx, func() (*TResource, error) {
-		resource, err := g.Get(ctx, resourceID)
+	start := time.Now()
+	// Either the previous attempt failed, or it succeeded without meeting
+	// the condition and the next one is a poll.
+	retryReason := ""
+	unmet := false
+
+	resource, err := backoff.Retry(ctx, func() (*TResource, error) {
+		attemptCtx := ctx
+		switch {
+		case retryReason != "":
+			attemptCtx = WithRetryReason(ctx, retryReason)
+		case unmet:
+			attemptCtx = WithWaitPoll(ctx)
+		}
+
+		resource, err := g.Get(attemptCtx, resourceID)
 		if err != nil {
+			retryReason, unmet = retryReasonFor(err), false
 			return nil, err
 		}
 
@@ -157,6 +189,8 @@ func (g GenericServiceOperations[TResource, TCreateRequest, TUpdateRequest]) Wai
 		if ok {
 			return resource, nil // Exit when the condition is met.
 		}
+		// A permanent error means the condition can no longer be met.
+		retryReason, unmet = "", !isPermanent(condErr)
 
 		// If the condition provided an error, return it as our retry error message.
 		if condErr != nil {
@@ -164,4 +198,35 @@ func (g GenericServiceOperations[TResource, TCreateRequest, TUpdateRequest]) Wai
 		}
 		return nil, fmt.Errorf("condition not met yet") // Continue retrying
 	}, options...)
+
+	if g.client.WaitObserver != nil {
+		g.client.WaitObserver.ObserveWait(reflect.TypeFor[TResource]().Name(), waitOutcome(ctx, err, unmet), time.Since(start))
+	}
+
+	return resource, err
+}
+
+// retryReasonFor classifies the error of a failed attempt into a short reason
+// suitable for a metric label: the HTTP status code for API errors, "error"
+// otherwise.
+func retryReasonFor(err error) string {
+	var errorResponse *ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return strconv.Itoa(errorResponse.StatusCode)
+	}
+	return "error"
+}
+
+// waitOutcome classifies how a WaitFor call ended. Giving up while the
+// condition was still unmet counts as a timeout, just like an expired context.
+func waitOutcome(ctx context.Context, err error, unmet bool) string {
+	switch {
+	case err == nil:
+		return WaitOutcomeSuccess
+	case errors.Is(ctx.Err(), context.Canceled):
+		return WaitOutcomeCanceled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), unmet:
+		return WaitOutcomeTimeout
+	}
+	return WaitOutcomeError
 }
@@ -2,10 +2,14 @@ package cloudscale
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
 )
 
 // pathCaptureTransport records OperationPath from each request's context and
@@ -92,3 +96,119 @@ func TestGenericServiceOperations_OperationPathPrecedence(t *testing.T) {
 		})
 	}
 }
+
+type recordingWaitObserver struct {
+	resourceType string
+	outcome      string
+	calls        int
+}
+
+func (o *recordingWaitObserver) ObserveWait(resourceType string, outcome string, _ time.Duration) {
+	o.resourceType = resourceType
+	o.outcome = outcome
+	o.calls++
+}
+
+func TestGenericServiceOperations_WaitForObserver(t *testing.T) {
+	setup()
+	defer teardown()
+
+	observer := &recordingWaitObserver{}
+	client.WaitObserver = observer
+
+	requests := 0
+	mux.HandleFunc("/v1/servers/abc", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		status := ServerStopped
+		if requests >= 2 {
+			status = ServerRunning
+		}
+		fmt.Fprintf(w, `{"uuid": "abc", "status": %q}`, status)
+	})
+
+	_, err := client.Servers.WaitFor(ctx, "abc", ServerIsRunning,
+		backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("WaitFor returned error: %v", err)
+	}
+
+	assertEqual(t, 1, observer.calls)
+	assertEqual(t, "Server", observer.resourceType)
+	assertEqual(t, WaitOutcomeSuccess, observer.outcome)
+}
+
+func TestGenericServiceOperations_WaitForObserverTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	observer := &recordingWaitObserver{}
+	client.WaitObserver = observer
+
+	mux.HandleFunc("/v1/volumes/abc", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "abc", "size_gb": 10}`)
+	})
+
+	_, err := client.Volumes.WaitFor(ctx, "abc",
+		func(volume *Volume) (bool, error) { return volume.SizeGB == 20, nil },
+		backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+		backoff.WithMaxTries(3),
+	)
+	if err == nil {
+		t.Fatal("expected WaitFor to give up")
+	}
+
+	assertEqual(t, "Volume", observer.resourceType)
+	assertEqual(t, WaitOutcomeTimeout, observer.outcome)
+}
+
+func TestGenericServiceOperations_WaitForRetryReason(t *testing.T) {
+	transport := &retryReasonTransport{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	c := NewClient(&http.Client{Transport: transport})
+
+	_, err := c.Servers.WaitFor(ctx, "abc",
+		func(*Server) (bool, error) { return true, nil },
+		backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("WaitFor returned error: %v", err)
+	}
+
+	assertEqual(t, []string{"", "503"}, transport.reasons)
+}
+
+func TestGenericServiceOperations_WaitForPolls(t *testing.T) {
+	transport := &retryReasonTransport{statuses: []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable, http.StatusOK}}
+	c := NewClient(&http.Client{Transport: transport})
+
+	polls := 0
+	_, err := c.Servers.WaitFor(ctx, "abc",
+		func(*Server) (bool, error) { polls++; return polls == 3, nil },
+		backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("WaitFor returned error: %v", err)
+	}
+
+	assertEqual(t, []string{"", "", "", "503"}, transport.reasons)
+	assertEqual(t, []bool{false, true, true, false}, transport.polls)
+}
+
+// retryReasonTransport answers with the given status codes in order and
+// records the RetryReason and IsWaitPoll of every request.
+type retryReasonTransport struct {
+	statuses []int
+	reasons  []string
+	polls    []bool
+}
+
+func (r *retryReasonTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := r.statuses[len(r.reasons)]
+	r.reasons = append(r.reasons, RetryReason(req.Context()))
+	r.polls = append(r.polls, IsWaitPoll(req.Context()))
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+	}, nil
+}
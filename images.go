@@ -0,0 +1,108 @@
+package cloudscale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const imagesBasePath = "v1/images"
+
+// Image represents a public image servers can be created from, referenced
+// by its slug in ServerRequest.Image. Images are read-only and zonal. A
+// server's ImageServerStub can be resolved to its Image with Resolve.
+type Image struct {
+	UnknownFields
+	Slug            string     `json:"slug"`
+	Name            string     `json:"name"`
+	OperatingSystem string     `json:"operating_system"`
+	DefaultUsername string     `json:"default_username"`
+	Zones           []ZoneStub `json:"zones"`
+}
+
+// MarshalJSON encodes the image including its unknown fields.
+func (i Image) MarshalJSON() ([]byte, error) {
+	type plain Image
+	return marshalWithUnknownFields(plain(i), i.Extra)
+}
+
+// Raw returns the JSON of the image, including the fields the SDK does not
+// model.
+func (i Image) Raw() json.RawMessage {
+	return rawJSON(i)
+}
+
+// ImageService provides listing of the available public images.
+type ImageService interface {
+	List(ctx context.Context) ([]Image, error)
+}
+
+// ImageServiceOperations implements ImageService.
+type ImageServiceOperations struct {
+	client *Client
+}
+
+// _ ensures that ImageServiceOperations implements the ImageService interface at compile time.
+var _ ImageService = &ImageServiceOperations{}
+
+// List returns all available public images (GET /v1/images).
+func (s ImageServiceOperations) List(ctx context.Context) ([]Image, error) {
+	ctx = WithOperationPath(ctx, imagesBasePath)
+	req, err := s.client.NewRequest(ctx, http.MethodGet, imagesBasePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var images []Image
+	err = s.client.Do(ctx, req, &images)
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// ErrImageNotFound is returned when an image slug is not in the public
+// image catalog.
+var ErrImageNotFound = errors.New("image not found")
+
+// AvailableIn reports whether servers can be created from the image in the
+// given zone.
+func (i Image) AvailableIn(zone string) bool {
+	return containsZone(i.Zones, zone)
+}
+
+// Stub returns the summary of the image as it appears in Server.Image.
+func (i Image) Stub() ImageServerStub {
+	return ImageServerStub{
+		Slug:            i.Slug,
+		Name:            i.Name,
+		OperatingSystem: i.OperatingSystem,
+		DefaultUsername: i.DefaultUsername,
+	}
+}
+
+// FindImage returns the image with the given slug from images.
+func FindImage(images []Image, slug string) (*Image, bool) {
+	for i := range images {
+		if images[i].Slug == slug {
+			return &images[i], true
+		}
+	}
+	return nil, false
+}
+
+// Resolve looks up the full public image, including its zones, that the
+// stub refers to. It returns ErrImageNotFound if the image is no longer
+// offered, e.g. because the server was created from an outdated image.
+func (s ImageServerStub) Resolve(ctx context.Context, images ImageService) (*Image, error) {
+	list, err := images.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	image, ok := FindImage(list, s.Slug)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, s.Slug)
+	}
+	return image, nil
+}
@@ -0,0 +1,72 @@
+package cloudscale
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestImages_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/images", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodGet)
+		fmt.Fprint(w, imagesResponse)
+	})
+
+	images, err := client.Images.List(ctx)
+	if err != nil {
+		t.Errorf("Images.List returned error: %v", err)
+	}
+
+	expected := []Image{
+		{
+			Slug:            "debian-12",
+			Name:            "Debian 12 (2023-06-10)",
+			OperatingSystem: "Debian",
+			DefaultUsername: "debian",
+			Zones:           []ZoneStub{{Slug: "rma1"}, {Slug: "lpg1"}},
+		},
+		{
+			Slug:            "windows-2022",
+			Name:            "Windows Server 2022",
+			OperatingSystem: "Windows",
+			DefaultUsername: "Administrator",
+			Zones:           []ZoneStub{{Slug: "rma1"}},
+		},
+	}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("Images.List\n got=%#v\nwant=%#v", images, expected)
+	}
+}
+
+func TestImageServerStub_Resolve(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/images", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, imagesResponse)
+	})
+
+	stub := ImageServerStub{Slug: "windows-2022"}
+	image, err := stub.Resolve(ctx, client.Images)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	assertEqual(t, true, image.AvailableIn("rma1"))
+	assertEqual(t, false, image.AvailableIn("lpg1"))
+	assertEqual(t, ImageServerStub{
+		Slug:            "windows-2022",
+		Name:            "Windows Server 2022",
+		OperatingSystem: "Windows",
+		DefaultUsername: "Administrator",
+	}, image.Stub())
+
+	_, err = ImageServerStub{Slug: "debian-9"}.Resolve(ctx, client.Images)
+	if !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("expected ErrImageNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package instrumentation
+
+import _ "embed"
+
+// GrafanaDashboard is a Grafana dashboard covering the metrics recorded by
+// InstrumentedTransport and the WaitObserver returned by NewMetricsCollector.
+// It assumes the default "cloudscale" subsystem and a Prometheus data source
+// selected on import.
+//
+//go:embed grafana_dashboard.json
+var GrafanaDashboard []byte
@@ -1,8 +1,11 @@
 package instrumentation
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"sync"
 	"testing"
@@ -455,3 +458,235 @@ func TestInstrumentedTransport_Propagation(t *testing.T) {
 		t.Fatal("expected traceparent header to be injected")
 	}
 }
+
+func TestInstrumentedTransport_Retries(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, statusHandler(http.StatusOK))
+	client := newInstrumentedClient(reg, nil)
+
+	mustDoRequest(t, client, http.MethodGet, server.URL+"/v1/servers/123", "v1/servers/:id")
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/servers/123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := cloudscale.WithOperationPath(req.Context(), "v1/servers/:id")
+	resp, err := client.Do(req.WithContext(cloudscale.WithRetryReason(ctx, "429")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	retries := metricFamily(t, reg, "cloudscale_retries_total")
+	if len(retries.Metric) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(retries.Metric))
+	}
+	labels := labelsOf(retries.Metric[0])
+	if labels["endpoint"] != "v1/servers/:id" || labels["reason"] != "429" {
+		t.Errorf("labels=%v, want endpoint=v1/servers/:id reason=429", labels)
+	}
+	if got := retries.Metric[0].Counter.GetValue(); got != 1 {
+		t.Errorf("retries_total=%v, want 1", got)
+	}
+}
+
+func TestInstrumentedTransport_WaitPolls(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, statusHandler(http.StatusOK))
+	client := newInstrumentedClient(reg, nil)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/servers/123", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := cloudscale.WithOperationPath(req.Context(), "v1/servers/:id")
+	resp, err := client.Do(req.WithContext(cloudscale.WithWaitPoll(ctx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	polls := metricFamily(t, reg, "cloudscale_wait_polls_total")
+	if got := polls.Metric[0].Counter.GetValue(); got != 1 {
+		t.Errorf("wait_polls_total=%v, want 1", got)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() == "cloudscale_retries_total" {
+			t.Errorf("expected polls not to count as retries, got %v", f.Metric)
+		}
+	}
+}
+
+func TestInstrumentedTransport_RetryAfter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	client := newInstrumentedClient(reg, nil)
+
+	mustDoRequest(t, client, http.MethodPost, server.URL+"/v1/servers", "v1/servers")
+
+	retryAfter := metricFamily(t, reg, "cloudscale_retry_after_seconds")
+	h := retryAfter.Metric[0].Histogram
+	if h.GetSampleCount() != 1 || h.GetSampleSum() != 7 {
+		t.Errorf("retry_after_seconds count=%d sum=%v, want 1 and 7", h.GetSampleCount(), h.GetSampleSum())
+	}
+	if got := labelsOf(retryAfter.Metric[0])["status"]; got != "429" {
+		t.Errorf("status=%s, want 429", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value  string
+		want   float64
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"120", 120, true},
+		{"-3", 0, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseRetryAfter(tc.value, now)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("parseRetryAfter(%q)=%v,%v want %v,%v", tc.value, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestInstrumentedTransport_ClientErrorFields(t *testing.T) {
+	const body = `{"flavor": "Invalid flavor.", "name": "This field may not be blank.", "x-1": "?", "x-2": "?"}`
+
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(body))
+	})
+	client := newInstrumentedClient(reg, nil)
+
+	resp, err := doRequest(t, client, http.MethodPost, server.URL+"/v1/servers", "v1/servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The body must still be readable by the caller.
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != body {
+		t.Errorf("body=%q, want %q", data, body)
+	}
+
+	errors := metricFamily(t, reg, "cloudscale_client_errors_total")
+	fields := map[string]float64{}
+	for _, m := range errors.Metric {
+		labels := labelsOf(m)
+		if labels["status"] != "400" {
+			t.Errorf("status=%s, want 400", labels["status"])
+		}
+		fields[labels["field"]] = m.Counter.GetValue()
+	}
+	// Keys unknown to the SDK share one label value.
+	want := map[string]float64{"flavor": 1, "name": 1, "other": 1}
+	if len(fields) != len(want) || fields["flavor"] != 1 || fields["name"] != 1 || fields["other"] != 1 {
+		t.Errorf("fields=%v, want %v", fields, want)
+	}
+}
+
+func TestInstrumentedTransport_BodySizes(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"uuid": "47cec963-fcd2-482f-bdb6-24461b2d47b1"}`))
+	})
+	client := newInstrumentedClient(reg, nil)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/volumes", strings.NewReader(`{"size_gb": 50}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req.WithContext(cloudscale.WithOperationPath(req.Context(), "v1/volumes")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if got := metricFamily(t, reg, "cloudscale_request_size_bytes").Metric[0].Histogram.GetSampleSum(); got != 15 {
+		t.Errorf("request_size_bytes sum=%v, want 15", got)
+	}
+	if got := metricFamily(t, reg, "cloudscale_response_size_bytes").Metric[0].Histogram.GetSampleSum(); got != 48 {
+		t.Errorf("response_size_bytes sum=%v, want 48", got)
+	}
+}
+
+func TestMetricsCollector_WaitObserver(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	server := newTestServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"uuid": "abc", "status": "running"}`))
+	})
+
+	client := cloudscale.NewClient(newInstrumentedClient(reg, nil))
+	client.BaseURL, _ = url.Parse(server.URL)
+	client.WaitObserver = NewMetricsCollector(reg, "cloudscale")
+
+	if _, err := client.Servers.WaitFor(t.Context(), "abc", cloudscale.ServerIsRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	wait := metricFamily(t, reg, "cloudscale_wait_duration_seconds")
+	if len(wait.Metric) != 1 {
+		t.Fatalf("expected 1 series, got %d", len(wait.Metric))
+	}
+	labels := labelsOf(wait.Metric[0])
+	if labels["resource"] != "Server" || labels["outcome"] != cloudscale.WaitOutcomeSuccess {
+		t.Errorf("labels=%v, want resource=Server outcome=success", labels)
+	}
+}
+
+func TestGrafanaDashboard(t *testing.T) {
+	var dashboard struct {
+		Panels []struct {
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(GrafanaDashboard, &dashboard); err != nil {
+		t.Fatalf("dashboard is not valid JSON: %v", err)
+	}
+
+	// Every metric family must show up in at least one panel.
+	var exprs strings.Builder
+	for _, panel := range dashboard.Panels {
+		for _, target := range panel.Targets {
+			exprs.WriteString(target.Expr)
+		}
+	}
+	for _, name := range []string{
+		"cloudscale_requests_total",
+		"cloudscale_request_duration_seconds",
+		"cloudscale_in_flight_requests",
+		"cloudscale_retries_total",
+		"cloudscale_retry_after_seconds",
+		"cloudscale_request_size_bytes",
+		"cloudscale_response_size_bytes",
+		"cloudscale_client_errors_total",
+		"cloudscale_wait_duration_seconds",
+	} {
+		if !strings.Contains(exprs.String(), name) {
+			t.Errorf("dashboard does not use %s", name)
+		}
+	}
+}
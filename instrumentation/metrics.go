@@ -1,9 +1,16 @@
 package instrumentation
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"reflect"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -11,13 +18,27 @@ import (
 	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
 )
 
+// maxErrorBodySize limits how much of a 4xx response body is buffered to
+// break errors down by the fields the API complains about.
+const maxErrorBodySize = 64 << 10
+
 // metricsCollector holds the Prometheus metrics for cloudscale API calls.
 type metricsCollector struct {
-	requestsTotal   *prometheus.CounterVec
-	requestDuration *prometheus.HistogramVec
-	inFlight        prometheus.Gauge
+	requestsTotal     *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	inFlight          prometheus.Gauge
+	retriesTotal      *prometheus.CounterVec
+	retryAfterSeconds *prometheus.HistogramVec
+	waitPollsTotal    *prometheus.CounterVec
+	requestSize       *prometheus.HistogramVec
+	responseSize      *prometheus.HistogramVec
+	clientErrorsTotal *prometheus.CounterVec
+	waitDuration      *prometheus.HistogramVec
 }
 
+// _ ensures that metricsCollector can be used as a Client.WaitObserver.
+var _ cloudscale.WaitObserver = &metricsCollector{}
+
 // registerOrReuseCounterVec tries to register a CounterVec and, if it is
 // already registered, returns the existing one.
 func registerOrReuseCounterVec(reg prometheus.Registerer, opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
@@ -60,7 +81,13 @@ func registerOrReuseGauge(reg prometheus.Registerer, opts prometheus.GaugeOpts)
 	return g
 }
 
+// sizeBuckets are the histogram buckets for request and response body sizes,
+// ranging from 64 bytes to 4 MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 9)
+
 // NewMetricsCollector creates a metricsCollector backed by the given registry.
+// Besides being used by InstrumentedTransport, the collector can be assigned
+// to Client.WaitObserver to record WaitFor durations and outcomes.
 func NewMetricsCollector(reg prometheus.Registerer, subsystem string) *metricsCollector {
 	return &metricsCollector{
 		requestsTotal: registerOrReuseCounterVec(reg, prometheus.CounterOpts{
@@ -79,9 +106,53 @@ func NewMetricsCollector(reg prometheus.Registerer, subsystem string) *metricsCo
 			Name:      "in_flight_requests",
 			Help:      "Number of requests currently in flight.",
 		}),
+		retriesTotal: registerOrReuseCounterVec(reg, prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "retries_total",
+			Help:      "Retried API requests by endpoint and reason the previous attempt failed.",
+		}, []string{"method", "endpoint", "reason"}),
+		retryAfterSeconds: registerOrReuseHistogramVec(reg, prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "retry_after_seconds",
+			Help:      "Wait time requested by the API through the Retry-After header.",
+			Buckets:   []float64{1, 2, 5, 10, 30, 60, 120, 300},
+		}, []string{"method", "endpoint", "status"}),
+		waitPollsTotal: registerOrReuseCounterVec(reg, prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "wait_polls_total",
+			Help:      "Repeated polls of WaitFor whose previous poll did not meet the condition yet.",
+		}, []string{"method", "endpoint"}),
+		requestSize: registerOrReuseHistogramVec(reg, prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "request_size_bytes",
+			Help:      "Request body size distribution.",
+			Buckets:   sizeBuckets,
+		}, []string{"method", "endpoint"}),
+		responseSize: registerOrReuseHistogramVec(reg, prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "response_size_bytes",
+			Help:      "Response body size distribution.",
+			Buckets:   sizeBuckets,
+		}, []string{"method", "endpoint"}),
+		clientErrorsTotal: registerOrReuseCounterVec(reg, prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "client_errors_total",
+			Help:      "4xx responses by endpoint, status code and the field reported in the error body, \"other\" for fields unknown to the SDK.",
+		}, []string{"method", "endpoint", "status", "field"}),
+		waitDuration: registerOrReuseHistogramVec(reg, prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "wait_duration_seconds",
+			Help:      "Duration of WaitFor calls by resource type and outcome.",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+		}, []string{"resource", "outcome"}),
 	}
 }
 
+// ObserveWait implements cloudscale.WaitObserver.
+func (m *metricsCollector) ObserveWait(resourceType string, outcome string, duration time.Duration) {
+	m.waitDuration.WithLabelValues(resourceType, outcome).Observe(duration.Seconds())
+}
+
 // metricsTransport wraps an http.RoundTripper with Prometheus metrics.
 type metricsTransport struct {
 	next    http.RoundTripper
@@ -92,19 +163,45 @@ func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	t.metrics.inFlight.Inc()
 	defer t.metrics.inFlight.Dec()
 
-	start := time.Now()
-	resp, err := t.next.RoundTrip(req)
-	duration := time.Since(start)
-
 	endpoint := cloudscale.OperationPath(req.Context())
 	if endpoint == "" {
 		endpoint = "unknown"
 	}
 	method := req.Method
+
+	if reason := cloudscale.RetryReason(req.Context()); reason != "" {
+		t.metrics.retriesTotal.WithLabelValues(method, endpoint, reason).Inc()
+	}
+	if cloudscale.IsWaitPoll(req.Context()) {
+		t.metrics.waitPollsTotal.WithLabelValues(method, endpoint).Inc()
+	}
+	if req.ContentLength >= 0 {
+		t.metrics.requestSize.WithLabelValues(method, endpoint).Observe(float64(req.ContentLength))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := time.Since(start)
+
 	status := "error"
 
 	if resp != nil {
 		status = strconv.Itoa(resp.StatusCode)
+
+		if seconds, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			t.metrics.retryAfterSeconds.WithLabelValues(method, endpoint, status).Observe(seconds)
+		}
+		if resp.StatusCode >= 400 && resp.StatusCode <= 499 {
+			for _, field := range errorFields(resp) {
+				t.metrics.clientErrorsTotal.WithLabelValues(method, endpoint, status, field).Inc()
+			}
+		}
+		responseSize := t.metrics.responseSize.WithLabelValues(method, endpoint)
+		if resp.ContentLength >= 0 {
+			responseSize.Observe(float64(resp.ContentLength))
+		} else if resp.Body != nil {
+			resp.Body = &sizeObservingBody{ReadCloser: resp.Body, observer: responseSize}
+		}
 	}
 
 	t.metrics.requestDuration.WithLabelValues(method, endpoint).Observe(duration.Seconds())
@@ -112,3 +209,118 @@ func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error)
 
 	return resp, err
 }
+
+// parseRetryAfter interprets a Retry-After header value, which is either a
+// number of seconds or an HTTP date, relative to now.
+func parseRetryAfter(value string, now time.Time) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return float64(max(seconds, 0)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now).Seconds(), 0), true
+	}
+	return 0, false
+}
+
+// errorFields returns the top-level keys of a JSON error body, e.g. "detail"
+// or the name of an invalid request field. Keys that are not in
+// knownErrorFields are reported as "other", so the server cannot create an
+// unbounded number of label values. The body is buffered and restored so
+// callers can still read it. If the body cannot be interpreted, "unknown" is
+// returned.
+func errorFields(resp *http.Response) []string {
+	if resp.Body == nil {
+		return []string{"unknown"}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		return []string{"unknown"}
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) == 0 {
+		return []string{"unknown"}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if !knownErrorFields[key] {
+			key = "other"
+		}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// knownErrorFields are the keys of error bodies used as metric labels: the
+// generic keys of the API and the JSON fields of all request types.
+var knownErrorFields = requestFields(
+	cloudscale.CustomImageImportRequest{},
+	cloudscale.CustomImageRequest{},
+	cloudscale.FloatingIPCreateRequest{},
+	cloudscale.FloatingIPUpdateRequest{},
+	cloudscale.LoadBalancerHealthMonitorRequest{},
+	cloudscale.LoadBalancerListenerRequest{},
+	cloudscale.LoadBalancerPoolMemberRequest{},
+	cloudscale.LoadBalancerPoolRequest{},
+	cloudscale.LoadBalancerRequest{},
+	cloudscale.NetworkCreateRequest{},
+	cloudscale.NetworkUpdateRequest{},
+	cloudscale.ObjectsUserRequest{},
+	cloudscale.ServerGroupRequest{},
+	cloudscale.ServerRequest{},
+	cloudscale.ServerUpdateRequest{},
+	cloudscale.SubnetCreateRequest{},
+	cloudscale.SubnetUpdateRequest{},
+	cloudscale.VolumeSnapshotCreateRequest{},
+	cloudscale.VolumeSnapshotUpdateRequest{},
+	cloudscale.VolumeCreateRequest{},
+	cloudscale.VolumeUpdateRequest{},
+)
+
+func requestFields(requests ...any) map[string]bool {
+	fields := map[string]bool{"detail": true, "non_field_errors": true}
+	for _, request := range requests {
+		for _, field := range reflect.VisibleFields(reflect.TypeOf(request)) {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.IsExported() && !field.Anonymous && name != "" && name != "-" {
+				fields[name] = true
+			}
+		}
+	}
+	return fields
+}
+
+// sizeObservingBody counts the bytes read from a response body of unknown
+// length and records the total once the body is closed.
+type sizeObservingBody struct {
+	io.ReadCloser
+	observer prometheus.Observer
+	size     int64
+	closed   bool
+}
+
+func (b *sizeObservingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	return n, err
+}
+
+func (b *sizeObservingBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.observer.Observe(float64(b.size))
+	}
+	return b.ReadCloser.Close()
+}
@@ -43,6 +43,9 @@ func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	if endpoint != "" {
 		span.SetAttributes(attribute.String("cloudscale.endpoint", endpoint))
 	}
+	if project := cloudscale.Project(req.Context()); project != "" {
+		span.SetAttributes(attribute.String("cloudscale.project", project))
+	}
 
 	// Clone so we can inject trace headers without aliasing the caller's
 	// header map across retries.
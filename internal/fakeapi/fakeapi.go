@@ -0,0 +1,81 @@
+// Package fakeapi serves canned cloudscale.ch API responses over HTTP, for
+// the tests of the packages built on the SDK.
+package fakeapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// API is a test server answering GET requests for its list paths, e.g.
+// "v1/servers", with their body. Every other request is passed to the
+// fallback handler, or answered with 404 Not Found if there is none.
+//
+// Requests are served one at a time, so the fallback handler may use state
+// of the test without locking it.
+type API struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	lists    map[string]string
+	fallback http.HandlerFunc
+}
+
+// New starts an API serving lists and fallback. It is closed when the test
+// ends.
+func New(t testing.TB, lists map[string]string, fallback http.HandlerFunc) *API {
+	t.Helper()
+	if lists == nil {
+		lists = map[string]string{}
+	}
+	api := &API{lists: lists, fallback: fallback}
+	api.Server = httptest.NewServer(http.HandlerFunc(api.handle))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// NewClient starts an API like New and returns a client using it.
+func NewClient(t testing.TB, lists map[string]string, fallback http.HandlerFunc) (*cloudscale.Client, *API) {
+	t.Helper()
+	api := New(t, lists, fallback)
+	client := cloudscale.NewClient(nil)
+	client.BaseURL, _ = url.Parse(api.URL)
+	return client, api
+}
+
+// Set replaces the body of a list path, also while the API is in use.
+func (a *API) Set(path, body string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lists[path] = body
+}
+
+func (a *API) handle(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	body, ok := a.lists[strings.TrimPrefix(r.URL.Path, "/")]
+	a.mu.Unlock()
+
+	switch {
+	case ok && r.Method == http.MethodGet:
+		fmt.Fprint(w, body)
+	case a.fallback != nil:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.fallback(w, r)
+	default:
+		NotFound(w)
+	}
+}
+
+// NotFound answers a request the way the API does for a missing resource.
+func NotFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"detail": "Not found."}`)
+}
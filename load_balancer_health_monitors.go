@@ -1,12 +1,42 @@
 package cloudscale
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const loadBalancerHealthMonitorBasePath = "v1/load-balancers/health-monitors"
 
+// LoadBalancerHealthMonitorType is the kind of check a health monitor
+// performs against the pool members.
+type LoadBalancerHealthMonitorType string
+
+const (
+	LoadBalancerHealthMonitorTypePing     LoadBalancerHealthMonitorType = "ping"
+	LoadBalancerHealthMonitorTypeTCP      LoadBalancerHealthMonitorType = "tcp"
+	LoadBalancerHealthMonitorTypeHTTP     LoadBalancerHealthMonitorType = "http"
+	LoadBalancerHealthMonitorTypeHTTPS    LoadBalancerHealthMonitorType = "https"
+	LoadBalancerHealthMonitorTypeTLSHello LoadBalancerHealthMonitorType = "tls-hello"
+)
+
+// IsValid reports whether t is a known health monitor type.
+func (t LoadBalancerHealthMonitorType) IsValid() bool {
+	switch t {
+	case LoadBalancerHealthMonitorTypePing, LoadBalancerHealthMonitorTypeTCP, LoadBalancerHealthMonitorTypeHTTP, LoadBalancerHealthMonitorTypeHTTPS, LoadBalancerHealthMonitorTypeTLSHello:
+		return true
+	}
+	return false
+}
+
+func (t LoadBalancerHealthMonitorType) String() string {
+	return string(t)
+}
+
 type LoadBalancerHealthMonitor struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
@@ -18,11 +48,23 @@ type LoadBalancerHealthMonitor struct {
 	TimeoutS      int                            `json:"timeout_s,omitempty"`
 	UpThreshold   int                            `json:"up_threshold,omitempty"`
 	DownThreshold int                            `json:"down_threshold,omitempty"`
-	Type          string                         `json:"type,omitempty"`
+	Type          LoadBalancerHealthMonitorType  `json:"type,omitempty"`
 	HTTP          *LoadBalancerHealthMonitorHTTP `json:"http,omitempty"`
 	CreatedAt     time.Time                      `json:"created_at,omitempty"`
 }
 
+// MarshalJSON encodes the load balancer health monitor including its unknown fields.
+func (l LoadBalancerHealthMonitor) MarshalJSON() ([]byte, error) {
+	type plain LoadBalancerHealthMonitor
+	return marshalWithUnknownFields(plain(l), l.Extra)
+}
+
+// Raw returns the JSON of the load balancer health monitor, including the
+// fields the SDK does not model.
+func (l LoadBalancerHealthMonitor) Raw() json.RawMessage {
+	return rawJSON(l)
+}
+
 type LoadBalancerHealthMonitorHTTP struct {
 	ExpectedCodes []string `json:"expected_codes,omitempty"`
 	Method        string   `json:"method,omitempty"`
@@ -38,23 +80,69 @@ type LoadBalancerHealthMonitorRequest struct {
 	TimeoutS      int                                   `json:"timeout_s,omitempty"`
 	UpThreshold   int                                   `json:"up_threshold,omitempty"`
 	DownThreshold int                                   `json:"down_threshold,omitempty"`
-	Type          string                                `json:"type,omitempty"`
+	Type          LoadBalancerHealthMonitorType         `json:"type,omitempty"`
 	HTTP          *LoadBalancerHealthMonitorHTTPRequest `json:"http,omitempty"`
 }
 
 type LoadBalancerHealthMonitorHTTPRequest struct {
-	ExpectedCodes []string `json:"expected_codes,omitempty"`
-	Method        string   `json:"method,omitempty"`
-	UrlPath       string   `json:"url_path,omitempty"`
-	Version       string   `json:"version,omitempty"`
-	Host          *string  `json:"host,omitempty"`
+	ExpectedCodes []string         `json:"expected_codes,omitempty"`
+	Method        string           `json:"method,omitempty"`
+	UrlPath       string           `json:"url_path,omitempty"`
+	Version       string           `json:"version,omitempty"`
+	Host          Nullable[string] `json:"host,omitzero"`
 }
 
 type LoadBalancerHealthMonitorService interface {
 	GenericCreateService[LoadBalancerHealthMonitor, LoadBalancerHealthMonitorRequest]
 	GenericGetService[LoadBalancerHealthMonitor]
 	GenericListService[LoadBalancerHealthMonitor]
+	GenericWatchService[LoadBalancerHealthMonitor]
 	GenericUpdateService[LoadBalancerHealthMonitor, LoadBalancerHealthMonitorRequest]
 	GenericDeleteService[LoadBalancerHealthMonitor]
 	GenericWaitForService[LoadBalancerHealthMonitor]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerHealthMonitorRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.notNegative("delay_s", r.DelayS)
+	f.notNegative("timeout_s", r.TimeoutS)
+	if r.DelayS != 0 && r.TimeoutS > r.DelayS {
+		f.add("timeout_s", "must not be larger than delay_s (%d), got %d", r.DelayS, r.TimeoutS)
+	}
+	validateThreshold(&f, "up_threshold", r.UpThreshold)
+	validateThreshold(&f, "down_threshold", r.DownThreshold)
+	if r.HTTP != nil {
+		if r.Type != "" && r.Type != LoadBalancerHealthMonitorTypeHTTP && r.Type != LoadBalancerHealthMonitorTypeHTTPS {
+			f.add("http", "can only be set for http and https monitors, not %q", r.Type)
+		}
+		f.addNested("http", r.HTTP.Validate())
+	}
+	return f.err()
+}
+
+func validateThreshold(f *fieldErrors, field string, threshold int) {
+	if threshold != 0 && (threshold < 1 || threshold > 10) {
+		f.add(field, "must be between 1 and 10, got %d", threshold)
+	}
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerHealthMonitorHTTPRequest) Validate() error {
+	f := fieldErrors{}
+	f.oneOf("method", r.Method, "CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE")
+	f.oneOf("version", r.Version, "1.0", "1.1")
+	if r.UrlPath != "" && !strings.HasPrefix(r.UrlPath, "/") {
+		f.add("url_path", "must start with /, got %q", r.UrlPath)
+	}
+	if _, hasHost := r.Host.Get(); r.Version == "1.0" && hasHost {
+		f.add("host", "cannot be set for HTTP version 1.0")
+	}
+	for i, code := range r.ExpectedCodes {
+		if _, err := strconv.Atoi(code); err != nil || len(code) != 3 {
+			f.add(fmt.Sprintf("expected_codes[%d]", i), "must be an HTTP status code, got %q", code)
+		}
+	}
+	return f.err()
+}
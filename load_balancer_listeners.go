@@ -1,11 +1,33 @@
 package cloudscale
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
 const loadBalancerListenerBasePath = "v1/load-balancers/listeners"
 
+// LoadBalancerListenerProtocol is the protocol a listener accepts.
+type LoadBalancerListenerProtocol string
+
+const (
+	LoadBalancerListenerProtocolTCP LoadBalancerListenerProtocol = "tcp"
+)
+
+// IsValid reports whether p is a known listener protocol.
+func (p LoadBalancerListenerProtocol) IsValid() bool {
+	switch p {
+	case LoadBalancerListenerProtocolTCP:
+		return true
+	}
+	return false
+}
+
+func (p LoadBalancerListenerProtocol) String() string {
+	return string(p)
+}
+
 type LoadBalancerPoolStub struct {
 	HREF string `json:"href,omitempty"`
 	UUID string `json:"uuid,omitempty"`
@@ -13,40 +35,69 @@ type LoadBalancerPoolStub struct {
 }
 
 type LoadBalancerListener struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
-	HREF                   string                `json:"href,omitempty"`
-	UUID                   string                `json:"uuid,omitempty"`
-	Name                   string                `json:"name,omitempty"`
-	Pool                   *LoadBalancerPoolStub `json:"pool,omitempty"`
-	LoadBalancer           LoadBalancerStub      `json:"load_balancer,omitempty"`
-	Protocol               string                `json:"protocol,omitempty"`
-	ProtocolPort           int                   `json:"protocol_port,omitempty"`
-	AllowedCIDRs           []string              `json:"allowed_cidrs,omitempty"`
-	TimeoutClientDataMS    int                   `json:"timeout_client_data_ms,omitempty"`
-	TimeoutMemberConnectMS int                   `json:"timeout_member_connect_ms,omitempty"`
-	TimeoutMemberDataMS    int                   `json:"timeout_member_data_ms,omitempty"`
-	CreatedAt              time.Time             `json:"created_at,omitempty"`
+	HREF                   string                       `json:"href,omitempty"`
+	UUID                   string                       `json:"uuid,omitempty"`
+	Name                   string                       `json:"name,omitempty"`
+	Pool                   *LoadBalancerPoolStub        `json:"pool,omitempty"`
+	LoadBalancer           LoadBalancerStub             `json:"load_balancer,omitempty"`
+	Protocol               LoadBalancerListenerProtocol `json:"protocol,omitempty"`
+	ProtocolPort           int                          `json:"protocol_port,omitempty"`
+	AllowedCIDRs           []string                     `json:"allowed_cidrs,omitempty"`
+	TimeoutClientDataMS    int                          `json:"timeout_client_data_ms,omitempty"`
+	TimeoutMemberConnectMS int                          `json:"timeout_member_connect_ms,omitempty"`
+	TimeoutMemberDataMS    int                          `json:"timeout_member_data_ms,omitempty"`
+	CreatedAt              time.Time                    `json:"created_at,omitempty"`
+}
+
+// MarshalJSON encodes the load balancer listener including its unknown fields.
+func (l LoadBalancerListener) MarshalJSON() ([]byte, error) {
+	type plain LoadBalancerListener
+	return marshalWithUnknownFields(plain(l), l.Extra)
+}
+
+// Raw returns the JSON of the load balancer listener, including the fields the
+// SDK does not model.
+func (l LoadBalancerListener) Raw() json.RawMessage {
+	return rawJSON(l)
 }
 
 type LoadBalancerListenerRequest struct {
 	TaggedResourceRequest
-	Name                   string    `json:"name,omitempty"`
-	Pool                   string    `json:"pool,omitempty"`
-	Protocol               string    `json:"protocol,omitempty"`
-	ProtocolPort           int       `json:"protocol_port,omitempty"`
-	AllowedCIDRs           *[]string `json:"allowed_cidrs,omitempty"`
-	TimeoutClientDataMS    int       `json:"timeout_client_data_ms,omitempty"`
-	TimeoutMemberConnectMS int       `json:"timeout_member_connect_ms,omitempty"`
-	TimeoutMemberDataMS    int       `json:"timeout_member_data_ms,omitempty"`
+	Name                   string                       `json:"name,omitempty"`
+	Pool                   string                       `json:"pool,omitempty"`
+	Protocol               LoadBalancerListenerProtocol `json:"protocol,omitempty"`
+	ProtocolPort           int                          `json:"protocol_port,omitempty"`
+	AllowedCIDRs           Optional[[]string]           `json:"allowed_cidrs,omitzero"`
+	TimeoutClientDataMS    int                          `json:"timeout_client_data_ms,omitempty"`
+	TimeoutMemberConnectMS int                          `json:"timeout_member_connect_ms,omitempty"`
+	TimeoutMemberDataMS    int                          `json:"timeout_member_data_ms,omitempty"`
 }
 
 type LoadBalancerListenerService interface {
 	GenericCreateService[LoadBalancerListener, LoadBalancerListenerRequest]
 	GenericGetService[LoadBalancerListener]
 	GenericListService[LoadBalancerListener]
+	GenericWatchService[LoadBalancerListener]
 	GenericUpdateService[LoadBalancerListener, LoadBalancerListenerRequest]
 	GenericDeleteService[LoadBalancerListener]
 	GenericWaitForService[LoadBalancerListener]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerListenerRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	validatePort(&f, "protocol_port", r.ProtocolPort)
+	allowedCIDRs, _ := r.AllowedCIDRs.Get()
+	for i, cidr := range allowedCIDRs {
+		f.cidr(fmt.Sprintf("allowed_cidrs[%d]", i), cidr)
+	}
+	f.notNegative("timeout_client_data_ms", r.TimeoutClientDataMS)
+	f.notNegative("timeout_member_connect_ms", r.TimeoutMemberConnectMS)
+	f.notNegative("timeout_member_data_ms", r.TimeoutMemberDataMS)
+	return f.err()
+}
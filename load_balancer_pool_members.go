@@ -2,6 +2,7 @@ package cloudscale
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -10,7 +11,32 @@ import (
 
 const loadBalancerPoolMemberBasePath = "v1/load-balancers/pools/%s/members"
 
+// MonitorStatus is the health of a pool member as seen by the health monitor
+// of its pool.
+type MonitorStatus string
+
+const (
+	MonitorStatusUp        MonitorStatus = "up"
+	MonitorStatusDown      MonitorStatus = "down"
+	MonitorStatusChanging  MonitorStatus = "changing"
+	MonitorStatusNoMonitor MonitorStatus = "no_monitor"
+)
+
+// IsValid reports whether s is a known monitor status.
+func (s MonitorStatus) IsValid() bool {
+	switch s {
+	case MonitorStatusUp, MonitorStatusDown, MonitorStatusChanging, MonitorStatusNoMonitor:
+		return true
+	}
+	return false
+}
+
+func (s MonitorStatus) String() string {
+	return string(s)
+}
+
 type LoadBalancerPoolMember struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
@@ -25,17 +51,29 @@ type LoadBalancerPoolMember struct {
 	MonitorPort   int                  `json:"monitor_port,omitempty"`
 	Address       string               `json:"address,omitempty"`
 	Subnet        SubnetStub           `json:"subnet,omitempty"`
-	MonitorStatus string               `json:"monitor_status,omitempty"`
+	MonitorStatus MonitorStatus        `json:"monitor_status,omitempty"`
+}
+
+// MarshalJSON encodes the load balancer pool member including its unknown fields.
+func (l LoadBalancerPoolMember) MarshalJSON() ([]byte, error) {
+	type plain LoadBalancerPoolMember
+	return marshalWithUnknownFields(plain(l), l.Extra)
+}
+
+// Raw returns the JSON of the load balancer pool member, including the fields
+// the SDK does not model.
+func (l LoadBalancerPoolMember) Raw() json.RawMessage {
+	return rawJSON(l)
 }
 
 type LoadBalancerPoolMemberRequest struct {
 	TaggedResourceRequest
-	Name         string `json:"name,omitempty"`
-	Enabled      *bool  `json:"enabled,omitempty"`
-	ProtocolPort int    `json:"protocol_port,omitempty"`
-	MonitorPort  int    `json:"monitor_port,omitempty"`
-	Address      string `json:"address,omitempty"`
-	Subnet       string `json:"subnet,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Enabled      Optional[bool] `json:"enabled,omitzero"`
+	ProtocolPort int            `json:"protocol_port,omitempty"`
+	MonitorPort  int            `json:"monitor_port,omitempty"`
+	Address      string         `json:"address,omitempty"`
+	Subnet       string         `json:"subnet,omitempty"`
 }
 
 type LoadBalancerPoolMemberService interface {
@@ -45,6 +83,8 @@ type LoadBalancerPoolMemberService interface {
 	Update(ctx context.Context, poolID string, resourceID string, updateRequest *LoadBalancerPoolMemberRequest) error
 	Delete(ctx context.Context, poolID string, resourceID string) error
 	WaitFor(ctx context.Context, poolID string, resourceID string, condition func(resource *LoadBalancerPoolMember) (bool, error), opts ...backoff.RetryOption) (*LoadBalancerPoolMember, error)
+	Watch(ctx context.Context, poolID string, interval time.Duration, modifiers ...ListRequestModifier) <-chan Event[LoadBalancerPoolMember]
+	WatchWithOptions(ctx context.Context, poolID string, options WatchOptions) <-chan Event[LoadBalancerPoolMember]
 }
 
 type LoadBalancerPoolMemberServiceOperations struct {
@@ -93,6 +133,16 @@ func (l LoadBalancerPoolMemberServiceOperations) WaitFor(
 	return g.WaitFor(ctx, resourceID, condition, opts...)
 }
 
+func (l LoadBalancerPoolMemberServiceOperations) Watch(ctx context.Context, poolID string, interval time.Duration, modifiers ...ListRequestModifier) <-chan Event[LoadBalancerPoolMember] {
+	return l.WatchWithOptions(ctx, poolID, WatchOptions{Interval: interval, Modifiers: modifiers})
+}
+
+func (l LoadBalancerPoolMemberServiceOperations) WatchWithOptions(ctx context.Context, poolID string, options WatchOptions) <-chan Event[LoadBalancerPoolMember] {
+	g := parameterizeGenericInstance(l, poolID)
+	ctx = WithOperationPath(ctx, "v1/load-balancers/pools/:pool_id/members")
+	return g.WatchWithOptions(ctx, options)
+}
+
 func parameterizeGenericInstance(l LoadBalancerPoolMemberServiceOperations, poolID string) GenericServiceOperations[LoadBalancerPoolMember, LoadBalancerPoolMemberRequest, LoadBalancerPoolMemberRequest] {
 	return GenericServiceOperations[LoadBalancerPoolMember, LoadBalancerPoolMemberRequest, LoadBalancerPoolMemberRequest]{
 		client: l.client,
@@ -100,9 +150,47 @@ func parameterizeGenericInstance(l LoadBalancerPoolMemberServiceOperations, pool
 	}
 }
 
-var LoadBalancerPoolMemberIsUp = func(member *LoadBalancerPoolMember) (bool, error) {
-	if member.MonitorStatus == "up" {
-		return true, nil
+// LoadBalancerPoolMemberIsUp waits for the health monitor to report a pool
+// member as up. It fails with a *StateError if the member is disabled or its
+// pool has no health monitor.
+var LoadBalancerPoolMemberIsUp = LoadBalancerPoolMemberHasMonitorStatus(MonitorStatusUp)
+
+// LoadBalancerPoolMemberIsDown waits for the health monitor to report a pool
+// member as down. It fails with a *StateError if the pool of the member has
+// no health monitor.
+var LoadBalancerPoolMemberIsDown = LoadBalancerPoolMemberHasMonitorStatus(MonitorStatusDown)
+
+// LoadBalancerPoolMemberHasMonitorStatus waits for the health monitor to
+// report status for a pool member. It fails with a *StateError if the
+// status can no longer be reported: while the pool has no health monitor,
+// or while the member is disabled and status is MonitorStatusUp.
+func LoadBalancerPoolMemberHasMonitorStatus(status MonitorStatus) Condition[LoadBalancerPoolMember] {
+	return func(member *LoadBalancerPoolMember) (bool, error) {
+		if member.MonitorStatus == status {
+			return true, nil
+		}
+		if member.MonitorStatus == MonitorStatusNoMonitor {
+			return false, backoff.Permanent(&StateError{Resource: "load balancer pool member", ID: member.UUID, Status: member.MonitorStatus.String()})
+		}
+		if status == MonitorStatusUp && !member.Enabled {
+			return false, backoff.Permanent(&StateError{Resource: "load balancer pool member", ID: member.UUID, Status: "disabled"})
+		}
+		return false, fmt.Errorf("waiting for monitor status: %s, current status: %s", status, member.MonitorStatus)
+	}
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerPoolMemberRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	validatePort(&f, "protocol_port", r.ProtocolPort)
+	validatePort(&f, "monitor_port", r.MonitorPort)
+	f.ip("address", r.Address)
+	return f.err()
+}
+
+func validatePort(f *fieldErrors, field string, port int) {
+	if port < 0 || port > 65535 {
+		f.add(field, "must be between 1 and 65535, got %d", port)
 	}
-	return false, fmt.Errorf("waiting for monitor status: %s, current status: %s", "up", member.MonitorStatus)
 }
@@ -1,37 +1,105 @@
 package cloudscale
 
 import (
+	"encoding/json"
 	"time"
 )
 
 const loadBalancerPoolBasePath = "v1/load-balancers/pools"
 
+// LoadBalancerPoolAlgorithm decides which pool member receives a new
+// connection.
+type LoadBalancerPoolAlgorithm string
+
+const (
+	LoadBalancerPoolAlgorithmRoundRobin       LoadBalancerPoolAlgorithm = "round_robin"
+	LoadBalancerPoolAlgorithmLeastConnections LoadBalancerPoolAlgorithm = "least_connections"
+	LoadBalancerPoolAlgorithmSourceIP         LoadBalancerPoolAlgorithm = "source_ip"
+)
+
+// IsValid reports whether a is a known algorithm.
+func (a LoadBalancerPoolAlgorithm) IsValid() bool {
+	switch a {
+	case LoadBalancerPoolAlgorithmRoundRobin, LoadBalancerPoolAlgorithmLeastConnections, LoadBalancerPoolAlgorithmSourceIP:
+		return true
+	}
+	return false
+}
+
+func (a LoadBalancerPoolAlgorithm) String() string {
+	return string(a)
+}
+
+// LoadBalancerPoolProtocol is the protocol used between the load balancer and
+// the pool members. The PROXY protocol variants pass on the client address.
+type LoadBalancerPoolProtocol string
+
+const (
+	LoadBalancerPoolProtocolTCP     LoadBalancerPoolProtocol = "tcp"
+	LoadBalancerPoolProtocolProxy   LoadBalancerPoolProtocol = "proxy"
+	LoadBalancerPoolProtocolProxyV2 LoadBalancerPoolProtocol = "proxyv2"
+)
+
+// IsValid reports whether p is a known pool protocol.
+func (p LoadBalancerPoolProtocol) IsValid() bool {
+	switch p {
+	case LoadBalancerPoolProtocolTCP, LoadBalancerPoolProtocolProxy, LoadBalancerPoolProtocolProxyV2:
+		return true
+	}
+	return false
+}
+
+func (p LoadBalancerPoolProtocol) String() string {
+	return string(p)
+}
+
 type LoadBalancerPool struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
-	HREF         string           `json:"href,omitempty"`
-	UUID         string           `json:"uuid,omitempty"`
-	Name         string           `json:"name,omitempty"`
-	CreatedAt    time.Time        `json:"created_at,omitempty"`
-	LoadBalancer LoadBalancerStub `json:"load_balancer,omitempty"`
-	Algorithm    string           `json:"algorithm,omitempty"`
-	Protocol     string           `json:"protocol,omitempty"`
+	HREF         string                    `json:"href,omitempty"`
+	UUID         string                    `json:"uuid,omitempty"`
+	Name         string                    `json:"name,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at,omitempty"`
+	LoadBalancer LoadBalancerStub          `json:"load_balancer,omitempty"`
+	Algorithm    LoadBalancerPoolAlgorithm `json:"algorithm,omitempty"`
+	Protocol     LoadBalancerPoolProtocol  `json:"protocol,omitempty"`
+}
+
+// MarshalJSON encodes the load balancer pool including its unknown fields.
+func (l LoadBalancerPool) MarshalJSON() ([]byte, error) {
+	type plain LoadBalancerPool
+	return marshalWithUnknownFields(plain(l), l.Extra)
+}
+
+// Raw returns the JSON of the load balancer pool, including the fields the SDK
+// does not model.
+func (l LoadBalancerPool) Raw() json.RawMessage {
+	return rawJSON(l)
 }
 
 type LoadBalancerPoolRequest struct {
 	TaggedResourceRequest
-	Name         string `json:"name,omitempty"`
-	LoadBalancer string `json:"load_balancer,omitempty"`
-	Algorithm    string `json:"algorithm,omitempty"`
-	Protocol     string `json:"protocol,omitempty"`
+	Name         string                    `json:"name,omitempty"`
+	LoadBalancer string                    `json:"load_balancer,omitempty"`
+	Algorithm    LoadBalancerPoolAlgorithm `json:"algorithm,omitempty"`
+	Protocol     LoadBalancerPoolProtocol  `json:"protocol,omitempty"`
 }
 
 type LoadBalancerPoolService interface {
 	GenericCreateService[LoadBalancerPool, LoadBalancerPoolRequest]
 	GenericGetService[LoadBalancerPool]
 	GenericListService[LoadBalancerPool]
+	GenericWatchService[LoadBalancerPool]
 	GenericUpdateService[LoadBalancerPool, LoadBalancerPoolRequest]
 	GenericDeleteService[LoadBalancerPool]
 	GenericWaitForService[LoadBalancerPool]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerPoolRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	return f.err()
+}
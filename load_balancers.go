@@ -1,12 +1,37 @@
 package cloudscale
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
+
+	"github.com/cenkalti/backoff/v5"
 )
 
 const loadBalancerBasePath = "v1/load-balancers"
 
+// LoadBalancerStatus is the state of a load balancer.
+type LoadBalancerStatus string
+
+const (
+	LoadBalancerRunning  LoadBalancerStatus = "running"
+	LoadBalancerChanging LoadBalancerStatus = "changing"
+	LoadBalancerError    LoadBalancerStatus = "error"
+)
+
+// IsValid reports whether s is a known load balancer status.
+func (s LoadBalancerStatus) IsValid() bool {
+	switch s {
+	case LoadBalancerRunning, LoadBalancerChanging, LoadBalancerError:
+		return true
+	}
+	return false
+}
+
+func (s LoadBalancerStatus) String() string {
+	return string(s)
+}
+
 type LoadBalancerStub struct {
 	HREF string `json:"href,omitempty"`
 	UUID string `json:"uuid,omitempty"`
@@ -19,6 +44,7 @@ type LoadBalancerFlavorStub struct {
 }
 
 type LoadBalancer struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
@@ -27,11 +53,23 @@ type LoadBalancer struct {
 	UUID         string                 `json:"uuid,omitempty"`
 	Name         string                 `json:"name,omitempty"`
 	Flavor       LoadBalancerFlavorStub `json:"flavor,omitempty"`
-	Status       string                 `json:"status,omitempty"`
+	Status       LoadBalancerStatus     `json:"status,omitempty"`
 	VIPAddresses []VIPAddress           `json:"vip_addresses,omitempty"`
 	CreatedAt    time.Time              `json:"created_at,omitempty"`
 }
 
+// MarshalJSON encodes the load balancer including its unknown fields.
+func (l LoadBalancer) MarshalJSON() ([]byte, error) {
+	type plain LoadBalancer
+	return marshalWithUnknownFields(plain(l), l.Extra)
+}
+
+// Raw returns the JSON of the load balancer, including the fields the SDK does
+// not model.
+func (l LoadBalancer) Raw() json.RawMessage {
+	return rawJSON(l)
+}
+
 type VIPAddress struct {
 	Version int        `json:"version,omitempty"`
 	Address string     `json:"address,omitempty"`
@@ -55,14 +93,46 @@ type LoadBalancerService interface {
 	GenericCreateService[LoadBalancer, LoadBalancerRequest]
 	GenericGetService[LoadBalancer]
 	GenericListService[LoadBalancer]
+	GenericWatchService[LoadBalancer]
 	GenericUpdateService[LoadBalancer, LoadBalancerRequest]
 	GenericDeleteService[LoadBalancer]
 	GenericWaitForService[LoadBalancer]
 }
 
-var LoadBalancerIsRunning = func(lb *LoadBalancer) (bool, error) {
-	if lb.Status == "running" {
+// LoadBalancerIsRunning waits for a load balancer to be running. It fails
+// with a *StateError once the load balancer has status "error".
+var LoadBalancerIsRunning Condition[LoadBalancer] = func(lb *LoadBalancer) (bool, error) {
+	if lb.Status == LoadBalancerRunning {
 		return true, nil
 	}
-	return false, fmt.Errorf("waiting for status: %s, current status: %s", "running", lb.Status)
+	if lb.Status == LoadBalancerError {
+		return false, backoff.Permanent(&StateError{Resource: "load balancer", ID: lb.UUID, Status: lb.Status.String()})
+	}
+	return false, fmt.Errorf("waiting for status: %s, current status: %s", LoadBalancerRunning, lb.Status)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r LoadBalancerRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	if r.VIPAddresses != nil {
+		for i, address := range *r.VIPAddresses {
+			f.addNested(fmt.Sprintf("vip_addresses[%d]", i), address.Validate())
+		}
+	}
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the zone exists.
+func (r LoadBalancerRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.zone(catalog, "zone", r.Zone)
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r VIPAddressRequest) Validate() error {
+	f := fieldErrors{}
+	f.ip("address", r.Address)
+	return f.err()
 }
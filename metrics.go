@@ -2,6 +2,7 @@ package cloudscale
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -23,11 +24,24 @@ type BucketMetricsRequest struct {
 }
 
 type BucketMetrics struct {
+	UnknownFields
 	Start time.Time `json:"start"`
 	End   time.Time `json:"end"`
 	Data  []BucketMetricsData
 }
 
+// MarshalJSON encodes the bucket metrics including its unknown fields.
+func (b BucketMetrics) MarshalJSON() ([]byte, error) {
+	type plain BucketMetrics
+	return marshalWithUnknownFields(plain(b), b.Extra)
+}
+
+// Raw returns the JSON of the bucket metrics, including the fields the SDK does
+// not model.
+func (b BucketMetrics) Raw() json.RawMessage {
+	return rawJSON(b)
+}
+
 type BucketMetricsDataSubject struct {
 	BucketName    string `json:"name"`
 	ObjectsUserID string `json:"objects_user_id"`
@@ -104,3 +118,18 @@ func (s MetricsServiceOperations) GetBucketMetrics(ctx context.Context, request
 
 	return result, nil
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r BucketMetricsRequest) Validate() error {
+	f := fieldErrors{}
+	if r.Start.IsZero() {
+		f.add("start", "must be set")
+	}
+	if r.End.IsZero() {
+		f.add("end", "must be set")
+	}
+	if !r.Start.IsZero() && !r.End.IsZero() && r.End.Before(r.Start) {
+		f.add("end", "must not be before start")
+	}
+	return f.err()
+}
@@ -1,12 +1,15 @@
 package cloudscale
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
 const networkBasePath = "v1/networks"
 
 type Network struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
@@ -19,6 +22,18 @@ type Network struct {
 	CreatedAt time.Time    `json:"created_at"`
 }
 
+// MarshalJSON encodes the network including its unknown fields.
+func (n Network) MarshalJSON() ([]byte, error) {
+	type plain Network
+	return marshalWithUnknownFields(plain(n), n.Extra)
+}
+
+// Raw returns the JSON of the network, including the fields the SDK does not
+// model.
+func (n Network) Raw() json.RawMessage {
+	return rawJSON(n)
+}
+
 type NetworkStub struct {
 	HREF string `json:"href,omitempty"`
 	Name string `json:"name,omitempty"`
@@ -44,6 +59,7 @@ type NetworkService interface {
 	GenericCreateService[Network, NetworkCreateRequest]
 	GenericGetService[Network]
 	GenericListService[Network]
+	GenericWatchService[Network]
 	GenericUpdateService[Network, NetworkUpdateRequest]
 	GenericDeleteService[Network]
 	GenericWaitForService[Network]
@@ -52,3 +68,45 @@ type NetworkService interface {
 type NetworkServiceOperations struct {
 	client *Client
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r NetworkCreateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	validateMTU(&f, r.MTU)
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the zone exists.
+func (r NetworkCreateRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.zone(catalog, "zone", r.Zone)
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r NetworkUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	validateMTU(&f, r.MTU)
+	return f.err()
+}
+
+func validateMTU(f *fieldErrors, mtu int) {
+	if mtu != 0 && (mtu < 1280 || mtu > 9000) {
+		f.add("mtu", "must be between 1280 and 9000, got %d", mtu)
+	}
+}
+
+// NetworkHasSubnet waits for the subnet with the given UUID to be part of a
+// network.
+func NetworkHasSubnet(subnetUUID string) Condition[Network] {
+	return func(network *Network) (bool, error) {
+		for _, subnet := range network.Subnets {
+			if subnet.UUID == subnetUUID {
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("waiting for subnet %s", subnetUUID)
+	}
+}
@@ -1,14 +1,43 @@
 package cloudscale
 
+import "encoding/json"
+
 const objectsUsersBasePath = "v1/objects-users"
 
 // ObjectsUser contains information
 type ObjectsUser struct {
+	UnknownFields
 	TaggedResource
-	HREF        string              `json:"href,omitempty"`
-	ID          string              `json:"id,omitempty"`
-	DisplayName string              `json:"display_name,omitempty"`
-	Keys        []map[string]string `json:"keys,omitempty"`
+	HREF        string           `json:"href,omitempty"`
+	ID          string           `json:"id,omitempty"`
+	DisplayName string           `json:"display_name,omitempty"`
+	Keys        []ObjectsUserKey `json:"keys,omitempty"`
+}
+
+// MarshalJSON encodes the objects user including its unknown fields.
+func (o ObjectsUser) MarshalJSON() ([]byte, error) {
+	type plain ObjectsUser
+	return marshalWithUnknownFields(plain(o), o.Extra)
+}
+
+// Raw returns the JSON of the objects user, including the fields the SDK does
+// not model.
+func (o ObjectsUser) Raw() json.RawMessage {
+	return rawJSON(o)
+}
+
+// ObjectsUserKey is an S3 access key pair of an Objects User.
+type ObjectsUserKey struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+}
+
+// Key returns the first key of the user, if any.
+func (u ObjectsUser) Key() (ObjectsUserKey, bool) {
+	if len(u.Keys) == 0 {
+		return ObjectsUserKey{}, false
+	}
+	return u.Keys[0], true
 }
 
 // ObjectsUserRequest is used to create and update Objects Users
@@ -22,7 +51,15 @@ type ObjectsUsersService interface {
 	GenericCreateService[ObjectsUser, ObjectsUserRequest]
 	GenericGetService[ObjectsUser]
 	GenericListService[ObjectsUser]
+	GenericWatchService[ObjectsUser]
 	GenericUpdateService[ObjectsUser, ObjectsUserRequest]
 	GenericDeleteService[ObjectsUser]
 	GenericWaitForService[ObjectsUser]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r ObjectsUserRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	return f.err()
+}
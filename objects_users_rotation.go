@@ -0,0 +1,307 @@
+package cloudscale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+const (
+	// ObjectsUserGenerationTag numbers the generations of a rotated objects
+	// user, starting at 1.
+	ObjectsUserGenerationTag = "objects-user-generation"
+	// ObjectsUserRetireAfterTag is set on older generations of a rotated
+	// objects user to the time, in RFC 3339 format, after which they are
+	// deleted.
+	ObjectsUserRetireAfterTag = "objects-user-retire-after"
+)
+
+// EnvSecretTemplate is a FileSecretSink template that writes the credentials
+// as environment variables for S3 clients.
+const EnvSecretTemplate = `AWS_ACCESS_KEY_ID={{.AccessKey}}
+AWS_SECRET_ACCESS_KEY={{.SecretKey}}
+`
+
+// ObjectsUserCredentials are the credentials of a generation of a rotated
+// objects user.
+type ObjectsUserCredentials struct {
+	ObjectsUserKey
+	UserID     string `json:"user_id"`
+	Generation int    `json:"generation"`
+}
+
+// SecretSink stores the credentials of a new generation where the
+// applications using them read them.
+type SecretSink interface {
+	StoreCredentials(ctx context.Context, credentials ObjectsUserCredentials) error
+}
+
+// SecretSinkFunc is a SecretSink calling a function, e.g. to update a
+// Kubernetes secret or a vault.
+type SecretSinkFunc func(ctx context.Context, credentials ObjectsUserCredentials) error
+
+func (f SecretSinkFunc) StoreCredentials(ctx context.Context, credentials ObjectsUserCredentials) error {
+	return f(ctx, credentials)
+}
+
+// FileSecretSink writes the credentials to a file readable only by its owner.
+// The file is replaced atomically, so readers never see partial credentials.
+type FileSecretSink struct {
+	Path string
+	// Template is a text/template executed with the ObjectsUserCredentials,
+	// e.g. EnvSecretTemplate. The credentials are written as JSON if it is
+	// empty.
+	Template string
+}
+
+func (s FileSecretSink) StoreCredentials(ctx context.Context, credentials ObjectsUserCredentials) error {
+	var content bytes.Buffer
+	if s.Template == "" {
+		encoder := json.NewEncoder(&content)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(credentials); err != nil {
+			return err
+		}
+	} else {
+		tmpl, err := template.New(filepath.Base(s.Path)).Option("missingkey=error").Parse(s.Template)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(&content, credentials); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.Path), "."+filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.Path)
+}
+
+// ObjectsUserRotation describes an objects user whose credentials are
+// rotated. The API issues a single key pair per objects user, so every
+// rotation creates a new objects user, a generation, and retires the older
+// ones once applications had time to pick up the new credentials.
+type ObjectsUserRotation struct {
+	// Tags select the generations of the user and are set on new ones.
+	// They must not be empty.
+	Tags TagMap
+	// DisplayName of new generations, followed by the generation, e.g.
+	// "backup-3".
+	DisplayName string
+
+	// Sink stores the credentials of a new generation.
+	Sink SecretSink
+	// Verify checks the stored credentials, e.g. by listing a bucket with
+	// them, and is retried with the options passed to RotateObjectsUser
+	// until it succeeds. By default, the API must list the new key for the
+	// new generation.
+	Verify func(ctx context.Context, credentials ObjectsUserCredentials) error
+
+	// GracePeriod is how long older generations are kept after a rotation.
+	// They are deleted by the first RotateObjectsUser or RetireObjectsUsers
+	// after it ended, or right away if it is zero.
+	GracePeriod time.Duration
+}
+
+// ObjectsUserRotationResult is the result of RotateObjectsUser and
+// RetireObjectsUsers.
+type ObjectsUserRotationResult struct {
+	// Created is the new generation, nil for RetireObjectsUsers.
+	Created     *ObjectsUser
+	Credentials ObjectsUserCredentials
+	// Retiring are older generations still within their grace period.
+	Retiring []ObjectsUser
+	// Retired are the older generations that were deleted.
+	Retired []ObjectsUser
+}
+
+// Generation returns the generation of an objects user of a rotation, or 0
+// if it has none.
+func (u ObjectsUser) Generation() int {
+	generation, err := strconv.Atoi(u.Tags[ObjectsUserGenerationTag])
+	if err != nil {
+		return 0
+	}
+	return generation
+}
+
+// RotateObjectsUser creates the next generation of rotation, hands its
+// credentials to rotation.Sink and verifies them. Only then are the older
+// generations marked for retirement, and those whose grace period ended are
+// deleted. If the credentials cannot be stored, the new generation is deleted
+// again; if they cannot be verified, it is kept for inspection, and the
+// older generations are left alone.
+func RotateObjectsUser(ctx context.Context, client *Client, rotation ObjectsUserRotation, opts ...backoff.RetryOption) (*ObjectsUserRotationResult, error) {
+	if err := rotation.validate(); err != nil {
+		return nil, err
+	}
+	if rotation.DisplayName == "" {
+		return nil, errors.New("objects user rotation needs a display name")
+	}
+	if rotation.Sink == nil {
+		return nil, errors.New("objects user rotation needs a secret sink")
+	}
+
+	older, err := listGenerations(ctx, client, rotation)
+	if err != nil {
+		return nil, err
+	}
+	generation := 1
+	if len(older) > 0 {
+		generation = older[len(older)-1].Generation() + 1
+	}
+
+	tags := TagMap{ObjectsUserGenerationTag: strconv.Itoa(generation)}
+	for key, value := range rotation.Tags {
+		tags[key] = value
+	}
+	created, err := client.ObjectsUsers.Create(ctx, &ObjectsUserRequest{
+		DisplayName:           fmt.Sprintf("%s-%d", rotation.DisplayName, generation),
+		TaggedResourceRequest: TaggedResourceRequest{Tags: &tags},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &ObjectsUserRotationResult{Created: created}
+
+	key, ok := created.Key()
+	if !ok {
+		err := fmt.Errorf("objects user %s was created without a key", created.ID)
+		return result, errors.Join(err, client.ObjectsUsers.Delete(ctx, created.ID))
+	}
+	result.Credentials = ObjectsUserCredentials{ObjectsUserKey: key, UserID: created.ID, Generation: generation}
+
+	if err := rotation.Sink.StoreCredentials(ctx, result.Credentials); err != nil {
+		err = fmt.Errorf("storing credentials of objects user %s: %w", created.ID, err)
+		return result, errors.Join(err, client.ObjectsUsers.Delete(ctx, created.ID))
+	}
+
+	verify := rotation.Verify
+	if verify == nil {
+		verify = func(ctx context.Context, credentials ObjectsUserCredentials) error {
+			return verifyObjectsUserKey(ctx, client, credentials)
+		}
+	}
+	_, err = backoff.Retry(ctx, func() (struct{}, error) {
+		return struct{}{}, verify(ctx, result.Credentials)
+	}, opts...)
+	if err != nil {
+		return result, fmt.Errorf("verifying credentials of objects user %s: %w", created.ID, err)
+	}
+
+	return result, retireGenerations(ctx, client, rotation, older, result)
+}
+
+// RetireObjectsUsers marks all but the newest generation of rotation for
+// retirement and deletes those whose grace period ended, e.g. to clean up
+// periodically between rotations.
+func RetireObjectsUsers(ctx context.Context, client *Client, rotation ObjectsUserRotation) (*ObjectsUserRotationResult, error) {
+	if err := rotation.validate(); err != nil {
+		return nil, err
+	}
+	generations, err := listGenerations(ctx, client, rotation)
+	if err != nil {
+		return nil, err
+	}
+	result := &ObjectsUserRotationResult{}
+	if len(generations) == 0 {
+		return result, nil
+	}
+	return result, retireGenerations(ctx, client, rotation, generations[:len(generations)-1], result)
+}
+
+func (r ObjectsUserRotation) validate() error {
+	if len(r.Tags) == 0 {
+		return errors.New("objects user rotation needs tags to select its generations")
+	}
+	for _, key := range []string{ObjectsUserGenerationTag, ObjectsUserRetireAfterTag} {
+		if _, ok := r.Tags[key]; ok {
+			return fmt.Errorf("objects user rotation tags must not contain %s", key)
+		}
+	}
+	return nil
+}
+
+// listGenerations returns the generations of rotation, oldest first.
+func listGenerations(ctx context.Context, client *Client, rotation ObjectsUserRotation) ([]ObjectsUser, error) {
+	users, err := client.ObjectsUsers.List(ctx, WithTagFilter(rotation.Tags))
+	if err != nil {
+		return nil, err
+	}
+	generations := []ObjectsUser{}
+	for _, user := range users {
+		if hasTags(user.Tags, rotation.Tags) && user.Generation() > 0 {
+			generations = append(generations, user)
+		}
+	}
+	slices.SortFunc(generations, func(a, b ObjectsUser) int {
+		return a.Generation() - b.Generation()
+	})
+	return generations, nil
+}
+
+func verifyObjectsUserKey(ctx context.Context, client *Client, credentials ObjectsUserCredentials) error {
+	user, err := client.ObjectsUsers.Get(ctx, credentials.UserID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(user.Keys, credentials.ObjectsUserKey) {
+		return fmt.Errorf("objects user %s does not list access key %s", credentials.UserID, credentials.AccessKey)
+	}
+	return nil
+}
+
+func retireGenerations(ctx context.Context, client *Client, rotation ObjectsUserRotation, older []ObjectsUser, result *ObjectsUserRotationResult) error {
+	now := time.Now()
+	errs := []error{}
+	for _, user := range older {
+		if retireAfter, ok := user.Tags[ObjectsUserRetireAfterTag]; ok {
+			deadline, err := time.Parse(time.RFC3339, retireAfter)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("objects user %s has an invalid %s tag: %w", user.ID, ObjectsUserRetireAfterTag, err))
+				continue
+			}
+			if now.Before(deadline) {
+				result.Retiring = append(result.Retiring, user)
+				continue
+			}
+		} else if rotation.GracePeriod > 0 {
+			retireAfter := now.Add(rotation.GracePeriod).UTC().Format(time.RFC3339)
+			tags, err := MergeTags[ObjectsUser, ObjectsUserRequest](ctx, client.ObjectsUsers, user.ID, TagMap{ObjectsUserRetireAfterTag: retireAfter})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("marking objects user %s for retirement: %w", user.ID, err))
+				continue
+			}
+			user.Tags = tags
+			result.Retiring = append(result.Retiring, user)
+			continue
+		}
+
+		if err := client.ObjectsUsers.Delete(ctx, user.ID); err != nil {
+			errs = append(errs, fmt.Errorf("retiring objects user %s: %w", user.ID, err))
+			continue
+		}
+		result.Retired = append(result.Retired, user)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,264 @@
+package cloudscale
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+var fastRetry = []backoff.RetryOption{
+	backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+	backoff.WithMaxTries(3),
+}
+
+// objectsUsersAPI serves objects users from memory and records the requests
+// changing them.
+type objectsUsersAPI struct {
+	users    map[string]*ObjectsUser
+	requests []string
+}
+
+func newObjectsUsersAPI(users ...ObjectsUser) *objectsUsersAPI {
+	api := &objectsUsersAPI{users: map[string]*ObjectsUser{}}
+	for _, user := range users {
+		api.users[user.ID] = &user
+	}
+
+	mux.HandleFunc("/v1/objects-users", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var request ObjectsUserRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			user := &ObjectsUser{
+				ID:             "u-new",
+				DisplayName:    request.DisplayName,
+				Keys:           []ObjectsUserKey{{AccessKey: "AK-NEW", SecretKey: "SK-NEW"}},
+				TaggedResource: TaggedResource{Tags: *request.Tags},
+			}
+			api.users[user.ID] = user
+			api.requests = append(api.requests, fmt.Sprintf("POST %s %s", request.DisplayName, user.Tags[ObjectsUserGenerationTag]))
+			json.NewEncoder(w).Encode(user)
+			return
+		}
+		list := []ObjectsUser{}
+		for _, user := range api.users {
+			list = append(list, *user)
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("/v1/objects-users/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/objects-users/")
+		user, ok := api.users[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"detail": "Not found."}`)
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			var request ObjectsUserRequest
+			json.NewDecoder(r.Body).Decode(&request)
+			user.Tags = *request.Tags
+			api.requests = append(api.requests, fmt.Sprintf("PATCH %s", id))
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			delete(api.users, id)
+			api.requests = append(api.requests, fmt.Sprintf("DELETE %s", id))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			json.NewEncoder(w).Encode(user)
+		}
+	})
+	return api
+}
+
+func generationUser(id string, generation string, tags TagMap) ObjectsUser {
+	user := ObjectsUser{ID: id, TaggedResource: TaggedResource{Tags: TagMap{"app": "backup", ObjectsUserGenerationTag: generation}}}
+	for key, value := range tags {
+		user.Tags[key] = value
+	}
+	return user
+}
+
+func TestObjectsUser_Keys(t *testing.T) {
+	var user ObjectsUser
+	if err := json.Unmarshal([]byte(`{"id": "u1", "keys": [{"access_key": "AK", "secret_key": "SK"}]}`), &user); err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := user.Key(); !ok || key != (ObjectsUserKey{AccessKey: "AK", SecretKey: "SK"}) {
+		t.Errorf("expected the typed key, got %+v", user.Keys)
+	}
+	if _, ok := (ObjectsUser{}).Key(); ok {
+		t.Error("expected no key for a user without keys")
+	}
+}
+
+func TestRotateObjectsUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	api := newObjectsUsersAPI(
+		generationUser("u1", "1", TagMap{ObjectsUserRetireAfterTag: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)}),
+		generationUser("u2", "2", nil),
+		ObjectsUser{ID: "other", TaggedResource: TaggedResource{Tags: TagMap{"app": "web", ObjectsUserGenerationTag: "7"}}},
+	)
+
+	var stored []ObjectsUserCredentials
+	rotation := ObjectsUserRotation{
+		Tags:        TagMap{"app": "backup"},
+		DisplayName: "backup",
+		Sink: SecretSinkFunc(func(ctx context.Context, credentials ObjectsUserCredentials) error {
+			stored = append(stored, credentials)
+			return nil
+		}),
+		GracePeriod: time.Hour,
+	}
+	result, err := RotateObjectsUser(ctx, client, rotation, fastRetry...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ObjectsUserCredentials{ObjectsUserKey: ObjectsUserKey{AccessKey: "AK-NEW", SecretKey: "SK-NEW"}, UserID: "u-new", Generation: 3}
+	if !reflect.DeepEqual(stored, []ObjectsUserCredentials{expected}) || result.Credentials != expected {
+		t.Errorf("expected the credentials of generation 3 to be stored, got %+v", stored)
+	}
+	if result.Created.DisplayName != "backup-3" || result.Created.Tags["app"] != "backup" {
+		t.Errorf("unexpected new generation %+v", result.Created)
+	}
+	if len(result.Retired) != 1 || result.Retired[0].ID != "u1" {
+		t.Errorf("expected generation 1 to be retired, got %+v", result.Retired)
+	}
+	if len(result.Retiring) != 1 || result.Retiring[0].ID != "u2" {
+		t.Errorf("expected generation 2 to be retiring, got %+v", result.Retiring)
+	}
+	deadline, err := time.Parse(time.RFC3339, api.users["u2"].Tags[ObjectsUserRetireAfterTag])
+	if err != nil || deadline.Before(time.Now().Add(59*time.Minute)) {
+		t.Errorf("expected generation 2 to be marked for retirement in an hour, got %v", api.users["u2"].Tags)
+	}
+	if _, ok := api.users["other"]; !ok {
+		t.Error("expected users of other rotations to be kept")
+	}
+}
+
+func TestRotateObjectsUser_First(t *testing.T) {
+	setup()
+	defer teardown()
+
+	api := newObjectsUsersAPI()
+	rotation := ObjectsUserRotation{
+		Tags:        TagMap{"app": "backup"},
+		DisplayName: "backup",
+		Sink:        SecretSinkFunc(func(context.Context, ObjectsUserCredentials) error { return nil }),
+	}
+	result, err := RotateObjectsUser(ctx, client, rotation, fastRetry...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Credentials.Generation != 1 || !reflect.DeepEqual(api.requests, []string{"POST backup-1 1"}) {
+		t.Errorf("expected the first generation to be created, got %+v and %v", result.Credentials, api.requests)
+	}
+}
+
+func TestRotateObjectsUser_SinkFails(t *testing.T) {
+	setup()
+	defer teardown()
+
+	api := newObjectsUsersAPI(generationUser("u1", "1", nil))
+	sinkErr := errors.New("vault sealed")
+	rotation := ObjectsUserRotation{
+		Tags:        TagMap{"app": "backup"},
+		DisplayName: "backup",
+		Sink:        SecretSinkFunc(func(context.Context, ObjectsUserCredentials) error { return sinkErr }),
+	}
+	_, err := RotateObjectsUser(ctx, client, rotation, fastRetry...)
+	if !errors.Is(err, sinkErr) {
+		t.Errorf("expected the sink error, got %v", err)
+	}
+	expected := []string{"POST backup-2 2", "DELETE u-new"}
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("expected the new generation to be deleted again, got %v", api.requests)
+	}
+}
+
+func TestRotateObjectsUser_VerifyFails(t *testing.T) {
+	setup()
+	defer teardown()
+
+	api := newObjectsUsersAPI(generationUser("u1", "1", nil))
+	attempts := 0
+	rotation := ObjectsUserRotation{
+		Tags:        TagMap{"app": "backup"},
+		DisplayName: "backup",
+		Sink:        SecretSinkFunc(func(context.Context, ObjectsUserCredentials) error { return nil }),
+		Verify: func(ctx context.Context, credentials ObjectsUserCredentials) error {
+			attempts++
+			return errors.New("access denied")
+		},
+	}
+	_, err := RotateObjectsUser(ctx, client, rotation, fastRetry...)
+	if err == nil || !strings.Contains(err.Error(), "access denied") || attempts != 3 {
+		t.Errorf("expected verification to fail after 3 attempts, got %d: %v", attempts, err)
+	}
+	if _, ok := api.users["u1"]; !ok || len(api.requests) != 1 {
+		t.Errorf("expected the older generation to be left alone, got %v", api.requests)
+	}
+}
+
+func TestRetireObjectsUsers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	api := newObjectsUsersAPI(generationUser("u1", "1", nil), generationUser("u2", "2", nil))
+	result, err := RetireObjectsUsers(ctx, client, ObjectsUserRotation{Tags: TagMap{"app": "backup"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Retired) != 1 || result.Retired[0].ID != "u1" || !reflect.DeepEqual(api.requests, []string{"DELETE u1"}) {
+		t.Errorf("expected all but the newest generation to be deleted without a grace period, got %v", api.requests)
+	}
+
+	if _, err := RetireObjectsUsers(ctx, client, ObjectsUserRotation{}); err == nil {
+		t.Error("expected a rotation without tags to fail")
+	}
+}
+
+func TestFileSecretSink(t *testing.T) {
+	dir := t.TempDir()
+	credentials := ObjectsUserCredentials{ObjectsUserKey: ObjectsUserKey{AccessKey: "AK", SecretKey: "SK"}, UserID: "u1", Generation: 2}
+
+	envFile := filepath.Join(dir, "s3.env")
+	if err := (FileSecretSink{Path: envFile, Template: EnvSecretTemplate}).StoreCredentials(ctx, credentials); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(envFile)
+	if string(content) != "AWS_ACCESS_KEY_ID=AK\nAWS_SECRET_ACCESS_KEY=SK\n" {
+		t.Errorf("unexpected env file %q", content)
+	}
+	if info, _ := os.Stat(envFile); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file to be readable only by its owner, got %v", info.Mode())
+	}
+
+	jsonFile := filepath.Join(dir, "s3.json")
+	if err := (FileSecretSink{Path: jsonFile}).StoreCredentials(ctx, credentials); err != nil {
+		t.Fatal(err)
+	}
+	var decoded ObjectsUserCredentials
+	content, _ = os.ReadFile(jsonFile)
+	if err := json.Unmarshal(content, &decoded); err != nil || decoded != credentials {
+		t.Errorf("unexpected JSON file %s: %v", content, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files to be left, got %v", entries)
+	}
+}
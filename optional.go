@@ -0,0 +1,184 @@
+package cloudscale
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Update requests use Optional and Nullable for fields whose zero value or
+// null is meaningful, and which therefore cannot be told apart from unset
+// fields with omitempty. Both are omitted from requests while unset, which
+// is their zero value, through the omitzero option of their JSON tags.
+//
+// Other fields keep their pointer types, which already tell unset apart from
+// any value: the Tags of TaggedResourceRequest, where null has no meaning of
+// its own, and the fields of requests only used to create resources, where
+// unset fields get the defaults of the API. The DNSServers of
+// SubnetCreateRequest are the exception, as null has a meaning there.
+// Changing the others would break callers without making any new request
+// expressible.
+
+// OptionalField is implemented by pointers to Optional and Nullable, so that
+// tools can set request fields whose value type they only know at run time,
+// e.g. from command-line flags.
+type OptionalField interface {
+	IsSet() bool
+	// ValueType returns the type of the value.
+	ValueType() reflect.Type
+	// SetValue sets the field to value, which must be of the value type.
+	SetValue(value any)
+}
+
+// NullableField is implemented by pointers to Nullable.
+type NullableField interface {
+	OptionalField
+	SetNull()
+}
+
+// Optional is a request field that is either unset and omitted, or set to a
+// value that is sent even if it is the zero value of T, e.g. false or an
+// empty list.
+type Optional[T any] struct {
+	value T
+	set   bool
+}
+
+// NewOptional returns an Optional set to value.
+func NewOptional[T any](value T) Optional[T] {
+	return Optional[T]{value: value, set: true}
+}
+
+// Get returns the value and whether it is set.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.set
+}
+
+// IsSet returns whether the value is set.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// IsZero returns whether the value is unset, for the omitzero option of JSON
+// tags.
+func (o Optional[T]) IsZero() bool {
+	return !o.set
+}
+
+// ValueType returns the type of the value.
+func (o Optional[T]) ValueType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// SetValue sets the value, which must be a T.
+func (o *Optional[T]) SetValue(value any) {
+	*o = NewOptional(value.(T))
+}
+
+// MarshalJSON encodes the value. Nil slices and maps are sent as empty ones,
+// as an Optional set to them would otherwise clear the field like null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	return marshalValue(o.value)
+}
+
+func marshalValue[T any](value T) ([]byte, error) {
+	switch v := reflect.ValueOf(value); {
+	case v.Kind() == reflect.Slice && v.IsNil():
+		return []byte("[]"), nil
+	case v.Kind() == reflect.Map && v.IsNil():
+		return []byte("{}"), nil
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalJSON sets the value. A null leaves it unset.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	*o = Optional[T]{}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(data, &o.value); err != nil {
+		return err
+	}
+	o.set = true
+	return nil
+}
+
+// Nullable is a request field that is either unset and omitted, set to null
+// to clear it, or set to a value, e.g. the server of a floating IP, which is
+// unassigned by setting it to null.
+type Nullable[T any] struct {
+	value T
+	set   bool
+	null  bool
+}
+
+// NewNullable returns a Nullable set to value.
+func NewNullable[T any](value T) Nullable[T] {
+	return Nullable[T]{value: value, set: true}
+}
+
+// Null returns a Nullable set to null.
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{set: true, null: true}
+}
+
+// Get returns the value and whether it is set to a value, as opposed to
+// unset or null.
+func (n Nullable[T]) Get() (T, bool) {
+	return n.value, n.set && !n.null
+}
+
+// IsSet returns whether the field is set, to a value or to null.
+func (n Nullable[T]) IsSet() bool {
+	return n.set
+}
+
+// IsNull returns whether the field is set to null.
+func (n Nullable[T]) IsNull() bool {
+	return n.null
+}
+
+// IsZero returns whether the field is unset, for the omitzero option of JSON
+// tags.
+func (n Nullable[T]) IsZero() bool {
+	return !n.set
+}
+
+// ValueType returns the type of the value.
+func (n Nullable[T]) ValueType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// SetValue sets the field to value, which must be a T.
+func (n *Nullable[T]) SetValue(value any) {
+	*n = NewNullable(value.(T))
+}
+
+// SetNull sets the field to null.
+func (n *Nullable[T]) SetNull() {
+	*n = Null[T]()
+}
+
+// MarshalJSON encodes the value or null. Like for Optional, nil slices and
+// maps are sent as empty ones; only Null is sent as null.
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if n.null {
+		return []byte("null"), nil
+	}
+	return marshalValue(n.value)
+}
+
+// UnmarshalJSON sets the field to the value or to null.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*n = Null[T]()
+		return nil
+	}
+	*n = Nullable[T]{}
+	if err := json.Unmarshal(data, &n.value); err != nil {
+		return err
+	}
+	n.set = true
+	return nil
+}
@@ -0,0 +1,143 @@
+package cloudscale
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestOptional_Marshal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		request  LoadBalancerListenerRequest
+		expected string
+	}{
+		{"unset", LoadBalancerListenerRequest{}, `{}`},
+		{"empty", LoadBalancerListenerRequest{AllowedCIDRs: NewOptional([]string{})}, `{"allowed_cidrs":[]}`},
+		{"nil", LoadBalancerListenerRequest{AllowedCIDRs: NewOptional([]string(nil))}, `{"allowed_cidrs":[]}`},
+		{"value", LoadBalancerListenerRequest{AllowedCIDRs: NewOptional([]string{"10.0.0.0/8"})}, `{"allowed_cidrs":["10.0.0.0/8"]}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, data)
+			}
+		})
+	}
+
+	data, _ := json.Marshal(LoadBalancerPoolMemberRequest{Enabled: NewOptional(false)})
+	if string(data) != `{"enabled":false}` {
+		t.Errorf("expected false to be sent, got %s", data)
+	}
+
+	data, _ = json.Marshal(VolumeUpdateRequest{ServerUUIDs: NewOptional[[]string](nil)})
+	if string(data) != `{"server_uuids":[]}` {
+		t.Errorf("expected a nil slice to detach all servers, got %s", data)
+	}
+	data, _ = json.Marshal(NewOptional[map[string]string](nil))
+	if string(data) != `{}` {
+		t.Errorf("expected a nil map to be sent as an empty object, got %s", data)
+	}
+}
+
+func TestOptionalField(t *testing.T) {
+	var request SubnetUpdateRequest
+	var field OptionalField = &request.DNSServers
+	if field.IsSet() || field.ValueType() != reflect.TypeFor[[]string]() {
+		t.Errorf("expected an unset []string field, got %+v", request.DNSServers)
+	}
+	field.SetValue([]string{"10.0.0.1"})
+	if value, ok := request.DNSServers.Get(); !ok || !reflect.DeepEqual(value, []string{"10.0.0.1"}) {
+		t.Errorf("expected the value to be set, got %+v", request.DNSServers)
+	}
+	field.(NullableField).SetNull()
+	if !request.DNSServers.IsNull() {
+		t.Errorf("expected the field to be null, got %+v", request.DNSServers)
+	}
+
+	if _, ok := any(&request.TaggedResourceRequest).(OptionalField); ok {
+		t.Error("expected other fields not to be optional")
+	}
+	if _, ok := any(new(Optional[bool])).(NullableField); ok {
+		t.Error("expected Optional not to be nullable")
+	}
+}
+
+func TestNullable_Marshal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		request  FloatingIPUpdateRequest
+		expected string
+	}{
+		{"unset", FloatingIPUpdateRequest{}, `{}`},
+		{"null", FloatingIPUpdateRequest{Server: Null[string]()}, `{"server":null}`},
+		{"empty", FloatingIPUpdateRequest{ReversePointer: NewNullable("")}, `{"reverse_ptr":""}`},
+		{"value", FloatingIPUpdateRequest{Server: NewNullable("s1")}, `{"server":"s1"}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestOptionalAndNullable_Unmarshal(t *testing.T) {
+	var request FloatingIPUpdateRequest
+	if err := json.Unmarshal([]byte(`{"server": null, "reverse_ptr": "www.example.com"}`), &request); err != nil {
+		t.Fatal(err)
+	}
+	if !request.Server.IsNull() || !request.Server.IsSet() {
+		t.Errorf("expected the server to be null, got %+v", request.Server)
+	}
+	if value, ok := request.ReversePointer.Get(); !ok || value != "www.example.com" {
+		t.Errorf("expected the reverse pointer to be set, got %+v", request.ReversePointer)
+	}
+	if request.LoadBalancer.IsSet() {
+		t.Errorf("expected the missing load balancer to be unset, got %+v", request.LoadBalancer)
+	}
+
+	var member LoadBalancerPoolMemberRequest
+	if err := json.Unmarshal([]byte(`{"enabled": false}`), &member); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, ok := member.Enabled.Get(); !ok || enabled {
+		t.Errorf("expected enabled to be set to false, got %+v", member.Enabled)
+	}
+	if err := json.Unmarshal([]byte(`{"enabled": null}`), &member); err != nil || member.Enabled.IsSet() {
+		t.Errorf("expected null to leave enabled unset, got %+v, %v", member.Enabled, err)
+	}
+}
+
+func TestFloatingIPs_Unassign(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/floating-ips/192.0.2.123", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodPatch)
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode json: %v", err)
+		}
+		if fmt.Sprint(body) != "map[server:<nil>]" {
+			t.Errorf("expected only a null server, got %v", body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.FloatingIPs.Update(ctx, "192.0.2.123", &FloatingIPUpdateRequest{Server: Null[string]()})
+	if err != nil {
+		t.Errorf("FloatingIPs.Update returned error: %v", err)
+	}
+}
@@ -0,0 +1,1335 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// current holds the resources listed by Build.
+type current struct {
+	networks      []cloudscale.Network
+	subnets       []cloudscale.Subnet
+	serverGroups  []cloudscale.ServerGroup
+	servers       []cloudscale.Server
+	volumes       []cloudscale.Volume
+	loadBalancers []cloudscale.LoadBalancer
+	floatingIPs   []cloudscale.FloatingIP
+
+	loadBalancerPools          []cloudscale.LoadBalancerPool
+	loadBalancerPoolMembers    map[string][]cloudscale.LoadBalancerPoolMember
+	loadBalancerHealthMonitors []cloudscale.LoadBalancerHealthMonitor
+	loadBalancerListeners      []cloudscale.LoadBalancerListener
+}
+
+type builder struct {
+	client  *cloudscale.Client
+	desired *State
+	options Options
+	current current
+
+	refs     refs
+	declared map[Kind]map[string]bool
+	// matched holds the IDs of existing resources that are declared.
+	matched map[Kind]map[string]bool
+	// names maps the UUIDs of existing servers and load balancers to their
+	// names, for display.
+	names refs
+
+	plan *Plan
+	errs []error
+}
+
+// Build compares the desired state to the resources of the client's project
+// and returns the plan to reconcile them. It fails if the desired state is
+// invalid, refers to resources that neither exist nor are declared, or if a
+// declared name matches more than one existing resource.
+func Build(ctx context.Context, client *cloudscale.Client, desired *State, options Options) (*Plan, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+	if options.OwnerTag == "" {
+		options.OwnerTag = defaultOwnerTag
+	}
+	if options.NameTag == "" {
+		options.NameTag = defaultNameTag
+	}
+
+	b := &builder{
+		client:   client,
+		desired:  desired,
+		options:  options,
+		refs:     refs{},
+		declared: map[Kind]map[string]bool{},
+		matched:  map[Kind]map[string]bool{},
+		names:    refs{},
+		plan:     &Plan{},
+	}
+	if err := b.read(ctx); err != nil {
+		return nil, err
+	}
+	b.index()
+
+	b.planNetworks()
+	b.planSubnets()
+	b.planServerGroups()
+	b.planServers()
+	b.planVolumes()
+	b.planLoadBalancers()
+	b.planLoadBalancerPools()
+	b.planLoadBalancerPoolMembers()
+	b.planLoadBalancerHealthMonitors()
+	b.planLoadBalancerListeners()
+	b.planFloatingIPs()
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+
+	if options.Prune && options.Owner != "" {
+		b.planDeletions()
+	}
+	return b.plan, nil
+}
+
+func (b *builder) read(ctx context.Context) error {
+	var err error
+	c := &b.current
+	if c.networks, err = b.client.Networks.List(ctx); err != nil {
+		return fmt.Errorf("listing networks: %w", err)
+	}
+	if c.subnets, err = b.client.Subnets.List(ctx); err != nil {
+		return fmt.Errorf("listing subnets: %w", err)
+	}
+	if c.serverGroups, err = b.client.ServerGroups.List(ctx); err != nil {
+		return fmt.Errorf("listing server groups: %w", err)
+	}
+	if c.servers, err = b.client.Servers.List(ctx); err != nil {
+		return fmt.Errorf("listing servers: %w", err)
+	}
+	if c.volumes, err = b.client.Volumes.List(ctx); err != nil {
+		return fmt.Errorf("listing volumes: %w", err)
+	}
+	if c.loadBalancers, err = b.client.LoadBalancers.List(ctx); err != nil {
+		return fmt.Errorf("listing load balancers: %w", err)
+	}
+	if c.floatingIPs, err = b.client.FloatingIPs.List(ctx); err != nil {
+		return fmt.Errorf("listing floating IPs: %w", err)
+	}
+	if c.loadBalancerPools, err = b.client.LoadBalancerPools.List(ctx); err != nil {
+		return fmt.Errorf("listing load balancer pools: %w", err)
+	}
+	c.loadBalancerPoolMembers = map[string][]cloudscale.LoadBalancerPoolMember{}
+	for _, pool := range c.loadBalancerPools {
+		if c.loadBalancerPoolMembers[pool.UUID], err = b.client.LoadBalancerPoolMembers.List(ctx, pool.UUID); err != nil {
+			return fmt.Errorf("listing members of load balancer pool %s: %w", pool.UUID, err)
+		}
+	}
+	if c.loadBalancerHealthMonitors, err = b.client.LoadBalancerHealthMonitors.List(ctx); err != nil {
+		return fmt.Errorf("listing load balancer health monitors: %w", err)
+	}
+	if c.loadBalancerListeners, err = b.client.LoadBalancerListeners.List(ctx); err != nil {
+		return fmt.Errorf("listing load balancer listeners: %w", err)
+	}
+	return nil
+}
+
+// index records the declared names and the UUIDs of existing resources with
+// unique names, so that references to them can be resolved.
+func (b *builder) index() {
+	declare := func(kind Kind, name string) {
+		if b.declared[kind] == nil {
+			b.declared[kind] = map[string]bool{}
+		}
+		b.declared[kind][name] = true
+	}
+	for _, n := range b.desired.Networks {
+		declare(Networks, n.Name)
+	}
+	for _, s := range b.desired.Subnets {
+		declare(Subnets, s.Name)
+	}
+	for _, g := range b.desired.ServerGroups {
+		declare(ServerGroups, g.Name)
+	}
+	for _, s := range b.desired.Servers {
+		declare(Servers, s.Name)
+	}
+	for _, v := range b.desired.Volumes {
+		declare(Volumes, v.Name)
+	}
+	for _, l := range b.desired.LoadBalancers {
+		declare(LoadBalancers, l.Name)
+	}
+
+	indexUnique(b.refs, Networks, b.current.networks, func(n *cloudscale.Network) (string, string) { return n.Name, n.UUID })
+	indexUnique(b.refs, Subnets, b.current.subnets, func(s *cloudscale.Subnet) (string, string) { return s.CIDR, s.UUID })
+	indexUnique(b.refs, ServerGroups, b.current.serverGroups, func(g *cloudscale.ServerGroup) (string, string) { return g.Name, g.UUID })
+	indexUnique(b.refs, Servers, b.current.servers, func(s *cloudscale.Server) (string, string) { return s.Name, s.UUID })
+	indexUnique(b.refs, LoadBalancers, b.current.loadBalancers, func(l *cloudscale.LoadBalancer) (string, string) { return l.Name, l.UUID })
+
+	for _, server := range b.current.servers {
+		b.names.set(Servers, server.UUID, server.Name)
+	}
+	for _, loadBalancer := range b.current.loadBalancers {
+		b.names.set(LoadBalancers, loadBalancer.UUID, loadBalancer.Name)
+	}
+}
+
+// nameOf returns the name of an existing server or load balancer, or id if
+// it is unknown.
+func (b *builder) nameOf(kind Kind, id string) string {
+	if name, ok := b.names.lookup(kind, id); ok {
+		return name
+	}
+	return id
+}
+
+func indexUnique[TResource any](r refs, kind Kind, resources []TResource, describe func(resource *TResource) (string, string)) {
+	count := map[string]int{}
+	for i := range resources {
+		name, _ := describe(&resources[i])
+		count[name]++
+	}
+	for i := range resources {
+		if name, id := describe(&resources[i]); count[name] == 1 {
+			r.set(kind, name, id)
+		}
+	}
+}
+
+// find returns the existing resource with the given name, or nil.
+func find[TResource any](b *builder, kind Kind, name string, resources []TResource, matches func(resource *TResource) bool) *TResource {
+	var found *TResource
+	for i := range resources {
+		if !matches(&resources[i]) {
+			continue
+		}
+		if found != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s %q: %w", kind, name, errAmbiguous))
+			return nil
+		}
+		found = &resources[i]
+	}
+	return found
+}
+
+// checkRef records an error unless name refers to a declared or existing
+// resource of the given kind.
+func (b *builder) checkRef(from Kind, fromName string, kind Kind, name string) {
+	if b.declared[kind][name] {
+		return
+	}
+	if _, ok := b.refs.lookup(kind, name); ok {
+		return
+	}
+	b.errs = append(b.errs, fmt.Errorf("%s %q: unknown %s %q", from, fromName, kind, name))
+}
+
+// adopt records an error and returns false if an existing resource matched
+// by name may not be managed by the plan: with an Owner, it must be tagged
+// with it unless Options.Adopt is set.
+func (b *builder) adopt(kind Kind, name string, tags cloudscale.TagMap) bool {
+	if b.options.Owner == "" || b.options.Adopt || tags[b.options.OwnerTag] == b.options.Owner {
+		return true
+	}
+	b.errs = append(b.errs, fmt.Errorf("%s %q: %w", kind, name, errNotOwned))
+	return false
+}
+
+func (b *builder) match(kind Kind, id string) {
+	if b.matched[kind] == nil {
+		b.matched[kind] = map[string]bool{}
+	}
+	b.matched[kind][id] = true
+}
+
+func (b *builder) change(action Action, kind Kind, name, id string, d diffs, apply func(ctx context.Context) error) {
+	b.plan.Changes = append(b.plan.Changes, Change{Action: action, Kind: kind, Name: name, ID: id, Diffs: d, apply: apply})
+}
+
+func (b *builder) warn(kind Kind, name string, format string, args ...any) {
+	b.plan.Warnings = append(b.plan.Warnings, fmt.Sprintf("%s %q: ", kind, name)+fmt.Sprintf(format, args...))
+}
+
+// tags returns the complete tags of a declared resource.
+func (b *builder) tags(declared cloudscale.TagMap) cloudscale.TagMap {
+	tags := maps.Clone(declared)
+	if tags == nil {
+		tags = cloudscale.TagMap{}
+	}
+	if b.options.Owner != "" {
+		tags[b.options.OwnerTag] = b.options.Owner
+	}
+	return tags
+}
+
+func diffTags(d *diffs, request *cloudscale.TaggedResourceRequest, current, desired cloudscale.TagMap) {
+	if !maps.Equal(current, desired) {
+		d.add("tags", current, desired)
+		request.Tags = &desired
+	}
+}
+
+func (b *builder) planNetworks() {
+	for _, n := range b.desired.Networks {
+		tags := b.tags(n.Tags)
+		current := find(b, Networks, n.Name, b.current.networks, func(c *cloudscale.Network) bool { return c.Name == n.Name })
+
+		if current != nil && !b.adopt(Networks, n.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("zone", "", n.Zone)
+			d.add("mtu", 0, n.MTU)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.NetworkCreateRequest{Name: n.Name, MTU: n.MTU, AutoCreateIPV4Subnet: new(bool)}
+			request.Zone = n.Zone
+			request.Tags = &tags
+			b.change(Create, Networks, n.Name, "", d, func(ctx context.Context) error {
+				created, err := b.client.Networks.Create(ctx, request)
+				if err != nil {
+					return err
+				}
+				b.refs.set(Networks, n.Name, created.UUID)
+				return nil
+			})
+			continue
+		}
+
+		b.match(Networks, current.UUID)
+		if current.Zone.Slug != n.Zone {
+			b.warn(Networks, n.Name, "zone cannot be changed from %q to %q", current.Zone.Slug, n.Zone)
+		}
+		d := diffs{}
+		request := &cloudscale.NetworkUpdateRequest{}
+		if n.MTU != 0 && n.MTU != current.MTU {
+			d.add("mtu", current.MTU, n.MTU)
+			request.MTU = n.MTU
+		}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, Networks, n.Name, id, d, func(ctx context.Context) error {
+				return b.client.Networks.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+func (b *builder) planSubnets() {
+	for _, s := range b.desired.Subnets {
+		b.checkRef(Subnets, s.Name, Networks, s.Network)
+		tags := b.tags(s.Tags)
+
+		var current *cloudscale.Subnet
+		if networkID, ok := b.refs.lookup(Networks, s.Network); ok {
+			current = find(b, Subnets, s.Name, b.current.subnets, func(c *cloudscale.Subnet) bool {
+				return c.Network.UUID == networkID && c.CIDR == s.CIDR
+			})
+		}
+
+		if current != nil && !b.adopt(Subnets, s.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("network", "", s.Network)
+			d.add("cidr", "", s.CIDR)
+			d.add("gateway_address", "", s.GatewayAddress)
+			d.add("dns_servers", []string(nil), s.DNSServers)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.SubnetCreateRequest{CIDR: s.CIDR, GatewayAddress: s.GatewayAddress}
+			if s.DNSServers != nil {
+				request.DNSServers = cloudscale.NewNullable(s.DNSServers)
+			}
+			request.Tags = &tags
+			b.change(Create, Subnets, s.Name, "", d, func(ctx context.Context) error {
+				networkID, err := b.refs.get(Networks, s.Network)
+				if err != nil {
+					return err
+				}
+				request.Network = networkID
+				created, err := b.client.Subnets.Create(ctx, request)
+				if err != nil {
+					return err
+				}
+				b.refs.set(Subnets, s.Name, created.UUID)
+				return nil
+			})
+			continue
+		}
+
+		b.match(Subnets, current.UUID)
+		b.refs.set(Subnets, s.Name, current.UUID)
+		d := diffs{}
+		request := &cloudscale.SubnetUpdateRequest{}
+		if s.GatewayAddress != "" && s.GatewayAddress != current.GatewayAddress {
+			d.add("gateway_address", current.GatewayAddress, s.GatewayAddress)
+			request.GatewayAddress = s.GatewayAddress
+		}
+		if s.DNSServers != nil && !slices.Equal(s.DNSServers, current.DNSServers) {
+			d.add("dns_servers", current.DNSServers, s.DNSServers)
+			request.DNSServers = cloudscale.NewNullable(s.DNSServers)
+		}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, Subnets, s.Name, id, d, func(ctx context.Context) error {
+				return b.client.Subnets.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+func (b *builder) planServerGroups() {
+	for _, g := range b.desired.ServerGroups {
+		tags := b.tags(g.Tags)
+		groupType := g.Type
+		if groupType == "" {
+			groupType = cloudscale.ServerGroupTypeAntiAffinity
+		}
+		current := find(b, ServerGroups, g.Name, b.current.serverGroups, func(c *cloudscale.ServerGroup) bool { return c.Name == g.Name })
+
+		if current != nil && !b.adopt(ServerGroups, g.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("zone", "", g.Zone)
+			d.add("type", "", groupType)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.ServerGroupRequest{Name: g.Name, Type: groupType}
+			request.Zone = g.Zone
+			request.Tags = &tags
+			b.change(Create, ServerGroups, g.Name, "", d, func(ctx context.Context) error {
+				created, err := b.client.ServerGroups.Create(ctx, request)
+				if err != nil {
+					return err
+				}
+				b.refs.set(ServerGroups, g.Name, created.UUID)
+				return nil
+			})
+			continue
+		}
+
+		b.match(ServerGroups, current.UUID)
+		if current.Zone.Slug != g.Zone {
+			b.warn(ServerGroups, g.Name, "zone cannot be changed from %q to %q", current.Zone.Slug, g.Zone)
+		}
+		if current.Type != groupType {
+			b.warn(ServerGroups, g.Name, "type cannot be changed from %q to %q", current.Type, groupType)
+		}
+		d := diffs{}
+		request := &cloudscale.ServerGroupRequest{}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, ServerGroups, g.Name, id, d, func(ctx context.Context) error {
+				return b.client.ServerGroups.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+func (b *builder) planServers() {
+	for _, v := range b.desired.Servers {
+		for _, group := range v.ServerGroups {
+			b.checkRef(Servers, v.Name, ServerGroups, group)
+		}
+		for _, i := range v.Interfaces {
+			if i.Network != PublicNetwork {
+				b.checkRef(Servers, v.Name, Networks, i.Network)
+			}
+			if i.Subnet != "" {
+				b.checkRef(Servers, v.Name, Subnets, i.Subnet)
+			}
+		}
+		tags := b.tags(v.Tags)
+		current := find(b, Servers, v.Name, b.current.servers, func(c *cloudscale.Server) bool { return c.Name == v.Name })
+
+		if current != nil && !b.adopt(Servers, v.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("zone", "", v.Zone)
+			d.add("flavor", "", v.Flavor)
+			d.add("image", "", v.Image)
+			d.add("volume_size_gb", 0, v.VolumeSizeGB)
+			d.add("interfaces", []string(nil), describeInterfaces(v.Interfaces))
+			d.add("server_groups", []string(nil), v.ServerGroups)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.ServerRequest{
+				Name:         v.Name,
+				Flavor:       v.Flavor,
+				Image:        v.Image,
+				Zone:         v.Zone,
+				VolumeSizeGB: v.VolumeSizeGB,
+				SSHKeys:      v.SSHKeys,
+				UserData:     v.UserData,
+			}
+			request.Tags = &tags
+			b.change(Create, Servers, v.Name, "", d, func(ctx context.Context) error {
+				groups, err := b.refs.getAll(ServerGroups, v.ServerGroups)
+				if err != nil {
+					return err
+				}
+				request.ServerGroups = groups
+				if len(v.Interfaces) > 0 {
+					interfaces, err := b.interfaceRequests(v.Interfaces)
+					if err != nil {
+						return err
+					}
+					request.Interfaces = &interfaces
+				}
+
+				created, err := b.client.Servers.Create(ctx, request)
+				if err != nil {
+					return err
+				}
+				b.refs.set(Servers, v.Name, created.UUID)
+				_, err = b.client.Servers.WaitFor(ctx, created.UUID, cloudscale.ServerIsRunning, b.options.WaitOptions...)
+				return err
+			})
+			continue
+		}
+
+		b.match(Servers, current.UUID)
+		if current.Zone.Slug != v.Zone {
+			b.warn(Servers, v.Name, "zone cannot be changed from %q to %q", current.Zone.Slug, v.Zone)
+		}
+		if current.Image.Slug != v.Image {
+			b.warn(Servers, v.Name, "image cannot be changed from %q to %q", current.Image.Slug, v.Image)
+		}
+		currentGroups := []string{}
+		for _, group := range current.ServerGroups {
+			currentGroups = append(currentGroups, group.Name)
+		}
+		if !slices.Equal(sortedCopy(currentGroups), sortedCopy(v.ServerGroups)) {
+			b.warn(Servers, v.Name, "server groups cannot be changed from %s to %s", show(currentGroups), show(v.ServerGroups))
+		}
+
+		d := diffs{}
+		request := &cloudscale.ServerUpdateRequest{}
+		if current.Flavor.Slug != v.Flavor {
+			d.add("flavor", current.Flavor.Slug, v.Flavor)
+			request.Flavor = v.Flavor
+		}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, Servers, v.Name, id, d, func(ctx context.Context) error {
+				if request.Flavor == "" {
+					return b.client.Servers.Update(ctx, id, request)
+				}
+				return b.resizeServer(ctx, id, request)
+			})
+		}
+	}
+}
+
+// resizeServer applies a flavor change, which requires the server to be
+// stopped. A running server is started again afterwards.
+func (b *builder) resizeServer(ctx context.Context, id string, request *cloudscale.ServerUpdateRequest) error {
+	server, err := b.client.Servers.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	running := server.Status == cloudscale.ServerRunning
+	if running {
+		if err := b.client.Servers.Stop(ctx, id); err != nil {
+			return err
+		}
+		if _, err := b.client.Servers.WaitFor(ctx, id, cloudscale.ServerIsStopped, b.options.WaitOptions...); err != nil {
+			return err
+		}
+	}
+	if err := b.client.Servers.Update(ctx, id, request); err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+	if err := b.client.Servers.Start(ctx, id); err != nil {
+		return err
+	}
+	_, err = b.client.Servers.WaitFor(ctx, id, cloudscale.ServerIsRunning, b.options.WaitOptions...)
+	return err
+}
+
+func (b *builder) interfaceRequests(interfaces []Interface) ([]cloudscale.InterfaceRequest, error) {
+	requests := make([]cloudscale.InterfaceRequest, 0, len(interfaces))
+	for _, i := range interfaces {
+		if i.Network == PublicNetwork {
+			requests = append(requests, cloudscale.InterfaceRequest{Network: PublicNetwork})
+			continue
+		}
+		networkID, err := b.refs.get(Networks, i.Network)
+		if err != nil {
+			return nil, err
+		}
+		request := cloudscale.InterfaceRequest{Network: networkID}
+		if i.Subnet != "" || i.Address != "" {
+			address := cloudscale.AddressRequest{Address: i.Address}
+			if i.Subnet != "" {
+				if address.Subnet, err = b.refs.get(Subnets, i.Subnet); err != nil {
+					return nil, err
+				}
+			}
+			request.Addresses = &[]cloudscale.AddressRequest{address}
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func describeInterfaces(interfaces []Interface) []string {
+	described := make([]string, 0, len(interfaces))
+	for _, i := range interfaces {
+		description := i.Network
+		if i.Subnet != "" {
+			description += "/" + i.Subnet
+		}
+		if i.Address != "" {
+			description += " " + i.Address
+		}
+		described = append(described, description)
+	}
+	return described
+}
+
+func (b *builder) planVolumes() {
+	for _, v := range b.desired.Volumes {
+		for _, server := range v.Servers {
+			b.checkRef(Volumes, v.Name, Servers, server)
+		}
+		tags := b.tags(v.Tags)
+		current := find(b, Volumes, v.Name, b.current.volumes, func(c *cloudscale.Volume) bool { return c.Name == v.Name })
+
+		if current != nil && !b.adopt(Volumes, v.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("zone", "", v.Zone)
+			d.add("size_gb", 0, v.SizeGB)
+			d.add("type", "", v.Type)
+			d.add("servers", []string(nil), v.Servers)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.VolumeCreateRequest{Name: v.Name, SizeGB: v.SizeGB, Type: v.Type}
+			request.Zone = v.Zone
+			request.Tags = &tags
+			b.change(Create, Volumes, v.Name, "", d, func(ctx context.Context) error {
+				if len(v.Servers) > 0 {
+					serverIDs, err := b.refs.getAll(Servers, v.Servers)
+					if err != nil {
+						return err
+					}
+					request.ServerUUIDs = &serverIDs
+				}
+				_, err := b.client.Volumes.Create(ctx, request)
+				return err
+			})
+			continue
+		}
+
+		b.match(Volumes, current.UUID)
+		if current.Zone.Slug != v.Zone {
+			b.warn(Volumes, v.Name, "zone cannot be changed from %q to %q", current.Zone.Slug, v.Zone)
+		}
+		if v.Type != "" && current.Type != v.Type {
+			b.warn(Volumes, v.Name, "type cannot be changed from %q to %q", current.Type, v.Type)
+		}
+
+		d := diffs{}
+		request := &cloudscale.VolumeUpdateRequest{}
+		switch {
+		case v.SizeGB > current.SizeGB:
+			d.add("size_gb", current.SizeGB, v.SizeGB)
+			request.SizeGB = v.SizeGB
+		case v.SizeGB < current.SizeGB:
+			b.warn(Volumes, v.Name, "size cannot be reduced from %d to %d GB", current.SizeGB, v.SizeGB)
+		}
+
+		currentServers := []string{}
+		if current.ServerUUIDs != nil {
+			for _, id := range *current.ServerUUIDs {
+				currentServers = append(currentServers, b.nameOf(Servers, id))
+			}
+		}
+		attach := !slices.Equal(sortedCopy(currentServers), sortedCopy(v.Servers))
+		if attach {
+			d.add("servers", currentServers, v.Servers)
+		}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, Volumes, v.Name, id, d, func(ctx context.Context) error {
+				if attach {
+					serverIDs, err := b.refs.getAll(Servers, v.Servers)
+					if err != nil {
+						return err
+					}
+					request.ServerUUIDs = cloudscale.NewOptional(serverIDs)
+				}
+				return b.client.Volumes.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+func (b *builder) planLoadBalancers() {
+	for _, l := range b.desired.LoadBalancers {
+		if l.VIPSubnet != "" {
+			b.checkRef(LoadBalancers, l.Name, Subnets, l.VIPSubnet)
+		}
+		tags := b.tags(l.Tags)
+		current := find(b, LoadBalancers, l.Name, b.current.loadBalancers, func(c *cloudscale.LoadBalancer) bool { return c.Name == l.Name })
+
+		if current != nil && !b.adopt(LoadBalancers, l.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			d := diffs{}
+			d.add("zone", "", l.Zone)
+			d.add("flavor", "", l.Flavor)
+			d.add("vip_subnet", "", l.VIPSubnet)
+			d.add("vip_address", "", l.VIPAddress)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.LoadBalancerRequest{Name: l.Name, Flavor: l.Flavor}
+			request.Zone = l.Zone
+			request.Tags = &tags
+			b.change(Create, LoadBalancers, l.Name, "", d, func(ctx context.Context) error {
+				if l.VIPSubnet != "" {
+					subnetID, err := b.refs.get(Subnets, l.VIPSubnet)
+					if err != nil {
+						return err
+					}
+					request.VIPAddresses = &[]cloudscale.VIPAddressRequest{{Subnet: subnetID, Address: l.VIPAddress}}
+				}
+				created, err := b.client.LoadBalancers.Create(ctx, request)
+				if err != nil {
+					return err
+				}
+				b.refs.set(LoadBalancers, l.Name, created.UUID)
+				_, err = b.client.LoadBalancers.WaitFor(ctx, created.UUID, cloudscale.LoadBalancerIsRunning, b.options.WaitOptions...)
+				return err
+			})
+			continue
+		}
+
+		b.match(LoadBalancers, current.UUID)
+		if current.Zone.Slug != l.Zone {
+			b.warn(LoadBalancers, l.Name, "zone cannot be changed from %q to %q", current.Zone.Slug, l.Zone)
+		}
+		if current.Flavor.Slug != l.Flavor {
+			b.warn(LoadBalancers, l.Name, "flavor cannot be changed from %q to %q", current.Flavor.Slug, l.Flavor)
+		}
+		d := diffs{}
+		request := &cloudscale.LoadBalancerRequest{}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.UUID
+			b.change(Update, LoadBalancers, l.Name, id, d, func(ctx context.Context) error {
+				return b.client.LoadBalancers.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+func (b *builder) planLoadBalancerPools() {
+	for _, l := range b.desired.LoadBalancers {
+		loadBalancerID, exists := b.refs.lookup(LoadBalancers, l.Name)
+		for _, p := range l.Pools {
+			name := qualify(l.Name, p.Name)
+			tags := b.tags(p.Tags)
+			var current *cloudscale.LoadBalancerPool
+			if exists {
+				current = find(b, LoadBalancerPools, name, b.current.loadBalancerPools, func(c *cloudscale.LoadBalancerPool) bool {
+					return c.LoadBalancer.UUID == loadBalancerID && c.Name == p.Name
+				})
+			}
+			if current != nil && !b.adopt(LoadBalancerPools, name, current.Tags) {
+				continue
+			}
+
+			if current == nil {
+				d := diffs{}
+				d.add("algorithm", "", p.Algorithm)
+				d.add("protocol", "", p.Protocol)
+				d.add("tags", cloudscale.TagMap(nil), tags)
+				request := &cloudscale.LoadBalancerPoolRequest{Name: p.Name, Algorithm: p.Algorithm, Protocol: p.Protocol}
+				request.Tags = &tags
+				b.change(Create, LoadBalancerPools, name, "", d, func(ctx context.Context) error {
+					loadBalancerID, err := b.refs.get(LoadBalancers, l.Name)
+					if err != nil {
+						return err
+					}
+					request.LoadBalancer = loadBalancerID
+					created, err := b.client.LoadBalancerPools.Create(ctx, request)
+					if err != nil {
+						return err
+					}
+					b.refs.set(LoadBalancerPools, name, created.UUID)
+					return nil
+				})
+				continue
+			}
+
+			b.match(LoadBalancerPools, current.UUID)
+			b.refs.set(LoadBalancerPools, name, current.UUID)
+			if current.Algorithm != p.Algorithm {
+				b.warn(LoadBalancerPools, name, "algorithm cannot be changed from %q to %q", current.Algorithm, p.Algorithm)
+			}
+			if current.Protocol != p.Protocol {
+				b.warn(LoadBalancerPools, name, "protocol cannot be changed from %q to %q", current.Protocol, p.Protocol)
+			}
+			d := diffs{}
+			request := &cloudscale.LoadBalancerPoolRequest{}
+			diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+			if len(d) > 0 {
+				id := current.UUID
+				b.change(Update, LoadBalancerPools, name, id, d, func(ctx context.Context) error {
+					return b.client.LoadBalancerPools.Update(ctx, id, request)
+				})
+			}
+		}
+	}
+}
+
+func (b *builder) planLoadBalancerPoolMembers() {
+	for _, l := range b.desired.LoadBalancers {
+		for _, p := range l.Pools {
+			poolName := qualify(l.Name, p.Name)
+			poolID, exists := b.refs.lookup(LoadBalancerPools, poolName)
+			for _, m := range p.Members {
+				name := qualify(poolName, m.Name)
+				b.checkRef(LoadBalancerPoolMembers, name, Subnets, m.Subnet)
+				tags := b.tags(m.Tags)
+				var current *cloudscale.LoadBalancerPoolMember
+				if exists {
+					current = find(b, LoadBalancerPoolMembers, name, b.current.loadBalancerPoolMembers[poolID], func(c *cloudscale.LoadBalancerPoolMember) bool {
+						return c.Name == m.Name
+					})
+				}
+				if current != nil && !b.adopt(LoadBalancerPoolMembers, name, current.Tags) {
+					continue
+				}
+
+				if current == nil {
+					d := diffs{}
+					d.add("address", "", m.Address)
+					d.add("subnet", "", m.Subnet)
+					d.add("protocol_port", 0, m.ProtocolPort)
+					d.add("monitor_port", 0, m.MonitorPort)
+					d.add("disabled", false, m.Disabled)
+					d.add("tags", cloudscale.TagMap(nil), tags)
+					request := &cloudscale.LoadBalancerPoolMemberRequest{
+						Name:         m.Name,
+						ProtocolPort: m.ProtocolPort,
+						MonitorPort:  m.MonitorPort,
+						Address:      m.Address,
+					}
+					if m.Disabled {
+						request.Enabled = cloudscale.NewOptional(false)
+					}
+					request.Tags = &tags
+					b.change(Create, LoadBalancerPoolMembers, name, "", d, func(ctx context.Context) error {
+						poolID, err := b.refs.get(LoadBalancerPools, poolName)
+						if err != nil {
+							return err
+						}
+						if request.Subnet, err = b.refs.get(Subnets, m.Subnet); err != nil {
+							return err
+						}
+						_, err = b.client.LoadBalancerPoolMembers.Create(ctx, poolID, request)
+						return err
+					})
+					continue
+				}
+
+				b.match(LoadBalancerPoolMembers, current.UUID)
+				if current.Address != m.Address {
+					b.warn(LoadBalancerPoolMembers, name, "address cannot be changed from %q to %q", current.Address, m.Address)
+				}
+				if subnetID, ok := b.refs.lookup(Subnets, m.Subnet); !ok || current.Subnet.UUID != subnetID {
+					b.warn(LoadBalancerPoolMembers, name, "subnet cannot be changed from %q to %q", current.Subnet.CIDR, m.Subnet)
+				}
+				if current.ProtocolPort != m.ProtocolPort {
+					b.warn(LoadBalancerPoolMembers, name, "protocol port cannot be changed from %d to %d", current.ProtocolPort, m.ProtocolPort)
+				}
+				if m.MonitorPort != 0 && current.MonitorPort != m.MonitorPort {
+					b.warn(LoadBalancerPoolMembers, name, "monitor port cannot be changed from %d to %d", current.MonitorPort, m.MonitorPort)
+				}
+
+				d := diffs{}
+				request := &cloudscale.LoadBalancerPoolMemberRequest{}
+				if current.Enabled == m.Disabled {
+					d.add("disabled", !current.Enabled, m.Disabled)
+					request.Enabled = cloudscale.NewOptional(!m.Disabled)
+				}
+				diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+				if len(d) > 0 {
+					id := current.UUID
+					b.change(Update, LoadBalancerPoolMembers, name, id, d, func(ctx context.Context) error {
+						return b.client.LoadBalancerPoolMembers.Update(ctx, poolID, id, request)
+					})
+				}
+			}
+		}
+	}
+}
+
+func (b *builder) planLoadBalancerHealthMonitors() {
+	for _, l := range b.desired.LoadBalancers {
+		for _, p := range l.Pools {
+			h := p.HealthMonitor
+			if h == nil {
+				continue
+			}
+			name := qualify(l.Name, p.Name)
+			tags := b.tags(h.Tags)
+			var current *cloudscale.LoadBalancerHealthMonitor
+			if poolID, exists := b.refs.lookup(LoadBalancerPools, name); exists {
+				current = find(b, LoadBalancerHealthMonitors, name, b.current.loadBalancerHealthMonitors, func(c *cloudscale.LoadBalancerHealthMonitor) bool {
+					return c.Pool.UUID == poolID
+				})
+			}
+			if current != nil && !b.adopt(LoadBalancerHealthMonitors, name, current.Tags) {
+				continue
+			}
+
+			if current == nil {
+				d := diffs{}
+				d.add("type", "", h.Type)
+				d.add("delay_s", 0, h.DelayS)
+				d.add("timeout_s", 0, h.TimeoutS)
+				d.add("up_threshold", 0, h.UpThreshold)
+				d.add("down_threshold", 0, h.DownThreshold)
+				if h.HTTP != nil {
+					diffHealthMonitorHTTP(&d, cloudscale.LoadBalancerHealthMonitorHTTP{}, h.HTTP)
+				}
+				d.add("tags", cloudscale.TagMap(nil), tags)
+				request := &cloudscale.LoadBalancerHealthMonitorRequest{
+					Type:          h.Type,
+					DelayS:        h.DelayS,
+					TimeoutS:      h.TimeoutS,
+					UpThreshold:   h.UpThreshold,
+					DownThreshold: h.DownThreshold,
+				}
+				if h.HTTP != nil {
+					request.HTTP = healthMonitorHTTPRequest(h.HTTP)
+				}
+				request.Tags = &tags
+				b.change(Create, LoadBalancerHealthMonitors, name, "", d, func(ctx context.Context) error {
+					poolID, err := b.refs.get(LoadBalancerPools, name)
+					if err != nil {
+						return err
+					}
+					request.Pool = poolID
+					_, err = b.client.LoadBalancerHealthMonitors.Create(ctx, request)
+					return err
+				})
+				continue
+			}
+
+			b.match(LoadBalancerHealthMonitors, current.UUID)
+			if current.Type != h.Type {
+				b.warn(LoadBalancerHealthMonitors, name, "type cannot be changed from %q to %q", current.Type, h.Type)
+			}
+			d := diffs{}
+			request := &cloudscale.LoadBalancerHealthMonitorRequest{}
+			diffInt(&d, "delay_s", current.DelayS, h.DelayS, &request.DelayS)
+			diffInt(&d, "timeout_s", current.TimeoutS, h.TimeoutS, &request.TimeoutS)
+			diffInt(&d, "up_threshold", current.UpThreshold, h.UpThreshold, &request.UpThreshold)
+			diffInt(&d, "down_threshold", current.DownThreshold, h.DownThreshold, &request.DownThreshold)
+			if h.HTTP != nil {
+				currentHTTP := cloudscale.LoadBalancerHealthMonitorHTTP{}
+				if current.HTTP != nil {
+					currentHTTP = *current.HTTP
+				}
+				before := len(d)
+				diffHealthMonitorHTTP(&d, currentHTTP, h.HTTP)
+				if len(d) > before {
+					request.HTTP = healthMonitorHTTPRequest(h.HTTP)
+				}
+			}
+			diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+			if len(d) > 0 {
+				id := current.UUID
+				b.change(Update, LoadBalancerHealthMonitors, name, id, d, func(ctx context.Context) error {
+					return b.client.LoadBalancerHealthMonitors.Update(ctx, id, request)
+				})
+			}
+		}
+	}
+}
+
+// diffHealthMonitorHTTP adds the declared HTTP settings that differ from the
+// current ones. Settings that are not declared are left to the API.
+func diffHealthMonitorHTTP(d *diffs, current cloudscale.LoadBalancerHealthMonitorHTTP, desired *HealthMonitorHTTP) {
+	currentHost := ""
+	if current.Host != nil {
+		currentHost = *current.Host
+	}
+	if desired.ExpectedCodes != nil {
+		d.add("http.expected_codes", current.ExpectedCodes, desired.ExpectedCodes)
+	}
+	if desired.Method != "" {
+		d.add("http.method", current.Method, desired.Method)
+	}
+	if desired.URLPath != "" {
+		d.add("http.url_path", current.UrlPath, desired.URLPath)
+	}
+	if desired.Version != "" {
+		d.add("http.version", current.Version, desired.Version)
+	}
+	if desired.Host != "" {
+		d.add("http.host", currentHost, desired.Host)
+	}
+}
+
+func healthMonitorHTTPRequest(h *HealthMonitorHTTP) *cloudscale.LoadBalancerHealthMonitorHTTPRequest {
+	request := &cloudscale.LoadBalancerHealthMonitorHTTPRequest{
+		ExpectedCodes: h.ExpectedCodes,
+		Method:        h.Method,
+		UrlPath:       h.URLPath,
+		Version:       h.Version,
+	}
+	if h.Host != "" {
+		request.Host = cloudscale.NewNullable(h.Host)
+	}
+	return request
+}
+
+// diffInt adds a declared, non-zero setting that differs from the current
+// one and sets it in the update request.
+func diffInt(d *diffs, field string, current, desired int, request *int) {
+	if desired != 0 && desired != current {
+		d.add(field, current, desired)
+		*request = desired
+	}
+}
+
+func (b *builder) planLoadBalancerListeners() {
+	for _, l := range b.desired.LoadBalancers {
+		loadBalancerID, exists := b.refs.lookup(LoadBalancers, l.Name)
+		for _, n := range l.Listeners {
+			name := qualify(l.Name, n.Name)
+			poolName := qualify(l.Name, n.Pool)
+			tags := b.tags(n.Tags)
+			protocol := n.Protocol
+			if protocol == "" {
+				protocol = cloudscale.LoadBalancerListenerProtocolTCP
+			}
+			var current *cloudscale.LoadBalancerListener
+			if exists {
+				current = find(b, LoadBalancerListeners, name, b.current.loadBalancerListeners, func(c *cloudscale.LoadBalancerListener) bool {
+					return c.LoadBalancer.UUID == loadBalancerID && c.Name == n.Name
+				})
+			}
+			if current != nil && !b.adopt(LoadBalancerListeners, name, current.Tags) {
+				continue
+			}
+
+			if current == nil {
+				d := diffs{}
+				d.add("pool", "", n.Pool)
+				d.add("protocol", "", protocol)
+				d.add("protocol_port", 0, n.ProtocolPort)
+				d.add("allowed_cidrs", []string(nil), n.AllowedCIDRs)
+				d.add("timeout_client_data_ms", 0, n.TimeoutClientDataMS)
+				d.add("timeout_member_connect_ms", 0, n.TimeoutMemberConnectMS)
+				d.add("timeout_member_data_ms", 0, n.TimeoutMemberDataMS)
+				d.add("tags", cloudscale.TagMap(nil), tags)
+				request := &cloudscale.LoadBalancerListenerRequest{
+					Name:                   n.Name,
+					Protocol:               protocol,
+					ProtocolPort:           n.ProtocolPort,
+					TimeoutClientDataMS:    n.TimeoutClientDataMS,
+					TimeoutMemberConnectMS: n.TimeoutMemberConnectMS,
+					TimeoutMemberDataMS:    n.TimeoutMemberDataMS,
+				}
+				if n.AllowedCIDRs != nil {
+					request.AllowedCIDRs = cloudscale.NewOptional(n.AllowedCIDRs)
+				}
+				request.Tags = &tags
+				b.change(Create, LoadBalancerListeners, name, "", d, func(ctx context.Context) error {
+					poolID, err := b.refs.get(LoadBalancerPools, poolName)
+					if err != nil {
+						return err
+					}
+					request.Pool = poolID
+					_, err = b.client.LoadBalancerListeners.Create(ctx, request)
+					return err
+				})
+				continue
+			}
+
+			b.match(LoadBalancerListeners, current.UUID)
+			if current.Protocol != protocol {
+				b.warn(LoadBalancerListeners, name, "protocol cannot be changed from %q to %q", current.Protocol, protocol)
+			}
+			d := diffs{}
+			request := &cloudscale.LoadBalancerListenerRequest{}
+			currentPool, currentPoolName := "", ""
+			if current.Pool != nil {
+				currentPool, currentPoolName = current.Pool.UUID, current.Pool.Name
+			}
+			var repool func() error
+			if id, ok := b.refs.lookup(LoadBalancerPools, poolName); !ok || id != currentPool {
+				d.add("pool", currentPoolName, n.Pool)
+				repool = func() error {
+					id, err := b.refs.get(LoadBalancerPools, poolName)
+					request.Pool = id
+					return err
+				}
+			}
+			diffInt(&d, "protocol_port", current.ProtocolPort, n.ProtocolPort, &request.ProtocolPort)
+			if n.AllowedCIDRs != nil && !slices.Equal(sortedCopy(current.AllowedCIDRs), sortedCopy(n.AllowedCIDRs)) {
+				d.add("allowed_cidrs", current.AllowedCIDRs, n.AllowedCIDRs)
+				request.AllowedCIDRs = cloudscale.NewOptional(n.AllowedCIDRs)
+			}
+			diffInt(&d, "timeout_client_data_ms", current.TimeoutClientDataMS, n.TimeoutClientDataMS, &request.TimeoutClientDataMS)
+			diffInt(&d, "timeout_member_connect_ms", current.TimeoutMemberConnectMS, n.TimeoutMemberConnectMS, &request.TimeoutMemberConnectMS)
+			diffInt(&d, "timeout_member_data_ms", current.TimeoutMemberDataMS, n.TimeoutMemberDataMS, &request.TimeoutMemberDataMS)
+			diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+			if len(d) > 0 {
+				id := current.UUID
+				b.change(Update, LoadBalancerListeners, name, id, d, func(ctx context.Context) error {
+					if repool != nil {
+						if err := repool(); err != nil {
+							return err
+						}
+					}
+					return b.client.LoadBalancerListeners.Update(ctx, id, request)
+				})
+			}
+		}
+	}
+}
+
+func (b *builder) planFloatingIPs() {
+	for _, f := range b.desired.FloatingIPs {
+		if f.Server != "" {
+			b.checkRef(FloatingIPs, f.Name, Servers, f.Server)
+		}
+		if f.LoadBalancer != "" {
+			b.checkRef(FloatingIPs, f.Name, LoadBalancers, f.LoadBalancer)
+		}
+		tags := b.tags(f.Tags)
+		tags[b.options.NameTag] = f.Name
+		current := find(b, FloatingIPs, f.Name, b.current.floatingIPs, func(c *cloudscale.FloatingIP) bool {
+			return c.Tags[b.options.NameTag] == f.Name
+		})
+
+		if current != nil && !b.adopt(FloatingIPs, f.Name, current.Tags) {
+			continue
+		}
+
+		if current == nil {
+			ipVersion := f.IPVersion
+			if ipVersion == 0 {
+				ipVersion = 4
+			}
+			d := diffs{}
+			d.add("region", "", f.Region)
+			d.add("ip_version", 0, ipVersion)
+			d.add("type", "", f.Type)
+			d.add("server", "", f.Server)
+			d.add("load_balancer", "", f.LoadBalancer)
+			d.add("reverse_ptr", "", f.ReversePointer)
+			d.add("tags", cloudscale.TagMap(nil), tags)
+			request := &cloudscale.FloatingIPCreateRequest{
+				IPVersion:      ipVersion,
+				Type:           f.Type,
+				PrefixLength:   f.PrefixLength,
+				ReversePointer: f.ReversePointer,
+			}
+			request.Region = f.Region
+			request.Tags = &tags
+			b.change(Create, FloatingIPs, f.Name, "", d, func(ctx context.Context) error {
+				var err error
+				if f.Server != "" {
+					if request.Server, err = b.refs.get(Servers, f.Server); err != nil {
+						return err
+					}
+				}
+				if f.LoadBalancer != "" {
+					if request.LoadBalancer, err = b.refs.get(LoadBalancers, f.LoadBalancer); err != nil {
+						return err
+					}
+				}
+				_, err = b.client.FloatingIPs.Create(ctx, request)
+				return err
+			})
+			continue
+		}
+
+		b.match(FloatingIPs, current.IP())
+		if f.Region != "" && current.Region != nil && current.Region.Slug != f.Region {
+			b.warn(FloatingIPs, f.Name, "region cannot be changed from %q to %q", current.Region.Slug, f.Region)
+		}
+
+		d := diffs{}
+		request := &cloudscale.FloatingIPUpdateRequest{}
+		currentServer, currentLoadBalancer := "", ""
+		if current.Server != nil {
+			currentServer = current.Server.UUID
+		}
+		if current.LoadBalancer != nil {
+			currentLoadBalancer = current.LoadBalancer.UUID
+		}
+		var reassign func() error
+		switch {
+		case f.Server != "":
+			if id, ok := b.refs.lookup(Servers, f.Server); !ok || id != currentServer {
+				d.add("server", b.nameOf(Servers, currentServer), f.Server)
+				reassign = func() error {
+					id, err := b.refs.get(Servers, f.Server)
+					request.Server = cloudscale.NewNullable(id)
+					return err
+				}
+			}
+		case f.LoadBalancer != "":
+			if id, ok := b.refs.lookup(LoadBalancers, f.LoadBalancer); !ok || id != currentLoadBalancer {
+				d.add("load_balancer", b.nameOf(LoadBalancers, currentLoadBalancer), f.LoadBalancer)
+				reassign = func() error {
+					id, err := b.refs.get(LoadBalancers, f.LoadBalancer)
+					request.LoadBalancer = cloudscale.NewNullable(id)
+					return err
+				}
+			}
+		case currentServer != "":
+			d.add("server", b.nameOf(Servers, currentServer), "")
+			request.Server = cloudscale.Null[string]()
+		case currentLoadBalancer != "":
+			d.add("load_balancer", b.nameOf(LoadBalancers, currentLoadBalancer), "")
+			request.LoadBalancer = cloudscale.Null[string]()
+		}
+		if f.ReversePointer != "" && f.ReversePointer != current.ReversePointer {
+			d.add("reverse_ptr", current.ReversePointer, f.ReversePointer)
+			request.ReversePointer = cloudscale.NewNullable(f.ReversePointer)
+		}
+		diffTags(&d, &request.TaggedResourceRequest, current.Tags, tags)
+		if len(d) > 0 {
+			id := current.IP()
+			b.change(Update, FloatingIPs, f.Name, id, d, func(ctx context.Context) error {
+				if reassign != nil {
+					if err := reassign(); err != nil {
+						return err
+					}
+				}
+				return b.client.FloatingIPs.Update(ctx, id, request)
+			})
+		}
+	}
+}
+
+// planDeletions deletes the resources owned by the plan that are not
+// declared, in the reverse of CreationOrder.
+func (b *builder) planDeletions() {
+	owned := func(tags cloudscale.TagMap) bool {
+		return tags[b.options.OwnerTag] == b.options.Owner
+	}
+	for _, kind := range slices.Backward(CreationOrder) {
+		switch kind {
+		case FloatingIPs:
+			planDeletion(b, kind, b.client.FloatingIPs, b.current.floatingIPs, func(f *cloudscale.FloatingIP) (string, string, bool) {
+				return f.IP(), f.Network, owned(f.Tags)
+			})
+		case LoadBalancerListeners:
+			planDeletion(b, kind, b.client.LoadBalancerListeners, b.current.loadBalancerListeners, func(l *cloudscale.LoadBalancerListener) (string, string, bool) {
+				return l.UUID, qualify(b.nameOf(LoadBalancers, l.LoadBalancer.UUID), l.Name), owned(l.Tags)
+			})
+		case LoadBalancerHealthMonitors:
+			planDeletion(b, kind, b.client.LoadBalancerHealthMonitors, b.current.loadBalancerHealthMonitors, func(h *cloudscale.LoadBalancerHealthMonitor) (string, string, bool) {
+				return h.UUID, qualify(b.nameOf(LoadBalancers, h.LoadBalancer.UUID), h.Pool.Name), owned(h.Tags)
+			})
+		case LoadBalancerPoolMembers:
+			for _, pool := range b.current.loadBalancerPools {
+				members := poolMembers{service: b.client.LoadBalancerPoolMembers, poolID: pool.UUID}
+				poolName := qualify(b.nameOf(LoadBalancers, pool.LoadBalancer.UUID), pool.Name)
+				planDeletion(b, kind, members, b.current.loadBalancerPoolMembers[pool.UUID], func(m *cloudscale.LoadBalancerPoolMember) (string, string, bool) {
+					return m.UUID, qualify(poolName, m.Name), owned(m.Tags)
+				})
+			}
+		case LoadBalancerPools:
+			planDeletion(b, kind, b.client.LoadBalancerPools, b.current.loadBalancerPools, func(p *cloudscale.LoadBalancerPool) (string, string, bool) {
+				return p.UUID, qualify(b.nameOf(LoadBalancers, p.LoadBalancer.UUID), p.Name), owned(p.Tags)
+			})
+		case LoadBalancers:
+			planDeletion(b, kind, b.client.LoadBalancers, b.current.loadBalancers, func(l *cloudscale.LoadBalancer) (string, string, bool) {
+				return l.UUID, l.Name, owned(l.Tags)
+			})
+		case Volumes:
+			planDeletion(b, kind, b.client.Volumes, b.current.volumes, func(v *cloudscale.Volume) (string, string, bool) {
+				return v.UUID, v.Name, owned(v.Tags)
+			})
+		case Servers:
+			planDeletion(b, kind, b.client.Servers, b.current.servers, func(s *cloudscale.Server) (string, string, bool) {
+				return s.UUID, s.Name, owned(s.Tags)
+			})
+		case ServerGroups:
+			planDeletion(b, kind, b.client.ServerGroups, b.current.serverGroups, func(g *cloudscale.ServerGroup) (string, string, bool) {
+				return g.UUID, g.Name, owned(g.Tags)
+			})
+		case Subnets:
+			planDeletion(b, kind, b.client.Subnets, b.current.subnets, func(s *cloudscale.Subnet) (string, string, bool) {
+				return s.UUID, s.CIDR, owned(s.Tags)
+			})
+		case Networks:
+			planDeletion(b, kind, b.client.Networks, b.current.networks, func(n *cloudscale.Network) (string, string, bool) {
+				return n.UUID, n.Name, owned(n.Tags)
+			})
+		}
+	}
+}
+
+func planDeletion[TResource any](
+	b *builder,
+	kind Kind,
+	service cloudscale.BulkDeleteService[TResource],
+	resources []TResource,
+	describe func(resource *TResource) (id, name string, owned bool),
+) {
+	for i := range resources {
+		id, name, owned := describe(&resources[i])
+		if !owned || b.matched[kind][id] {
+			continue
+		}
+		b.change(Delete, kind, name, id, nil, func(ctx context.Context) error {
+			results, _ := cloudscale.BulkDelete(ctx, service, []string{id}, cloudscale.BulkOptions[TResource]{
+				WaitForDeletion: true,
+				RetryOptions:    b.options.WaitOptions,
+			})
+			return results[0].Err
+		})
+	}
+}
+
+// poolMembers binds the members service to one pool, so that its members can
+// be deleted like resources of the other kinds.
+type poolMembers struct {
+	service cloudscale.LoadBalancerPoolMemberService
+	poolID  string
+}
+
+func (p poolMembers) Get(ctx context.Context, id string) (*cloudscale.LoadBalancerPoolMember, error) {
+	return p.service.Get(ctx, p.poolID, id)
+}
+
+func (p poolMembers) Delete(ctx context.Context, id string) error {
+	return p.service.Delete(ctx, p.poolID, id)
+}
@@ -0,0 +1,295 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Kind names a type of resource managed by a plan.
+type Kind string
+
+const (
+	Networks      Kind = "network"
+	Subnets       Kind = "subnet"
+	ServerGroups  Kind = "server-group"
+	Servers       Kind = "server"
+	Volumes       Kind = "volume"
+	LoadBalancers Kind = "load-balancer"
+	FloatingIPs   Kind = "floating-ip"
+
+	LoadBalancerPools          Kind = "load-balancer-pool"
+	LoadBalancerPoolMembers    Kind = "load-balancer-pool-member"
+	LoadBalancerHealthMonitors Kind = "load-balancer-health-monitor"
+	LoadBalancerListeners      Kind = "load-balancer-listener"
+)
+
+// CreationOrder lists all resource kinds in the order they are created and
+// updated. Deletions happen in the reverse order.
+var CreationOrder = []Kind{
+	Networks,
+	Subnets,
+	ServerGroups,
+	Servers,
+	Volumes,
+	LoadBalancers,
+	LoadBalancerPools,
+	LoadBalancerPoolMembers,
+	LoadBalancerHealthMonitors,
+	LoadBalancerListeners,
+	FloatingIPs,
+}
+
+// Action is what a Change does to a resource.
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+var actionSymbols = map[Action]string{Create: "+", Update: "~", Delete: "-"}
+
+const (
+	defaultOwnerTag = "managed-by"
+	defaultNameTag  = "name"
+)
+
+// Options configures Build.
+type Options struct {
+	// Owner, if set, is written to OwnerTag on every declared resource, which
+	// marks it as managed by this state.
+	Owner string
+
+	// OwnerTag is the tag key holding Owner. Defaults to "managed-by".
+	OwnerTag string
+
+	// Prune deletes resources tagged with Owner that are no longer declared.
+	// Without Owner, resources are never deleted.
+	Prune bool
+
+	// Adopt lets declared resources take over existing resources of the same
+	// name that are not tagged with Owner, e.g. ones created by hand. Without
+	// it, Build fails for such resources instead of tagging them with Owner.
+	// It has no effect without Owner.
+	Adopt bool
+
+	// NameTag is the tag key identifying floating IPs, which have no name of
+	// their own. Defaults to "name".
+	NameTag string
+
+	// WaitOptions are passed on to the waits made while applying, like the
+	// options of WaitFor.
+	WaitOptions []backoff.RetryOption
+}
+
+// Diff is the change of a single field. Old is empty for created resources,
+// New for deleted ones.
+type Diff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Change is the creation, update or deletion of one resource.
+type Change struct {
+	Action Action
+	Kind   Kind
+	Name   string
+	// ID is the UUID of the existing resource, or the IP of a floating IP.
+	// It is empty for created resources.
+	ID    string
+	Diffs []Diff
+
+	apply func(ctx context.Context) error
+}
+
+func (c Change) String() string {
+	header := fmt.Sprintf("%s %s %q", actionSymbols[c.Action], c.Kind, c.Name)
+	if c.ID != "" {
+		header += fmt.Sprintf(" (%s)", c.ID)
+	}
+	return header
+}
+
+// Plan is the set of changes turning the current state into the desired one.
+type Plan struct {
+	// Changes are in the order Apply makes them.
+	Changes []Change
+	// Warnings describe differences that cannot be applied, e.g. the image of
+	// an existing server.
+	Warnings []string
+}
+
+// Empty reports whether the plan makes no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of changes with the given action.
+func (p *Plan) Count(action Action) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// String renders the plan as a human-readable diff.
+func (p *Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n", p.Count(Create), p.Count(Update), p.Count(Delete))
+	for _, change := range p.Changes {
+		fmt.Fprintf(&b, "\n%s\n", change)
+		for _, diff := range change.Diffs {
+			switch change.Action {
+			case Create:
+				fmt.Fprintf(&b, "    %s: %s\n", diff.Field, diff.New)
+			case Delete:
+				fmt.Fprintf(&b, "    %s: %s\n", diff.Field, diff.Old)
+			default:
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", diff.Field, diff.Old, diff.New)
+			}
+		}
+	}
+	if len(p.Warnings) > 0 {
+		b.WriteString("\nWarnings:\n")
+		for _, warning := range p.Warnings {
+			fmt.Fprintf(&b, "  %s\n", warning)
+		}
+	}
+	return b.String()
+}
+
+// Apply makes the changes in order and stops at the first failure. Resources
+// created before the failure are picked up by the next Build.
+func (p *Plan) Apply(ctx context.Context) error {
+	for _, change := range p.Changes {
+		if err := change.apply(ctx); err != nil {
+			return fmt.Errorf("%s %s %q: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// refs maps resource names to UUIDs. Resources created by Apply are added as
+// they are created, so that later changes can refer to them.
+type refs map[Kind]map[string]string
+
+func (r refs) set(kind Kind, name, id string) {
+	if r[kind] == nil {
+		r[kind] = map[string]string{}
+	}
+	r[kind][name] = id
+}
+
+func (r refs) lookup(kind Kind, name string) (string, bool) {
+	id, ok := r[kind][name]
+	return id, ok
+}
+
+// get resolves a reference while applying. Build has checked that every
+// reference is either existing or created by an earlier change.
+func (r refs) get(kind Kind, name string) (string, error) {
+	if id, ok := r.lookup(kind, name); ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("%s %q does not exist", kind, name)
+}
+
+func (r refs) getAll(kind Kind, names []string) ([]string, error) {
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		id, err := r.get(kind, name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// diffs collects the differences of one resource.
+type diffs []Diff
+
+func (d *diffs) add(field string, old, new any) {
+	if o, n := show(old), show(new); o != n {
+		*d = append(*d, Diff{Field: field, Old: o, New: n})
+	}
+}
+
+// show formats a field value for display; zero values are shown as empty.
+func show(value any) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return ""
+		}
+		return strconv.Quote(v)
+	case fmt.Stringer:
+		return show(v.String())
+	case bool:
+		if !v {
+			return ""
+		}
+		return "true"
+	case int:
+		if v == 0 {
+			return ""
+		}
+		return strconv.Itoa(v)
+	case []string:
+		if len(v) == 0 {
+			return ""
+		}
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	case cloudscale.TagMap:
+		if len(v) == 0 {
+			return ""
+		}
+		pairs := make([]string, 0, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", key, v[key]))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return fmt.Sprint(value)
+}
+
+// sortedCopy returns values sorted without modifying them, for comparisons
+// where the order does not matter.
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// qualify returns the name of a resource within its parent, e.g. "lb/web"
+// for the pool "web" of the load balancer "lb".
+func qualify(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
+
+var (
+	errAmbiguous = errors.New("more than one resource has this name")
+	errNotOwned  = errors.New("exists but is not tagged with the owner; set Adopt to take it over")
+)
@@ -0,0 +1,542 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/internal/fakeapi"
+)
+
+var fastWait = []backoff.RetryOption{backoff.WithBackOff(backoff.NewConstantBackOff(time.Millisecond))}
+
+// fakeAPI serves fixed lists, creates resources with sequential UUIDs and
+// records every mutating request as "METHOD path body".
+type fakeAPI struct {
+	*fakeapi.API
+	requests []string
+	created  int
+	status   map[string]string
+	deleted  map[string]bool
+}
+
+func newFakeAPI(t *testing.T, lists map[string]string) (*cloudscale.Client, *fakeAPI) {
+	t.Helper()
+	api := &fakeAPI{status: map[string]string{}, deleted: map[string]bool{}}
+	client, server := fakeapi.NewClient(t, lists, api.handle)
+	api.API = server
+	return client, api
+}
+
+// subCollections are the path segments of collections nested in another
+// collection or resource.
+var subCollections = map[string]bool{"pools": true, "listeners": true, "health-monitors": true, "members": true}
+
+// splitPath splits an API path into the collection, the resource ID and the
+// action, e.g. "v1/load-balancers/pools/p1/members" into the collection
+// itself, or "v1/servers/s1/stop" into "v1/servers", "s1" and "stop".
+func splitPath(path string) (collection, id, action string) {
+	parts := strings.Split(path, "/")
+	collection = strings.Join(parts[:2], "/")
+	for i := 2; i < len(parts); i++ {
+		switch {
+		case subCollections[parts[i]]:
+			if id != "" {
+				collection += "/" + id
+				id = ""
+			}
+			collection += "/" + parts[i]
+		case id == "":
+			id = parts[i]
+		default:
+			action = parts[i]
+		}
+	}
+	return collection, id, action
+}
+
+func (a *fakeAPI) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	collection, id, action := splitPath(path)
+	if r.Method != http.MethodGet {
+		body, _ := io.ReadAll(r.Body)
+		a.requests = append(a.requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, path, body)))
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		fmt.Fprint(w, "[]")
+	case r.Method == http.MethodPost && id == "":
+		a.created++
+		if collection == "v1/floating-ips" {
+			fmt.Fprintf(w, `{"network": "192.0.2.%d/32"}`, a.created)
+			return
+		}
+		fmt.Fprintf(w, `{"uuid": "%s-%d"}`, collection[strings.LastIndex(collection, "/")+1:], a.created)
+	case r.Method == http.MethodPost:
+		a.status[id] = map[string]string{"stop": "stopped", "start": "running"}[action]
+	case r.Method == http.MethodDelete:
+		a.deleted[id] = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPatch:
+		w.WriteHeader(http.StatusNoContent)
+	case a.deleted[id]:
+		fakeapi.NotFound(w)
+	default:
+		status, ok := a.status[id]
+		if !ok {
+			status = "running"
+		}
+		fmt.Fprintf(w, `{"uuid": %q, "status": %q}`, id, status)
+	}
+}
+
+const desiredYAML = `
+networks:
+  - name: backend
+    zone: lpg1
+    mtu: 9000
+subnets:
+  - name: backend-v4
+    network: backend
+    cidr: 10.0.0.0/24
+server_groups:
+  - name: web
+    zone: lpg1
+servers:
+  - name: web1
+    zone: lpg1
+    flavor: flex-4-2
+    image: debian-12
+    ssh_keys: ["ssh-ed25519 AAAA"]
+    interfaces:
+      - network: public
+      - network: backend
+        subnet: backend-v4
+        address: 10.0.0.10
+    server_groups: [web]
+volumes:
+  - name: data
+    zone: lpg1
+    size_gb: 50
+    servers: [web1]
+load_balancers:
+  - name: lb
+    zone: lpg1
+    flavor: lb-standard
+    vip_subnet: backend-v4
+floating_ips:
+  - name: www
+    region: lpg
+    server: web1
+`
+
+func TestBuild_CreatesInDependencyOrder(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{})
+	desired, err := Load(strings.NewReader(desiredYAML))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	p, err := Build(context.Background(), client, desired, Options{Owner: "ci", WaitOptions: fastWait})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if n := p.Count(Create); n != 7 || len(p.Changes) != 7 {
+		t.Fatalf("expected 7 creations, got %d of %d changes", n, len(p.Changes))
+	}
+	rendered := p.String()
+	for _, expected := range []string{
+		"Plan: 7 to create, 0 to update, 0 to delete.",
+		"+ network \"backend\"\n    zone: \"lpg1\"\n    mtu: 9000\n    tags: {managed-by=\"ci\"}\n",
+		"+ server \"web1\"\n",
+		"    interfaces: [\"public\", \"backend/backend-v4 10.0.0.10\"]\n",
+		"+ floating-ip \"www\"\n",
+		"    tags: {managed-by=\"ci\", name=\"www\"}\n",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected plan to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+
+	if err := p.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	expected := []string{
+		`POST v1/networks {"zone":"lpg1","tags":{"managed-by":"ci"},"name":"backend","mtu":9000,"auto_create_ipv4_subnet":false}`,
+		`POST v1/subnets {"tags":{"managed-by":"ci"},"cidr":"10.0.0.0/24","network":"networks-1"}`,
+		`POST v1/server-groups {"zone":"lpg1","tags":{"managed-by":"ci"},"name":"web","type":"anti-affinity"}`,
+		`POST v1/servers {"tags":{"managed-by":"ci"},"name":"web1","flavor":"flex-4-2","image":"debian-12","zone":"lpg1",` +
+			`"interfaces":[{"network":"public"},{"network":"networks-1","addresses":[{"subnet":"subnets-2","address":"10.0.0.10"}]}],` +
+			`"ssh_keys":["ssh-ed25519 AAAA"],"server_groups":["server-groups-3"]}`,
+		`POST v1/volumes {"zone":"lpg1","tags":{"managed-by":"ci"},"name":"data","size_gb":50,"server_uuids":["servers-4"]}`,
+		`POST v1/load-balancers {"zone":"lpg1","tags":{"managed-by":"ci"},"name":"lb","flavor":"lb-standard","vip_addresses":[{"subnet":"subnets-2"}]}`,
+		`POST v1/floating-ips {"region":"lpg","tags":{"managed-by":"ci","name":"www"},"ip_version":4,"server":"servers-4"}`,
+	}
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(api.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBuild_UpdatesAndPrunes(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/networks": `[{"uuid": "n1", "name": "backend", "zone": {"slug": "lpg1"}, "mtu": 1500, "tags": {"managed-by": "ci"}}]`,
+		"v1/servers": `[
+			{"uuid": "s1", "name": "web1", "zone": {"slug": "lpg1"}, "flavor": {"slug": "flex-4-2"}, "image": {"slug": "debian-11"},
+			 "status": "running", "tags": {"managed-by": "ci"}},
+			{"uuid": "s2", "name": "db", "zone": {"slug": "lpg1"}, "tags": {}},
+			{"uuid": "s3", "name": "old", "zone": {"slug": "lpg1"}, "tags": {"managed-by": "ci"}}
+		]`,
+		"v1/volumes":      `[{"uuid": "v1", "name": "data", "zone": {"slug": "lpg1"}, "size_gb": 50, "server_uuids": ["s1"], "tags": {"managed-by": "ci"}}]`,
+		"v1/floating-ips": `[{"network": "192.0.2.7/32", "region": {"slug": "lpg"}, "server": {"uuid": "s1"}, "tags": {"name": "www", "managed-by": "ci"}}]`,
+	})
+
+	desired := &State{
+		Networks:    []Network{{Name: "backend", Zone: "lpg1", MTU: 9000}},
+		Servers:     []Server{{Name: "web1", Zone: "lpg1", Flavor: "flex-8-4", Image: "debian-12"}},
+		Volumes:     []Volume{{Name: "data", Zone: "lpg1", SizeGB: 100, Servers: []string{"web1", "db"}}},
+		FloatingIPs: []FloatingIP{{Name: "www", Server: "db"}},
+	}
+	p, err := Build(context.Background(), client, desired, Options{Owner: "ci", Prune: true, WaitOptions: fastWait})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	expectedPlan := `Plan: 0 to create, 4 to update, 1 to delete.
+
+~ network "backend" (n1)
+    mtu: 1500 -> 9000
+
+~ server "web1" (s1)
+    flavor: "flex-4-2" -> "flex-8-4"
+
+~ volume "data" (v1)
+    size_gb: 50 -> 100
+    servers: ["web1"] -> ["web1", "db"]
+
+~ floating-ip "www" (192.0.2.7)
+    server: "web1" -> "db"
+
+- server "old" (s3)
+
+Warnings:
+  server "web1": image cannot be changed from "debian-11" to "debian-12"
+`
+	if rendered := p.String(); rendered != expectedPlan {
+		t.Errorf("unexpected plan:\n%s\nexpected:\n%s", rendered, expectedPlan)
+	}
+
+	if err := p.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	expected := []string{
+		`PATCH v1/networks/n1 {"mtu":9000}`,
+		`POST v1/servers/s1/stop`,
+		`PATCH v1/servers/s1 {"flavor":"flex-8-4"}`,
+		`POST v1/servers/s1/start`,
+		`PATCH v1/volumes/v1 {"size_gb":100,"server_uuids":["s1","s2"]}`,
+		`PATCH v1/floating-ips/192.0.2.7 {"server":"s2"}`,
+		`DELETE v1/servers/s3`,
+	}
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(api.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+const loadBalancerYAML = `
+load_balancers:
+  - name: lb
+    zone: lpg1
+    flavor: lb-standard
+    pools:
+      - name: web
+        algorithm: round_robin
+        protocol: tcp
+        members:
+          - name: web1
+            address: 10.0.0.10
+            subnet: backend-v4
+            protocol_port: 80
+        health_monitor:
+          type: http
+          delay_s: 5
+          http:
+            url_path: /health
+    listeners:
+      - name: http
+        pool: web
+        protocol_port: 80
+        allowed_cidrs: [192.0.2.0/24]
+`
+
+func TestBuild_CreatesLoadBalancerChildren(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/subnets": `[{"uuid": "sn1", "cidr": "10.0.0.0/24", "network": {"uuid": "n1"}, "tags": {"managed-by": "ci"}}]`,
+	})
+	desired, err := Load(strings.NewReader(loadBalancerYAML))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	// The subnet is only referenced, by its CIDR like any existing subnet.
+	desired.LoadBalancers[0].Pools[0].Members[0].Subnet = "10.0.0.0/24"
+
+	p, err := Build(context.Background(), client, desired, Options{Owner: "ci", WaitOptions: fastWait})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	rendered := p.String()
+	for _, expected := range []string{
+		"Plan: 5 to create, 0 to update, 0 to delete.",
+		"+ load-balancer-pool \"lb/web\"\n    algorithm: \"round_robin\"\n",
+		"+ load-balancer-pool-member \"lb/web/web1\"\n    address: \"10.0.0.10\"\n",
+		"+ load-balancer-health-monitor \"lb/web\"\n    type: \"http\"\n    delay_s: 5\n    http.url_path: \"/health\"\n",
+		"+ load-balancer-listener \"lb/http\"\n    pool: \"web\"\n",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected plan to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+
+	if err := p.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	expected := []string{
+		`POST v1/load-balancers {"zone":"lpg1","tags":{"managed-by":"ci"},"name":"lb","flavor":"lb-standard"}`,
+		`POST v1/load-balancers/pools {"tags":{"managed-by":"ci"},"name":"web","load_balancer":"load-balancers-1","algorithm":"round_robin","protocol":"tcp"}`,
+		`POST v1/load-balancers/pools/pools-2/members {"tags":{"managed-by":"ci"},"name":"web1","protocol_port":80,"address":"10.0.0.10","subnet":"sn1"}`,
+		`POST v1/load-balancers/health-monitors {"tags":{"managed-by":"ci"},"pool":"pools-2","delay_s":5,"type":"http","http":{"url_path":"/health"}}`,
+		`POST v1/load-balancers/listeners {"tags":{"managed-by":"ci"},"name":"http","pool":"pools-2","protocol":"tcp","protocol_port":80,"allowed_cidrs":["192.0.2.0/24"]}`,
+	}
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(api.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBuild_UpdatesLoadBalancerChildren(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/networks":       `[{"uuid": "n1", "name": "backend", "zone": {"slug": "lpg1"}, "tags": {"managed-by": "ci"}}]`,
+		"v1/subnets":        `[{"uuid": "sn1", "cidr": "10.0.0.0/24", "network": {"uuid": "n1"}, "tags": {"managed-by": "ci"}}]`,
+		"v1/load-balancers": `[{"uuid": "lb1", "name": "lb", "zone": {"slug": "lpg1"}, "flavor": {"slug": "lb-standard"}, "tags": {"managed-by": "ci"}}]`,
+		"v1/load-balancers/pools": `[{"uuid": "p1", "name": "web", "load_balancer": {"uuid": "lb1"}, "algorithm": "round_robin",
+			"protocol": "tcp", "tags": {"managed-by": "ci"}}]`,
+		"v1/load-balancers/pools/p1/members": `[
+			{"uuid": "m1", "name": "web1", "enabled": true, "address": "10.0.0.10", "subnet": {"uuid": "sn1"}, "protocol_port": 80,
+			 "tags": {"managed-by": "ci"}},
+			{"uuid": "m2", "name": "web2", "enabled": true, "tags": {"managed-by": "ci"}}
+		]`,
+		"v1/load-balancers/health-monitors": `[{"uuid": "h1", "pool": {"uuid": "p1", "name": "web"}, "load_balancer": {"uuid": "lb1"},
+			"type": "http", "delay_s": 2, "http": {"url_path": "/"}, "tags": {"managed-by": "ci"}}]`,
+		"v1/load-balancers/listeners": `[{"uuid": "l1", "name": "http", "pool": {"uuid": "p1", "name": "web"}, "load_balancer": {"uuid": "lb1"},
+			"protocol": "tcp", "protocol_port": 8080, "allowed_cidrs": ["192.0.2.0/24"], "tags": {"managed-by": "ci"}}]`,
+	})
+	desired, err := Load(strings.NewReader(loadBalancerYAML))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	desired.Subnets = []Subnet{{Name: "backend-v4", Network: "backend", CIDR: "10.0.0.0/24"}}
+	desired.Networks = []Network{{Name: "backend", Zone: "lpg1"}}
+	desired.LoadBalancers[0].Pools[0].Members[0].Disabled = true
+
+	p, err := Build(context.Background(), client, desired, Options{Owner: "ci", Prune: true, WaitOptions: fastWait})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if len(p.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", p.Warnings)
+	}
+	rendered := p.String()
+	for _, expected := range []string{
+		"Plan: 0 to create, 3 to update, 1 to delete.",
+		"~ load-balancer-pool-member \"lb/web/web1\" (m1)\n    disabled:  -> true\n",
+		"~ load-balancer-health-monitor \"lb/web\" (h1)\n    delay_s: 2 -> 5\n    http.url_path: \"/\" -> \"/health\"\n",
+		"~ load-balancer-listener \"lb/http\" (l1)\n    protocol_port: 8080 -> 80\n",
+		"- load-balancer-pool-member \"lb/web/web2\" (m2)\n",
+	} {
+		if !strings.Contains(rendered, expected) {
+			t.Errorf("expected plan to contain %q, got:\n%s", expected, rendered)
+		}
+	}
+
+	api.requests = nil
+	if err := p.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	for _, expected := range []string{
+		`PATCH v1/load-balancers/pools/p1/members/m1 {"enabled":false}`,
+		`PATCH v1/load-balancers/health-monitors/h1 {"delay_s":5,"http":{"url_path":"/health"}}`,
+		`PATCH v1/load-balancers/listeners/l1 {"protocol_port":80}`,
+		`DELETE v1/load-balancers/pools/p1/members/m2`,
+	} {
+		if !slices.Contains(api.requests, expected) {
+			t.Errorf("expected request %s, got:\n%s", expected, strings.Join(api.requests, "\n"))
+		}
+	}
+}
+
+func TestBuild_Adopt(t *testing.T) {
+	lists := map[string]string{
+		"v1/networks": `[{"uuid": "n1", "name": "backend", "zone": {"slug": "lpg1"}, "tags": {"team": "db"}}]`,
+	}
+	desired := &State{Networks: []Network{{Name: "backend", Zone: "lpg1", Tags: cloudscale.TagMap{"team": "db"}}}}
+
+	client, _ := newFakeAPI(t, lists)
+	_, err := Build(context.Background(), client, desired, Options{Owner: "ci"})
+	if !errors.Is(err, errNotOwned) || !strings.Contains(err.Error(), `network "backend"`) {
+		t.Errorf("expected the unowned network to be rejected, got %v", err)
+	}
+
+	p, err := Build(context.Background(), client, desired, Options{Owner: "ci", Adopt: true})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	expected := "~ network \"backend\" (n1)\n    tags: {team=\"db\"} -> {managed-by=\"ci\", team=\"db\"}\n"
+	if rendered := p.String(); !strings.Contains(rendered, expected) {
+		t.Errorf("expected the network to be adopted, got:\n%s", rendered)
+	}
+}
+
+func TestBuild_UnassignsFloatingIP(t *testing.T) {
+	client, api := newFakeAPI(t, map[string]string{
+		"v1/servers":      `[{"uuid": "s1", "name": "web1", "zone": {"slug": "lpg1"}, "tags": {}}]`,
+		"v1/floating-ips": `[{"network": "192.0.2.7/32", "region": {"slug": "lpg"}, "server": {"uuid": "s1"}, "tags": {"name": "www"}}]`,
+	})
+
+	desired := &State{FloatingIPs: []FloatingIP{{Name: "www"}}}
+	p, err := Build(context.Background(), client, desired, Options{WaitOptions: fastWait})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if len(p.Changes) != 1 || len(p.Warnings) != 0 {
+		t.Fatalf("expected the floating IP to be unassigned, got:\n%s", p)
+	}
+	if err := p.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	expected := []string{`PATCH v1/floating-ips/192.0.2.7 {"server":null}`}
+	if !reflect.DeepEqual(api.requests, expected) {
+		t.Errorf("unexpected requests:\n%s\nexpected:\n%s", strings.Join(api.requests, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestBuild_NoChanges(t *testing.T) {
+	client, _ := newFakeAPI(t, map[string]string{
+		"v1/server-groups": `[{"uuid": "g1", "name": "web", "zone": {"slug": "rma1"}, "type": "anti-affinity", "tags": {"team": "web"}}]`,
+	})
+
+	desired := &State{ServerGroups: []ServerGroup{{Name: "web", Zone: "rma1", Tags: cloudscale.TagMap{"team": "web"}}}}
+	p, err := Build(context.Background(), client, desired, Options{})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if !p.Empty() || len(p.Warnings) != 0 {
+		t.Errorf("expected an empty plan, got:\n%s", p)
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	client, _ := newFakeAPI(t, map[string]string{
+		"v1/networks": `[{"uuid": "n1", "name": "dup"}, {"uuid": "n2", "name": "dup"}]`,
+	})
+
+	desired := &State{
+		Networks: []Network{{Name: "dup", Zone: "rma1"}},
+		Subnets:  []Subnet{{Name: "s", Network: "missing", CIDR: "10.0.0.0/24"}},
+	}
+	_, err := Build(context.Background(), client, desired, Options{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, expected := range []string{
+		`network "dup": more than one resource has this name`,
+		`subnet "s": unknown network "missing"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(strings.NewReader("servers:\n  - name: a\n    flavour: x\n")); err == nil || !strings.Contains(err.Error(), "flavour") {
+		t.Errorf("expected unknown field to be rejected, got %v", err)
+	}
+
+	_, err := Load(strings.NewReader(`
+volumes:
+  - name: a
+    zone: rma1
+  - name: a
+    zone: rma1
+    size_gb: 10
+`))
+	for _, expected := range []string{`volume "a": size_gb must be positive`, `volume "a": declared more than once`} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+
+	_, err = Load(strings.NewReader(`
+load_balancers:
+  - name: lb
+    zone: rma1
+    flavor: lb-standard
+    pools:
+      - name: web
+        algorithm: round_robin
+        protocol: tcp
+        members:
+          - name: a
+            address: 10.0.0.1
+            subnet: s
+            protocol_port: 80
+          - name: a
+            address: 10.0.0.2
+            subnet: s
+            protocol_port: 80
+    listeners:
+      - name: http
+        pool: api
+        protocol_port: 80
+`))
+	for _, expected := range []string{
+		`load-balancer-pool-member "lb/web/a": declared more than once`,
+		`load-balancer-listener "lb/http": pool must name a pool of the load balancer`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got %v", expected, err)
+		}
+	}
+
+	state, err := Load(strings.NewReader(""))
+	if err != nil || !reflect.DeepEqual(state, &State{}) {
+		t.Errorf("expected an empty state, got %+v, %v", state, err)
+	}
+}
+
+func TestShow(t *testing.T) {
+	for value, expected := range map[string]any{
+		`"a"`:             "a",
+		`12`:              12,
+		`["a", "b"]`:      []string{"a", "b"},
+		`{a="1", b="2"}`:  cloudscale.TagMap{"b": "2", "a": "1"},
+		`"anti-affinity"`: cloudscale.ServerGroupTypeAntiAffinity,
+	} {
+		if actual := show(expected); actual != value {
+			t.Errorf("show(%v) = %s, expected %s", expected, actual, value)
+		}
+	}
+	if show("") != "" || show(0) != "" || show([]string(nil)) != "" {
+		t.Error("expected zero values to be shown as empty")
+	}
+}
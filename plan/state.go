@@ -0,0 +1,316 @@
+// Package plan implements declarative infrastructure management for
+// cloudscale.ch, similar to Terraform's plan and apply.
+//
+// The desired State is declared in Go or loaded from YAML. Build reads the
+// current resources with the List calls of the SDK, matches them to the
+// declared ones and computes a Plan: the resources to create, update and,
+// for resources owned by the plan, delete. References between resources,
+// e.g. the network of a subnet, are made by name and resolved to UUIDs,
+// including those of resources only created while the plan is applied.
+// Apply makes the changes in dependency order and waits for new servers and
+// load balancers to be running before resources depending on them are
+// created.
+//
+// Pools, listeners, pool members and health monitors are declared within
+// their load balancer and pool, and are named "lb/pool" or "lb/pool/member"
+// in plans and errors. With an Owner, an existing resource of a declared
+// name is only taken over if it is tagged with the owner, or if
+// Options.Adopt is set.
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// PublicNetwork is the network name of a server interface in the public
+// network.
+const PublicNetwork = "public"
+
+// State is the desired state of a set of resources.
+type State struct {
+	Networks      []Network      `yaml:"networks,omitempty"`
+	Subnets       []Subnet       `yaml:"subnets,omitempty"`
+	ServerGroups  []ServerGroup  `yaml:"server_groups,omitempty"`
+	Servers       []Server       `yaml:"servers,omitempty"`
+	Volumes       []Volume       `yaml:"volumes,omitempty"`
+	LoadBalancers []LoadBalancer `yaml:"load_balancers,omitempty"`
+	FloatingIPs   []FloatingIP   `yaml:"floating_ips,omitempty"`
+}
+
+// Network is a private network, identified by its name.
+type Network struct {
+	Name string            `yaml:"name"`
+	Zone string            `yaml:"zone"`
+	MTU  int               `yaml:"mtu,omitempty"`
+	Tags cloudscale.TagMap `yaml:"tags,omitempty"`
+}
+
+// Subnet is identified by its network and CIDR. Name is only used to refer
+// to the subnet from servers and load balancers.
+type Subnet struct {
+	Name           string            `yaml:"name"`
+	Network        string            `yaml:"network"`
+	CIDR           string            `yaml:"cidr"`
+	GatewayAddress string            `yaml:"gateway_address,omitempty"`
+	DNSServers     []string          `yaml:"dns_servers,omitempty"`
+	Tags           cloudscale.TagMap `yaml:"tags,omitempty"`
+}
+
+// ServerGroup is identified by its name.
+type ServerGroup struct {
+	Name string                     `yaml:"name"`
+	Zone string                     `yaml:"zone"`
+	Type cloudscale.ServerGroupType `yaml:"type,omitempty"`
+	Tags cloudscale.TagMap          `yaml:"tags,omitempty"`
+}
+
+// Server is identified by its name. Only Flavor and Tags can be changed once
+// the server exists; changing the flavor restarts the server.
+type Server struct {
+	Name         string            `yaml:"name"`
+	Zone         string            `yaml:"zone"`
+	Flavor       string            `yaml:"flavor"`
+	Image        string            `yaml:"image"`
+	VolumeSizeGB int               `yaml:"volume_size_gb,omitempty"`
+	SSHKeys      []string          `yaml:"ssh_keys,omitempty"`
+	UserData     string            `yaml:"user_data,omitempty"`
+	Interfaces   []Interface       `yaml:"interfaces,omitempty"`
+	ServerGroups []string          `yaml:"server_groups,omitempty"`
+	Tags         cloudscale.TagMap `yaml:"tags,omitempty"`
+}
+
+// Interface attaches a server to PublicNetwork or to a network by name,
+// optionally with a fixed address in one of its subnets.
+type Interface struct {
+	Network string `yaml:"network"`
+	Subnet  string `yaml:"subnet,omitempty"`
+	Address string `yaml:"address,omitempty"`
+}
+
+// Volume is identified by its name. Servers names the servers it is attached
+// to.
+type Volume struct {
+	Name    string                `yaml:"name"`
+	Zone    string                `yaml:"zone"`
+	SizeGB  int                   `yaml:"size_gb"`
+	Type    cloudscale.VolumeType `yaml:"type,omitempty"`
+	Servers []string              `yaml:"servers,omitempty"`
+	Tags    cloudscale.TagMap     `yaml:"tags,omitempty"`
+}
+
+// LoadBalancer is identified by its name. VIPSubnet, if set, names the
+// subnet of its private VIP address. Its pools and listeners are managed
+// along with it.
+type LoadBalancer struct {
+	Name       string            `yaml:"name"`
+	Zone       string            `yaml:"zone"`
+	Flavor     string            `yaml:"flavor"`
+	VIPSubnet  string            `yaml:"vip_subnet,omitempty"`
+	VIPAddress string            `yaml:"vip_address,omitempty"`
+	Pools      []Pool            `yaml:"pools,omitempty"`
+	Listeners  []Listener        `yaml:"listeners,omitempty"`
+	Tags       cloudscale.TagMap `yaml:"tags,omitempty"`
+}
+
+// Pool is identified by its name within its load balancer. Only Tags can be
+// changed once the pool exists, apart from its members and health monitor.
+type Pool struct {
+	Name          string                               `yaml:"name"`
+	Algorithm     cloudscale.LoadBalancerPoolAlgorithm `yaml:"algorithm"`
+	Protocol      cloudscale.LoadBalancerPoolProtocol  `yaml:"protocol"`
+	Members       []PoolMember                         `yaml:"members,omitempty"`
+	HealthMonitor *HealthMonitor                       `yaml:"health_monitor,omitempty"`
+	Tags          cloudscale.TagMap                    `yaml:"tags,omitempty"`
+}
+
+// PoolMember is identified by its name within its pool. Subnet names the
+// subnet of Address. Only Disabled and Tags can be changed once the member
+// exists.
+type PoolMember struct {
+	Name         string            `yaml:"name"`
+	Address      string            `yaml:"address"`
+	Subnet       string            `yaml:"subnet"`
+	ProtocolPort int               `yaml:"protocol_port"`
+	MonitorPort  int               `yaml:"monitor_port,omitempty"`
+	Disabled     bool              `yaml:"disabled,omitempty"`
+	Tags         cloudscale.TagMap `yaml:"tags,omitempty"`
+}
+
+// HealthMonitor checks the members of the pool declaring it. Its type cannot
+// be changed once it exists.
+type HealthMonitor struct {
+	Type          cloudscale.LoadBalancerHealthMonitorType `yaml:"type"`
+	DelayS        int                                      `yaml:"delay_s,omitempty"`
+	TimeoutS      int                                      `yaml:"timeout_s,omitempty"`
+	UpThreshold   int                                      `yaml:"up_threshold,omitempty"`
+	DownThreshold int                                      `yaml:"down_threshold,omitempty"`
+	HTTP          *HealthMonitorHTTP                       `yaml:"http,omitempty"`
+	Tags          cloudscale.TagMap                        `yaml:"tags,omitempty"`
+}
+
+// HealthMonitorHTTP configures the requests of http and https health
+// monitors.
+type HealthMonitorHTTP struct {
+	ExpectedCodes []string `yaml:"expected_codes,omitempty"`
+	Method        string   `yaml:"method,omitempty"`
+	URLPath       string   `yaml:"url_path,omitempty"`
+	Version       string   `yaml:"version,omitempty"`
+	Host          string   `yaml:"host,omitempty"`
+}
+
+// Listener is identified by its name within its load balancer. Pool names a
+// pool of the same load balancer. Its protocol cannot be changed once it
+// exists.
+type Listener struct {
+	Name                   string                                  `yaml:"name"`
+	Pool                   string                                  `yaml:"pool"`
+	Protocol               cloudscale.LoadBalancerListenerProtocol `yaml:"protocol,omitempty"`
+	ProtocolPort           int                                     `yaml:"protocol_port"`
+	AllowedCIDRs           []string                                `yaml:"allowed_cidrs,omitempty"`
+	TimeoutClientDataMS    int                                     `yaml:"timeout_client_data_ms,omitempty"`
+	TimeoutMemberConnectMS int                                     `yaml:"timeout_member_connect_ms,omitempty"`
+	TimeoutMemberDataMS    int                                     `yaml:"timeout_member_data_ms,omitempty"`
+	Tags                   cloudscale.TagMap                       `yaml:"tags,omitempty"`
+}
+
+// FloatingIP has no name in the API; it is identified by Options.NameTag. It
+// is assigned to the server or load balancer with the given name.
+type FloatingIP struct {
+	Name           string                    `yaml:"name"`
+	Region         string                    `yaml:"region,omitempty"`
+	IPVersion      int                       `yaml:"ip_version,omitempty"`
+	Type           cloudscale.FloatingIPType `yaml:"type,omitempty"`
+	PrefixLength   int                       `yaml:"prefix_length,omitempty"`
+	Server         string                    `yaml:"server,omitempty"`
+	LoadBalancer   string                    `yaml:"load_balancer,omitempty"`
+	ReversePointer string                    `yaml:"reverse_ptr,omitempty"`
+	Tags           cloudscale.TagMap         `yaml:"tags,omitempty"`
+}
+
+// Load decodes a State from YAML. Unknown fields are rejected.
+func Load(r io.Reader) (*State, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	state := &State{}
+	if err := decoder.Decode(state); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decoding state: %w", err)
+	}
+	return state, state.Validate()
+}
+
+// LoadFile decodes a State from the YAML file at path.
+func LoadFile(path string) (*State, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Validate checks that all resources have the fields identifying them and
+// that names are unique per resource type, or per parent for the pools,
+// members and listeners of load balancers. References are checked by Build,
+// as they may point to existing resources that are not declared.
+func (s *State) Validate() error {
+	errs := []error{}
+	check := func(kind Kind, name string, ok bool, problem string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s %q: %s", kind, name, problem))
+		}
+	}
+	unique := func(kind Kind, parent string, names []string) {
+		seen := map[string]bool{}
+		for _, name := range names {
+			check(kind, qualify(parent, name), name != "", "name is required")
+			check(kind, qualify(parent, name), !seen[name], "declared more than once")
+			seen[name] = true
+		}
+	}
+
+	names := func(count int, name func(i int) string) []string {
+		result := make([]string, count)
+		for i := range result {
+			result[i] = name(i)
+		}
+		return result
+	}
+	unique(Networks, "", names(len(s.Networks), func(i int) string { return s.Networks[i].Name }))
+	unique(Subnets, "", names(len(s.Subnets), func(i int) string { return s.Subnets[i].Name }))
+	unique(ServerGroups, "", names(len(s.ServerGroups), func(i int) string { return s.ServerGroups[i].Name }))
+	unique(Servers, "", names(len(s.Servers), func(i int) string { return s.Servers[i].Name }))
+	unique(Volumes, "", names(len(s.Volumes), func(i int) string { return s.Volumes[i].Name }))
+	unique(LoadBalancers, "", names(len(s.LoadBalancers), func(i int) string { return s.LoadBalancers[i].Name }))
+	unique(FloatingIPs, "", names(len(s.FloatingIPs), func(i int) string { return s.FloatingIPs[i].Name }))
+
+	for _, n := range s.Networks {
+		check(Networks, n.Name, n.Zone != "", "zone is required")
+	}
+	for _, n := range s.Subnets {
+		check(Subnets, n.Name, n.Network != "", "network is required")
+		_, _, err := net.ParseCIDR(n.CIDR)
+		check(Subnets, n.Name, err == nil, "cidr must be a valid CIDR")
+	}
+	for _, g := range s.ServerGroups {
+		check(ServerGroups, g.Name, g.Zone != "", "zone is required")
+	}
+	for _, v := range s.Servers {
+		check(Servers, v.Name, v.Zone != "", "zone is required")
+		check(Servers, v.Name, v.Flavor != "", "flavor is required")
+		check(Servers, v.Name, v.Image != "", "image is required")
+		for _, i := range v.Interfaces {
+			check(Servers, v.Name, i.Network != "", "interface network is required")
+			check(Servers, v.Name, i.Network != PublicNetwork || (i.Subnet == "" && i.Address == ""), "the public interface takes no subnet or address")
+		}
+	}
+	for _, v := range s.Volumes {
+		check(Volumes, v.Name, v.Zone != "", "zone is required")
+		check(Volumes, v.Name, v.SizeGB > 0, "size_gb must be positive")
+	}
+	for _, l := range s.LoadBalancers {
+		check(LoadBalancers, l.Name, l.Zone != "", "zone is required")
+		check(LoadBalancers, l.Name, l.Flavor != "", "flavor is required")
+
+		unique(LoadBalancerPools, l.Name, names(len(l.Pools), func(i int) string { return l.Pools[i].Name }))
+		pools := map[string]bool{}
+		for _, p := range l.Pools {
+			pools[p.Name] = true
+			name := qualify(l.Name, p.Name)
+			check(LoadBalancerPools, name, p.Algorithm != "", "algorithm is required")
+			check(LoadBalancerPools, name, p.Protocol != "", "protocol is required")
+
+			unique(LoadBalancerPoolMembers, name, names(len(p.Members), func(i int) string { return p.Members[i].Name }))
+			for _, m := range p.Members {
+				check(LoadBalancerPoolMembers, qualify(name, m.Name), net.ParseIP(m.Address) != nil, "address must be a valid IP address")
+				check(LoadBalancerPoolMembers, qualify(name, m.Name), m.Subnet != "", "subnet is required")
+				check(LoadBalancerPoolMembers, qualify(name, m.Name), m.ProtocolPort > 0, "protocol_port must be positive")
+			}
+
+			if h := p.HealthMonitor; h != nil {
+				check(LoadBalancerHealthMonitors, name, h.Type != "", "type is required")
+				check(LoadBalancerHealthMonitors, name, h.HTTP == nil || h.Type == cloudscale.LoadBalancerHealthMonitorTypeHTTP || h.Type == cloudscale.LoadBalancerHealthMonitorTypeHTTPS, "http can only be set for http and https monitors")
+			}
+		}
+
+		unique(LoadBalancerListeners, l.Name, names(len(l.Listeners), func(i int) string { return l.Listeners[i].Name }))
+		for _, n := range l.Listeners {
+			name := qualify(l.Name, n.Name)
+			check(LoadBalancerListeners, name, pools[n.Pool], "pool must name a pool of the load balancer")
+			check(LoadBalancerListeners, name, n.ProtocolPort > 0, "protocol_port must be positive")
+		}
+	}
+	for _, f := range s.FloatingIPs {
+		check(FloatingIPs, f.Name, f.Server == "" || f.LoadBalancer == "", "cannot be assigned to a server and a load balancer")
+	}
+
+	return errors.Join(errs...)
+}
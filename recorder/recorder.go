@@ -0,0 +1,397 @@
+// Package recorder provides an http.RoundTripper that records API
+// interactions into golden files and replays them, so that tests run
+// deterministically and without an API token.
+//
+// In Record mode, requests are sent to the next transport and each request
+// and response pair is kept as an Interaction; Save writes them to the
+// cassette file. Authorization headers are never recorded, and the values of
+// secret JSON fields, like passwords and object storage keys, are scrubbed.
+//
+// In Replay mode, no request leaves the process. Interactions are matched by
+// method, path, query and body, and every interaction has to be used, so
+// that the requests of parallel tests get the responses of their own
+// resources whatever order they are made in. Lenient replay falls back to
+// other interactions of the same path, then to any interaction of the same
+// operation path template the SDK attaches to the request context (see
+// cloudscale.OperationPath), e.g. "v1/servers/:id", and reuses the last one
+// once all are used, which suits tests with random names or polling loops.
+//
+//	rec, err := recorder.New("testdata/servers.json", recorder.Options{Mode: recorder.Replay})
+//	client := cloudscale.NewClient(&http.Client{Transport: rec})
+//	...
+//	err = rec.Close()
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode string
+
+const (
+	// Record sends requests to the API and records them.
+	Record Mode = "record"
+	// Replay answers requests from the cassette.
+	Replay Mode = "replay"
+)
+
+// Redacted replaces scrubbed values.
+const Redacted = "REDACTED"
+
+// DefaultScrubFields are the JSON fields scrubbed when Options.ScrubFields is
+// nil.
+var DefaultScrubFields = []string{"password", "access_key", "secret_key", "api_token", "token"}
+
+// ErrNoInteraction is returned for requests without a matching interaction
+// in the cassette.
+var ErrNoInteraction = errors.New("no matching interaction recorded")
+
+// Options configures a Recorder.
+type Options struct {
+	Mode Mode
+
+	// Lenient relaxes the matching of replayed requests, see the package
+	// documentation.
+	Lenient bool
+
+	// ScrubFields are the names of JSON fields, at any depth of request and
+	// response bodies, whose values are replaced with Redacted. Defaults to
+	// DefaultScrubFields.
+	ScrubFields []string
+
+	// ScrubHeaders are response headers that are not recorded, in addition
+	// to Set-Cookie and Content-Length. Request headers are never recorded.
+	ScrubHeaders []string
+
+	// Next is the transport requests are recorded from. Defaults to
+	// http.DefaultTransport.
+	Next http.RoundTripper
+}
+
+// Cassette is the content of a golden file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Operation is the path template of the
+// request, or its path if it has none.
+type Request struct {
+	Method    string          `json:"method"`
+	Operation string          `json:"operation"`
+	Path      string          `json:"path"`
+	Query     string          `json:"query,omitempty"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	RawBody   []byte          `json:"raw_body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       json.RawMessage     `json:"body,omitempty"`
+	RawBody    []byte              `json:"raw_body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying a cassette. It is
+// safe for concurrent use.
+type Recorder struct {
+	path    string
+	options Options
+	scrub   map[string]bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a Recorder for the cassette at path. In Replay mode, the
+// cassette is read immediately.
+func New(path string, options Options) (*Recorder, error) {
+	if options.Mode != Record && options.Mode != Replay {
+		return nil, fmt.Errorf("unknown mode %q", options.Mode)
+	}
+	if options.Next == nil {
+		options.Next = http.DefaultTransport
+	}
+	if options.ScrubFields == nil {
+		options.ScrubFields = DefaultScrubFields
+	}
+	r := &Recorder{path: path, options: options, scrub: map[string]bool{}}
+	for _, field := range options.ScrubFields {
+		r.scrub[strings.ToLower(field)] = true
+	}
+
+	if options.Mode == Replay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		// Bodies are indented in the file but compared compactly.
+		for i := range r.cassette.Interactions {
+			request := &r.cassette.Interactions[i].Request
+			if len(request.Body) > 0 {
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, request.Body); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				request.Body = compact.Bytes()
+			}
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.request(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.options.Mode == Replay {
+		return r.replay(req, recorded)
+	}
+	return r.record(req, recorded)
+}
+
+// request reads the body of req, leaving it in place to be sent, and
+// returns its scrubbed recording.
+func (r *Recorder) request(req *http.Request) (Request, error) {
+	operation := cloudscale.OperationPath(req.Context())
+	if operation == "" {
+		operation = strings.TrimPrefix(req.URL.Path, "/")
+	}
+	recorded := Request{Method: req.Method, Operation: operation, Path: req.URL.Path, Query: req.URL.RawQuery}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return recorded, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		recorded.Body, recorded.RawBody = r.body(data)
+	}
+	return recorded, nil
+}
+
+// body returns data as scrubbed, canonical JSON, or as raw bytes if it is
+// not JSON.
+func (r *Recorder) body(data []byte) (json.RawMessage, []byte) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, data
+	}
+	// Marshalling decoded JSON cannot fail and sorts object keys.
+	canonical, _ := json.Marshal(r.scrubValue(decoded))
+	return canonical, nil
+}
+
+func (r *Recorder) scrubValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if r.scrub[strings.ToLower(key)] && field != nil {
+				v[key] = Redacted
+			} else {
+				v[key] = r.scrubValue(field)
+			}
+		}
+	case []any:
+		for i, element := range v {
+			v[i] = r.scrubValue(element)
+		}
+	}
+	return value
+}
+
+func (r *Recorder) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := r.options.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	response := Response{StatusCode: resp.StatusCode, Header: map[string][]string{}}
+	for key, values := range resp.Header {
+		if !r.scrubHeader(key) {
+			response.Header[key] = values
+		}
+	}
+	response.Body, response.RawBody = r.body(data)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) scrubHeader(key string) bool {
+	// The length changes with the canonical body and is set when replaying.
+	switch http.CanonicalHeaderKey(key) {
+	case "Set-Cookie", "Content-Length":
+		return true
+	}
+	for _, header := range r.options.ScrubHeaders {
+		if http.CanonicalHeaderKey(header) == http.CanonicalHeaderKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	index := r.match(recorded)
+	if index >= 0 {
+		r.used[index] = true
+	}
+	r.mu.Unlock()
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.Operation)
+	}
+
+	response := r.cassette.Interactions[index].Response
+	body := []byte(response.Body)
+	if response.RawBody != nil {
+		body = response.RawBody
+	}
+	header := http.Header{}
+	for key, values := range response.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// match returns the index of the interaction replayed for recorded, or -1.
+// It must be called with r.mu held.
+func (r *Recorder) match(recorded Request) int {
+	sameOperation := func(i int) bool {
+		candidate := r.cassette.Interactions[i].Request
+		return candidate.Method == recorded.Method && candidate.Operation == recorded.Operation
+	}
+	samePath := func(i int) bool {
+		return sameOperation(i) && r.cassette.Interactions[i].Request.Path == recorded.Path
+	}
+	sameContent := func(i int) bool {
+		candidate := r.cassette.Interactions[i].Request
+		return samePath(i) &&
+			candidate.Query == recorded.Query &&
+			bytes.Equal(candidate.Body, recorded.Body) &&
+			bytes.Equal(candidate.RawBody, recorded.RawBody)
+	}
+
+	for i := range r.cassette.Interactions {
+		if !r.used[i] && sameContent(i) {
+			return i
+		}
+	}
+	if !r.options.Lenient {
+		return -1
+	}
+	// Interactions of the same path are preferred, so that the requests of
+	// parallel tests get the responses for their own resources.
+	for _, matches := range []func(i int) bool{samePath, sameOperation} {
+		last := -1
+		for i := range r.cassette.Interactions {
+			if matches(i) {
+				if !r.used[i] {
+					return i
+				}
+				last = i
+			}
+		}
+		if last >= 0 {
+			return last
+		}
+	}
+	return -1
+}
+
+// Interactions returns the interactions recorded so far, or those of the
+// replayed cassette.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Unused returns the interactions of the cassette that were not replayed.
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unused := []Interaction{}
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Save writes the recorded interactions to the cassette file, creating its
+// directory if needed.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+// Close saves the cassette in Record mode. In strict Replay mode, it fails if
+// interactions of the cassette were not replayed.
+func (r *Recorder) Close() error {
+	if r.options.Mode == Record {
+		return r.Save()
+	}
+	if unused := r.Unused(); !r.options.Lenient && len(unused) > 0 {
+		first := unused[0].Request
+		return fmt.Errorf("%d recorded interaction(s) not replayed, the first is %s %s", len(unused), first.Method, first.Operation)
+	}
+	return nil
+}
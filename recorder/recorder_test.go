@@ -0,0 +1,213 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+)
+
+// fakeAPI serves servers and objects users, and counts the requests it gets.
+func fakeAPI(t *testing.T) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/servers":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"uuid": "s1", "name": "web", "status": "changing"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/servers/s1":
+			fmt.Fprintf(w, `{"uuid": "s1", "name": "web", "status": "running"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/servers/s2":
+			fmt.Fprintf(w, `{"uuid": "s2", "name": "db", "status": "stopped"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/objects-users/u1":
+			fmt.Fprint(w, `{"id": "u1", "keys": [{"access_key": "AK", "secret_key": "SK"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"detail": "Not found."}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func newClient(t *testing.T, transport http.RoundTripper, baseURL string) *cloudscale.Client {
+	client := cloudscale.NewClient(&http.Client{Transport: transport})
+	client.BaseURL, _ = url.Parse(baseURL)
+	client.AuthToken = "top-secret-token"
+	return client
+}
+
+// exercise makes the calls recorded and replayed by the tests.
+func exercise(t *testing.T, client *cloudscale.Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	server, err := client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "web", Flavor: "flex-4-2", Image: "debian-12", Password: "hunter2"})
+	if err != nil || server.UUID != "s1" {
+		t.Fatalf("Servers.Create returned %v, %v", server, err)
+	}
+	server, err = client.Servers.Get(ctx, "s1")
+	if err != nil || server.Status != cloudscale.ServerRunning {
+		t.Fatalf("Servers.Get returned %v, %v", server, err)
+	}
+	if _, err := client.ObjectsUsers.Get(ctx, "u1"); err != nil {
+		t.Fatalf("ObjectsUsers.Get returned %v", err)
+	}
+	_, err = client.Volumes.Get(ctx, "missing")
+	var errorResponse *cloudscale.ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404, got %v", err)
+	}
+}
+
+func record(t *testing.T) string {
+	t.Helper()
+	api, _ := fakeAPI(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "servers.json")
+	rec, err := New(path, Options{Mode: Record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise(t, newClient(t, rec, api.URL))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := record(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"top-secret-token", "hunter2", `"AK"`, `"SK"`, "session=secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %s:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"operation": "v1/servers/:id"`) {
+		t.Errorf("expected interactions keyed by operation template:\n%s", data)
+	}
+
+	rec, err := New(path, Options{Mode: Replay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The API is gone: everything has to come from the cassette.
+	exercise(t, newClient(t, rec, "http://127.0.0.1:1"))
+	if err := rec.Close(); err != nil {
+		t.Errorf("Close returned %v", err)
+	}
+}
+
+func TestReplay_Strict(t *testing.T) {
+	path := record(t)
+	rec, err := New(path, Options{Mode: Replay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, rec, "http://127.0.0.1:1")
+	ctx := context.Background()
+
+	// The password is scrubbed on both sides, so it does not have to match.
+	_, err = client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "other", Flavor: "flex-4-2", Image: "debian-12", Password: "other"})
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected a different body not to match, got %v", err)
+	}
+	if _, err := client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "web", Flavor: "flex-4-2", Image: "debian-12", Password: "other"}); err != nil {
+		t.Errorf("expected the recorded body to match, got %v", err)
+	}
+	if _, err := client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "web", Flavor: "flex-4-2", Image: "debian-12"}); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected an interaction to be replayed once, got %v", err)
+	}
+
+	err = rec.Close()
+	if err == nil || !strings.Contains(err.Error(), "3 recorded interaction(s) not replayed, the first is GET v1/servers/:id") {
+		t.Errorf("expected unused interactions to fail, got %v", err)
+	}
+}
+
+func TestReplay_Lenient(t *testing.T) {
+	path := record(t)
+	rec, err := New(path, Options{Mode: Replay, Lenient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, rec, "http://127.0.0.1:1")
+	ctx := context.Background()
+
+	server, err := client.Servers.Create(ctx, &cloudscale.ServerRequest{Name: "go-sdk-4711", Flavor: "flex-4-2", Image: "debian-12"})
+	if err != nil || server.UUID != "s1" {
+		t.Fatalf("expected a fallback to the recorded create, got %v, %v", server, err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.Servers.Get(ctx, "s1"); err != nil {
+			t.Fatalf("poll %d: expected the last interaction to be reused, got %v", i, err)
+		}
+	}
+	if _, err := client.Networks.List(ctx); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected an unrecorded operation to fail, got %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Errorf("lenient Close returned %v", err)
+	}
+}
+
+func TestReplay_MatchesPath(t *testing.T) {
+	api, _ := fakeAPI(t)
+	path := filepath.Join(t.TempDir(), "servers.json")
+	rec, err := New(path, Options{Mode: Record})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, rec, api.URL)
+	ctx := context.Background()
+	for _, id := range []string{"s1", "s2"} {
+		if _, err := client.Servers.Get(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lenient := range []bool{false, true} {
+		rec, err := New(path, Options{Mode: Replay, Lenient: lenient})
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := newClient(t, rec, "http://127.0.0.1:1")
+		// Parallel tests make their requests in any order.
+		for _, id := range []string{"s2", "s1"} {
+			server, err := client.Servers.Get(ctx, id)
+			if err != nil || server.UUID != id {
+				t.Errorf("lenient=%v: expected server %s, got %v, %v", lenient, id, server, err)
+			}
+		}
+		if err := rec.Close(); err != nil {
+			t.Errorf("lenient=%v: Close returned %v", lenient, err)
+		}
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New("cassette.json", Options{}); err == nil {
+		t.Error("expected an error without mode")
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "missing.json"), Options{Mode: Replay}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing cassette to fail, got %v", err)
+	}
+}
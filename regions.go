@@ -2,6 +2,7 @@ package cloudscale
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 )
 
@@ -16,10 +17,23 @@ type RegionStub struct {
 }
 
 type Region struct {
+	UnknownFields
 	Slug  string     `json:"slug"`
 	Zones []ZoneStub `json:"zones"`
 }
 
+// MarshalJSON encodes the region including its unknown fields.
+func (r Region) MarshalJSON() ([]byte, error) {
+	type plain Region
+	return marshalWithUnknownFields(plain(r), r.Extra)
+}
+
+// Raw returns the JSON of the region, including the fields the SDK does not
+// model.
+func (r Region) Raw() json.RawMessage {
+	return rawJSON(r)
+}
+
 type RegionService interface {
 	List(ctx context.Context) ([]Region, error)
 }
@@ -1,29 +1,95 @@
 package cloudscale
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 const serverGroupsBasePath = "v1/server-groups"
 
+// ServerGroupType is the placement policy of a server group.
+type ServerGroupType string
+
+const (
+	ServerGroupTypeAntiAffinity ServerGroupType = "anti-affinity"
+)
+
+// IsValid reports whether t is a known server group type.
+func (t ServerGroupType) IsValid() bool {
+	switch t {
+	case ServerGroupTypeAntiAffinity:
+		return true
+	}
+	return false
+}
+
+func (t ServerGroupType) String() string {
+	return string(t)
+}
+
 type ServerGroup struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
-	HREF    string       `json:"href"`
-	UUID    string       `json:"uuid"`
-	Name    string       `json:"name"`
-	Type    string       `json:"type"`
-	Servers []ServerStub `json:"servers"`
+	HREF    string          `json:"href"`
+	UUID    string          `json:"uuid"`
+	Name    string          `json:"name"`
+	Type    ServerGroupType `json:"type"`
+	Servers []ServerStub    `json:"servers"`
+}
+
+// MarshalJSON encodes the server group including its unknown fields.
+func (s ServerGroup) MarshalJSON() ([]byte, error) {
+	type plain ServerGroup
+	return marshalWithUnknownFields(plain(s), s.Extra)
+}
+
+// Raw returns the JSON of the server group, including the fields the SDK does
+// not model.
+func (s ServerGroup) Raw() json.RawMessage {
+	return rawJSON(s)
 }
 
 type ServerGroupRequest struct {
 	ZonalResourceRequest
 	TaggedResourceRequest
-	Name string `json:"name,omitempty"`
-	Type string `json:"type,omitempty"`
+	Name string          `json:"name,omitempty"`
+	Type ServerGroupType `json:"type,omitempty"`
 }
 
 type ServerGroupService interface {
 	GenericCreateService[ServerGroup, ServerGroupRequest]
 	GenericGetService[ServerGroup]
 	GenericListService[ServerGroup]
+	GenericWatchService[ServerGroup]
 	GenericUpdateService[ServerGroup, ServerGroupRequest]
 	GenericDeleteService[ServerGroup]
 	GenericWaitForService[ServerGroup]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r ServerGroupRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the zone exists.
+func (r ServerGroupRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.zone(catalog, "zone", r.Zone)
+	return mergeValidation(r.Validate(), f)
+}
+
+// ServerGroupContains waits for the server with the given UUID to be a member
+// of a server group.
+func ServerGroupContains(serverUUID string) Condition[ServerGroup] {
+	return func(group *ServerGroup) (bool, error) {
+		for _, server := range group.Servers {
+			if server.UUID == serverUUID {
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("waiting for server %s to join the group", serverUUID)
+	}
+}
@@ -2,25 +2,73 @@ package cloudscale
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
+
+	"github.com/cenkalti/backoff/v5"
 )
 
 const serverBasePath = "v1/servers"
 
-const ServerRunning = "running"
-const ServerStopped = "stopped"
-const ServerRebooted = "rebooted"
+// ServerStatus is the state of a server. ServerRunning, ServerStopped and
+// ServerRebooted can also be requested with ServerUpdateRequest.
+type ServerStatus string
+
+const (
+	ServerRunning  ServerStatus = "running"
+	ServerStopped  ServerStatus = "stopped"
+	ServerRebooted ServerStatus = "rebooted"
+	ServerChanging ServerStatus = "changing"
+	ServerErrored  ServerStatus = "errored"
+)
+
+// IsValid reports whether s is a known server status.
+func (s ServerStatus) IsValid() bool {
+	switch s {
+	case ServerRunning, ServerStopped, ServerRebooted, ServerChanging, ServerErrored:
+		return true
+	}
+	return false
+}
+
+func (s ServerStatus) String() string {
+	return string(s)
+}
+
+// InterfaceType tells whether a server interface is attached to the public
+// network or to a private network.
+type InterfaceType string
+
+const (
+	InterfaceTypePublic  InterfaceType = "public"
+	InterfaceTypePrivate InterfaceType = "private"
+)
+
+// IsValid reports whether t is a known interface type.
+func (t InterfaceType) IsValid() bool {
+	switch t {
+	case InterfaceTypePublic, InterfaceTypePrivate:
+		return true
+	}
+	return false
+}
+
+func (t InterfaceType) String() string {
+	return string(t)
+}
 
 type Server struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
 	HREF            string            `json:"href"`
 	UUID            string            `json:"uuid"`
 	Name            string            `json:"name"`
-	Status          string            `json:"status"`
+	Status          ServerStatus      `json:"status"`
 	Flavor          FlavorStub        `json:"flavor"`
 	Image           ImageServerStub   `json:"image"`
 	Volumes         []VolumeStub      `json:"volumes"`
@@ -32,6 +80,18 @@ type Server struct {
 	CreatedAt       time.Time         `json:"created_at"`
 }
 
+// MarshalJSON encodes the server including its unknown fields.
+func (s Server) MarshalJSON() ([]byte, error) {
+	type plain Server
+	return marshalWithUnknownFields(plain(s), s.Extra)
+}
+
+// Raw returns the JSON of the server, including the fields the SDK does not
+// model.
+func (s Server) Raw() json.RawMessage {
+	return rawJSON(s)
+}
+
 type ServerStub struct {
 	HREF string `json:"href"`
 	UUID string `json:"uuid"`
@@ -50,6 +110,9 @@ type FlavorStub struct {
 	MemoryGB  int    `json:"memory_gb"`
 }
 
+// ImageServerStub summarises the public image a server was created from. The
+// full Image, including the zones it is available in, is listed by
+// ImageService; see Resolve.
 type ImageServerStub struct {
 	Slug            string `json:"slug"`
 	Name            string `json:"name"`
@@ -58,17 +121,17 @@ type ImageServerStub struct {
 }
 
 type VolumeStub struct {
-	HREF   string `json:"href"`
-	UUID   string `json:"uuid"`
-	Name   string `json:"name"`
-	Type   string `json:"type"`
-	SizeGB int    `json:"size_gb"`
+	HREF   string     `json:"href"`
+	UUID   string     `json:"uuid"`
+	Name   string     `json:"name"`
+	Type   VolumeType `json:"type"`
+	SizeGB int        `json:"size_gb"`
 }
 
 type Interface struct {
-	Type      string      `json:"type,omitempty"`
-	Network   NetworkStub `json:"network,omitempty"`
-	Addresses []Address   `json:"addresses,omitempty"`
+	Type      InterfaceType `json:"type,omitempty"`
+	Network   NetworkStub   `json:"network,omitempty"`
+	Addresses []Address     `json:"addresses,omitempty"`
 }
 
 type Address struct {
@@ -103,15 +166,15 @@ type ServerRequest struct {
 
 type ServerUpdateRequest struct {
 	TaggedResourceRequest
-	Name       string              `json:"name,omitempty"`
-	Status     string              `json:"status,omitempty"`
-	Flavor     string              `json:"flavor,omitempty"`
-	Interfaces *[]InterfaceRequest `json:"interfaces,omitempty"`
+	Name       string                       `json:"name,omitempty"`
+	Status     ServerStatus                 `json:"status,omitempty"`
+	Flavor     string                       `json:"flavor,omitempty"`
+	Interfaces Optional[[]InterfaceRequest] `json:"interfaces,omitzero"`
 }
 
 type ServerVolumeRequest struct {
-	SizeGB int    `json:"size_gb,omitempty"`
-	Type   string `json:"type,omitempty"`
+	SizeGB int        `json:"size_gb,omitempty"`
+	Type   VolumeType `json:"type,omitempty"`
 }
 
 type InterfaceRequest struct {
@@ -128,6 +191,7 @@ type ServerService interface {
 	GenericCreateService[Server, ServerRequest]
 	GenericGetService[Server]
 	GenericListService[Server]
+	GenericWatchService[Server]
 	GenericUpdateService[Server, ServerUpdateRequest]
 	GenericDeleteService[Server]
 	GenericWaitForService[Server]
@@ -200,16 +264,176 @@ func (s ServerServiceOperations) Update(ctx context.Context, id string, req *Ser
 	return s.GenericServiceOperations.Update(ctx, id, req)
 }
 
-var ServerIsRunning = func(server *Server) (bool, error) {
-	if server.Status == ServerRunning {
-		return true, nil
+// ServerIsRunning waits for a server to be running. It fails with a
+// *StateError once the server is ServerErrored.
+var ServerIsRunning = ServerHasStatus(ServerRunning)
+
+// ServerIsStopped waits for a server to be stopped. It fails with a
+// *StateError once the server is ServerErrored.
+var ServerIsStopped = ServerHasStatus(ServerStopped)
+
+// ServerHasStatus waits for a server to reach status. It fails with a
+// *StateError once the server is ServerErrored, unless that is the status
+// waited for.
+func ServerHasStatus(status ServerStatus) Condition[Server] {
+	return func(server *Server) (bool, error) {
+		if server.Status == status {
+			return true, nil
+		}
+		if server.Status == ServerErrored {
+			return false, backoff.Permanent(&StateError{Resource: "server", ID: server.UUID, Status: server.Status.String()})
+		}
+		return false, fmt.Errorf("waiting for status: %s, current status: %s", status, server.Status)
 	}
-	return false, fmt.Errorf("waiting for status: %s, current status: %s", ServerRunning, server.Status)
 }
 
-var ServerIsStopped = func(server *Server) (bool, error) {
-	if server.Status == ServerStopped {
-		return true, nil
+// ServerHasFlavor waits for a server to be scaled to the flavor with the
+// given slug.
+func ServerHasFlavor(slug string) Condition[Server] {
+	return func(server *Server) (bool, error) {
+		if server.Flavor.Slug == slug {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for flavor: %s, current flavor: %s", slug, server.Flavor.Slug)
 	}
-	return false, fmt.Errorf("waiting for status: %s, current status: %s", ServerStopped, server.Status)
+}
+
+// ServerHasVolume waits for the volume with the given UUID to be attached to
+// a server.
+func ServerHasVolume(volumeUUID string) Condition[Server] {
+	return func(server *Server) (bool, error) {
+		for _, volume := range server.Volumes {
+			if volume.UUID == volumeUUID {
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("waiting for volume %s to be attached", volumeUUID)
+	}
+}
+
+// ServerIsInNetwork waits for a server to have an interface in the network
+// with the given UUID.
+func ServerIsInNetwork(networkUUID string) Condition[Server] {
+	return func(server *Server) (bool, error) {
+		for _, iface := range server.Interfaces {
+			if iface.Network.UUID == networkUUID {
+				return true, nil
+			}
+		}
+		return false, fmt.Errorf("waiting for an interface in network %s", networkUUID)
+	}
+}
+
+// minRootVolumeSizeGB is the smallest root volume a server can have.
+const minRootVolumeSizeGB = 10
+
+// Validate checks the request for mistakes the API would reject.
+func (r ServerRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.required("name", r.Name)
+	f.required("flavor", r.Flavor)
+	f.required("image", r.Image)
+	if r.VolumeSizeGB != 0 && r.VolumeSizeGB < minRootVolumeSizeGB {
+		f.add("volume_size_gb", "must be at least %d, got %d", minRootVolumeSizeGB, r.VolumeSizeGB)
+	}
+	f.notNegative("bulk_volume_size_gb", r.BulkVolumeSizeGB)
+	if r.Interfaces != nil {
+		if r.UsePublicNetwork != nil {
+			f.add("use_public_network", "cannot be combined with interfaces")
+		}
+		if r.UsePrivateNetwork != nil {
+			f.add("use_private_network", "cannot be combined with interfaces")
+		}
+		for i, iface := range *r.Interfaces {
+			f.addNested(fmt.Sprintf("interfaces[%d]", i), iface.Validate())
+		}
+	}
+	if r.Volumes != nil {
+		for i, volume := range *r.Volumes {
+			f.addNested(fmt.Sprintf("volumes[%d]", i), volume.Validate())
+		}
+	}
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that zone, flavor and image
+// exist, that flavor and image are available in the zone and that the root
+// volume is large enough for a custom image.
+func (r ServerRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.zone(catalog, "zone", r.Zone)
+
+	if r.Flavor != "" {
+		if _, ok := catalog.Flavor(r.Flavor); !ok {
+			f.add("flavor", "unknown flavor %q", r.Flavor)
+		} else if r.Zone != "" && catalog.HasZone(r.Zone) && !catalog.FlavorAvailableIn(r.Flavor, r.Zone) {
+			f.add("flavor", "%q is not available in zone %q", r.Flavor, r.Zone)
+		}
+	}
+
+	if r.Image != "" {
+		if _, ok := catalog.ZonesForImage(r.Image); !ok {
+			f.add("image", "unknown image %q", r.Image)
+		} else if r.Zone != "" && catalog.HasZone(r.Zone) && !catalog.ImageAvailableIn(r.Image, r.Zone) {
+			f.add("image", "%q is not available in zone %q", r.Image, r.Zone)
+		}
+		if custom, ok := strings.CutPrefix(r.Image, customImagePrefix); ok && r.VolumeSizeGB != 0 {
+			if image, found := catalog.CustomImage(custom); found && r.VolumeSizeGB < image.SizeGB {
+				f.add("volume_size_gb", "must be at least the image size of %d, got %d", image.SizeGB, r.VolumeSizeGB)
+			}
+		}
+	}
+
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r ServerUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	interfaces, _ := r.Interfaces.Get()
+	for i, iface := range interfaces {
+		f.addNested(fmt.Sprintf("interfaces[%d]", i), iface.Validate())
+	}
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the flavor exists.
+func (r ServerUpdateRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	if r.Flavor != "" {
+		if _, ok := catalog.Flavor(r.Flavor); !ok {
+			f.add("flavor", "unknown flavor %q", r.Flavor)
+		}
+	}
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r ServerVolumeRequest) Validate() error {
+	f := fieldErrors{}
+	f.notNegative("size_gb", r.SizeGB)
+	return f.err()
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r InterfaceRequest) Validate() error {
+	f := fieldErrors{}
+	if r.Network == "" && r.Addresses == nil {
+		f.add("network", "must be set if no addresses are given")
+	}
+	if r.Addresses != nil {
+		for i, address := range *r.Addresses {
+			f.addNested(fmt.Sprintf("addresses[%d]", i), address.Validate())
+		}
+	}
+	return f.err()
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r AddressRequest) Validate() error {
+	f := fieldErrors{}
+	f.ip("address", r.Address)
+	return f.err()
 }
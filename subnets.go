@@ -2,14 +2,20 @@ package cloudscale
 
 import (
 	"encoding/json"
-	"reflect"
+	"fmt"
+	"net"
 )
 
 const subnetBasePath = "v1/subnets"
 
-var UseCloudscaleDefaults = []string{"CLOUDSCALE_DEFAULTS"}
+// UseCloudscaleDefaults sets the DNSServers of a subnet request to the DNS
+// servers of cloudscale.ch.
+//
+// Deprecated: Use Null[[]string]() instead.
+var UseCloudscaleDefaults = Null[[]string]()
 
 type Subnet struct {
+	UnknownFields
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
@@ -21,88 +27,47 @@ type Subnet struct {
 	DNSServers     []string    `json:"dns_servers,omitempty"`
 }
 
+// MarshalJSON encodes the subnet including its unknown fields.
+func (s Subnet) MarshalJSON() ([]byte, error) {
+	type plain Subnet
+	return marshalWithUnknownFields(plain(s), s.Extra)
+}
+
+// Raw returns the JSON of the subnet, including the fields the SDK does not
+// model.
+func (s Subnet) Raw() json.RawMessage {
+	return rawJSON(s)
+}
+
 type SubnetStub struct {
 	HREF string `json:"href,omitempty"`
 	CIDR string `json:"cidr,omitempty"`
 	UUID string `json:"uuid,omitempty"`
 }
 
+// SubnetCreateRequest creates a subnet. A null DNSServers uses the DNS
+// servers of cloudscale.ch, the same as leaving it unset.
 type SubnetCreateRequest struct {
 	TaggedResourceRequest
-	CIDR           string    `json:"cidr,omitempty"`
-	Network        string    `json:"network,omitempty"`
-	GatewayAddress string    `json:"gateway_address,omitempty"`
-	DNSServers     *[]string `json:"dns_servers,omitempty"`
+	CIDR           string             `json:"cidr,omitempty"`
+	Network        string             `json:"network,omitempty"`
+	GatewayAddress string             `json:"gateway_address,omitempty"`
+	DNSServers     Nullable[[]string] `json:"dns_servers,omitzero"`
 }
 
+// SubnetUpdateRequest changes a subnet. Setting DNSServers to null switches
+// back to the DNS servers of cloudscale.ch.
 type SubnetUpdateRequest struct {
 	TaggedResourceRequest
-	GatewayAddress string    `json:"gateway_address,omitempty"`
-	DNSServers     *[]string `json:"dns_servers"`
-}
-
-func (request SubnetUpdateRequest) MarshalJSON() ([]byte, error) {
-	type Alias SubnetUpdateRequest // Create an alias to avoid recursion
-
-	if request.DNSServers == nil {
-		return json.Marshal(&struct {
-			Alias
-			DNSServers []string `json:"dns_servers,omitempty"`
-		}{
-			Alias: (Alias)(request),
-		})
-	}
-
-	if reflect.DeepEqual(*request.DNSServers, UseCloudscaleDefaults) {
-		return json.Marshal(&struct {
-			Alias
-			DNSServers []string `json:"dns_servers"` // important: no omitempty
-		}{
-			Alias:      (Alias)(request),
-			DNSServers: nil,
-		})
-	}
-
-	return json.Marshal(&struct {
-		Alias
-	}{
-		Alias: (Alias)(request),
-	})
-}
-
-func (request SubnetCreateRequest) MarshalJSON() ([]byte, error) {
-	type Alias SubnetCreateRequest // Create an alias to avoid recursion
-
-	if request.DNSServers == nil {
-		return json.Marshal(&struct {
-			Alias
-			DNSServers []string `json:"dns_servers,omitempty"`
-		}{
-			Alias: (Alias)(request),
-		})
-	}
-
-	if reflect.DeepEqual(*request.DNSServers, UseCloudscaleDefaults) {
-		return json.Marshal(&struct {
-			Alias
-			DNSServers []string `json:"dns_servers"` // important: no omitempty
-		}{
-			Alias:      (Alias)(request),
-			DNSServers: nil,
-		})
-	}
-
-	return json.Marshal(&struct {
-		Alias
-	}{
-		Alias: (Alias)(request),
-	})
+	GatewayAddress string             `json:"gateway_address,omitempty"`
+	DNSServers     Nullable[[]string] `json:"dns_servers,omitzero"`
 }
 
 type SubnetService interface {
 	GenericCreateService[Subnet, SubnetCreateRequest]
 	GenericGetService[Subnet]
 	GenericListService[Subnet]
+	GenericWatchService[Subnet]
 	GenericUpdateService[Subnet, SubnetUpdateRequest]
 	GenericDeleteService[Subnet]
 	GenericWaitForService[Subnet]
@@ -111,3 +76,45 @@ type SubnetService interface {
 type SubnetServiceOperations struct {
 	client *Client
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r SubnetCreateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.required("cidr", r.CIDR)
+	f.cidr("cidr", r.CIDR)
+	f.required("network", r.Network)
+	validateGateway(&f, r.CIDR, r.GatewayAddress)
+	validateDNSServers(&f, r.DNSServers)
+	return f.err()
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r SubnetUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.ip("gateway_address", r.GatewayAddress)
+	validateDNSServers(&f, r.DNSServers)
+	return f.err()
+}
+
+func validateGateway(f *fieldErrors, cidr string, gateway string) {
+	if gateway == "" {
+		return
+	}
+	ip := net.ParseIP(gateway)
+	if ip == nil {
+		f.ip("gateway_address", gateway)
+		return
+	}
+	if _, network, err := net.ParseCIDR(cidr); err == nil && !network.Contains(ip) {
+		f.add("gateway_address", "%s is not within %s", gateway, cidr)
+	}
+}
+
+func validateDNSServers(f *fieldErrors, dnsServers Nullable[[]string]) {
+	servers, _ := dnsServers.Get()
+	for i, server := range servers {
+		f.ip(fmt.Sprintf("dns_servers[%d]", i), server)
+	}
+}
@@ -58,28 +58,35 @@ func TestMarshalingOfDNSServersInSubnetUpdateRequest(t *testing.T) {
 		{
 			name: "one dns server",
 			request: SubnetUpdateRequest{
-				DNSServers: &[]string{"8.8.8.8"},
+				DNSServers: NewNullable([]string{"8.8.8.8"}),
 			},
 			expected: "{\"dns_servers\":[\"8.8.8.8\"]}",
 		},
 		{
 			name: "two dns servers",
 			request: SubnetUpdateRequest{
-				DNSServers: &[]string{"8.8.8.8", "8.8.4.4"},
+				DNSServers: NewNullable([]string{"8.8.8.8", "8.8.4.4"}),
 			},
 			expected: "{\"dns_servers\":[\"8.8.8.8\",\"8.8.4.4\"]}",
 		},
 		{
 			name: "no dns servers",
 			request: SubnetUpdateRequest{
-				DNSServers: &[]string{},
+				DNSServers: NewNullable([]string{}),
 			},
 			expected: "{\"dns_servers\":[]}",
 		},
 		{
 			name: "defaults",
 			request: SubnetUpdateRequest{
-				DNSServers: &UseCloudscaleDefaults,
+				DNSServers: Null[[]string](),
+			},
+			expected: "{\"dns_servers\":null}",
+		},
+		{
+			name: "deprecated defaults",
+			request: SubnetUpdateRequest{
+				DNSServers: UseCloudscaleDefaults,
 			},
 			expected: "{\"dns_servers\":null}",
 		},
@@ -114,28 +121,28 @@ func TestMarshalingOfDNSServersInSubnetSubnetCreateRequest(t *testing.T) {
 		{
 			name: "one dns server",
 			request: SubnetCreateRequest{
-				DNSServers: &[]string{"8.8.8.8"},
+				DNSServers: NewNullable([]string{"8.8.8.8"}),
 			},
 			expected: "{\"dns_servers\":[\"8.8.8.8\"]}",
 		},
 		{
 			name: "two dns servers",
 			request: SubnetCreateRequest{
-				DNSServers: &[]string{"8.8.8.8", "8.8.4.4"},
+				DNSServers: NewNullable([]string{"8.8.8.8", "8.8.4.4"}),
 			},
 			expected: "{\"dns_servers\":[\"8.8.8.8\",\"8.8.4.4\"]}",
 		},
 		{
 			name: "no dns servers",
 			request: SubnetCreateRequest{
-				DNSServers: &[]string{},
+				DNSServers: NewNullable([]string{}),
 			},
 			expected: "{\"dns_servers\":[]}",
 		},
 		{
 			name: "defaults",
 			request: SubnetCreateRequest{
-				DNSServers: &UseCloudscaleDefaults,
+				DNSServers: Null[[]string](),
 			},
 			expected: "{\"dns_servers\":null}",
 		},
@@ -0,0 +1,348 @@
+package cloudscale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TagOperator is the comparison a TagRequirement applies to a tag.
+type TagOperator string
+
+const (
+	TagOperatorEquals       TagOperator = "="
+	TagOperatorNotEquals    TagOperator = "!="
+	TagOperatorIn           TagOperator = "in"
+	TagOperatorNotIn        TagOperator = "notin"
+	TagOperatorExists       TagOperator = "exists"
+	TagOperatorDoesNotExist TagOperator = "!"
+)
+
+// TagRequirement is a single condition of a TagSelector. A Key ending in "*"
+// is a prefix and matches every tag key starting with the part before the
+// "*"; prefixes can only be used with TagOperatorExists and
+// TagOperatorDoesNotExist.
+type TagRequirement struct {
+	Key      string
+	Operator TagOperator
+	Values   []string
+}
+
+// TagSelector is a conjunction of TagRequirements, modelled after Kubernetes
+// label selectors. The zero value selects everything.
+type TagSelector struct {
+	Requirements []TagRequirement
+}
+
+// Tagged is implemented by every resource embedding TaggedResource.
+type Tagged interface {
+	GetTags() TagMap
+}
+
+// GetTags returns the tags of the resource.
+func (t TaggedResource) GetTags() TagMap {
+	return t.Tags
+}
+
+// ParseTagSelector parses a comma separated list of requirements:
+//
+//	env                     tag "env" exists
+//	!env                    tag "env" does not exist
+//	env=prod, env==prod     tag "env" has the value "prod"
+//	env!=prod               tag "env" is missing or has another value
+//	env in (prod,staging)   tag "env" has one of the values
+//	env notin (prod,dev)    tag "env" is missing or has none of the values
+//	team.example.com/*      some tag key starts with "team.example.com/"
+//	!team.example.com/*     no tag key starts with "team.example.com/"
+//
+// All requirements must match for a resource to be selected.
+func ParseTagSelector(expression string) (TagSelector, error) {
+	selector := TagSelector{}
+
+	parts, err := splitTagSelector(expression)
+	if err != nil {
+		return TagSelector{}, err
+	}
+
+	for _, part := range parts {
+		requirement, err := parseTagRequirement(part)
+		if err != nil {
+			return TagSelector{}, err
+		}
+		selector.Requirements = append(selector.Requirements, requirement)
+	}
+
+	return selector, nil
+}
+
+// MustParseTagSelector is like ParseTagSelector but panics if the expression
+// cannot be parsed. It simplifies initialization of selectors from constants.
+func MustParseTagSelector(expression string) TagSelector {
+	selector, err := ParseTagSelector(expression)
+	if err != nil {
+		panic(err)
+	}
+	return selector
+}
+
+// splitTagSelector splits expression at commas that are not enclosed in
+// parentheses.
+func splitTagSelector(expression string) ([]string, error) {
+	parts := []string{}
+	depth := 0
+	start := 0
+
+	for i, r := range expression {
+		switch r {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("tag selector %q: nested parentheses", expression)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("tag selector %q: unbalanced parentheses", expression)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, expression[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("tag selector %q: unbalanced parentheses", expression)
+	}
+	parts = append(parts, expression[start:])
+
+	if len(parts) == 1 && strings.TrimSpace(parts[0]) == "" {
+		return nil, nil
+	}
+	return parts, nil
+}
+
+func parseTagRequirement(part string) (TagRequirement, error) {
+	part = strings.TrimSpace(part)
+	if part == "" {
+		return TagRequirement{}, fmt.Errorf("tag selector: empty requirement")
+	}
+
+	if key, ok := strings.CutPrefix(part, "!"); ok {
+		return newTagRequirement(strings.TrimSpace(key), TagOperatorDoesNotExist, nil)
+	}
+
+	if strings.Contains(part, "(") {
+		return parseSetTagRequirement(part)
+	}
+
+	if key, value, ok := strings.Cut(part, "!="); ok {
+		return newTagRequirement(strings.TrimSpace(key), TagOperatorNotEquals, []string{strings.TrimSpace(value)})
+	}
+
+	if key, value, ok := strings.Cut(part, "="); ok {
+		value = strings.TrimPrefix(value, "=")
+		return newTagRequirement(strings.TrimSpace(key), TagOperatorEquals, []string{strings.TrimSpace(value)})
+	}
+
+	if fields := strings.Fields(part); len(fields) != 1 {
+		return TagRequirement{}, fmt.Errorf("tag selector: cannot parse requirement %q", part)
+	}
+	return newTagRequirement(part, TagOperatorExists, nil)
+}
+
+// parseSetTagRequirement parses "<key> in (<values>)" and
+// "<key> notin (<values>)".
+func parseSetTagRequirement(part string) (TagRequirement, error) {
+	key, rest, _ := strings.Cut(part, " ")
+	rest = strings.TrimSpace(rest)
+
+	var operator TagOperator
+	switch {
+	case strings.HasPrefix(rest, string(TagOperatorNotIn)):
+		operator = TagOperatorNotIn
+	case strings.HasPrefix(rest, string(TagOperatorIn)):
+		operator = TagOperatorIn
+	default:
+		return TagRequirement{}, fmt.Errorf("tag selector: cannot parse requirement %q", part)
+	}
+
+	list := strings.TrimSpace(strings.TrimPrefix(rest, string(operator)))
+	if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+		return TagRequirement{}, fmt.Errorf("tag selector: expected parenthesized values in %q", part)
+	}
+
+	values := []string{}
+	if strings.TrimSpace(list[1:len(list)-1]) == "" {
+		return newTagRequirement(key, operator, values)
+	}
+	for _, value := range strings.Split(list[1:len(list)-1], ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+
+	return newTagRequirement(key, operator, values)
+}
+
+func newTagRequirement(key string, operator TagOperator, values []string) (TagRequirement, error) {
+	if key == "" || strings.ContainsAny(key, " ()!=,") {
+		return TagRequirement{}, fmt.Errorf("tag selector: invalid key %q", key)
+	}
+	if strings.HasSuffix(key, "*") && operator != TagOperatorExists && operator != TagOperatorDoesNotExist {
+		return TagRequirement{}, fmt.Errorf("tag selector: key prefix %q can only be used to check existence", key)
+	}
+	if (operator == TagOperatorIn || operator == TagOperatorNotIn) && len(values) == 0 {
+		return TagRequirement{}, fmt.Errorf("tag selector: %q requires at least one value", operator)
+	}
+	return TagRequirement{Key: key, Operator: operator, Values: values}, nil
+}
+
+// Matches reports whether tags fulfil the requirement.
+func (r TagRequirement) Matches(tags TagMap) bool {
+	if prefix, ok := strings.CutSuffix(r.Key, "*"); ok {
+		found := false
+		for key := range tags {
+			if strings.HasPrefix(key, prefix) {
+				found = true
+				break
+			}
+		}
+		return found == (r.Operator == TagOperatorExists)
+	}
+
+	value, exists := tags[r.Key]
+
+	switch r.Operator {
+	case TagOperatorExists:
+		return exists
+	case TagOperatorDoesNotExist:
+		return !exists
+	case TagOperatorEquals:
+		return exists && value == r.Values[0]
+	case TagOperatorNotEquals:
+		return !exists || value != r.Values[0]
+	case TagOperatorIn:
+		return exists && slices.Contains(r.Values, value)
+	case TagOperatorNotIn:
+		return !exists || !slices.Contains(r.Values, value)
+	}
+	return false
+}
+
+// String returns the requirement in the syntax accepted by ParseTagSelector.
+func (r TagRequirement) String() string {
+	switch r.Operator {
+	case TagOperatorExists:
+		return r.Key
+	case TagOperatorDoesNotExist:
+		return "!" + r.Key
+	case TagOperatorIn, TagOperatorNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	}
+	return fmt.Sprintf("%s%s%s", r.Key, r.Operator, strings.Join(r.Values, ""))
+}
+
+// Matches reports whether tags fulfil all requirements of the selector.
+func (s TagSelector) Matches(tags TagMap) bool {
+	for _, requirement := range s.Requirements {
+		if !requirement.Matches(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the selector has no requirements and therefore
+// selects everything.
+func (s TagSelector) Empty() bool {
+	return len(s.Requirements) == 0
+}
+
+// String returns the selector in the syntax accepted by ParseTagSelector.
+func (s TagSelector) String() string {
+	parts := make([]string, 0, len(s.Requirements))
+	for _, requirement := range s.Requirements {
+		parts = append(parts, requirement.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// serverSideTags returns the requirements the API can evaluate itself. The
+// API supports filtering by tag value ("tag:key=value") and by the existence
+// of a tag key ("tag:key=").
+func (s TagSelector) serverSideTags() TagMap {
+	tags := TagMap{}
+	// Keys only required to exist, which a value for the key narrows down.
+	exists := map[string]bool{}
+	for _, requirement := range s.Requirements {
+		if strings.HasSuffix(requirement.Key, "*") {
+			continue
+		}
+		switch requirement.Operator {
+		case TagOperatorEquals:
+			if existing, ok := tags[requirement.Key]; ok && !exists[requirement.Key] && existing != requirement.Values[0] {
+				// Contradicting requirements, leave them to client-side evaluation.
+				continue
+			}
+			tags[requirement.Key] = requirement.Values[0]
+			delete(exists, requirement.Key)
+		case TagOperatorExists:
+			if _, ok := tags[requirement.Key]; !ok {
+				tags[requirement.Key] = ""
+				exists[requirement.Key] = true
+			}
+		}
+	}
+	return tags
+}
+
+// WithTagSelector sends the parts of selector the API supports as query
+// parameters. The remaining requirements are not applied; use
+// ListWithTagSelector or FilterByTagSelector to evaluate them client-side.
+func WithTagSelector(selector TagSelector) ListRequestModifier {
+	tags := selector.serverSideTags()
+	return func(request *http.Request) {
+		query := request.URL.Query()
+		keys := make([]string, 0, len(tags))
+		for key := range tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			query.Add(fmt.Sprintf("tag:%s", key), tags[key])
+		}
+		request.URL.RawQuery = query.Encode()
+	}
+}
+
+// FilterByTagSelector returns the resources whose tags match selector.
+func FilterByTagSelector[TResource Tagged](resources []TResource, selector TagSelector) []TResource {
+	result := []TResource{}
+	for _, resource := range resources {
+		if selector.Matches(resource.GetTags()) {
+			result = append(result, resource)
+		}
+	}
+	return result
+}
+
+// ListWithTagSelector lists resources of service that match selector. The
+// requirements the API supports are sent as query parameters, the others are
+// evaluated on the returned resources.
+//
+//	selector, err := cloudscale.ParseTagSelector("env in (prod,staging), !decommissioned")
+//	servers, err := cloudscale.ListWithTagSelector(ctx, client.Servers, selector)
+func ListWithTagSelector[TResource Tagged](
+	ctx context.Context,
+	service GenericListService[TResource],
+	selector TagSelector,
+	modifiers ...ListRequestModifier,
+) ([]TResource, error) {
+	resources, err := service.List(ctx, append(modifiers, WithTagSelector(selector))...)
+	if err != nil {
+		return nil, err
+	}
+	return FilterByTagSelector(resources, selector), nil
+}
@@ -0,0 +1,164 @@
+package cloudscale
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestParseTagSelector(t *testing.T) {
+	cases := []struct {
+		expression string
+		expected   []TagRequirement
+	}{
+		{"", nil},
+		{"env", []TagRequirement{{Key: "env", Operator: TagOperatorExists}}},
+		{"!env", []TagRequirement{{Key: "env", Operator: TagOperatorDoesNotExist}}},
+		{"env=prod", []TagRequirement{{Key: "env", Operator: TagOperatorEquals, Values: []string{"prod"}}}},
+		{"env == prod", []TagRequirement{{Key: "env", Operator: TagOperatorEquals, Values: []string{"prod"}}}},
+		{"env!=prod", []TagRequirement{{Key: "env", Operator: TagOperatorNotEquals, Values: []string{"prod"}}}},
+		{"env=", []TagRequirement{{Key: "env", Operator: TagOperatorEquals, Values: []string{""}}}},
+		{"team.example.com/*", []TagRequirement{{Key: "team.example.com/*", Operator: TagOperatorExists}}},
+		{
+			"env in (prod, staging), tier notin (db),owner",
+			[]TagRequirement{
+				{Key: "env", Operator: TagOperatorIn, Values: []string{"prod", "staging"}},
+				{Key: "tier", Operator: TagOperatorNotIn, Values: []string{"db"}},
+				{Key: "owner", Operator: TagOperatorExists},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expression, func(t *testing.T) {
+			selector, err := ParseTagSelector(tc.expression)
+			if err != nil {
+				t.Fatalf("ParseTagSelector returned error: %v", err)
+			}
+			assertEqual(t, tc.expected, selector.Requirements)
+		})
+	}
+}
+
+func TestParseTagSelector_Invalid(t *testing.T) {
+	for _, expression := range []string{
+		"env,",
+		"env in (prod",
+		"env in prod",
+		"env in ()",
+		"env in ((prod))",
+		"env foo",
+		"=prod",
+		"env*=prod",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := ParseTagSelector(expression); err == nil {
+				t.Errorf("expected error for %q", expression)
+			}
+		})
+	}
+}
+
+func TestTagSelector_Matches(t *testing.T) {
+	tags := TagMap{"env": "prod", "team.example.com/owner": "ops", "empty": ""}
+
+	cases := []struct {
+		expression string
+		expected   bool
+	}{
+		{"", true},
+		{"env", true},
+		{"empty", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!env", false},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"missing!=dev", true},
+		{"env in (dev,prod)", true},
+		{"env in (dev)", false},
+		{"env notin (dev)", true},
+		{"env notin (prod)", false},
+		{"missing notin (prod)", true},
+		{"team.example.com/*", true},
+		{"!team.example.com/*", false},
+		{"other.example.com/*", false},
+		{"env=prod,!missing,team.example.com/*", true},
+		{"env=prod,missing", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.expression, func(t *testing.T) {
+			selector := MustParseTagSelector(tc.expression)
+			if actual := selector.Matches(tags); actual != tc.expected {
+				t.Errorf("Matches(%v) = %v, expected %v", tags, actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestTagSelector_String(t *testing.T) {
+	expression := "env in (prod,staging),!missing,tier!=db,owner=ops,team/*"
+	assertEqual(t, expression, MustParseTagSelector(expression).String())
+}
+
+func TestWithTagSelector(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	selector := MustParseTagSelector("env=prod,owner,tier!=db,team/*,zone in (rma1)")
+	WithTagSelector(selector)(req)
+
+	// Only equality and existence are sent to the API.
+	expected := "http://example.com?tag%3Aenv=prod&tag%3Aowner="
+	if actual := req.URL.String(); actual != expected {
+		t.Errorf("Unexpected result\n got=%#v\nwant=%#v", actual, expected)
+	}
+}
+
+func TestTagSelector_ServerSideTags(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   TagMap
+	}{
+		{"env,env=prod", TagMap{"env": "prod"}},
+		{"env=prod,env", TagMap{"env": "prod"}},
+		{"env=,env=prod", TagMap{"env": ""}},
+		{"env=prod,env=dev", TagMap{"env": "prod"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			assertEqual(t, tc.expected, MustParseTagSelector(tc.expression).serverSideTags())
+		})
+	}
+}
+
+func TestListWithTagSelector(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/servers", func(w http.ResponseWriter, r *http.Request) {
+		testHTTPMethod(t, r, http.MethodGet)
+		if got := r.URL.Query().Get("tag:env"); got != "" {
+			t.Errorf("tag:env = %q, expected existence filter", got)
+		}
+		fmt.Fprint(w, `[
+			{"uuid": "1", "tags": {"env": "prod"}},
+			{"uuid": "2", "tags": {"env": "staging"}},
+			{"uuid": "3", "tags": {"env": "dev"}},
+			{"uuid": "4", "tags": {"env": "prod", "decommissioned": ""}}
+		]`)
+	})
+
+	selector := MustParseTagSelector("env, env in (prod,staging), !decommissioned")
+	servers, err := ListWithTagSelector(ctx, client.Servers, selector)
+	if err != nil {
+		t.Fatalf("ListWithTagSelector returned error: %v", err)
+	}
+
+	uuids := []string{}
+	for _, server := range servers {
+		uuids = append(uuids, server.UUID)
+	}
+	assertEqual(t, []string{"1", "2"}, uuids)
+}
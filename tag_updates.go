@@ -0,0 +1,202 @@
+package cloudscale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// ErrTagConflict is returned by SetTags, MergeTags and RemoveTags when
+// concurrent writers kept overwriting the tags of a resource and the update
+// could not be made to stick.
+var ErrTagConflict = errors.New("tags were modified concurrently")
+
+// ErrTagNotOwned is returned when a tag update would touch a key outside the
+// prefixes passed with WithOwnedTagPrefixes.
+var ErrTagNotOwned = errors.New("tag key is not owned by the caller")
+
+const defaultTagConflictRetries = 3
+
+// TaggedService is implemented by every service whose resources can be read
+// and re-tagged through an update request embedding TaggedResourceRequest.
+type TaggedService[TResource Tagged, TUpdateRequest any] interface {
+	GenericGetService[TResource]
+	GenericUpdateService[TResource, TUpdateRequest]
+}
+
+// taggedRequest is implemented by pointers to request types embedding
+// TaggedResourceRequest.
+type taggedRequest[TUpdateRequest any] interface {
+	*TUpdateRequest
+	setTags(tags TagMap)
+}
+
+func (t *TaggedResourceRequest) setTags(tags TagMap) {
+	t.Tags = &tags
+}
+
+type tagUpdateOptions struct {
+	ownedPrefixes   []string
+	conflictRetries int
+}
+
+// TagUpdateOption configures SetTags, MergeTags and RemoveTags.
+type TagUpdateOption func(*tagUpdateOptions)
+
+// WithOwnedTagPrefixes restricts a tag update to keys starting with one of
+// prefixes, e.g. "backup.example.com/". Keys outside these namespaces are
+// never modified, and requesting a change to one fails with ErrTagNotOwned.
+func WithOwnedTagPrefixes(prefixes ...string) TagUpdateOption {
+	return func(o *tagUpdateOptions) {
+		o.ownedPrefixes = append(o.ownedPrefixes, prefixes...)
+	}
+}
+
+// WithTagConflictRetries sets how often a tag update is written again when a
+// concurrent writer overwrote it. The default is 3.
+func WithTagConflictRetries(retries int) TagUpdateOption {
+	return func(o *tagUpdateOptions) {
+		o.conflictRetries = retries
+	}
+}
+
+func (o tagUpdateOptions) owns(key string) bool {
+	if len(o.ownedPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range o.ownedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o tagUpdateOptions) checkOwnership(keys ...string) error {
+	for _, key := range keys {
+		if !o.owns(key) {
+			return fmt.Errorf("%w: %s", ErrTagNotOwned, key)
+		}
+	}
+	return nil
+}
+
+// SetTags makes tags the complete set of owned tags on the resource: owned
+// keys missing from tags are removed, tags outside the owned prefixes are
+// left untouched. Without WithOwnedTagPrefixes every key is owned, and
+// SetTags replaces all tags. It returns the tags written to the resource.
+func SetTags[TResource Tagged, TUpdateRequest any, PUpdateRequest taggedRequest[TUpdateRequest]](
+	ctx context.Context,
+	service TaggedService[TResource, TUpdateRequest],
+	resourceID string,
+	tags TagMap,
+	opts ...TagUpdateOption,
+) (TagMap, error) {
+	return updateTags[TResource, TUpdateRequest, PUpdateRequest](ctx, service, resourceID, tagKeys(tags), func(current TagMap, o tagUpdateOptions) TagMap {
+		updated := TagMap{}
+		for key, value := range current {
+			if !o.owns(key) {
+				updated[key] = value
+			}
+		}
+		maps.Copy(updated, tags)
+		return updated
+	}, opts...)
+}
+
+// MergeTags adds tags to the resource, overwriting existing values of the
+// same keys and leaving all other tags untouched. It returns the tags written
+// to the resource.
+func MergeTags[TResource Tagged, TUpdateRequest any, PUpdateRequest taggedRequest[TUpdateRequest]](
+	ctx context.Context,
+	service TaggedService[TResource, TUpdateRequest],
+	resourceID string,
+	tags TagMap,
+	opts ...TagUpdateOption,
+) (TagMap, error) {
+	return updateTags[TResource, TUpdateRequest, PUpdateRequest](ctx, service, resourceID, tagKeys(tags), func(current TagMap, _ tagUpdateOptions) TagMap {
+		updated := maps.Clone(current)
+		if updated == nil {
+			updated = TagMap{}
+		}
+		maps.Copy(updated, tags)
+		return updated
+	}, opts...)
+}
+
+// RemoveTags removes the tags with the given keys from the resource, leaving
+// all other tags untouched. It returns the tags written to the resource.
+func RemoveTags[TResource Tagged, TUpdateRequest any, PUpdateRequest taggedRequest[TUpdateRequest]](
+	ctx context.Context,
+	service TaggedService[TResource, TUpdateRequest],
+	resourceID string,
+	keys []string,
+	opts ...TagUpdateOption,
+) (TagMap, error) {
+	return updateTags[TResource, TUpdateRequest, PUpdateRequest](ctx, service, resourceID, keys, func(current TagMap, _ tagUpdateOptions) TagMap {
+		updated := maps.Clone(current)
+		if updated == nil {
+			updated = TagMap{}
+		}
+		for _, key := range keys {
+			delete(updated, key)
+		}
+		return updated
+	}, opts...)
+}
+
+// updateTags performs a read-modify-write of the resource's tags. The API
+// has no conditional updates, so a concurrent write between reading and
+// writing the tags is overwritten. To make sure the own change sticks, the
+// tags are read again after writing them; if another writer changed them in
+// the meantime and the change is missing, it is merged into the fresh tags
+// and written again. Callers using these helpers on both sides therefore
+// converge, while changes of other writers can still be lost.
+func updateTags[TResource Tagged, TUpdateRequest any, PUpdateRequest taggedRequest[TUpdateRequest]](
+	ctx context.Context,
+	service TaggedService[TResource, TUpdateRequest],
+	resourceID string,
+	touchedKeys []string,
+	modify func(current TagMap, o tagUpdateOptions) TagMap,
+	opts ...TagUpdateOption,
+) (TagMap, error) {
+	o := tagUpdateOptions{conflictRetries: defaultTagConflictRetries}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := o.checkOwnership(touchedKeys...); err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resource, err := service.Get(ctx, resourceID)
+		if err != nil {
+			return nil, err
+		}
+		current := (*resource).GetTags()
+		updated := modify(current, o)
+		if maps.Equal(updated, current) {
+			return updated, nil
+		}
+		if attempt > o.conflictRetries {
+			return nil, fmt.Errorf("updating tags of %s: %w", resourceID, ErrTagConflict)
+		}
+
+		request := PUpdateRequest(new(TUpdateRequest))
+		request.setTags(updated)
+		if err := service.Update(ctx, resourceID, (*TUpdateRequest)(request)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func tagKeys(tags TagMap) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	return keys
+}
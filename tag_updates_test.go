@@ -0,0 +1,167 @@
+package cloudscale
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// tagStore serves a single server at /v1/servers/abc whose tags can be read
+// and patched. beforeGet, if set, is called before every GET and may modify
+// the tags to simulate concurrent writers.
+type tagStore struct {
+	tags      TagMap
+	gets      int
+	patches   []TagMap
+	beforeGet func(s *tagStore)
+}
+
+func (s *tagStore) handle(t *testing.T) {
+	mux.HandleFunc("/v1/servers/abc", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			s.gets++
+			if s.beforeGet != nil {
+				s.beforeGet(s)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"uuid": "abc", "tags": s.tags})
+		case http.MethodPatch:
+			var request struct {
+				Tags TagMap `json:"tags"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Fatalf("decode json: %v", err)
+			}
+			s.patches = append(s.patches, request.Tags)
+			s.tags = request.Tags
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	})
+}
+
+func TestMergeTags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{"cost.example.com/center": "42", "env": "prod"}}
+	store.handle(t)
+
+	tags, err := MergeTags(ctx, client.Servers, "abc", TagMap{"backup.example.com/schedule": "daily", "env": "prod"})
+	if err != nil {
+		t.Fatalf("MergeTags returned error: %v", err)
+	}
+
+	expected := TagMap{"cost.example.com/center": "42", "env": "prod", "backup.example.com/schedule": "daily"}
+	assertEqual(t, expected, tags)
+	assertEqual(t, []TagMap{expected}, store.patches)
+}
+
+func TestMergeTags_NoChange(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{"env": "prod"}}
+	store.handle(t)
+
+	if _, err := MergeTags(ctx, client.Servers, "abc", TagMap{"env": "prod"}); err != nil {
+		t.Fatalf("MergeTags returned error: %v", err)
+	}
+	assertEqual(t, 0, len(store.patches))
+}
+
+func TestSetTags_OwnedPrefixes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{
+		"cost.example.com/center":     "42",
+		"backup.example.com/schedule": "hourly",
+		"backup.example.com/retain":   "7",
+	}}
+	store.handle(t)
+
+	tags, err := SetTags(ctx, client.Servers, "abc",
+		TagMap{"backup.example.com/schedule": "daily"},
+		WithOwnedTagPrefixes("backup.example.com/"),
+	)
+	if err != nil {
+		t.Fatalf("SetTags returned error: %v", err)
+	}
+
+	assertEqual(t, TagMap{"cost.example.com/center": "42", "backup.example.com/schedule": "daily"}, tags)
+}
+
+func TestSetTags_NotOwned(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{}}
+	store.handle(t)
+
+	_, err := SetTags(ctx, client.Servers, "abc",
+		TagMap{"cost.example.com/center": "42"},
+		WithOwnedTagPrefixes("backup.example.com/"),
+	)
+	if !errors.Is(err, ErrTagNotOwned) {
+		t.Errorf("expected ErrTagNotOwned, got %v", err)
+	}
+	assertEqual(t, 0, store.gets)
+}
+
+func TestRemoveTags(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{"a": "1", "b": "2"}}
+	store.handle(t)
+
+	tags, err := RemoveTags(ctx, client.Servers, "abc", []string{"a", "missing"})
+	if err != nil {
+		t.Fatalf("RemoveTags returned error: %v", err)
+	}
+	assertEqual(t, TagMap{"b": "2"}, tags)
+	assertEqual(t, TagMap{"b": "2"}, store.tags)
+}
+
+func TestMergeTags_ConcurrentModification(t *testing.T) {
+	setup()
+	defer teardown()
+
+	// Another writer overwrites our tag right after we wrote it; the update
+	// must be written again and keep the other writer's tag.
+	store := &tagStore{tags: TagMap{"a": "1"}}
+	store.beforeGet = func(s *tagStore) {
+		if s.gets == 2 {
+			s.tags = TagMap{"a": "1", "other": "x"}
+		}
+	}
+	store.handle(t)
+
+	tags, err := MergeTags(ctx, client.Servers, "abc", TagMap{"mine": "y"})
+	if err != nil {
+		t.Fatalf("MergeTags returned error: %v", err)
+	}
+	assertEqual(t, TagMap{"a": "1", "other": "x", "mine": "y"}, tags)
+	assertEqual(t, 2, len(store.patches))
+}
+
+func TestMergeTags_Conflict(t *testing.T) {
+	setup()
+	defer teardown()
+
+	store := &tagStore{tags: TagMap{}}
+	store.beforeGet = func(s *tagStore) {
+		s.tags = TagMap{"counter": fmt.Sprint(s.gets)}
+	}
+	store.handle(t)
+
+	_, err := MergeTags(ctx, client.Servers, "abc", TagMap{"mine": "y"}, WithTagConflictRetries(2))
+	if !errors.Is(err, ErrTagConflict) {
+		t.Errorf("expected ErrTagConflict, got %v", err)
+	}
+	assertEqual(t, 3, len(store.patches))
+}
@@ -3,10 +3,21 @@ package cloudscale
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 type TagMap map[string]string
 
+// hasTags reports whether tags contains all of expected with the same values.
+func hasTags(tags TagMap, expected TagMap) bool {
+	for key, value := range expected {
+		if actual, ok := tags[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
 type TaggedResource struct {
 	Tags TagMap `json:"tags"`
 }
@@ -24,3 +35,21 @@ func WithTagFilter(tags TagMap) ListRequestModifier {
 		request.URL.RawQuery = query.Encode()
 	}
 }
+
+// Validate checks that no tag key is blank.
+func (t TaggedResourceRequest) Validate() error {
+	f := fieldErrors{}
+	t.validateTags(&f)
+	return f.err()
+}
+
+func (t TaggedResourceRequest) validateTags(f *fieldErrors) {
+	if t.Tags == nil {
+		return
+	}
+	for key := range *t.Tags {
+		if strings.TrimSpace(key) == "" {
+			f.add("tags", "keys must not be blank")
+		}
+	}
+}
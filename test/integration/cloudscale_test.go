@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
@@ -15,8 +16,14 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/cloudscale-ch/cloudscale-go-sdk/v9"
+	"github.com/cloudscale-ch/cloudscale-go-sdk/v9/recorder"
 )
 
+// defaultCassette is the file the interactions are recorded into with
+// CLOUDSCALE_CASSETTE_MODE=record, unless CLOUDSCALE_CASSETTE is set. No
+// cassette is committed: replaying one requires recording it first.
+const defaultCassette = "testdata/cassette.json"
+
 var (
 	client        *cloudscale.Client
 	testRunPrefix string
@@ -27,14 +34,44 @@ func TestMain(m *testing.M) {
 	// setup tests
 	testRunPrefix = fmt.Sprintf("go-sdk-%d", rand.Intn(100000))
 
-	token := os.Getenv("CLOUDSCALE_API_TOKEN")
-	if token == "" {
-		log.Fatal("Missing CLOUDSCALE_API_TOKEN, tests won't run!\n")
+	mode := recorder.Mode(os.Getenv("CLOUDSCALE_CASSETTE_MODE"))
+	cassette := os.Getenv("CLOUDSCALE_CASSETTE")
+	if cassette == "" {
+		cassette = defaultCassette
+	}
+
+	var rec *recorder.Recorder
+	if mode == recorder.Replay {
+		var err error
+		rec, err = recorder.New(cassette, recorder.Options{Mode: mode, Lenient: true})
+		if err != nil {
+			log.Fatalf("Loading cassette: %s\n", err)
+		}
+		client = cloudscale.NewClient(&http.Client{Transport: rec})
+	} else {
+		token := os.Getenv("CLOUDSCALE_API_TOKEN")
+		if token == "" {
+			log.Fatal("Missing CLOUDSCALE_API_TOKEN, tests won't run!\n")
+		}
+		tc := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
+		))
+		if mode == recorder.Record {
+			var err error
+			rec, err = recorder.New(cassette, recorder.Options{Mode: mode, Next: tc.Transport})
+			if err != nil {
+				log.Fatalf("Creating recorder: %s\n", err)
+			}
+			tc = &http.Client{Transport: rec}
+		}
+		client = cloudscale.NewClient(tc)
+	}
+	// Fails tests on fields the SDK does not model yet.
+	client.StrictDecoding = os.Getenv("CLOUDSCALE_STRICT_DECODING") != ""
+	if rec != nil {
+		// Names have to match between recording and replay.
+		testRunPrefix = "go-sdk-recorded"
 	}
-	tc := oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	))
-	client = cloudscale.NewClient(tc)
 
 	testZone = os.Getenv("INTEGRATION_TEST_ZONE")
 	if testZone == "" {
@@ -56,6 +93,12 @@ func TestMain(m *testing.M) {
 	foundResource = foundResource || DeleteRemainingCustomImages()
 	foundResource = foundResource || DeleteRemainingLoadBalancers()
 
+	if rec != nil {
+		if err := rec.Close(); err != nil {
+			log.Fatalf("Closing recorder: %s\n", err)
+		}
+	}
+
 	if foundResource {
 		log.Fatal("Failing due to leftover resource\n")
 	}
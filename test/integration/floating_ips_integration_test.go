@@ -226,7 +226,7 @@ func TestIntegrationFloatingIP_Update(t *testing.T) {
 	}
 
 	updateRequest := &cloudscale.FloatingIPUpdateRequest{
-		Server: expected.UUID,
+		Server: cloudscale.NewNullable(expected.UUID),
 	}
 
 	ip := expectedIP.IP()
@@ -459,7 +459,7 @@ func TestIntegrationFloatingIP_Global(t *testing.T) {
 	for _, server := range append(servers, servers...) {
 		expectedServerUUID := server.UUID
 		updateRequest := &cloudscale.FloatingIPUpdateRequest{
-			Server: expectedServerUUID,
+			Server: cloudscale.NewNullable(expectedServerUUID),
 		}
 		err = client.FloatingIPs.Update(context.Background(), ip, updateRequest)
 		if err != nil {
@@ -476,6 +476,21 @@ func TestIntegrationFloatingIP_Global(t *testing.T) {
 		}
 	}
 
+	unassignRequest := &cloudscale.FloatingIPUpdateRequest{
+		Server: cloudscale.Null[string](),
+	}
+	err = client.FloatingIPs.Update(context.Background(), ip, unassignRequest)
+	if err != nil {
+		t.Fatalf("FloatingIPs.Update returned error %s\n", err)
+	}
+	actualFloatingIP, err = client.FloatingIPs.Get(context.Background(), ip)
+	if err != nil {
+		t.Fatalf("FloatingIPs.Get returned error %s\n", err)
+	}
+	if actualFloatingIP.Server != nil {
+		t.Errorf("Server \n got=%#v\nwant=%#v", actualFloatingIP.Server, nil)
+	}
+
 	for _, server := range servers {
 		err = client.Servers.Delete(context.Background(), server.UUID)
 		if err != nil {
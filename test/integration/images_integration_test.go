@@ -0,0 +1,34 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListImages(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping: short flag passed")
+	}
+	t.Parallel()
+
+	allImages, err := client.Images.List(context.Background())
+	if err != nil {
+		t.Fatalf("Images.List returned error %s\n", err)
+	}
+
+	if len(allImages) <= 0 {
+		t.Fatal("Images.List returned empty slice\n", err)
+	}
+
+	for _, image := range allImages {
+		if image.Slug == "" || image.Name == "" || image.OperatingSystem == "" {
+			t.Errorf("image %#v: expected slug, name and operating system", image)
+		}
+		if len(image.Zones) == 0 {
+			t.Errorf("image %q: expected at least one zone", image.Slug)
+		}
+	}
+}
@@ -177,7 +177,7 @@ func TestIntegrationLoadBalancerHealthMonitor_HTTP_Update(t *testing.T) {
 		Type:        "http",
 		UpThreshold: 10,
 		HTTP: &cloudscale.LoadBalancerHealthMonitorHTTPRequest{
-			Host: &hostName,
+			Host: cloudscale.NewNullable(hostName),
 		},
 	}
 
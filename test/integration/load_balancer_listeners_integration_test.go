@@ -102,7 +102,7 @@ func TestIntegrationLoadBalancerListener_Update(t *testing.T) {
 	}
 
 	condition := func(lb *cloudscale.LoadBalancer) (bool, error) {
-		if lb.Status == "running" {
+		if lb.Status == cloudscale.LoadBalancerRunning {
 			return true, nil
 		}
 		return false, fmt.Errorf("load balancer status is not 'running', current status: %s", lb.Status)
@@ -153,7 +153,7 @@ func TestIntegrationLoadBalancerListener_Update(t *testing.T) {
 	// update allowed ciders
 	updatedAllowedCIDRs := []string{"10.0.0.0/24"}
 	updateRequest2 := &cloudscale.LoadBalancerListenerRequest{
-		AllowedCIDRs: &updatedAllowedCIDRs,
+		AllowedCIDRs: cloudscale.NewOptional(updatedAllowedCIDRs),
 	}
 
 	err = client.LoadBalancerListeners.Update(context.Background(), uuid, updateRequest2)
@@ -173,7 +173,7 @@ func TestIntegrationLoadBalancerListener_Update(t *testing.T) {
 	// set allowed CIDRs to an empty list
 	updatedAllowedCIDRsEmpty := []string{}
 	updateRequest3 := &cloudscale.LoadBalancerListenerRequest{
-		AllowedCIDRs: &updatedAllowedCIDRsEmpty,
+		AllowedCIDRs: cloudscale.NewOptional(updatedAllowedCIDRsEmpty),
 	}
 
 	err = client.LoadBalancerListeners.Update(context.Background(), uuid, updateRequest3)
@@ -153,7 +153,7 @@ func TestIntegrationLoadBalancerPoolMember_Update(t *testing.T) {
 	// Disable
 	newEnabled := false
 	updateRequest2 := &cloudscale.LoadBalancerPoolMemberRequest{
-		Enabled: &newEnabled,
+		Enabled: cloudscale.NewOptional(newEnabled),
 	}
 
 	err = client.LoadBalancerPoolMembers.Update(context.Background(), pool.UUID, uuid, updateRequest2)
@@ -275,7 +275,7 @@ func TestIntegrationNetwork_Reattach(t *testing.T) {
 		Addresses: &addresses,
 	})
 	updateRequest := cloudscale.ServerUpdateRequest{
-		Interfaces: &interfaces,
+		Interfaces: cloudscale.NewOptional(interfaces),
 	}
 	err = client.Servers.Update(context.Background(), server.UUID, &updateRequest)
 	if err != nil {
@@ -366,7 +366,7 @@ func TestIntegrationNetwork_Reorder(t *testing.T) {
 	}
 
 	updateRequest := cloudscale.ServerUpdateRequest{
-		Interfaces: &interfaces,
+		Interfaces: cloudscale.NewOptional(interfaces),
 	}
 	err = client.Servers.Update(context.Background(), server.UUID, &updateRequest)
 	if err != nil {
@@ -27,11 +27,11 @@ func TestIntegrationObjectsUser_CRUD(t *testing.T) {
 	if id := objectsUser.ID; id != expected.ID {
 		t.Errorf("ObjectsUser.ID got=%s\nwant=%s", id, expected.ID)
 	}
-	if access_key := objectsUser.Keys[0]["access_key"]; access_key != expected.Keys[0]["access_key"] {
-		t.Errorf("ObjectsUser.Keys[0][\"access_key\"] got=%s\nwant=%s", access_key, expected.Keys[0]["access_key"])
+	if accessKey := objectsUser.Keys[0].AccessKey; accessKey != expected.Keys[0].AccessKey {
+		t.Errorf("ObjectsUser.Keys[0].AccessKey got=%s\nwant=%s", accessKey, expected.Keys[0].AccessKey)
 	}
-	if secret_key := objectsUser.Keys[0]["secret_key"]; secret_key != expected.Keys[0]["secret_key"] {
-		t.Errorf("ObjectsUser.Keys[0][\"secret_key\"] got=%s\nwant=%s", secret_key, expected.Keys[0]["secret_key"])
+	if secretKey := objectsUser.Keys[0].SecretKey; secretKey != expected.Keys[0].SecretKey {
+		t.Errorf("ObjectsUser.Keys[0].SecretKey got=%s\nwant=%s", secretKey, expected.Keys[0].SecretKey)
 	}
 
 	err = client.ObjectsUsers.Delete(context.Background(), objectsUser.ID)
@@ -83,3 +83,41 @@ func createObjectsUser(t *testing.T) (*cloudscale.ObjectsUser, error) {
 
 	return client.ObjectsUsers.Create(context.Background(), createRequest)
 }
+
+func TestIntegrationObjectsUser_Rotate(t *testing.T) {
+	t.Parallel()
+
+	var stored []cloudscale.ObjectsUserCredentials
+	rotation := cloudscale.ObjectsUserRotation{
+		Tags:        cloudscale.TagMap{"rotation": testRunPrefix},
+		DisplayName: testRunPrefix,
+		Sink: cloudscale.SecretSinkFunc(func(ctx context.Context, credentials cloudscale.ObjectsUserCredentials) error {
+			stored = append(stored, credentials)
+			return nil
+		}),
+	}
+
+	first, err := cloudscale.RotateObjectsUser(context.Background(), client, rotation)
+	if err != nil {
+		t.Fatalf("RotateObjectsUser returned error %s\n", err)
+	}
+	second, err := cloudscale.RotateObjectsUser(context.Background(), client, rotation)
+	if err != nil {
+		t.Fatalf("RotateObjectsUser returned error %s\n", err)
+	}
+
+	if generation := second.Credentials.Generation; generation != 2 {
+		t.Errorf("Generation got=%d\nwant=%d", generation, 2)
+	}
+	if len(stored) != 2 || stored[1].AccessKey == stored[0].AccessKey {
+		t.Errorf("expected two different keys to be stored, got %d", len(stored))
+	}
+	if len(second.Retired) != 1 || second.Retired[0].ID != first.Created.ID {
+		t.Errorf("expected the first generation to be retired, got %v", second.Retired)
+	}
+
+	err = client.ObjectsUsers.Delete(context.Background(), second.Created.ID)
+	if err != nil {
+		t.Fatalf("ObjectsUsers.Delete returned error %s\n", err)
+	}
+}
@@ -80,7 +80,7 @@ func TestIntegrationSubnet_CRUD(t *testing.T) {
 	createSubnetRequest := &cloudscale.SubnetCreateRequest{
 		CIDR:           "192.168.192.0/22",
 		GatewayAddress: "192.168.192.2",
-		DNSServers:     &[]string{"77.109.128.2", "213.144.129.20"},
+		DNSServers:     cloudscale.NewNullable([]string{"77.109.128.2", "213.144.129.20"}),
 		Network:        network.UUID,
 	}
 	expected, err := client.Subnets.Create(context.TODO(), createSubnetRequest)
@@ -168,9 +168,9 @@ func TestIntegrationSubnet_Update(t *testing.T) {
 	}
 
 	// update DNSServers
-	expectedDNSServers := &[]string{"77.109.128.2", "213.144.129.20", "1.1.1.1"}
+	expectedDNSServers := []string{"77.109.128.2", "213.144.129.20", "1.1.1.1"}
 	updateRequest = &cloudscale.SubnetUpdateRequest{
-		DNSServers: expectedDNSServers,
+		DNSServers: cloudscale.NewNullable(expectedDNSServers),
 	}
 
 	err = client.Subnets.Update(context.Background(), subnet.UUID, updateRequest)
@@ -183,13 +183,13 @@ func TestIntegrationSubnet_Update(t *testing.T) {
 		t.Fatalf("Subnets.Get returned error %s\n", err)
 	}
 
-	if actualDNSServers := updatedSubnet.DNSServers; !reflect.DeepEqual(actualDNSServers, *expectedDNSServers) {
-		t.Errorf("Subnet DNSServers\ngot=%#v\nwant=%#v", actualDNSServers, *expectedDNSServers)
+	if actualDNSServers := updatedSubnet.DNSServers; !reflect.DeepEqual(actualDNSServers, expectedDNSServers) {
+		t.Errorf("Subnet DNSServers\ngot=%#v\nwant=%#v", actualDNSServers, expectedDNSServers)
 	}
 
 	// update to no DNSServers
 	updateRequest = &cloudscale.SubnetUpdateRequest{
-		DNSServers: &[]string{},
+		DNSServers: cloudscale.NewNullable([]string{}),
 	}
 
 	err = client.Subnets.Update(context.Background(), subnet.UUID, updateRequest)
@@ -208,7 +208,7 @@ func TestIntegrationSubnet_Update(t *testing.T) {
 
 	// update to Default DNSServer
 	updateRequest = &cloudscale.SubnetUpdateRequest{
-		DNSServers: &cloudscale.UseCloudscaleDefaults,
+		DNSServers: cloudscale.Null[[]string](),
 	}
 
 	err = client.Subnets.Update(context.Background(), subnet.UUID, updateRequest)
@@ -227,9 +227,9 @@ func TestIntegrationSubnet_Update(t *testing.T) {
 	}
 
 	// update to invalid DNSServer value 0.0.0.0
-	invalidDNSServers := &[]string{"0.0.0.0"}
+	invalidDNSServers := []string{"0.0.0.0"}
 	updateRequest = &cloudscale.SubnetUpdateRequest{
-		DNSServers: invalidDNSServers,
+		DNSServers: cloudscale.NewNullable(invalidDNSServers),
 	}
 	err = client.Subnets.Update(context.Background(), subnet.UUID, updateRequest)
 	if err == nil {
@@ -276,7 +276,7 @@ func TestIntegrationSubnet_InitialEmptyDNSServers(t *testing.T) {
 	createSubnetRequest := &cloudscale.SubnetCreateRequest{
 		Network:    network.UUID,
 		CIDR:       "10.0.0.0/8",
-		DNSServers: &[]string{},
+		DNSServers: cloudscale.NewNullable([]string{}),
 	}
 
 	subnet, err := client.Subnets.Create(context.TODO(), createSubnetRequest)
@@ -291,7 +291,7 @@ func TestIntegrationSubnet_InitialEmptyDNSServers(t *testing.T) {
 
 	// update DNSServers
 	updateRequest := &cloudscale.SubnetUpdateRequest{
-		DNSServers: &cloudscale.UseCloudscaleDefaults,
+		DNSServers: cloudscale.Null[[]string](),
 	}
 
 	err = client.Subnets.Update(context.Background(), subnet.UUID, updateRequest)
@@ -165,7 +165,7 @@ func waitForSnapshotDeletion(ctx context.Context, snapshotUUID string, maxWaitSe
 		}
 
 		// if snapshot still exists, it must be in state deleting
-		if snapshot.Status != "deleting" {
+		if snapshot.Status != cloudscale.VolumeSnapshotDeleting {
 			return fmt.Errorf(
 				"snapshot %s exists but is in unexpected state %q while waiting for deletion",
 				snapshotUUID,
@@ -60,14 +60,14 @@ func TestIntegrationVolume_CreateAttached(t *testing.T) {
 
 	time.Sleep(3 * time.Second)
 	detachVolumeRequest := &cloudscale.VolumeUpdateRequest{
-		ServerUUIDs: &[]string{},
+		ServerUUIDs: cloudscale.NewOptional([]string{}),
 	}
 	err = client.Volumes.Update(context.TODO(), volume.UUID, detachVolumeRequest)
 	if err != nil {
 		t.Errorf("Volumes.Update returned error %s\n", err)
 	}
 	attachVolumeRequest := &cloudscale.VolumeUpdateRequest{
-		ServerUUIDs: &[]string{server.UUID},
+		ServerUUIDs: cloudscale.NewOptional([]string{server.UUID}),
 	}
 
 	time.Sleep(3 * time.Second)
@@ -254,7 +254,7 @@ func TestIntegrationVolume_AttachToNewServer(t *testing.T) {
 		t.Fatalf("Servers.WaitFor returned error %s\n", err)
 	}
 	volumeAttachRequest := &cloudscale.VolumeUpdateRequest{
-		ServerUUIDs: &[]string{server.UUID},
+		ServerUUIDs: cloudscale.NewOptional([]string{server.UUID}),
 	}
 
 	err = client.Volumes.Update(context.Background(), volume.UUID, volumeAttachRequest)
@@ -0,0 +1,231 @@
+package cloudscale
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownField is returned by clients with StrictDecoding when the API
+// returns a field the SDK does not model.
+var ErrUnknownField = errors.New("unknown field")
+
+// UnknownFields is embedded in all resources. It keeps the top-level fields
+// of the API's JSON that the SDK does not model yet, so that they are not
+// lost when a resource is read and written back: they are included again
+// when the resource is encoded to JSON.
+//
+// Only top-level fields are kept. Unknown fields of nested objects, like the
+// Interfaces of a server or the zone of a ZonalResource, are dropped. To find
+// the unknown fields, responses are decoded twice, and every item of a list
+// once more.
+type UnknownFields struct {
+	// Extra maps the names of unknown fields to their JSON values. It is nil
+	// if the API returned no unknown fields.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (u *UnknownFields) unknownFields() *UnknownFields {
+	return u
+}
+
+type unknownFieldsHolder interface {
+	unknownFields() *UnknownFields
+}
+
+var unknownFieldsHolderType = reflect.TypeFor[unknownFieldsHolder]()
+
+// decodeResponse decodes the response body data into v. Unknown fields of
+// resources are kept in their UnknownFields, unless strict is set, which
+// fails with ErrUnknownField on unknown fields at any depth.
+func decodeResponse(data []byte, v interface{}, strict bool) error {
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return err
+	}
+	if !strict {
+		return keepUnknownFields(data, reflect.ValueOf(v))
+	}
+	if unknown := findUnknownFields(data, reflect.TypeOf(v), ""); len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// findUnknownFields compares the keys of the JSON objects in data with the
+// known fields of type t and its nested types. It returns the unknown fields
+// and where they are, e.g. `"gateway" in subnets[0]`.
+func findUnknownFields(data []byte, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	unknown := []string{}
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := map[string]json.RawMessage{}
+		if json.Unmarshal(data, &fields) != nil {
+			return nil
+		}
+		known := knownFields(t)
+		for _, name := range sortedKeys(fields) {
+			fieldType, ok := known[strings.ToLower(name)]
+			switch {
+			case !ok && path == "":
+				unknown = append(unknown, strconv.Quote(name))
+			case !ok:
+				unknown = append(unknown, fmt.Sprintf("%q in %s", name, path))
+			default:
+				unknown = append(unknown, findUnknownFields(fields[name], fieldType, joinPath(path, name))...)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items := []json.RawMessage{}
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for i, item := range items {
+			unknown = append(unknown, findUnknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		values := map[string]json.RawMessage{}
+		if json.Unmarshal(data, &values) != nil {
+			return nil
+		}
+		for _, key := range sortedKeys(values) {
+			unknown = append(unknown, findUnknownFields(values[key], t.Elem(), joinPath(path, key))...)
+		}
+	}
+	return unknown
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys[TValue any](m map[string]TValue) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// keepUnknownFields fills the UnknownFields of the resource or the slice of
+// resources v points to.
+func keepUnknownFields(data []byte, v reflect.Value) error {
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+	elem := v.Elem()
+	switch {
+	case v.Type().Implements(unknownFieldsHolderType):
+		return fillUnknownFields(data, v)
+	case elem.Kind() == reflect.Slice && reflect.PointerTo(elem.Type().Elem()).Implements(unknownFieldsHolderType):
+		items := []json.RawMessage{}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for i := 0; i < elem.Len() && i < len(items); i++ {
+			if err := fillUnknownFields(items[i], elem.Index(i).Addr()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fillUnknownFields(data []byte, resource reflect.Value) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	known := knownFields(resource.Type().Elem())
+	for name := range fields {
+		// Like encoding/json, match names case-insensitively.
+		if _, ok := known[strings.ToLower(name)]; ok {
+			delete(fields, name)
+		}
+	}
+	holder := resource.Interface().(unknownFieldsHolder).unknownFields()
+	holder.Extra = nil
+	if len(fields) > 0 {
+		holder.Extra = fields
+	}
+	return nil
+}
+
+var knownFieldsCache sync.Map
+
+// knownFields maps the lower-case JSON names of the fields of struct type t,
+// including those of embedded structs, to their types.
+func knownFields(t reflect.Type) map[string]reflect.Type {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.(map[string]reflect.Type)
+	}
+	known := map[string]reflect.Type{}
+	for _, field := range reflect.VisibleFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case !field.IsExported() || name == "-":
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			// Fields of embedded structs are listed by VisibleFields.
+		case name == "":
+			known[strings.ToLower(field.Name)] = field.Type
+		default:
+			known[strings.ToLower(name)] = field.Type
+		}
+	}
+	knownFieldsCache.Store(t, known)
+	return known
+}
+
+// marshalWithUnknownFields encodes v, the resource converted to a type
+// without MarshalJSON method, and appends the unknown fields, sorted by
+// name.
+func marshalWithUnknownFields(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	names := sortedKeys(extra)
+	buf := bytes.NewBuffer(bytes.TrimSuffix(data, []byte("}")))
+	for i, name := range names {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// rawJSON returns the JSON of resource, which includes its unknown fields.
+func rawJSON(resource interface{}) json.RawMessage {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,126 @@
+package cloudscale
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownFields_Get(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/volumes/v1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "v1", "name": "data", "size_gb": 50, "encryption": {"enabled": true}, "iops": 1000}`)
+	})
+
+	volume, err := client.Volumes.Get(ctx, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]json.RawMessage{
+		"encryption": json.RawMessage(`{"enabled": true}`),
+		"iops":       json.RawMessage(`1000`),
+	}
+	if !reflect.DeepEqual(volume.Extra, expected) {
+		t.Errorf("expected unknown fields %s, got %s", expected, volume.Extra)
+	}
+	if volume.Name != "data" || volume.SizeGB != 50 {
+		t.Errorf("expected known fields to be decoded, got %+v", volume)
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(volume.Raw(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"uuid", "name", "size_gb", "encryption", "iops"} {
+		if _, ok := raw[field]; !ok {
+			t.Errorf("expected %s in %s", field, volume.Raw())
+		}
+	}
+
+	// Writing the resource back and reading it again keeps the fields.
+	data, err := json.Marshal([]Volume{*volume})
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumes := []Volume{}
+	if err := decodeResponse(data, &volumes, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 1 || string(volumes[0].Extra["encryption"]) != `{"enabled":true}` {
+		t.Errorf("expected a round trip to keep unknown fields, got %+v", volumes)
+	}
+}
+
+func TestUnknownFields_List(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/servers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"uuid": "s1", "zone": {"slug": "rma1"}, "tags": {}}, {"uuid": "s2", "rescue": true}]`)
+	})
+
+	servers, err := client.Servers.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if servers[0].Extra != nil {
+		t.Errorf("expected no unknown fields, got %s", servers[0].Extra)
+	}
+	if string(servers[1].Extra["rescue"]) != "true" {
+		t.Errorf("expected the unknown field of the second server, got %s", servers[1].Extra)
+	}
+	if servers[0].Zone.Slug != "rma1" {
+		t.Errorf("expected fields of embedded structs to be decoded, got %+v", servers[0])
+	}
+}
+
+func TestUnknownFields_MarshalWithoutExtra(t *testing.T) {
+	network := Network{UUID: "n1", Name: "private"}
+	type plain Network
+	expected, _ := json.Marshal(plain(network))
+	if data, _ := json.Marshal(network); string(data) != string(expected) {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestClient_StrictDecoding(t *testing.T) {
+	setup()
+	defer teardown()
+
+	mux.HandleFunc("/v1/networks/n1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "n1", "name": "private"}`)
+	})
+	mux.HandleFunc("/v1/networks/n2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uuid": "n2", "subnets": [{"uuid": "s1", "gateway": "10.0.0.1"}]}`)
+	})
+
+	client.StrictDecoding = true
+	if _, err := client.Networks.Get(ctx, "n1"); err != nil {
+		t.Errorf("expected known fields to decode, got %v", err)
+	}
+	_, err := client.Networks.Get(ctx, "n2")
+	if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), `"gateway" in subnets[0]`) {
+		t.Errorf("expected the nested unknown field to fail, got %v", err)
+	}
+}
+
+func TestFindUnknownFields(t *testing.T) {
+	data := []byte(`[
+		{"uuid": "s1", "iops": 1, "zone": {"slug": "rma1", "region": "rma"}, "tags": {"a": "b"}, "created_at": "2024-01-01T00:00:00Z"},
+		{"uuid": "s2", "interfaces": [{"type": "public", "mac": "x"}]}
+	]`)
+	expected := []string{`"iops" in [0]`, `"region" in [0].zone`, `"mac" in [1].interfaces[0]`}
+	if unknown := findUnknownFields(data, reflect.TypeFor[[]Server](), ""); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected %v, got %v", expected, unknown)
+	}
+
+	if unknown := findUnknownFields([]byte(`{"uuid": "v1", "extra": 1}`), reflect.TypeFor[*Volume](), ""); !reflect.DeepEqual(unknown, []string{`"extra"`}) {
+		t.Errorf("expected the top-level field, got %v", unknown)
+	}
+}
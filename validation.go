@@ -0,0 +1,181 @@
+package cloudscale
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+)
+
+// FieldError describes an invalid field of a request. Field is the JSON
+// name of the field, with the path of nested fields separated by dots, e.g.
+// "interfaces[0].network".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError is returned by Validate and by Client.NewRequest when a
+// request is rejected before it is sent. It lists every invalid field.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.Error())
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// Unwrap allows errors.As to find individual FieldErrors.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		errs = append(errs, fieldError)
+	}
+	return errs
+}
+
+// Validator is implemented by all request types. Validate checks a request
+// for mistakes the API would reject, without making any API calls. Values of
+// enums the SDK does not know, e.g. a new volume type, are left to the API.
+type Validator interface {
+	Validate() error
+}
+
+// CatalogValidator is implemented by request types referring to zones,
+// flavors or images. ValidateWithCatalog runs Validate and additionally
+// checks those references against catalog.
+type CatalogValidator interface {
+	Validator
+	ValidateWithCatalog(catalog *Catalog) error
+}
+
+// validateRequest validates body if Client.ValidateRequests is set.
+func (c *Client) validateRequest(body interface{}) error {
+	if !c.ValidateRequests || body == nil {
+		return nil
+	}
+	// A nil pointer to a request implements Validator through its value
+	// receiver but cannot be validated.
+	if value := reflect.ValueOf(body); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	if c.Catalog != nil {
+		if validator, ok := body.(CatalogValidator); ok {
+			return validator.ValidateWithCatalog(c.Catalog)
+		}
+	}
+	if validator, ok := body.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// fieldErrors collects the errors found while validating a request.
+type fieldErrors []FieldError
+
+func (f *fieldErrors) add(field string, format string, args ...interface{}) {
+	*f = append(*f, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// addNested adds the errors of a nested request, prefixing their fields.
+func (f *fieldErrors) addNested(prefix string, err error) {
+	if err == nil {
+		return
+	}
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		f.add(prefix, "%s", err)
+		return
+	}
+	for _, fieldError := range validationError.Errors {
+		f.add(prefix+"."+fieldError.Field, "%s", fieldError.Message)
+	}
+}
+
+func (f *fieldErrors) required(field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		f.add(field, "must not be blank")
+	}
+}
+
+func (f *fieldErrors) notNegative(field string, value int) {
+	if value < 0 {
+		f.add(field, "must not be negative, got %d", value)
+	}
+}
+
+func (f *fieldErrors) oneOf(field string, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	f.add(field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (f *fieldErrors) cidr(field string, value string) {
+	if value == "" {
+		return
+	}
+	if _, _, err := net.ParseCIDR(value); err != nil {
+		f.add(field, "must be a CIDR like 192.0.2.0/24, got %q", value)
+	}
+}
+
+func (f *fieldErrors) ip(field string, value string) {
+	if value == "" {
+		return
+	}
+	if net.ParseIP(value) == nil {
+		f.add(field, "must be an IP address, got %q", value)
+	}
+}
+
+func (f *fieldErrors) zone(catalog *Catalog, field string, zone string) {
+	if zone != "" && !catalog.HasZone(zone) {
+		f.add(field, "unknown zone %q", zone)
+	}
+}
+
+func (f *fieldErrors) region(catalog *Catalog, field string, region string) {
+	if region == "" {
+		return
+	}
+	for _, r := range catalog.Regions() {
+		if r.Slug == region {
+			return
+		}
+	}
+	f.add(field, "unknown region %q", region)
+}
+
+func (f fieldErrors) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: f}
+}
+
+// mergeValidation combines the result of Validate with further catalog
+// errors into a single ValidationError.
+func mergeValidation(validateErr error, catalogErrors fieldErrors) error {
+	var all fieldErrors
+	var validationError *ValidationError
+	if errors.As(validateErr, &validationError) {
+		all = append(all, validationError.Errors...)
+	}
+	all = append(all, catalogErrors...)
+	return all.err()
+}
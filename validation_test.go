@@ -0,0 +1,204 @@
+package cloudscale
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func fieldsOf(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	fields := []string{}
+	for _, fieldError := range validationError.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	return fields
+}
+
+func TestValidate_Requests(t *testing.T) {
+	tags := TagMap{" ": "blank"}
+	public := true
+	host := "example.com"
+
+	cases := []struct {
+		name    string
+		request Validator
+		fields  []string
+	}{
+		{"valid server", ServerRequest{Name: "db", Flavor: "flex-4-1", Image: "debian-12"}, nil},
+		{
+			"server missing fields",
+			ServerRequest{VolumeSizeGB: 5, TaggedResourceRequest: TaggedResourceRequest{Tags: &tags}},
+			[]string{"tags", "name", "flavor", "image", "volume_size_gb"},
+		},
+		{
+			"server networks and interfaces",
+			ServerRequest{
+				Name: "db", Flavor: "flex-4-1", Image: "debian-12",
+				UsePublicNetwork: &public,
+				Interfaces: &[]InterfaceRequest{
+					{Network: "public"},
+					{Addresses: &[]AddressRequest{{Address: "not-an-ip"}}},
+				},
+				Volumes: &[]ServerVolumeRequest{{SizeGB: -1, Type: "tape"}},
+			},
+			[]string{"use_public_network", "interfaces[1].addresses[0].address", "volumes[0].size_gb"},
+		},
+		{"server update status", ServerUpdateRequest{Status: "paused"}, nil},
+		{"volume without size", VolumeCreateRequest{}, []string{"size_gb"}},
+		{"volume from snapshot", VolumeCreateRequest{VolumeSnapshotUUID: "snap"}, nil},
+		{"snapshot without source", VolumeSnapshotCreateRequest{}, []string{"source_volume"}},
+		{"network mtu", NetworkCreateRequest{MTU: 100}, []string{"mtu"}},
+		{
+			"subnet",
+			SubnetCreateRequest{CIDR: "10.0.0.0/24", GatewayAddress: "10.0.1.1", DNSServers: NewNullable([]string{"dns"})},
+			[]string{"network", "gateway_address", "dns_servers[0]"},
+		},
+		{"subnet bad cidr", SubnetCreateRequest{CIDR: "10.0.0.0", Network: "net"}, []string{"cidr"}},
+		{"subnet default dns", SubnetUpdateRequest{DNSServers: Null[[]string]()}, nil},
+		{
+			"floating ip",
+			FloatingIPCreateRequest{IPVersion: 5, Server: "a", LoadBalancer: "b"},
+			[]string{"ip_version", "load_balancer"},
+		},
+		{"floating ip prefix", FloatingIPCreateRequest{IPVersion: 4, PrefixLength: 33}, []string{"prefix_length"}},
+		{"server group", ServerGroupRequest{Type: "affinity"}, nil},
+		{"objects user", ObjectsUserRequest{DisplayName: "backup"}, nil},
+		{"custom image", CustomImageRequest{UserDataHandling: "ignore"}, nil},
+		{
+			"custom image import",
+			CustomImageImportRequest{URL: "ftp://example.com/image.raw", FirmwareType: "coreboot"},
+			[]string{"url", "name"},
+		},
+		{"load balancer vip", LoadBalancerRequest{VIPAddresses: &[]VIPAddressRequest{{Address: "x"}}}, []string{"vip_addresses[0].address"}},
+		{"pool", LoadBalancerPoolRequest{Algorithm: "random", Protocol: "udp"}, nil},
+		{"pool member", LoadBalancerPoolMemberRequest{ProtocolPort: 70000, Address: "10.0.0.1"}, []string{"protocol_port"}},
+		{
+			"listener",
+			LoadBalancerListenerRequest{Protocol: "tcp", ProtocolPort: 80, AllowedCIDRs: NewOptional([]string{"10.0.0.1"})},
+			[]string{"allowed_cidrs[0]"},
+		},
+		{
+			"health monitor",
+			LoadBalancerHealthMonitorRequest{
+				DelayS: 2, TimeoutS: 5, UpThreshold: 11, Type: "ping",
+				HTTP: &LoadBalancerHealthMonitorHTTPRequest{Version: "1.0", Host: NewNullable(host), ExpectedCodes: []string{"2xx"}},
+			},
+			[]string{"timeout_s", "up_threshold", "http", "http.host", "http.expected_codes[0]"},
+		},
+		{"bucket metrics", BucketMetricsRequest{}, []string{"start", "end"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := fieldsOf(t, tc.request.Validate())
+			if !reflect.DeepEqual(fields, tc.fields) {
+				t.Errorf("expected invalid fields %v, got %v", tc.fields, fields)
+			}
+		})
+	}
+}
+
+func TestValidate_WithCatalog(t *testing.T) {
+	setup()
+	defer teardown()
+	handleCatalog(t)
+
+	catalog := NewCatalog(client)
+	if err := catalog.Load(ctx); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		request CatalogValidator
+		fields  []string
+	}{
+		{"valid server", ServerRequest{Name: "db", Flavor: "flex-4-1", Image: "debian-12", Zone: "lpg1"}, nil},
+		{"unknown references", ServerRequest{Name: "db", Flavor: "flex-1-1", Image: "arch", Zone: "xyz1"}, []string{"zone", "flavor", "image"}},
+		{"image not in zone", ServerRequest{Name: "db", Flavor: "flex-4-1", Image: "windows-2022", Zone: "lpg1"}, []string{"image"}},
+		{"custom image not in zone", ServerRequest{Name: "db", Flavor: "flex-4-1", Image: "custom:golden", Zone: "rma1"}, []string{"image"}},
+		{"combines errors", ServerRequest{Flavor: "flex-4-1", Image: "debian-12", Zone: "xyz1"}, []string{"name", "zone"}},
+		{"floating ip region", FloatingIPCreateRequest{IPVersion: 4, RegionalResourceRequest: RegionalResourceRequest{Region: "zrh"}}, []string{"region"}},
+		{"import zones", CustomImageImportRequest{URL: "https://example.com/i.raw", Name: "i", Zones: []string{"rma1", "abc1"}}, []string{"zones[1]"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields := fieldsOf(t, tc.request.ValidateWithCatalog(catalog))
+			if !reflect.DeepEqual(fields, tc.fields) {
+				t.Errorf("expected invalid fields %v, got %v", tc.fields, fields)
+			}
+		})
+	}
+}
+
+func TestClient_ValidateRequests(t *testing.T) {
+	setup()
+	defer teardown()
+
+	called := false
+	mux.HandleFunc("/v1/volumes", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	client.ValidateRequests = true
+	_, err := client.Volumes.Create(ctx, &VolumeCreateRequest{Name: "empty"})
+
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if called {
+		t.Error("expected invalid request not to be sent")
+	}
+	if expected := "invalid request: size_gb: must be positive unless created from a snapshot, got 0"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	if err := client.validateRequest((*VolumeCreateRequest)(nil)); err != nil {
+		t.Errorf("expected a nil request to be left to the API, got %v", err)
+	}
+}
+
+// enumValue is implemented by the string enums of the package, e.g.
+// VolumeType.
+type enumValue interface {
+	IsValid() bool
+	String() string
+}
+
+func TestEnums(t *testing.T) {
+	known := []enumValue{
+		ServerRunning, InterfaceTypePrivate, VolumeTypeBulk, FloatingIPTypeGlobal,
+		ServerGroupTypeAntiAffinity, LoadBalancerPoolAlgorithmSourceIP, LoadBalancerPoolProtocolProxyV2,
+		LoadBalancerListenerProtocolTCP, LoadBalancerHealthMonitorTypeTLSHello, MonitorStatusNoMonitor,
+		CustomImageImportInProgress, UserDataHandlingExtendCloudConfig, FirmwareTypeUEFI,
+		LoadBalancerRunning, VolumeSnapshotAvailable,
+	}
+	for _, value := range known {
+		if !value.IsValid() {
+			t.Errorf("expected %q to be valid", value)
+		}
+	}
+	if VolumeType("nvme").IsValid() || ServerStatus("").IsValid() {
+		t.Error("expected unknown values to be invalid")
+	}
+
+	var volume Volume
+	if err := json.Unmarshal([]byte(`{"type": "nvme"}`), &volume); err != nil {
+		t.Fatal(err)
+	}
+	if volume.Type.String() != "nvme" || volume.Type.IsValid() {
+		t.Errorf("expected unknown type to be preserved, got %q", volume.Type)
+	}
+}
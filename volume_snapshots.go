@@ -1,7 +1,37 @@
 package cloudscale
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
 const volumeSnapshotsBasePath = "v1/volume-snapshots"
 
+// VolumeSnapshotStatus is the state of a volume snapshot.
+type VolumeSnapshotStatus string
+
+const (
+	VolumeSnapshotCreating  VolumeSnapshotStatus = "creating"
+	VolumeSnapshotAvailable VolumeSnapshotStatus = "available"
+	VolumeSnapshotDeleting  VolumeSnapshotStatus = "deleting"
+	VolumeSnapshotError     VolumeSnapshotStatus = "error"
+)
+
+// IsValid reports whether s is a known volume snapshot status.
+func (s VolumeSnapshotStatus) IsValid() bool {
+	switch s {
+	case VolumeSnapshotCreating, VolumeSnapshotAvailable, VolumeSnapshotDeleting, VolumeSnapshotError:
+		return true
+	}
+	return false
+}
+
+func (s VolumeSnapshotStatus) String() string {
+	return string(s)
+}
+
 type SourceVolumeStub struct {
 	HREF string `json:"href"`
 	UUID string `json:"uuid"`
@@ -9,15 +39,28 @@ type SourceVolumeStub struct {
 }
 
 type VolumeSnapshot struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
-	HREF         string           `json:"href,omitempty"`
-	UUID         string           `json:"uuid,omitempty"`
-	Name         string           `json:"name,omitempty"`
-	SizeGB       int              `json:"size_gb,omitempty"`
-	CreatedAt    string           `json:"created_at,omitempty"`
-	SourceVolume SourceVolumeStub `json:"source_volume,omitempty"`
-	Status       string           `json:"status,omitempty"`
+	HREF         string               `json:"href,omitempty"`
+	UUID         string               `json:"uuid,omitempty"`
+	Name         string               `json:"name,omitempty"`
+	SizeGB       int                  `json:"size_gb,omitempty"`
+	CreatedAt    string               `json:"created_at,omitempty"`
+	SourceVolume SourceVolumeStub     `json:"source_volume,omitempty"`
+	Status       VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+// MarshalJSON encodes the volume snapshot including its unknown fields.
+func (v VolumeSnapshot) MarshalJSON() ([]byte, error) {
+	type plain VolumeSnapshot
+	return marshalWithUnknownFields(plain(v), v.Extra)
+}
+
+// Raw returns the JSON of the volume snapshot, including the fields the SDK
+// does not model.
+func (v VolumeSnapshot) Raw() json.RawMessage {
+	return rawJSON(v)
 }
 
 type VolumeSnapshotCreateRequest struct {
@@ -35,7 +78,35 @@ type VolumeSnapshotService interface {
 	GenericCreateService[VolumeSnapshot, VolumeSnapshotCreateRequest]
 	GenericGetService[VolumeSnapshot]
 	GenericListService[VolumeSnapshot]
+	GenericWatchService[VolumeSnapshot]
 	GenericUpdateService[VolumeSnapshot, VolumeSnapshotUpdateRequest]
 	GenericDeleteService[VolumeSnapshot]
 	GenericWaitForService[VolumeSnapshot]
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r VolumeSnapshotCreateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.required("source_volume", r.SourceVolume)
+	return f.err()
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r VolumeSnapshotUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	return f.err()
+}
+
+// VolumeSnapshotIsAvailable waits for a volume snapshot to be available. It
+// fails with a *StateError once the snapshot has status "error".
+var VolumeSnapshotIsAvailable Condition[VolumeSnapshot] = func(snapshot *VolumeSnapshot) (bool, error) {
+	if snapshot.Status == VolumeSnapshotAvailable {
+		return true, nil
+	}
+	if snapshot.Status == VolumeSnapshotError {
+		return false, backoff.Permanent(&StateError{Resource: "volume snapshot", ID: snapshot.UUID, Status: snapshot.Status.String()})
+	}
+	return false, fmt.Errorf("waiting for status: %s, current status: %s", VolumeSnapshotAvailable, snapshot.Status)
+}
@@ -1,50 +1,89 @@
 package cloudscale
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 )
 
 const volumeBasePath = "v1/volumes"
 
+// VolumeType is the storage class of a volume.
+type VolumeType string
+
+const (
+	VolumeTypeSSD  VolumeType = "ssd"
+	VolumeTypeBulk VolumeType = "bulk"
+)
+
+// IsValid reports whether t is a known volume type.
+func (t VolumeType) IsValid() bool {
+	switch t {
+	case VolumeTypeSSD, VolumeTypeBulk:
+		return true
+	}
+	return false
+}
+
+func (t VolumeType) String() string {
+	return string(t)
+}
+
 type Volume struct {
+	UnknownFields
 	ZonalResource
 	TaggedResource
 	// Just use omitempty everywhere. This makes it easy to use restful. Errors
 	// will be coming from the API if something is disabled.
-	HREF        string    `json:"href,omitempty"`
-	UUID        string    `json:"uuid,omitempty"`
-	Name        string    `json:"name,omitempty"`
-	SizeGB      int       `json:"size_gb,omitempty"`
-	Type        string    `json:"type,omitempty"`
-	ServerUUIDs *[]string `json:"server_uuids,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	HREF        string     `json:"href,omitempty"`
+	UUID        string     `json:"uuid,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	SizeGB      int        `json:"size_gb,omitempty"`
+	Type        VolumeType `json:"type,omitempty"`
+	ServerUUIDs *[]string  `json:"server_uuids,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// MarshalJSON encodes the volume including its unknown fields.
+func (v Volume) MarshalJSON() ([]byte, error) {
+	type plain Volume
+	return marshalWithUnknownFields(plain(v), v.Extra)
+}
+
+// Raw returns the JSON of the volume, including the fields the SDK does not
+// model.
+func (v Volume) Raw() json.RawMessage {
+	return rawJSON(v)
 }
 
 type VolumeCreateRequest struct {
 	ZonalResourceRequest
 	TaggedResourceRequest
-	Name               string    `json:"name,omitempty"`
-	SizeGB             int       `json:"size_gb,omitempty"`
-	Type               string    `json:"type,omitempty"`
-	ServerUUIDs        *[]string `json:"server_uuids,omitempty"`
-	VolumeSnapshotUUID string    `json:"volume_snapshot_uuid,omitempty"`
+	Name               string     `json:"name,omitempty"`
+	SizeGB             int        `json:"size_gb,omitempty"`
+	Type               VolumeType `json:"type,omitempty"`
+	ServerUUIDs        *[]string  `json:"server_uuids,omitempty"`
+	VolumeSnapshotUUID string     `json:"volume_snapshot_uuid,omitempty"`
 }
 
+// VolumeUpdateRequest changes a volume. Setting ServerUUIDs to an empty list
+// detaches the volume.
 type VolumeUpdateRequest struct {
 	ZonalResourceRequest
 	TaggedResourceRequest
-	Name        string    `json:"name,omitempty"`
-	SizeGB      int       `json:"size_gb,omitempty"`
-	Type        string    `json:"type,omitempty"`
-	ServerUUIDs *[]string `json:"server_uuids,omitempty"`
+	Name        string             `json:"name,omitempty"`
+	SizeGB      int                `json:"size_gb,omitempty"`
+	Type        VolumeType         `json:"type,omitempty"`
+	ServerUUIDs Optional[[]string] `json:"server_uuids,omitzero"`
 }
 
 type VolumeService interface {
 	GenericCreateService[Volume, VolumeCreateRequest]
 	GenericGetService[Volume]
 	GenericListService[Volume]
+	GenericWatchService[Volume]
 	GenericUpdateService[Volume, VolumeUpdateRequest]
 	GenericDeleteService[Volume]
 	GenericWaitForService[Volume]
@@ -58,3 +97,57 @@ func WithNameFilter(name string) ListRequestModifier {
 		request.URL.RawQuery = query.Encode()
 	}
 }
+
+// Validate checks the request for mistakes the API would reject.
+func (r VolumeCreateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	if r.VolumeSnapshotUUID == "" && r.SizeGB <= 0 {
+		f.add("size_gb", "must be positive unless created from a snapshot, got %d", r.SizeGB)
+	}
+	return f.err()
+}
+
+// ValidateWithCatalog runs Validate and checks that the zone exists.
+func (r VolumeCreateRequest) ValidateWithCatalog(catalog *Catalog) error {
+	f := fieldErrors{}
+	f.zone(catalog, "zone", r.Zone)
+	return mergeValidation(r.Validate(), f)
+}
+
+// Validate checks the request for mistakes the API would reject.
+func (r VolumeUpdateRequest) Validate() error {
+	f := fieldErrors{}
+	r.validateTags(&f)
+	f.notNegative("size_gb", r.SizeGB)
+	return f.err()
+}
+
+// VolumeIsAttachedTo waits for a volume to be attached to the server with the
+// given UUID.
+func VolumeIsAttachedTo(serverUUID string) Condition[Volume] {
+	return func(volume *Volume) (bool, error) {
+		if volume.ServerUUIDs != nil && slices.Contains(*volume.ServerUUIDs, serverUUID) {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for volume to be attached to %s", serverUUID)
+	}
+}
+
+// VolumeIsDetached waits for a volume to be attached to no server.
+var VolumeIsDetached Condition[Volume] = func(volume *Volume) (bool, error) {
+	if volume.ServerUUIDs == nil || len(*volume.ServerUUIDs) == 0 {
+		return true, nil
+	}
+	return false, fmt.Errorf("waiting for volume to be detached, attached to: %v", *volume.ServerUUIDs)
+}
+
+// VolumeHasSize waits for a volume to be resized to sizeGB.
+func VolumeHasSize(sizeGB int) Condition[Volume] {
+	return func(volume *Volume) (bool, error) {
+		if volume.SizeGB == sizeGB {
+			return true, nil
+		}
+		return false, fmt.Errorf("waiting for size: %d GB, current size: %d GB", sizeGB, volume.SizeGB)
+	}
+}
@@ -0,0 +1,276 @@
+package cloudscale
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// EventType describes what happened to a watched resource.
+type EventType string
+
+const (
+	// EventAdded is emitted for resources seen for the first time, including
+	// all resources found by the initial list.
+	EventAdded EventType = "Added"
+	// EventModified is emitted when a resource differs from the last poll.
+	EventModified EventType = "Modified"
+	// EventDeleted is emitted when a resource is no longer listed.
+	EventDeleted EventType = "Deleted"
+	// EventSync is emitted for every known resource on each resync.
+	EventSync EventType = "Sync"
+	// EventError is emitted when listing the resources failed. The watch
+	// continues after backing off.
+	EventError EventType = "Error"
+	// EventBookmark is emitted after the events of every successful poll if
+	// WatchOptions.Bookmarks is set. The first one marks the end of the
+	// initial list.
+	EventBookmark EventType = "Bookmark"
+)
+
+// Event is a change of a watched resource.
+type Event[TResource any] struct {
+	Type EventType
+	// ID identifies the resource: its UUID, the ID of an ObjectsUser or the
+	// IP of a FloatingIP.
+	ID string
+	// Resource is the current state of the resource, or its last known state
+	// for EventDeleted. It is nil for EventError and EventBookmark.
+	Resource *TResource
+	// Old is the previous state of the resource for EventModified.
+	Old *TResource
+	// Err is set for EventError.
+	Err error
+}
+
+const defaultWatchInterval = 30 * time.Second
+
+// WatchOptions configures a watch.
+type WatchOptions struct {
+	// Interval is the time between two polls. Defaults to 30 seconds.
+	Interval time.Duration
+
+	// ResyncPeriod, if set, makes the watch emit an EventSync for every known
+	// resource this often, so that consumers can reconcile missed events.
+	ResyncPeriod time.Duration
+
+	// Selector, if not empty, restricts the watch to resources whose tags
+	// match it. The requirements the API supports are sent with the list
+	// request. A resource that stops matching is reported as deleted.
+	Selector TagSelector
+
+	// BackOff controls the delay before polling again after a failed list
+	// request. It is reset after every successful poll. Defaults to an
+	// exponential back-off starting at Interval.
+	BackOff backoff.BackOff
+
+	// Modifiers are applied to every list request, e.g. WithTagFilter.
+	Modifiers []ListRequestModifier
+
+	// Bookmarks makes the watch emit an EventBookmark after every successful
+	// poll, e.g. to tell when a cache built from the events is complete.
+	Bookmarks bool
+}
+
+// GenericWatchService is implemented by services whose resources can be
+// watched for changes.
+type GenericWatchService[TResource any] interface {
+	Watch(ctx context.Context, interval time.Duration, modifiers ...ListRequestModifier) <-chan Event[TResource]
+	WatchWithOptions(ctx context.Context, options WatchOptions) <-chan Event[TResource]
+}
+
+// Watch polls List every interval and emits an event for every resource that
+// was added, modified or deleted since the previous poll, much like a
+// Kubernetes informer. The first poll reports all existing resources as
+// added. Failed polls are reported as EventError and retried with back-off.
+// The channel is closed once ctx is done.
+func (g GenericServiceOperations[TResource, TCreateRequest, TUpdateRequest]) Watch(
+	ctx context.Context,
+	interval time.Duration,
+	modifiers ...ListRequestModifier,
+) <-chan Event[TResource] {
+	return g.WatchWithOptions(ctx, WatchOptions{Interval: interval, Modifiers: modifiers})
+}
+
+// WatchWithOptions works like Watch, with support for resyncs, tag selectors
+// and custom back-off.
+func (g GenericServiceOperations[TResource, TCreateRequest, TUpdateRequest]) WatchWithOptions(
+	ctx context.Context,
+	options WatchOptions,
+) <-chan Event[TResource] {
+	return watch(ctx, g.List, options)
+}
+
+func watch[TResource any](
+	ctx context.Context,
+	list func(ctx context.Context, modifiers ...ListRequestModifier) ([]TResource, error),
+	options WatchOptions,
+) <-chan Event[TResource] {
+	if options.Interval <= 0 {
+		options.Interval = defaultWatchInterval
+	}
+	if options.BackOff == nil {
+		exponential := backoff.NewExponentialBackOff()
+		exponential.InitialInterval = options.Interval
+		exponential.MaxInterval = 10 * options.Interval
+		options.BackOff = exponential
+	}
+	modifiers := options.Modifiers
+	if !options.Selector.Empty() {
+		modifiers = append(append([]ListRequestModifier(nil), modifiers...), WithTagSelector(options.Selector))
+	}
+
+	events := make(chan Event[TResource])
+	w := &watcher[TResource]{
+		events:  events,
+		known:   map[string]*TResource{},
+		options: options,
+	}
+
+	go func() {
+		defer close(events)
+
+		lastResync := time.Now()
+		for {
+			delay := options.Interval
+			resources, err := list(ctx, modifiers...)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if !w.send(ctx, Event[TResource]{Type: EventError, Err: err}) {
+					return
+				}
+				if next := options.BackOff.NextBackOff(); next != backoff.Stop {
+					delay = next
+				}
+			} else {
+				options.BackOff.Reset()
+				if !w.update(ctx, resources) {
+					return
+				}
+				if options.ResyncPeriod > 0 && time.Since(lastResync) >= options.ResyncPeriod {
+					lastResync = time.Now()
+					if !w.resync(ctx) {
+						return
+					}
+				}
+				if options.Bookmarks && !w.send(ctx, Event[TResource]{Type: EventBookmark}) {
+					return
+				}
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+type watcher[TResource any] struct {
+	events  chan<- Event[TResource]
+	known   map[string]*TResource
+	options WatchOptions
+}
+
+// update compares resources to the known state and emits the differences. It
+// returns false if ctx was done before all events were sent.
+func (w *watcher[TResource]) update(ctx context.Context, resources []TResource) bool {
+	current := map[string]*TResource{}
+	for i := range resources {
+		resource := &resources[i]
+		if !w.matches(resource) {
+			continue
+		}
+		id := ResourceID(resource)
+		current[id] = resource
+
+		old, ok := w.known[id]
+		switch {
+		case !ok:
+			if !w.send(ctx, Event[TResource]{Type: EventAdded, ID: id, Resource: resource}) {
+				return false
+			}
+		case !reflect.DeepEqual(old, resource):
+			if !w.send(ctx, Event[TResource]{Type: EventModified, ID: id, Resource: resource, Old: old}) {
+				return false
+			}
+		}
+	}
+
+	deleted := []string{}
+	for id := range w.known {
+		if _, ok := current[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		if !w.send(ctx, Event[TResource]{Type: EventDeleted, ID: id, Resource: w.known[id]}) {
+			return false
+		}
+	}
+
+	w.known = current
+	return true
+}
+
+func (w *watcher[TResource]) resync(ctx context.Context) bool {
+	ids := make([]string, 0, len(w.known))
+	for id := range w.known {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if !w.send(ctx, Event[TResource]{Type: EventSync, ID: id, Resource: w.known[id]}) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches evaluates the selector client-side, as the API only supports some
+// of its requirements.
+func (w *watcher[TResource]) matches(resource *TResource) bool {
+	if w.options.Selector.Empty() {
+		return true
+	}
+	tagged, ok := any(*resource).(Tagged)
+	return !ok || w.options.Selector.Matches(tagged.GetTags())
+}
+
+func (w *watcher[TResource]) send(ctx context.Context, event Event[TResource]) bool {
+	select {
+	case w.events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// ResourceID returns the identifier Watch uses for a resource: its UUID, the
+// ID of an ObjectsUser or the IP of a FloatingIP.
+func ResourceID[TResource any](resource *TResource) string {
+	if floatingIP, ok := any(*resource).(interface{ IP() string }); ok {
+		return floatingIP.IP()
+	}
+	value := reflect.Indirect(reflect.ValueOf(resource))
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+	for _, name := range []string{"UUID", "ID"} {
+		if field := value.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+			return field.String()
+		}
+	}
+	return ""
+}
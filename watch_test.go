@@ -0,0 +1,168 @@
+package cloudscale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v5"
+)
+
+// pollResponses serves the given responses to consecutive requests, repeating
+// the last one. A response of "" fails the request.
+func pollResponses(t *testing.T, path string, responses ...string) *[]string {
+	var mu sync.Mutex
+	queries := &[]string{}
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		*queries = append(*queries, r.URL.RawQuery)
+		response := responses[0]
+		if len(responses) > 1 {
+			responses = responses[1:]
+		}
+		if response == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"detail": "try again"}`)
+			return
+		}
+		fmt.Fprint(w, response)
+	})
+	return queries
+}
+
+func collectEvents[TResource any](t *testing.T, events <-chan Event[TResource], count int) []Event[TResource] {
+	t.Helper()
+	collected := []Event[TResource]{}
+	timeout := time.After(5 * time.Second)
+	for len(collected) < count {
+		select {
+		case event := <-events:
+			collected = append(collected, event)
+		case <-timeout:
+			t.Fatalf("timed out after %d of %d events: %+v", len(collected), count, collected)
+		}
+	}
+	return collected
+}
+
+func TestWatch_Events(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pollResponses(t, "/v1/servers",
+		`[{"uuid": "a", "status": "running"}, {"uuid": "b", "status": "running"}]`,
+		"",
+		`[{"uuid": "a", "status": "stopped"}, {"uuid": "c", "status": "running"}]`,
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := client.Servers.WatchWithOptions(ctx, WatchOptions{
+		Interval: time.Millisecond,
+		BackOff:  backoff.NewConstantBackOff(time.Millisecond),
+	})
+
+	collected := collectEvents(t, events, 6)
+	expected := []struct {
+		eventType EventType
+		id        string
+	}{
+		{EventAdded, "a"}, {EventAdded, "b"}, {EventError, ""},
+		{EventModified, "a"}, {EventAdded, "c"}, {EventDeleted, "b"},
+	}
+	for i, e := range expected {
+		if collected[i].Type != e.eventType || collected[i].ID != e.id {
+			t.Errorf("event %d: expected %s %s, got %s %s", i, e.eventType, e.id, collected[i].Type, collected[i].ID)
+		}
+	}
+
+	modified := collected[3]
+	if modified.Old.Status != ServerRunning || modified.Resource.Status != ServerStopped {
+		t.Errorf("expected old and new state, got %+v", modified)
+	}
+	if collected[2].Err == nil {
+		t.Error("expected error event to carry the error")
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatch_Bookmarks(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pollResponses(t, "/v1/servers", `[]`, `[{"uuid": "a"}]`)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := client.Servers.WatchWithOptions(ctx, WatchOptions{Interval: time.Millisecond, Bookmarks: true})
+
+	collected := collectEvents(t, events, 3)
+	expected := []EventType{EventBookmark, EventAdded, EventBookmark}
+	for i, eventType := range expected {
+		if collected[i].Type != eventType {
+			t.Errorf("event %d: expected %s, got %s", i, eventType, collected[i].Type)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatch_SelectorAndResync(t *testing.T) {
+	setup()
+	defer teardown()
+
+	queries := pollResponses(t, "/v1/volumes",
+		`[{"uuid": "a", "tags": {"env": "prod"}}, {"uuid": "b", "tags": {"env": "dev"}}]`,
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := client.Volumes.WatchWithOptions(ctx, WatchOptions{
+		Interval:     time.Millisecond,
+		ResyncPeriod: time.Millisecond,
+		Selector:     MustParseTagSelector("env, env!=dev"),
+	})
+
+	collected := collectEvents(t, events, 3)
+	for i, eventType := range []EventType{EventAdded, EventSync, EventSync} {
+		if collected[i].Type != eventType || collected[i].ID != "a" {
+			t.Errorf("event %d: expected %s a, got %s %s", i, eventType, collected[i].Type, collected[i].ID)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+	if (*queries)[0] != "tag%3Aenv=" {
+		t.Errorf("expected server-side existence filter, got %q", (*queries)[0])
+	}
+}
+
+func TestWatch_FloatingIPsAndObjectsUsers(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pollResponses(t, "/v1/floating-ips", `[{"network": "192.0.2.1/32"}, {"network": "2001:db8::/56"}]`)
+	pollResponses(t, "/v1/objects-users", `[{"id": "6fe3"}]`)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	floatingIPs := collectEvents(t, client.FloatingIPs.Watch(ctx, time.Hour), 2)
+	if floatingIPs[0].ID != "192.0.2.1" || floatingIPs[1].ID != "2001:db8::" {
+		t.Errorf("expected floating IPs to be keyed by IP, got %q and %q", floatingIPs[0].ID, floatingIPs[1].ID)
+	}
+	users := collectEvents(t, client.ObjectsUsers.Watch(ctx, time.Hour), 1)
+	if users[0].ID != "6fe3" {
+		t.Errorf("expected objects users to be keyed by ID, got %q", users[0].ID)
+	}
+}